@@ -4,20 +4,31 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"github.com/dzhdmitry/link-shorter/internal/apikeys"
+	"github.com/dzhdmitry/link-shorter/internal/links"
 	"github.com/dzhdmitry/link-shorter/internal/utils"
+	"github.com/dzhdmitry/link-shorter/internal/utm"
+	"github.com/dzhdmitry/link-shorter/internal/webhooks"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 )
 
 const StorageTypeFile = "file"
 const StorageTypePostgres = "postgres"
+const StorageTypeSQLite = "sqlite"
 const CacheTypeDisabled = "disabled"
 const CacheTypeInMemory = "in-memory"
 const CacheTypeRedis = "redis"
+const SplitStickyCookie = "cookie"
+const SplitStickyHash = "hash"
 
 type Config struct {
 	ProjectHost        string `env:"PROJECT_HOST" env-default:""`
@@ -29,12 +40,176 @@ type Config struct {
 	DbMaxIdleConns     int    `env:"DB_MAX_IDLE_CONNS" env-default:"25"`
 	DbMaxIdleTime      string `env:"DB_MAX_OPEN_TIME" env-default:"15m"`
 	DbTimeout          int    `env:"DATABASE_TIMEOUT" env-default:"1"`
+	SQLitePath         string `env:"SQLITE_PATH" env-default:"tmp/storage.db"`
 	CacheType          string `env:"CACHE_TYPE" env-default:"disabled"`
 	CacheCapacity      int    `env:"CACHE_CAPACITY" env-default:"10"`
 	CacheRedisDSN      string `env:"CACHE_REDIS_DSN" env-default:"redis://localhost:6379/0"`
 	LimiterEnabled     bool   `env:"LIMITER_ENABLED" env-default:"true"`
 	LimiterRPS         int    `env:"LIMITER_RPS" env-default:"2"`
 	LimiterBurst       int    `env:"LIMITER_BURST" env-default:"4"`
+	AuthEnabled        bool   `env:"AUTH_ENABLED" env-default:"false"`
+	AdminAPIKey        string `env:"ADMIN_API_KEY" env-default:""`
+	RedirectStatus     int    `env:"REDIRECT_STATUS" env-default:"302"`
+	DedupEnabled       bool   `env:"DEDUP_ENABLED" env-default:"false"`
+	SplitSticky        string `env:"SPLIT_STICKY" env-default:"cookie"`
+	BlocklistFile      string `env:"BLOCKLIST_FILE" env-default:""`
+	BlocklistReload    string `env:"BLOCKLIST_RELOAD_INTERVAL" env-default:"10s"`
+	HealthCheck        bool   `env:"HEALTH_CHECK_ENABLED" env-default:"false"`
+	HealthInterval     string `env:"HEALTH_CHECK_INTERVAL" env-default:"1h"`
+	HealthTimeout      string `env:"HEALTH_CHECK_TIMEOUT" env-default:"10s"`
+	HealthBatchSize    int    `env:"HEALTH_CHECK_BATCH_SIZE" env-default:"100"`
+	FallbackURL        string `env:"FALLBACK_URL" env-default:""`
+	Unfurl             bool   `env:"UNFURL_ENABLED" env-default:"false"`
+	UnfurlTimeout      string `env:"UNFURL_TIMEOUT" env-default:"5s"`
+	UnfurlMaxSize      int    `env:"UNFURL_MAX_SIZE" env-default:"524288"`
+	WebhookTimeout     string `env:"WEBHOOK_TIMEOUT" env-default:"10s"`
+	WebhookRetryDelay  string `env:"WEBHOOK_RETRY_DELAY" env-default:"30s"`
+	WebhookMaxAttempts int    `env:"WEBHOOK_MAX_ATTEMPTS" env-default:"8"`
+	WebhookBufferSize  int    `env:"WEBHOOK_BUFFER_SIZE" env-default:"1000"`
+	URLNormalize       string `env:"URL_NORMALIZE" env-default:"lowercase,default-port,dot-segments,punycode,sort-query"`
+	URLTrackingParams  string `env:"URL_TRACKING_PARAMS" env-default:"utm_*,gclid,fbclid,yclid,msclkid"`
+	URLSchemes         string `env:"URL_SCHEMES" env-default:""`
+	URLAllowInternal   string `env:"URL_ALLOW_INTERNAL" env-default:""`
+	URLResolveHosts    bool   `env:"URL_RESOLVE_HOSTS" env-default:"false"`
+	PasswordAttempts   int    `env:"PASSWORD_ATTEMPTS" env-default:"5"`
+	PasswordInterval   string `env:"PASSWORD_ATTEMPTS_INTERVAL" env-default:"1m"`
+	ClicksBufferSize   int    `env:"CLICKS_BUFFER_SIZE" env-default:"1000"`
+	ClicksBatchSize    int    `env:"CLICKS_BATCH_SIZE" env-default:"100"`
+	ClicksFlushTime    string `env:"CLICKS_FLUSH_TIME" env-default:"5s"`
+	KeyAlphabet        string `env:"KEY_ALPHABET" env-default:"base36"`
+	KeySecret          string `env:"KEY_SECRET" env-default:""`
+	KeySequentialMaxID int64  `env:"KEY_SEQUENTIAL_MAX_ID" env-default:"0"`
+}
+
+func (c *Config) Validate() error {
+	switch c.RedirectStatus {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+	default:
+		return fmt.Errorf("unsupported redirect status: %d", c.RedirectStatus)
+	}
+
+	if c.SplitSticky != SplitStickyCookie && c.SplitSticky != SplitStickyHash {
+		return fmt.Errorf("unsupported sticky assignment of A/B split: %s", c.SplitSticky)
+	}
+
+	if interval, err := time.ParseDuration(c.BlocklistReload); c.BlocklistFile != "" && (err != nil || interval <= 0) {
+		return fmt.Errorf("invalid blocklist reload interval: %s", c.BlocklistReload)
+	}
+
+	if c.HealthCheck {
+		if interval, err := time.ParseDuration(c.HealthInterval); err != nil || interval <= 0 {
+			return fmt.Errorf("invalid health check interval: %s", c.HealthInterval)
+		}
+
+		if timeout, err := time.ParseDuration(c.HealthTimeout); err != nil || timeout <= 0 {
+			return fmt.Errorf("invalid health check timeout: %s", c.HealthTimeout)
+		}
+
+		if c.HealthBatchSize < 1 {
+			return errors.New("health check batch size must be positive")
+		}
+	}
+
+	if fallback, err := url.Parse(c.FallbackURL); c.FallbackURL != "" &&
+		(err != nil || (fallback.Scheme != "http" && fallback.Scheme != "https") || fallback.Host == "") {
+		return fmt.Errorf("invalid fallback URL: %s", c.FallbackURL)
+	}
+
+	if c.Unfurl {
+		if timeout, err := time.ParseDuration(c.UnfurlTimeout); err != nil || timeout <= 0 {
+			return fmt.Errorf("invalid unfurl timeout: %s", c.UnfurlTimeout)
+		}
+
+		if c.UnfurlMaxSize < 1 {
+			return errors.New("unfurl max size must be positive")
+		}
+	}
+
+	if timeout, err := time.ParseDuration(c.WebhookTimeout); err != nil || timeout <= 0 {
+		return fmt.Errorf("invalid webhook timeout: %s", c.WebhookTimeout)
+	}
+
+	if delay, err := time.ParseDuration(c.WebhookRetryDelay); err != nil || delay <= 0 {
+		return fmt.Errorf("invalid webhook retry delay: %s", c.WebhookRetryDelay)
+	}
+
+	if c.WebhookMaxAttempts < 1 || c.WebhookBufferSize < 1 {
+		return errors.New("webhook max attempts and buffer size must be positive")
+	}
+
+	if c.ClicksBufferSize < 1 || c.ClicksBatchSize < 1 {
+		return errors.New("clicks buffer and batch sizes must be positive")
+	}
+
+	flushTime, err := time.ParseDuration(c.ClicksFlushTime)
+
+	if err != nil {
+		return fmt.Errorf("invalid clicks flush time: %w", err)
+	}
+
+	// ticker of clicks recorder panics on non-positive interval
+	if flushTime <= 0 {
+		return errors.New("clicks flush time must be positive")
+	}
+
+	if c.PasswordAttempts < 1 {
+		return errors.New("password attempts must be positive")
+	}
+
+	if interval, err := time.ParseDuration(c.PasswordInterval); err != nil || interval <= 0 {
+		return fmt.Errorf("invalid password attempts interval: %s", c.PasswordInterval)
+	}
+
+	if _, err := links.NewNormalizer(c.NormalizeSteps(), c.TrackingParams()); err != nil {
+		return err
+	}
+
+	if _, err := NewURLPolicy(c.ExtraSchemes(), c.InternalExceptions(), nil); err != nil {
+		return err
+	}
+
+	if _, ok := links.Alphabets[c.KeyAlphabet]; !ok {
+		return fmt.Errorf("unknown key alphabet: %s", c.KeyAlphabet)
+	}
+
+	if c.KeySequentialMaxID < 0 {
+		return errors.New("last id with sequential key must not be negative")
+	}
+
+	return nil
+}
+
+// splitList Splits comma-separated list, blank items are skipped
+func splitList(list string) []string {
+	var items []string
+
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// NormalizeSteps Returns names of URL normalization steps in order of applying
+func (c *Config) NormalizeSteps() []string {
+	return splitList(c.URLNormalize)
+}
+
+// TrackingParams Returns names of query parameters removed by "strip-tracking" step
+func (c *Config) TrackingParams() []string {
+	return splitList(c.URLTrackingParams)
+}
+
+// ExtraSchemes Returns schemes of URLs allowed besides http and https
+func (c *Config) ExtraSchemes() []string {
+	return splitList(c.URLSchemes)
+}
+
+// InternalExceptions Returns hosts, IP addresses and CIDRs which may be destinations although they are internal
+func (c *Config) InternalExceptions() []string {
+	return splitList(c.URLAllowInternal)
 }
 
 func (c *Config) Info() string {
@@ -51,6 +226,11 @@ func (c *Config) Info() string {
 		inf.addInt(4, "Max idle connections", c.DbMaxIdleConns)
 		inf.addString(4, "Max idle time", c.DbMaxIdleTime)
 		inf.addInt(4, "Timeout (seconds)", c.DbTimeout)
+	} else if c.ProjectStorageType == StorageTypeSQLite {
+		inf.addString(4, "Path", c.SQLitePath)
+		inf.addInt(4, "Timeout (seconds)", c.DbTimeout)
+		// only links are kept in database
+		inf.addString(4, "Kept in files", "clicks, API keys, UTM templates, webhooks")
 	}
 
 	inf.addString(2, "Cache", c.CacheType)
@@ -61,6 +241,85 @@ func (c *Config) Info() string {
 		inf.addString(4, "Redis DSN", c.CacheRedisDSN)
 	}
 
+	if c.KeySecret == "" {
+		inf.addString(2, "Keys", "sequential")
+	} else {
+		inf.addString(2, "Keys", "non-enumerable")
+		sequentialMaxID := strconv.FormatInt(c.KeySequentialMaxID, 10)
+
+		// last stored id is recorded when the secret is enabled
+		if c.KeySequentialMaxID == 0 {
+			sequentialMaxID = "recorded in storage"
+		}
+
+		inf.addString(4, "Sequential until id", sequentialMaxID)
+	}
+
+	inf.addString(4, "Alphabet", c.KeyAlphabet)
+
+	inf.addBool(2, "API keys required", c.AuthEnabled)
+	inf.addBool(4, "Admin key configured", c.AdminAPIKey != "")
+	inf.addInt(2, "Redirect status", c.RedirectStatus)
+	inf.addBool(2, "Deduplicate URLs", c.DedupEnabled)
+	inf.addString(2, "A/B split sticky by", c.SplitSticky)
+
+	if c.BlocklistFile == "" {
+		inf.addString(2, "Blocklist", "disabled")
+	} else {
+		inf.addString(2, "Blocklist", c.BlocklistFile)
+		inf.addString(4, "Reload interval", c.BlocklistReload)
+	}
+
+	inf.addBool(2, "Health check enabled", c.HealthCheck)
+
+	if c.HealthCheck {
+		inf.addString(4, "Check interval", c.HealthInterval)
+		inf.addString(4, "Request timeout", c.HealthTimeout)
+		inf.addInt(4, "Batch size", c.HealthBatchSize)
+	}
+
+	if c.FallbackURL == "" {
+		inf.addString(2, "Fallback URL", "none")
+	} else {
+		inf.addString(2, "Fallback URL", c.FallbackURL)
+	}
+
+	inf.addBool(2, "Unfurl enabled", c.Unfurl)
+
+	if c.Unfurl {
+		inf.addString(4, "Request timeout", c.UnfurlTimeout)
+		inf.addInt(4, "Max page size", c.UnfurlMaxSize)
+	}
+
+	inf.addString(2, "Webhook timeout", c.WebhookTimeout)
+	inf.addString(4, "Retry delay", c.WebhookRetryDelay)
+	inf.addInt(4, "Max attempts", c.WebhookMaxAttempts)
+	inf.addInt(4, "Buffer size", c.WebhookBufferSize)
+
+	if len(c.NormalizeSteps()) == 0 {
+		inf.addString(2, "URL normalization", "disabled")
+	} else {
+		inf.addString(2, "URL normalization", strings.Join(c.NormalizeSteps(), ", "))
+	}
+
+	if slices.Contains(c.NormalizeSteps(), links.NormalizeStripTracking) {
+		inf.addString(4, "Tracking parameters", strings.Join(c.TrackingParams(), ", "))
+	}
+
+	inf.addString(2, "URL schemes", strings.Join(append(slices.Clone(defaultSchemes), c.ExtraSchemes()...), ", "))
+
+	if len(c.InternalExceptions()) == 0 {
+		inf.addString(2, "Internal URLs allowed", "none")
+	} else {
+		inf.addString(2, "Internal URLs allowed", strings.Join(c.InternalExceptions(), ", "))
+	}
+
+	inf.addBool(4, "Resolve hosts", c.URLResolveHosts)
+	inf.addInt(2, "Password attempts", c.PasswordAttempts)
+	inf.addString(4, "Restored every", c.PasswordInterval)
+	inf.addInt(2, "Clicks buffer size", c.ClicksBufferSize)
+	inf.addInt(4, "Batch size", c.ClicksBatchSize)
+	inf.addString(4, "Flush time", c.ClicksFlushTime)
 	inf.addBool(2, "Rate limiter enabled", c.LimiterEnabled)
 
 	if c.LimiterEnabled {
@@ -72,18 +331,89 @@ func (c *Config) Info() string {
 }
 
 type LinksCollectionInterface interface {
-	GenerateKey(URL string) (string, error)
-	GenerateKeys(URLs []string) (map[string]string, error)
-	GetURL(key string) (string, error)
-	GetURLs(keys []string) (map[string]string, error)
+	GenerateKey(URL string, options links.LinkOptions) (string, error)
+	GenerateKeys(URLs []string, options links.LinkOptions) (map[string]string, error)
+	GenerateUniqueKeys(URLs []string, options links.LinkOptions) (map[string]string, map[string]bool, error)
+	GenerateAlias(alias, URL string, options links.LinkOptions) (string, error)
+	GetLink(key string) (links.Link, error)
+	GetLinks(keys []string) (map[string]links.Link, error)
+	UpdateLink(key string, update links.LinkUpdate) (links.Link, error)
+	DeleteLink(key string) (links.Link, error)
+	ListLinks(query links.ListQuery) ([]links.Link, string, error)
+}
+
+type ClicksRecorderInterface interface {
+	Record(click links.Click)
+	Close()
+}
+
+type StatsInterface interface {
+	GetStats(key string, from, to time.Time) (links.LinkStats, error)
+}
+
+type APIKeysInterface interface {
+	StoreKey(key apikeys.Key) error
+	GetKey(hash string) (apikeys.Key, error)
+	RevokeKey(id string, revokedAt time.Time) (apikeys.Key, error)
+}
+
+type UTMTemplatesInterface interface {
+	StoreTemplate(template utm.Template) error
+	GetTemplate(name string) (utm.Template, error)
+	ListTemplates() ([]utm.Template, error)
+	DeleteTemplate(name string) error
+}
+
+type BlocklistInterface interface {
+	Match(URL string) (string, bool)
+	Close()
+}
+
+type HealthCheckerInterface interface {
+	Close()
+}
+
+type UnfurlerInterface interface {
+	// Unfurl Fetches preview of destination of new link in background
+	Unfurl(key, URL string)
+	Close()
+}
+
+type WebhooksInterface interface {
+	StoreSubscription(subscription webhooks.Subscription) error
+	ListSubscriptions() ([]webhooks.Subscription, error)
+	DeleteSubscription(id string) (webhooks.Subscription, error)
+}
+
+type DispatcherInterface interface {
+	// Publish Sends event with data to subscribed endpoints in background
+	Publish(event string, data any)
+	Close()
 }
 
 type Application struct {
-	Config     Config
-	Logger     *utils.Logger
-	Validator  Validator
-	Links      LinksCollectionInterface
-	Background *utils.Background
+	Config        Config
+	Logger        *utils.Logger
+	Clock         utils.ClockInterface
+	Random        utils.RandomInterface
+	Validator     Validator
+	Normalizer    *links.Normalizer
+	Links         LinksCollectionInterface
+	Clicks        ClicksRecorderInterface
+	Stats         StatsInterface
+	APIKeys       APIKeysInterface
+	UTMTemplates  UTMTemplatesInterface
+	Blocklist     BlocklistInterface
+	HealthChecker HealthCheckerInterface
+	Unfurler      UnfurlerInterface
+	Webhooks      WebhooksInterface
+	Dispatcher    DispatcherInterface
+	Background    *utils.Background
+
+	passwordAttempts     *limiters
+	passwordLimitersOnce sync.Once
+	apiKeyUsage          *keyUsage
+	apiKeyUsageOnce      sync.Once
 }
 
 func (app *Application) Serve() error {
@@ -110,11 +440,33 @@ func (app *Application) Serve() error {
 
 		defer cancel()
 
+		err := server.Shutdown(ctx)
+
+		app.Logger.LogInfo("drain clicks buffer...")
+		app.Clicks.Close()
+
+		if app.Dispatcher != nil {
+			app.Logger.LogInfo("drain webhook events...")
+			app.Dispatcher.Close()
+		}
+
+		if app.Blocklist != nil {
+			app.Blocklist.Close()
+		}
+
+		if app.HealthChecker != nil {
+			app.HealthChecker.Close()
+		}
+
+		if app.Unfurler != nil {
+			app.Unfurler.Close()
+		}
+
 		app.Logger.LogInfo("wait for background tasks...")
 		app.Background.Wait()
 		app.Logger.LogInfo("background tasks completed")
 
-		shutdownError <- server.Shutdown(ctx)
+		shutdownError <- err
 	}()
 
 	err := server.ListenAndServe()
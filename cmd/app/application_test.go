@@ -10,6 +10,18 @@ func TestInfo(t *testing.T) {
 		ProjectPort:        80,
 		ProjectStorageType: StorageTypeFile,
 		CacheType:          CacheTypeDisabled,
+		KeyAlphabet:        "base36",
+		RedirectStatus:     302,
+		SplitSticky:        "cookie",
+		PasswordAttempts:   5,
+		PasswordInterval:   "1m",
+		ClicksBufferSize:   1000,
+		ClicksBatchSize:    100,
+		ClicksFlushTime:    "5s",
+		WebhookTimeout:     "10s",
+		WebhookRetryDelay:  "30s",
+		WebhookMaxAttempts: 8,
+		WebhookBufferSize:  1000,
 		LimiterEnabled:     true,
 		LimiterRPS:         2,
 		LimiterBurst:       4,
@@ -20,11 +32,148 @@ func TestInfo(t *testing.T) {
 		"  Storage:                file\n"+
 		"    Async:                false\n"+
 		"  Cache:                  disabled\n"+
+		"  Keys:                   sequential\n"+
+		"    Alphabet:             base36\n"+
+		"  API keys required:      false\n"+
+		"    Admin key configured: false\n"+
+		"  Redirect status:        302\n"+
+		"  Deduplicate URLs:       false\n"+
+		"  A/B split sticky by:    cookie\n"+
+		"  Blocklist:              disabled\n"+
+		"  Health check enabled:   false\n"+
+		"  Fallback URL:           none\n"+
+		"  Unfurl enabled:         false\n"+
+		"  Webhook timeout:        10s\n"+
+		"    Retry delay:          30s\n"+
+		"    Max attempts:         8\n"+
+		"    Buffer size:          1000\n"+
+		"  URL normalization:      disabled\n"+
+		"  URL schemes:            http, https\n"+
+		"  Internal URLs allowed:  none\n"+
+		"    Resolve hosts:        false\n"+
+		"  Password attempts:      5\n"+
+		"    Restored every:       1m\n"+
+		"  Clicks buffer size:     1000\n"+
+		"    Batch size:           100\n"+
+		"    Flush time:           5s\n"+
 		"  Rate limiter enabled:   true\n"+
 		"    RPS per IP:           2\n"+
 		"    Maximum burst:        4", config.Info())
 }
 
+func TestInfoKeys(t *testing.T) {
+	config := Config{
+		ProjectPort:        80,
+		ProjectStorageType: StorageTypeFile,
+		CacheType:          CacheTypeDisabled,
+		KeySecret:          "secret",
+		KeySequentialMaxID: 1000,
+		KeyAlphabet:        "base36",
+		RedirectStatus:     302,
+		SplitSticky:        "cookie",
+		PasswordAttempts:   5,
+		PasswordInterval:   "1m",
+		ClicksBufferSize:   1000,
+		ClicksBatchSize:    100,
+		ClicksFlushTime:    "5s",
+		WebhookTimeout:     "10s",
+		WebhookRetryDelay:  "30s",
+		WebhookMaxAttempts: 8,
+		WebhookBufferSize:  1000,
+		LimiterEnabled:     false,
+	}
+
+	assert.Equal(t, "Using config:\n"+
+		"  Start server on:        \":80\"\n"+
+		"  Storage:                file\n"+
+		"    Async:                false\n"+
+		"  Cache:                  disabled\n"+
+		"  Keys:                   non-enumerable\n"+
+		"    Sequential until id:  1000\n"+
+		"    Alphabet:             base36\n"+
+		"  API keys required:      false\n"+
+		"    Admin key configured: false\n"+
+		"  Redirect status:        302\n"+
+		"  Deduplicate URLs:       false\n"+
+		"  A/B split sticky by:    cookie\n"+
+		"  Blocklist:              disabled\n"+
+		"  Health check enabled:   false\n"+
+		"  Fallback URL:           none\n"+
+		"  Unfurl enabled:         false\n"+
+		"  Webhook timeout:        10s\n"+
+		"    Retry delay:          30s\n"+
+		"    Max attempts:         8\n"+
+		"    Buffer size:          1000\n"+
+		"  URL normalization:      disabled\n"+
+		"  URL schemes:            http, https\n"+
+		"  Internal URLs allowed:  none\n"+
+		"    Resolve hosts:        false\n"+
+		"  Password attempts:      5\n"+
+		"    Restored every:       1m\n"+
+		"  Clicks buffer size:     1000\n"+
+		"    Batch size:           100\n"+
+		"    Flush time:           5s\n"+
+		"  Rate limiter enabled:   false", config.Info())
+}
+
+func TestInfoNormalization(t *testing.T) {
+	config := Config{
+		ProjectPort:        80,
+		ProjectStorageType: StorageTypeFile,
+		CacheType:          CacheTypeDisabled,
+		KeyAlphabet:        "base36",
+		RedirectStatus:     302,
+		SplitSticky:        "cookie",
+		URLNormalize:       "lowercase, sort-query,strip-tracking",
+		URLSchemes:         "mailto,myapp",
+		URLAllowInternal:   "10.1.0.0/16,intranet",
+		URLResolveHosts:    true,
+		URLTrackingParams:  "utm_*,gclid",
+		PasswordAttempts:   5,
+		PasswordInterval:   "1m",
+		ClicksBufferSize:   1000,
+		ClicksBatchSize:    100,
+		ClicksFlushTime:    "5s",
+		WebhookTimeout:     "10s",
+		WebhookRetryDelay:  "30s",
+		WebhookMaxAttempts: 8,
+		WebhookBufferSize:  1000,
+		LimiterEnabled:     false,
+	}
+
+	assert.Equal(t, "Using config:\n"+
+		"  Start server on:        \":80\"\n"+
+		"  Storage:                file\n"+
+		"    Async:                false\n"+
+		"  Cache:                  disabled\n"+
+		"  Keys:                   sequential\n"+
+		"    Alphabet:             base36\n"+
+		"  API keys required:      false\n"+
+		"    Admin key configured: false\n"+
+		"  Redirect status:        302\n"+
+		"  Deduplicate URLs:       false\n"+
+		"  A/B split sticky by:    cookie\n"+
+		"  Blocklist:              disabled\n"+
+		"  Health check enabled:   false\n"+
+		"  Fallback URL:           none\n"+
+		"  Unfurl enabled:         false\n"+
+		"  Webhook timeout:        10s\n"+
+		"    Retry delay:          30s\n"+
+		"    Max attempts:         8\n"+
+		"    Buffer size:          1000\n"+
+		"  URL normalization:      lowercase, sort-query, strip-tracking\n"+
+		"    Tracking parameters:  utm_*, gclid\n"+
+		"  URL schemes:            http, https, mailto, myapp\n"+
+		"  Internal URLs allowed:  10.1.0.0/16, intranet\n"+
+		"    Resolve hosts:        true\n"+
+		"  Password attempts:      5\n"+
+		"    Restored every:       1m\n"+
+		"  Clicks buffer size:     1000\n"+
+		"    Batch size:           100\n"+
+		"    Flush time:           5s\n"+
+		"  Rate limiter enabled:   false", config.Info())
+}
+
 func TestInfoPostgres(t *testing.T) {
 	config := Config{
 		ProjectPort:        80,
@@ -35,6 +184,18 @@ func TestInfoPostgres(t *testing.T) {
 		DbMaxIdleTime:      "15m",
 		DbTimeout:          1,
 		CacheType:          CacheTypeDisabled,
+		KeyAlphabet:        "base36",
+		RedirectStatus:     302,
+		SplitSticky:        "cookie",
+		PasswordAttempts:   5,
+		PasswordInterval:   "1m",
+		ClicksBufferSize:   1000,
+		ClicksBatchSize:    100,
+		ClicksFlushTime:    "5s",
+		WebhookTimeout:     "10s",
+		WebhookRetryDelay:  "30s",
+		WebhookMaxAttempts: 8,
+		WebhookBufferSize:  1000,
 		LimiterEnabled:     false,
 	}
 
@@ -47,6 +208,86 @@ func TestInfoPostgres(t *testing.T) {
 		"    Max idle time:        15m\n"+
 		"    Timeout (seconds):    1\n"+
 		"  Cache:                  disabled\n"+
+		"  Keys:                   sequential\n"+
+		"    Alphabet:             base36\n"+
+		"  API keys required:      false\n"+
+		"    Admin key configured: false\n"+
+		"  Redirect status:        302\n"+
+		"  Deduplicate URLs:       false\n"+
+		"  A/B split sticky by:    cookie\n"+
+		"  Blocklist:              disabled\n"+
+		"  Health check enabled:   false\n"+
+		"  Fallback URL:           none\n"+
+		"  Unfurl enabled:         false\n"+
+		"  Webhook timeout:        10s\n"+
+		"    Retry delay:          30s\n"+
+		"    Max attempts:         8\n"+
+		"    Buffer size:          1000\n"+
+		"  URL normalization:      disabled\n"+
+		"  URL schemes:            http, https\n"+
+		"  Internal URLs allowed:  none\n"+
+		"    Resolve hosts:        false\n"+
+		"  Password attempts:      5\n"+
+		"    Restored every:       1m\n"+
+		"  Clicks buffer size:     1000\n"+
+		"    Batch size:           100\n"+
+		"    Flush time:           5s\n"+
+		"  Rate limiter enabled:   false", config.Info())
+}
+
+func TestInfoSQLite(t *testing.T) {
+	config := Config{
+		ProjectPort:        80,
+		ProjectStorageType: StorageTypeSQLite,
+		DbTimeout:          1,
+		SQLitePath:         "tmp/storage.db",
+		CacheType:          CacheTypeDisabled,
+		KeyAlphabet:        "base36",
+		RedirectStatus:     302,
+		SplitSticky:        "cookie",
+		PasswordAttempts:   5,
+		PasswordInterval:   "1m",
+		ClicksBufferSize:   1000,
+		ClicksBatchSize:    100,
+		ClicksFlushTime:    "5s",
+		WebhookTimeout:     "10s",
+		WebhookRetryDelay:  "30s",
+		WebhookMaxAttempts: 8,
+		WebhookBufferSize:  1000,
+		LimiterEnabled:     false,
+	}
+
+	assert.Equal(t, "Using config:\n"+
+		"  Start server on:        \":80\"\n"+
+		"  Storage:                sqlite\n"+
+		"    Path:                 tmp/storage.db\n"+
+		"    Timeout (seconds):    1\n"+
+		"    Kept in files:        clicks, API keys, UTM templates, webhooks\n"+
+		"  Cache:                  disabled\n"+
+		"  Keys:                   sequential\n"+
+		"    Alphabet:             base36\n"+
+		"  API keys required:      false\n"+
+		"    Admin key configured: false\n"+
+		"  Redirect status:        302\n"+
+		"  Deduplicate URLs:       false\n"+
+		"  A/B split sticky by:    cookie\n"+
+		"  Blocklist:              disabled\n"+
+		"  Health check enabled:   false\n"+
+		"  Fallback URL:           none\n"+
+		"  Unfurl enabled:         false\n"+
+		"  Webhook timeout:        10s\n"+
+		"    Retry delay:          30s\n"+
+		"    Max attempts:         8\n"+
+		"    Buffer size:          1000\n"+
+		"  URL normalization:      disabled\n"+
+		"  URL schemes:            http, https\n"+
+		"  Internal URLs allowed:  none\n"+
+		"    Resolve hosts:        false\n"+
+		"  Password attempts:      5\n"+
+		"    Restored every:       1m\n"+
+		"  Clicks buffer size:     1000\n"+
+		"    Batch size:           100\n"+
+		"    Flush time:           5s\n"+
 		"  Rate limiter enabled:   false", config.Info())
 }
 
@@ -56,6 +297,18 @@ func TestInfoCacheInMemory(t *testing.T) {
 		ProjectStorageType: StorageTypeFile,
 		CacheType:          CacheTypeInMemory,
 		CacheCapacity:      10,
+		KeyAlphabet:        "base36",
+		RedirectStatus:     302,
+		SplitSticky:        "cookie",
+		PasswordAttempts:   5,
+		PasswordInterval:   "1m",
+		ClicksBufferSize:   1000,
+		ClicksBatchSize:    100,
+		ClicksFlushTime:    "5s",
+		WebhookTimeout:     "10s",
+		WebhookRetryDelay:  "30s",
+		WebhookMaxAttempts: 8,
+		WebhookBufferSize:  1000,
 		LimiterEnabled:     false,
 	}
 
@@ -65,6 +318,30 @@ func TestInfoCacheInMemory(t *testing.T) {
 		"    Async:                false\n"+
 		"  Cache:                  in-memory\n"+
 		"    Capacity of cache:    10\n"+
+		"  Keys:                   sequential\n"+
+		"    Alphabet:             base36\n"+
+		"  API keys required:      false\n"+
+		"    Admin key configured: false\n"+
+		"  Redirect status:        302\n"+
+		"  Deduplicate URLs:       false\n"+
+		"  A/B split sticky by:    cookie\n"+
+		"  Blocklist:              disabled\n"+
+		"  Health check enabled:   false\n"+
+		"  Fallback URL:           none\n"+
+		"  Unfurl enabled:         false\n"+
+		"  Webhook timeout:        10s\n"+
+		"    Retry delay:          30s\n"+
+		"    Max attempts:         8\n"+
+		"    Buffer size:          1000\n"+
+		"  URL normalization:      disabled\n"+
+		"  URL schemes:            http, https\n"+
+		"  Internal URLs allowed:  none\n"+
+		"    Resolve hosts:        false\n"+
+		"  Password attempts:      5\n"+
+		"    Restored every:       1m\n"+
+		"  Clicks buffer size:     1000\n"+
+		"    Batch size:           100\n"+
+		"    Flush time:           5s\n"+
 		"  Rate limiter enabled:   false", config.Info())
 }
 
@@ -74,6 +351,28 @@ func TestInfoCacheRedis(t *testing.T) {
 		ProjectStorageType: StorageTypeFile,
 		CacheType:          CacheTypeRedis,
 		CacheRedisDSN:      "redis://redis:6379/0",
+		KeyAlphabet:        "base36",
+		RedirectStatus:     302,
+		SplitSticky:        "cookie",
+		BlocklistFile:      "blocklist.txt",
+		BlocklistReload:    "10s",
+		HealthCheck:        true,
+		HealthInterval:     "1h",
+		HealthTimeout:      "10s",
+		HealthBatchSize:    100,
+		FallbackURL:        "https://example.com/maintenance",
+		Unfurl:             true,
+		UnfurlTimeout:      "5s",
+		UnfurlMaxSize:      524288,
+		PasswordAttempts:   5,
+		PasswordInterval:   "1m",
+		ClicksBufferSize:   1000,
+		ClicksBatchSize:    100,
+		ClicksFlushTime:    "5s",
+		WebhookTimeout:     "10s",
+		WebhookRetryDelay:  "30s",
+		WebhookMaxAttempts: 8,
+		WebhookBufferSize:  1000,
 		LimiterEnabled:     false,
 	}
 
@@ -83,5 +382,476 @@ func TestInfoCacheRedis(t *testing.T) {
 		"    Async:                false\n"+
 		"  Cache:                  redis\n"+
 		"    Redis DSN:            redis://redis:6379/0\n"+
+		"  Keys:                   sequential\n"+
+		"    Alphabet:             base36\n"+
+		"  API keys required:      false\n"+
+		"    Admin key configured: false\n"+
+		"  Redirect status:        302\n"+
+		"  Deduplicate URLs:       false\n"+
+		"  A/B split sticky by:    cookie\n"+
+		"  Blocklist:              blocklist.txt\n"+
+		"    Reload interval:      10s\n"+
+		"  Health check enabled:   true\n"+
+		"    Check interval:       1h\n"+
+		"    Request timeout:      10s\n"+
+		"    Batch size:           100\n"+
+		"  Fallback URL:           https://example.com/maintenance\n"+
+		"  Unfurl enabled:         true\n"+
+		"    Request timeout:      5s\n"+
+		"    Max page size:        524288\n"+
+		"  Webhook timeout:        10s\n"+
+		"    Retry delay:          30s\n"+
+		"    Max attempts:         8\n"+
+		"    Buffer size:          1000\n"+
+		"  URL normalization:      disabled\n"+
+		"  URL schemes:            http, https\n"+
+		"  Internal URLs allowed:  none\n"+
+		"    Resolve hosts:        false\n"+
+		"  Password attempts:      5\n"+
+		"    Restored every:       1m\n"+
+		"  Clicks buffer size:     1000\n"+
+		"    Batch size:           100\n"+
+		"    Flush time:           5s\n"+
 		"  Rate limiter enabled:   false", config.Info())
 }
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name           string
+		redirectStatus int
+		expectedError  string
+	}{
+		{"Moved permanently", 301, ""},
+		{"Found", 302, ""},
+		{"Temporary redirect", 307, ""},
+		{"Permanent redirect", 308, ""},
+		{"Not a redirect", 200, "unsupported redirect status: 200"},
+		{"See other", 303, "unsupported redirect status: 303"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{
+				KeyAlphabet:        "base36",
+				RedirectStatus:     tt.redirectStatus,
+				SplitSticky:        "cookie",
+				PasswordAttempts:   5,
+				PasswordInterval:   "1m",
+				ClicksBufferSize:   1000,
+				ClicksBatchSize:    100,
+				ClicksFlushTime:    "5s",
+				WebhookTimeout:     "10s",
+				WebhookRetryDelay:  "30s",
+				WebhookMaxAttempts: 8,
+				WebhookBufferSize:  1000,
+			}
+			err := config.Validate()
+
+			if tt.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expectedError)
+			}
+		})
+	}
+}
+
+func TestValidateClicks(t *testing.T) {
+	tests := []struct {
+		name          string
+		bufferSize    int
+		batchSize     int
+		flushTime     string
+		expectedError string
+	}{
+		{"Valid", 1000, 100, "5s", ""},
+		{"Empty buffer", 0, 100, "5s", "clicks buffer and batch sizes must be positive"},
+		{"Empty batch", 1000, 0, "5s", "clicks buffer and batch sizes must be positive"},
+		{"Invalid flush time", 1000, 100, "5 seconds", "invalid clicks flush time: time: unknown unit \" seconds\" in duration \"5 seconds\""},
+		{"Zero flush time", 1000, 100, "0s", "clicks flush time must be positive"},
+		{"Negative flush time", 1000, 100, "-5s", "clicks flush time must be positive"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{
+				KeyAlphabet:        "base36",
+				RedirectStatus:     302,
+				SplitSticky:        "cookie",
+				PasswordAttempts:   5,
+				PasswordInterval:   "1m",
+				ClicksBufferSize:   tt.bufferSize,
+				ClicksBatchSize:    tt.batchSize,
+				ClicksFlushTime:    tt.flushTime,
+				WebhookTimeout:     "10s",
+				WebhookRetryDelay:  "30s",
+				WebhookMaxAttempts: 8,
+				WebhookBufferSize:  1000,
+			}
+			err := config.Validate()
+
+			if tt.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expectedError)
+			}
+		})
+	}
+}
+
+func TestValidateKeyAlphabet(t *testing.T) {
+	tests := []struct {
+		name          string
+		alphabet      string
+		expectedError string
+	}{
+		{"Base36", "base36", ""},
+		{"Base62", "base62", ""},
+		{"Human-safe", "human-safe", ""},
+		{"Unknown", "base64", "unknown key alphabet: base64"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{
+				KeyAlphabet:        tt.alphabet,
+				RedirectStatus:     302,
+				SplitSticky:        "cookie",
+				PasswordAttempts:   5,
+				PasswordInterval:   "1m",
+				ClicksBufferSize:   1000,
+				ClicksBatchSize:    100,
+				ClicksFlushTime:    "5s",
+				WebhookTimeout:     "10s",
+				WebhookRetryDelay:  "30s",
+				WebhookMaxAttempts: 8,
+				WebhookBufferSize:  1000,
+			}
+			err := config.Validate()
+
+			if tt.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expectedError)
+			}
+		})
+	}
+}
+
+func TestValidateURLNormalize(t *testing.T) {
+	config := Config{
+		KeyAlphabet:        "base36",
+		RedirectStatus:     302,
+		SplitSticky:        "cookie",
+		URLNormalize:       "lowercase,lowercase-path",
+		PasswordAttempts:   5,
+		PasswordInterval:   "1m",
+		ClicksBufferSize:   1000,
+		ClicksBatchSize:    100,
+		ClicksFlushTime:    "5s",
+		WebhookTimeout:     "10s",
+		WebhookRetryDelay:  "30s",
+		WebhookMaxAttempts: 8,
+		WebhookBufferSize:  1000,
+	}
+
+	assert.EqualError(t, config.Validate(), "unknown URL normalization step: lowercase-path")
+}
+
+func TestValidateSplitSticky(t *testing.T) {
+	tests := []struct {
+		name          string
+		sticky        string
+		expectedError string
+	}{
+		{"Cookie", SplitStickyCookie, ""},
+		{"Hash", SplitStickyHash, ""},
+		{"Unknown", "session", "unsupported sticky assignment of A/B split: session"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{
+				KeyAlphabet:        "base36",
+				RedirectStatus:     302,
+				SplitSticky:        tt.sticky,
+				PasswordAttempts:   5,
+				PasswordInterval:   "1m",
+				ClicksBufferSize:   1000,
+				ClicksBatchSize:    100,
+				ClicksFlushTime:    "5s",
+				WebhookTimeout:     "10s",
+				WebhookRetryDelay:  "30s",
+				WebhookMaxAttempts: 8,
+				WebhookBufferSize:  1000,
+			}
+			err := config.Validate()
+
+			if tt.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expectedError)
+			}
+		})
+	}
+}
+
+func TestValidateBlocklistReload(t *testing.T) {
+	tests := []struct {
+		name          string
+		file          string
+		reload        string
+		expectedError string
+	}{
+		{"Valid", "blocklist.txt", "10s", ""},
+		{"Disabled", "", "", ""},
+		{"Invalid", "blocklist.txt", "often", "invalid blocklist reload interval: often"},
+		{"Zero", "blocklist.txt", "0s", "invalid blocklist reload interval: 0s"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{
+				KeyAlphabet:        "base36",
+				RedirectStatus:     302,
+				SplitSticky:        "cookie",
+				BlocklistFile:      tt.file,
+				BlocklistReload:    tt.reload,
+				PasswordAttempts:   5,
+				PasswordInterval:   "1m",
+				ClicksBufferSize:   1000,
+				ClicksBatchSize:    100,
+				ClicksFlushTime:    "5s",
+				WebhookTimeout:     "10s",
+				WebhookRetryDelay:  "30s",
+				WebhookMaxAttempts: 8,
+				WebhookBufferSize:  1000,
+			}
+			err := config.Validate()
+
+			if tt.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expectedError)
+			}
+		})
+	}
+}
+
+func TestValidateHealthCheck(t *testing.T) {
+	tests := []struct {
+		name          string
+		enabled       bool
+		interval      string
+		timeout       string
+		batchSize     int
+		expectedError string
+	}{
+		{"Valid", true, "1h", "10s", 100, ""},
+		{"Disabled", false, "", "", 0, ""},
+		{"Invalid interval", true, "hourly", "10s", 100, "invalid health check interval: hourly"},
+		{"Zero interval", true, "0s", "10s", 100, "invalid health check interval: 0s"},
+		{"Invalid timeout", true, "1h", "-1s", 100, "invalid health check timeout: -1s"},
+		{"Zero batch size", true, "1h", "10s", 0, "health check batch size must be positive"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{
+				KeyAlphabet:        "base36",
+				RedirectStatus:     302,
+				SplitSticky:        "cookie",
+				HealthCheck:        tt.enabled,
+				HealthInterval:     tt.interval,
+				HealthTimeout:      tt.timeout,
+				HealthBatchSize:    tt.batchSize,
+				PasswordAttempts:   5,
+				PasswordInterval:   "1m",
+				ClicksBufferSize:   1000,
+				ClicksBatchSize:    100,
+				ClicksFlushTime:    "5s",
+				WebhookTimeout:     "10s",
+				WebhookRetryDelay:  "30s",
+				WebhookMaxAttempts: 8,
+				WebhookBufferSize:  1000,
+			}
+			err := config.Validate()
+
+			if tt.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expectedError)
+			}
+		})
+	}
+}
+
+func TestValidateFallbackURL(t *testing.T) {
+	tests := []struct {
+		name          string
+		fallback      string
+		expectedError string
+	}{
+		{"Empty", "", ""},
+		{"Valid", "https://example.com/maintenance", ""},
+		{"Relative", "/maintenance", "invalid fallback URL: /maintenance"},
+		{"Unsupported scheme", "ftp://example.com", "invalid fallback URL: ftp://example.com"},
+		{"Without host", "https://", "invalid fallback URL: https://"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{
+				KeyAlphabet:        "base36",
+				RedirectStatus:     302,
+				SplitSticky:        "cookie",
+				FallbackURL:        tt.fallback,
+				PasswordAttempts:   5,
+				PasswordInterval:   "1m",
+				ClicksBufferSize:   1000,
+				ClicksBatchSize:    100,
+				ClicksFlushTime:    "5s",
+				WebhookTimeout:     "10s",
+				WebhookRetryDelay:  "30s",
+				WebhookMaxAttempts: 8,
+				WebhookBufferSize:  1000,
+			}
+			err := config.Validate()
+
+			if tt.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expectedError)
+			}
+		})
+	}
+}
+
+func TestValidateUnfurl(t *testing.T) {
+	tests := []struct {
+		name          string
+		enabled       bool
+		timeout       string
+		maxSize       int
+		expectedError string
+	}{
+		{"Valid", true, "5s", 524288, ""},
+		{"Disabled", false, "", 0, ""},
+		{"Invalid timeout", true, "soon", 524288, "invalid unfurl timeout: soon"},
+		{"Zero timeout", true, "0s", 524288, "invalid unfurl timeout: 0s"},
+		{"Zero max size", true, "5s", 0, "unfurl max size must be positive"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{
+				KeyAlphabet:        "base36",
+				RedirectStatus:     302,
+				SplitSticky:        "cookie",
+				Unfurl:             tt.enabled,
+				UnfurlTimeout:      tt.timeout,
+				UnfurlMaxSize:      tt.maxSize,
+				PasswordAttempts:   5,
+				PasswordInterval:   "1m",
+				ClicksBufferSize:   1000,
+				ClicksBatchSize:    100,
+				ClicksFlushTime:    "5s",
+				WebhookTimeout:     "10s",
+				WebhookRetryDelay:  "30s",
+				WebhookMaxAttempts: 8,
+				WebhookBufferSize:  1000,
+			}
+			err := config.Validate()
+
+			if tt.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expectedError)
+			}
+		})
+	}
+}
+
+func TestValidateWebhooks(t *testing.T) {
+	tests := []struct {
+		name          string
+		timeout       string
+		retryDelay    string
+		maxAttempts   int
+		bufferSize    int
+		expectedError string
+	}{
+		{"Valid", "10s", "30s", 8, 1000, ""},
+		{"Invalid timeout", "soon", "30s", 8, 1000, "invalid webhook timeout: soon"},
+		{"Zero retry delay", "10s", "0s", 8, 1000, "invalid webhook retry delay: 0s"},
+		{"Zero max attempts", "10s", "30s", 0, 1000, "webhook max attempts and buffer size must be positive"},
+		{"Zero buffer", "10s", "30s", 8, 0, "webhook max attempts and buffer size must be positive"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{
+				KeyAlphabet:        "base36",
+				RedirectStatus:     302,
+				SplitSticky:        "cookie",
+				PasswordAttempts:   5,
+				PasswordInterval:   "1m",
+				ClicksBufferSize:   1000,
+				ClicksBatchSize:    100,
+				ClicksFlushTime:    "5s",
+				WebhookTimeout:     tt.timeout,
+				WebhookRetryDelay:  tt.retryDelay,
+				WebhookMaxAttempts: tt.maxAttempts,
+				WebhookBufferSize:  tt.bufferSize,
+			}
+			err := config.Validate()
+
+			if tt.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expectedError)
+			}
+		})
+	}
+}
+
+func TestValidateURLPolicy(t *testing.T) {
+	tests := []struct {
+		name          string
+		schemes       string
+		allowInternal string
+		expectedError string
+	}{
+		{"Valid", "mailto,myapp", "10.1.0.0/16,intranet", ""},
+		{"Invalid scheme", "my app", "", "invalid URL scheme: my app"},
+		{"Forbidden scheme", "mailto,javascript", "", "URL scheme can not be allowed: javascript"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{
+				KeyAlphabet:        "base36",
+				RedirectStatus:     302,
+				SplitSticky:        "cookie",
+				URLSchemes:         tt.schemes,
+				URLAllowInternal:   tt.allowInternal,
+				PasswordAttempts:   5,
+				PasswordInterval:   "1m",
+				ClicksBufferSize:   1000,
+				ClicksBatchSize:    100,
+				ClicksFlushTime:    "5s",
+				WebhookTimeout:     "10s",
+				WebhookRetryDelay:  "30s",
+				WebhookMaxAttempts: 8,
+				WebhookBufferSize:  1000,
+			}
+			err := config.Validate()
+
+			if tt.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expectedError)
+			}
+		})
+	}
+}
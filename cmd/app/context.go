@@ -0,0 +1,45 @@
+package app
+
+import (
+	"context"
+	"github.com/dzhdmitry/link-shorter/internal/apikeys"
+	"github.com/dzhdmitry/link-shorter/internal/links"
+	"net/http"
+)
+
+type contextKey string
+
+const apiKeyContextKey = contextKey("apiKey")
+
+// contextSetAPIKey Returns copy of request carrying API key it is authenticated with
+func (app *Application) contextSetAPIKey(r *http.Request, key apikeys.Key) *http.Request {
+	ctx := context.WithValue(r.Context(), apiKeyContextKey, key)
+
+	return r.WithContext(ctx)
+}
+
+// contextGetAPIKey Returns API key of request, false if request is not authenticated
+func (app *Application) contextGetAPIKey(r *http.Request) (apikeys.Key, bool) {
+	key, ok := r.Context().Value(apiKeyContextKey).(apikeys.Key)
+
+	return key, ok
+}
+
+// contextGetOwner Returns ID of API key which owns links created by request, empty if request is not authenticated
+func (app *Application) contextGetOwner(r *http.Request) string {
+	key, _ := app.contextGetAPIKey(r)
+
+	return key.ID
+}
+
+// ownsLink Reports whether request may manage the link: admin key manages any link, other keys manage links created by them,
+// and unauthenticated requests manage links created without a key
+func (app *Application) ownsLink(r *http.Request, link links.Link) bool {
+	key, _ := app.contextGetAPIKey(r)
+
+	if key.HasScope(apikeys.ScopeAdmin) {
+		return true
+	}
+
+	return link.Owner == key.ID
+}
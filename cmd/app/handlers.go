@@ -1,8 +1,19 @@
 package app
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dzhdmitry/link-shorter/internal/apikeys"
+	"github.com/dzhdmitry/link-shorter/internal/links"
+	"github.com/dzhdmitry/link-shorter/internal/qr"
+	"github.com/dzhdmitry/link-shorter/internal/utm"
+	"github.com/dzhdmitry/link-shorter/internal/webhooks"
 	"github.com/julienschmidt/httprouter"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 // @Summary      Index
@@ -23,21 +34,1153 @@ func (app *Application) indexHandler(w http.ResponseWriter, r *http.Request) {
 
 // generateHandler godoc
 // @Summary      Generate short link
-// @Description  Provide long link and get short one
+// @Description  Provide long link and get short one. URL is normalized before storing and returned in "url".
+// @Description  With DEDUP_ENABLED already shortened URL gets existing link, which is reported by "existing". Links with password, metadata, UTM template, rules, variants or fallback are never reused.
+// @Description  Title, description, tags and notes are kept for the owner and are never shown by short link. Parameters of UTM template are added to original url on redirect.
+// @Description  Rules are checked in order on redirect, the first rule which matches platform, language, time and query parameters of visitor chooses url instead of original one.
+// @Description  Variants split visitors whom no rule matched between urls in proportion to weights, visitor keeps the variant by cookie or by hash of IP and user agent (SPLIT_STICKY)
+// @Description  Fallback is used instead of all of them when destination is down, links without fallback use FALLBACK_URL
+// @Description  With UNFURL_ENABLED title, Open Graph properties and favicon of destination page are fetched in background, see GET /links/{key}
 // @Tags         Single link
 // @Accept       json
 // @Produce      json
-// @Param        request body object{URL=string} true "Original URL"
+// @Security     ApiKeyAuth
+// @Param        request body object{URL=string,expires_at=string,alias=string,password=string,title=string,description=string,tags=[]string,notes=string,utm_template=string,rules=[]object{url=string,platforms=[]string,languages=[]string,time_from=string,time_to=string,timezone=string,query=object},variants=[]object{url=string,weight=int},fallback=string} true "Original URL, optional expiration date (RFC 3339), optional custom alias, optional password, optional metadata, optional name of UTM template, optional redirect rules, optional A/B split variants and optional fallback URL"
+// @Success      200  {object}  object{link=string,url=string,existing=bool}
+// @Failure      400  {object}  object{error=string}
+// @Failure      422  {object}  object{error=string}
+// @Failure      401  {object}  object{error=string}
+// @Failure      403  {object}  object{error=string}
+// @Failure      404  {object}  object{error=string}
+// @Failure      409  {object}  object{error=string}
+// @Failure      429  {object}  object{error=string}
+// @Failure      500  {object}  object{error=string}
+// @Router       /generate [post]
+func (app *Application) generateHandler(w http.ResponseWriter, r *http.Request) {
+	data := struct {
+		URL         string
+		ExpiresAt   time.Time `json:"expires_at"`
+		Alias       string
+		Password    string
+		Title       string
+		Description string
+		Tags        []string
+		Notes       string
+		UTMTemplate string `json:"utm_template"`
+		Rules       links.Rules
+		Variants    links.Variants
+		Fallback    string
+	}{}
+
+	err := app.limitMaxBytes(app.extractGZIP(app.readJSON))(w, r, &data)
+
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	err = app.Validator.validateURL(data.URL)
+
+	if err == nil {
+		err = app.Validator.validateExpiresAt(data.ExpiresAt, app.Clock.Now())
+	}
+
+	if err == nil && data.Alias != "" {
+		err = app.Validator.validateAlias(data.Alias)
+	}
+
+	if err == nil {
+		err = app.Validator.validatePassword(data.Password)
+	}
+
+	metadata := links.Metadata{Title: data.Title, Description: data.Description, Notes: data.Notes}
+
+	if err == nil {
+		err = app.Validator.validateMetadata(metadata)
+	}
+
+	if err == nil {
+		metadata.Tags, err = app.Validator.parseTags(data.Tags)
+	}
+
+	if err == nil && data.UTMTemplate != "" {
+		err = app.Validator.validateUTMTemplateName(data.UTMTemplate)
+	}
+
+	if err == nil {
+		err = app.Validator.validateRules(data.Rules)
+	}
+
+	if err == nil && data.Variants != nil {
+		err = app.Validator.validateVariants(data.Variants)
+	}
+
+	if err == nil && data.Fallback != "" {
+		err = app.Validator.validateFallback(data.Fallback)
+	}
+
+	if err == nil {
+		data.URL, err = app.normalizeURL(data.URL)
+	}
+
+	if err != nil {
+		app.errorResponse(w, r, http.StatusUnprocessableEntity, err.Error())
+
+		return
+	}
+
+	if data.UTMTemplate != "" && !app.requireUTMTemplate(w, r, data.UTMTemplate) {
+		return
+	}
+
+	passwordHash, err := hashPassword(data.Password)
+
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+
+		return
+	}
+
+	var key string
+	var existing map[string]bool
+	options := links.LinkOptions{
+		ExpiresAt:    data.ExpiresAt,
+		Owner:        app.contextGetOwner(r),
+		PasswordHash: passwordHash,
+		Metadata:     metadata,
+		UTMTemplate:  data.UTMTemplate,
+		Rules:        data.Rules,
+		Variants:     data.Variants,
+		Fallback:     data.Fallback,
+	}
+	reusable := passwordHash == "" && metadata.IsZero() && data.UTMTemplate == "" && len(data.Rules) == 0 && len(data.Variants) == 0 &&
+		data.Fallback == ""
+
+	if data.Alias != "" {
+		key, err = app.Links.GenerateAlias(data.Alias, data.URL, options)
+	} else if app.Config.DedupEnabled && reusable {
+		var keys map[string]string
+		keys, existing, err = app.Links.GenerateUniqueKeys([]string{data.URL}, options)
+		key = keys[data.URL]
+	} else {
+		key, err = app.Links.GenerateKey(data.URL, options)
+	}
+
+	if errors.Is(err, links.ErrAliasTaken) {
+		app.errorResponse(w, r, http.StatusConflict, err.Error())
+
+		return
+	}
+
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+
+		return
+	}
+
+	if !existing[data.URL] {
+		app.unfurl(key, data.URL)
+		app.publish(webhooks.EventLinkCreated, app.linkEvent(key, data.URL))
+	}
+
+	result := envelope{"link": app.composeShortLink(key)}
+
+	if app.Normalizer != nil {
+		result["url"] = data.URL
+	}
+
+	if app.Config.DedupEnabled && data.Alias == "" {
+		result["existing"] = existing[data.URL]
+	}
+
+	response, err := app.compactGZIP(app.writeJSON)(w, r, result)
+
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(response)
+
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// unfurl Fetches preview of destination of new link in background, if unfurling is enabled
+func (app *Application) unfurl(key, URL string) {
+	if app.Unfurler != nil {
+		app.Unfurler.Unfurl(key, URL)
+	}
+}
+
+// goHandler godoc
+// @Summary      Go by short link
+// @Description  Redirect to url of the first matching rule of the link, to url of A/B split variant or to original url, parameters of UTM template of the link are added, parameters which url already has are kept.
+// @Description  Variant is kept for visitor by cookie "ab_{key}" or by hash of IP and user agent, depending on SPLIT_STICKY.
+// @Description  If destination is marked as down or the last health check found it broken, visitor is sent to fallback of the link or to FALLBACK_URL instead.
+// @Description  Destination which matches blocklist (BLOCKLIST_FILE) is not followed, link is answered with 403
+// @Description  Responds with JSON instead if "Accept: application/json" is requested.
+// @Description  Protected link requires password in X-Link-Password header, browsers are shown password form submitted by POST
+// @Tags         Single link
+// @Accept       json
+// @Produce      json
+// @Param        key   path string true "Short key"
+// @Param        X-Link-Password header string false "Password of protected link"
+// @Success      200  {object}  object{link=string}
+// @Success      302  {string}  string  "Redirect to original url (status is set by REDIRECT_STATUS: 301, 302, 307 or 308)"
+// @Header       302  {string}  Location  "Original url"
+// @Success      303  {string}  string  "Redirect to original url after password form is submitted"
+// @Failure      400  {object}  object{error=string}
+// @Failure      401  {object}  object{error=string}
+// @Failure      422  {object}  object{error=string}
+// @Failure      403  {object}  object{error=string}
+// @Failure      404  {object}  object{error=string}
+// @Failure      410  {object}  object{error=string}
+// @Failure      429  {object}  object{error=string}
+// @Failure      500  {object}  object{error=string}
+// @Router       /go/{key} [get]
+// @Router       /go/{key} [post]
+func (app *Application) goHandler(w http.ResponseWriter, r *http.Request) {
+	// redirect and JSON are served by the same url, so caches must not mix them up
+	w.Header().Add("Vary", "Accept")
+
+	link, ok := app.resolveLink(w, r)
+
+	if !ok || !app.authorizeLink(w, r, link, !app.acceptsJSON(r)) {
+		return
+	}
+
+	destination, variant := app.destination(w, link, r)
+
+	// link could be created before its destination was blocked
+	if app.Validator.isBlocked(destination, blockedOnRedirect) {
+		app.errorResponse(w, r, http.StatusForbidden, "Destination of the link is blocked")
+
+		return
+	}
+
+	app.recordClick(r, variant)
+
+	if app.acceptsJSON(r) {
+		app.linkResponse(w, r, destination)
+
+		return
+	}
+
+	status := app.Config.RedirectStatus
+
+	// browser submitted password form, target is requested with GET
+	if r.Method == http.MethodPost {
+		status = http.StatusSeeOther
+	}
+
+	http.Redirect(w, r, destination, status)
+}
+
+// destination Returns url visitor is sent to: fallback of destination which is down, url of the first matching rule,
+// url of A/B split variant or original url with parameters of UTM template, and number of the variant (0 if visitor is not split).
+// If UTM template of the link is deleted, url is returned as is
+func (app *Application) destination(w http.ResponseWriter, link links.Link, r *http.Request) (string, int) {
+	URL := link.URL
+	variant := 0
+	visit := links.Visit{
+		UserAgent:      r.UserAgent(),
+		AcceptLanguage: r.Header.Get("Accept-Language"),
+		Query:          r.URL.Query(),
+		Time:           app.Clock.Now(),
+	}
+
+	if fallback := app.fallback(link); fallback != "" {
+		URL = fallback
+	} else if ruleURL, ok := link.Rules.Destination(visit); ok {
+		URL = ruleURL
+	} else if len(link.Variants) > 0 {
+		variant = app.chooseVariant(w, r, link.Variants)
+		URL, _ = link.Variants.URL(variant)
+	}
+
+	if link.UTMTemplate == "" || app.UTMTemplates == nil {
+		return URL, variant
+	}
+
+	template, err := app.UTMTemplates.GetTemplate(link.UTMTemplate)
+
+	if err != nil {
+		if !errors.Is(err, utm.ErrTemplateNotFound) {
+			app.Logger.LogError(err)
+		}
+
+		return URL, variant
+	}
+
+	return template.Apply(URL), variant
+}
+
+// fallback Returns fallback of the link or global one if destination is down, empty string if destination is up
+// or there is no fallback
+func (app *Application) fallback(link links.Link) string {
+	if !link.IsDown() {
+		return ""
+	}
+
+	if link.Fallback != "" {
+		return link.Fallback
+	}
+
+	return app.Config.FallbackURL
+}
+
+const variantCookieMaxAge = 30 * 24 * 60 * 60
+
+// chooseVariant Returns number of A/B split variant of visitor. With sticky hash variant is chosen by hash of key, IP and
+// user agent, otherwise variant of cookie is kept and new visitor gets random variant which is set to cookie
+func (app *Application) chooseVariant(w http.ResponseWriter, r *http.Request, variants links.Variants) int {
+	key := httprouter.ParamsFromContext(r.Context()).ByName("key")
+
+	if app.Config.SplitSticky == SplitStickyHash {
+		return variants.Choose(visitorHash(key, r))
+	}
+
+	name := "ab_" + key
+
+	if cookie, err := r.Cookie(name); err == nil {
+		if variant, err := strconv.Atoi(cookie.Value); err == nil {
+			if _, ok := variants.URL(variant); ok {
+				return variant
+			}
+		}
+	}
+
+	variant := variants.Choose(app.Random.Uint64())
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    strconv.Itoa(variant),
+		Path:     "/go/" + key,
+		MaxAge:   variantCookieMaxAge,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return variant
+}
+
+// requireUTMTemplate Responds with error if there is no UTM template by name
+func (app *Application) requireUTMTemplate(w http.ResponseWriter, r *http.Request, name string) bool {
+	_, err := app.UTMTemplates.GetTemplate(name)
+
+	if errors.Is(err, utm.ErrTemplateNotFound) {
+		app.errorResponse(w, r, http.StatusUnprocessableEntity, "UTM template not found: "+name)
+
+		return false
+	}
+
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+
+		return false
+	}
+
+	return true
+}
+
+// linkHandler godoc
+// @Summary      Get original link
+// @Description  Get url which short key redirects to: fallback, rules, A/B split variant and UTM template are applied the same way as in /go/{key}, but click is not recorded.
+// @Description  Protected link requires password in X-Link-Password header
+// @Tags         Single link
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        key   path string true "Short key"
+// @Param        X-Link-Password header string false "Password of protected link"
 // @Success      200  {object}  object{link=string}
 // @Failure      400  {object}  object{error=string}
+// @Failure      401  {object}  object{error=string}
+// @Failure      422  {object}  object{error=string}
+// @Failure      403  {object}  object{error=string}
+// @Failure      404  {object}  object{error=string}
+// @Failure      410  {object}  object{error=string}
+// @Failure      429  {object}  object{error=string}
+// @Failure      500  {object}  object{error=string}
+// @Router       /api/links/{key} [get]
+func (app *Application) linkHandler(w http.ResponseWriter, r *http.Request) {
+	link, ok := app.resolveLink(w, r)
+
+	if !ok || !app.authorizeLink(w, r, link, false) {
+		return
+	}
+
+	// the same destination as /go/{key} is returned, but the lookup is not counted as a click
+	destination, _ := app.destination(w, link, r)
+
+	if app.Validator.isBlocked(destination, blockedOnRedirect) {
+		app.errorResponse(w, r, http.StatusForbidden, "Destination of the link is blocked")
+
+		return
+	}
+
+	app.linkResponse(w, r, destination)
+}
+
+// linkDetailsHandler godoc
+// @Summary      Get link details
+// @Description  Get short link with its settings, metadata, health and preview of destination page (title, Open Graph properties and favicon) fetched with UNFURL_ENABLED.
+// @Description  Disabled and expired links are returned as well. Only links created with the same API key are found
+// @Tags         Single link
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        key   path string true "Short key"
+// @Success      200  {object}  object{key=string,alias=string,link=string,url=string,created_at=string,expires_at=string,disabled=bool,protected=bool,title=string,description=string,tags=[]string,notes=string,utm_template=string,rules=[]object,variants=[]object,fallback=string,down=bool,health=object{status=int,broken=bool,checked_at=string,redirects=[]string,error=string},preview=object{title=string,open_graph=object,favicon=string,fetched_at=string}}
+// @Failure      400  {object}  object{error=string}
+// @Failure      401  {object}  object{error=string}
+// @Failure      403  {object}  object{error=string}
+// @Failure      404  {object}  object{error=string}
+// @Failure      429  {object}  object{error=string}
+// @Failure      500  {object}  object{error=string}
+// @Router       /links/{key} [get]
+func (app *Application) linkDetailsHandler(w http.ResponseWriter, r *http.Request) {
+	key := httprouter.ParamsFromContext(r.Context()).ByName("key")
+
+	if err := app.Validator.validateKey(key); err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	link, ok := app.requireOwnLink(w, r, key)
+
+	if !ok {
+		return
+	}
+
+	if link.Key == "" {
+		link.Key = key
+	}
+
+	response, err := app.compactGZIP(app.writeJSON)(w, r, app.linkDetails(link))
+
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(response)
+
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// linkDetails Makes response of link with all its fields which are set
+func (app *Application) linkDetails(link links.Link) envelope {
+	details := envelope{
+		"key":       link.Key,
+		"link":      app.composeShortLink(link.Key),
+		"url":       link.URL,
+		"disabled":  link.Disabled,
+		"protected": link.PasswordHash != "",
+	}
+
+	if link.Alias != "" {
+		details["alias"] = link.Alias
+	}
+
+	if !link.CreatedAt.IsZero() {
+		details["created_at"] = link.CreatedAt
+	}
+
+	if !link.ExpiresAt.IsZero() {
+		details["expires_at"] = link.ExpiresAt
+	}
+
+	return withDetails(details, link)
+}
+
+func (app *Application) resolveLink(w http.ResponseWriter, r *http.Request) (links.Link, bool) {
+	key := httprouter.ParamsFromContext(r.Context()).ByName("key")
+
+	if err := app.Validator.validateKey(key); err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+
+		return links.Link{}, false
+	}
+
+	link, err := app.Links.GetLink(key)
+
+	if errors.Is(err, links.ErrLinkExpired) {
+		app.errorResponse(w, r, http.StatusGone, "Link has expired for key "+key)
+
+		return links.Link{}, false
+	}
+
+	if errors.Is(err, links.ErrLinkDisabled) {
+		app.errorResponse(w, r, http.StatusForbidden, "Link is disabled for key "+key)
+
+		return links.Link{}, false
+	}
+
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+
+		return links.Link{}, false
+	}
+
+	if link.URL == "" {
+		app.errorResponse(w, r, http.StatusNotFound, "Full link not found for key "+key)
+
+		return links.Link{}, false
+	}
+
+	return link, true
+}
+
+// requireOwnLink Responds with 404 unless link exists and may be managed by request. Expired and disabled links are
+// returned too, as they are managed by the same endpoints
+func (app *Application) requireOwnLink(w http.ResponseWriter, r *http.Request, key string) (links.Link, bool) {
+	link, err := app.Links.GetLink(key)
+
+	if err != nil && !errors.Is(err, links.ErrLinkExpired) && !errors.Is(err, links.ErrLinkDisabled) {
+		app.serverErrorResponse(w, r, err)
+
+		return links.Link{}, false
+	}
+
+	if link.URL == "" || !app.ownsLink(r, link) {
+		app.errorResponse(w, r, http.StatusNotFound, "Full link not found for key "+key)
+
+		return links.Link{}, false
+	}
+
+	return link, true
+}
+
+func (app *Application) linkResponse(w http.ResponseWriter, r *http.Request, fullLink string) {
+	response, err := app.compactGZIP(app.writeJSON)(w, r, envelope{"link": fullLink})
+
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(response)
+
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateLinkHandler godoc
+// @Summary      Update link
+// @Description  Change original url of the link, disable or enable it, set password, metadata, UTM template, rules, variants, fallback and/or mark destination as down. Empty password removes protection, empty list of tags removes tags, empty name of UTM template removes template, empty list of rules removes rules, empty list of variants removes A/B split, empty fallback removes fallback.
+// @Description  Title, description, tags, notes, UTM template, rules and variants are returned when they are set
+// @Tags         Link management
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        key   path string true "Short key"
+// @Param        request body object{URL=string,disabled=bool,password=string,title=string,description=string,tags=[]string,notes=string,utm_template=string,rules=[]object{url=string,platforms=[]string,languages=[]string,time_from=string,time_to=string,timezone=string,query=object},variants=[]object{url=string,weight=int},fallback=string,down=bool} true "New original URL, disabled flag, password, metadata, name of UTM template, rules, variants, fallback and/or down flag"
+// @Success      200  {object}  object{link=string,url=string,disabled=bool,protected=bool,title=string,description=string,tags=[]string,notes=string,utm_template=string,rules=[]object,variants=[]object,fallback=string,down=bool}
+// @Failure      400  {object}  object{error=string}
+// @Failure      422  {object}  object{error=string}
+// @Failure      401  {object}  object{error=string}
+// @Failure      403  {object}  object{error=string}
+// @Failure      404  {object}  object{error=string}
+// @Failure      429  {object}  object{error=string}
+// @Failure      500  {object}  object{error=string}
+// @Router       /links/{key} [patch]
+func (app *Application) updateLinkHandler(w http.ResponseWriter, r *http.Request) {
+	key := httprouter.ParamsFromContext(r.Context()).ByName("key")
+
+	if err := app.Validator.validateKey(key); err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	data := struct {
+		URL         *string
+		Disabled    *bool
+		Password    *string
+		Title       *string
+		Description *string
+		Tags        *[]string
+		Notes       *string
+		UTMTemplate *string `json:"utm_template"`
+		Rules       *links.Rules
+		Variants    *links.Variants
+		Fallback    *string
+		Down        *bool
+	}{}
+
+	err := app.limitMaxBytes(app.extractGZIP(app.readJSON))(w, r, &data)
+
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	update := links.LinkUpdate{
+		URL:         data.URL,
+		Disabled:    data.Disabled,
+		Title:       data.Title,
+		Description: data.Description,
+		Tags:        data.Tags,
+		Notes:       data.Notes,
+		UTMTemplate: data.UTMTemplate,
+		Rules:       data.Rules,
+		Variants:    data.Variants,
+		Fallback:    data.Fallback,
+		Down:        data.Down,
+	}
+
+	if data.URL == nil && data.Disabled == nil && data.Password == nil && !update.ChangesMetadata() && data.UTMTemplate == nil &&
+		data.Rules == nil && data.Variants == nil && data.Fallback == nil && data.Down == nil {
+		err = errors.New("URL, disabled, password, title, description, tags, notes, utm_template, rules, variants, fallback or down must be provided")
+	} else if data.URL != nil {
+		err = app.Validator.validateURL(*data.URL)
+
+		if err == nil {
+			*data.URL, err = app.normalizeURL(*data.URL)
+		}
+	}
+
+	if err == nil && data.Password != nil {
+		err = app.Validator.validatePassword(*data.Password)
+	}
+
+	if err == nil {
+		err = app.Validator.validateMetadata(links.Metadata{
+			Title:       valueOrEmpty(data.Title),
+			Description: valueOrEmpty(data.Description),
+			Notes:       valueOrEmpty(data.Notes),
+		})
+	}
+
+	if err == nil && data.Tags != nil {
+		*update.Tags, err = app.Validator.parseTags(*data.Tags)
+	}
+
+	if err == nil && valueOrEmpty(data.UTMTemplate) != "" {
+		err = app.Validator.validateUTMTemplateName(*data.UTMTemplate)
+	}
+
+	if err == nil && data.Rules != nil {
+		err = app.Validator.validateRules(*data.Rules)
+	}
+
+	if err == nil && data.Variants != nil && len(*data.Variants) > 0 {
+		err = app.Validator.validateVariants(*data.Variants)
+	}
+
+	if err == nil && valueOrEmpty(data.Fallback) != "" {
+		err = app.Validator.validateFallback(*data.Fallback)
+	}
+
+	if err != nil {
+		app.errorResponse(w, r, http.StatusUnprocessableEntity, err.Error())
+
+		return
+	}
+
+	if _, ok := app.requireOwnLink(w, r, key); !ok {
+		return
+	}
+
+	if valueOrEmpty(data.UTMTemplate) != "" && !app.requireUTMTemplate(w, r, *data.UTMTemplate) {
+		return
+	}
+
+	if data.Password != nil {
+		passwordHash, err := hashPassword(*data.Password)
+
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+
+			return
+		}
+
+		update.PasswordHash = &passwordHash
+	}
+
+	link, err := app.Links.UpdateLink(key, update)
+
+	if errors.Is(err, links.ErrLinkNotFound) {
+		app.errorResponse(w, r, http.StatusNotFound, "Full link not found for key "+key)
+
+		return
+	}
+
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+
+		return
+	}
+
+	if data.URL != nil {
+		app.unfurl(key, link.URL)
+	}
+
+	if link.Key == "" {
+		link.Key = key
+	}
+
+	app.publish(webhooks.EventLinkUpdated, app.linkDetails(link))
+
+	response, err := app.compactGZIP(app.writeJSON)(w, r, withDetails(envelope{
+		"link":      app.composeShortLink(key),
+		"url":       link.URL,
+		"disabled":  link.Disabled,
+		"protected": link.PasswordHash != "",
+	}, link))
+
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(response)
+
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func valueOrEmpty(value *string) string {
+	if value == nil {
+		return ""
+	}
+
+	return *value
+}
+
+// withDetails Adds fields of metadata, UTM template, rules, variants, fallback, down mark, health and preview of the link
+// which are set to response
+func withDetails(response envelope, link links.Link) envelope {
+	metadata := link.Metadata
+
+	if metadata.Title != "" {
+		response["title"] = metadata.Title
+	}
+
+	if metadata.Description != "" {
+		response["description"] = metadata.Description
+	}
+
+	if len(metadata.Tags) > 0 {
+		response["tags"] = metadata.Tags
+	}
+
+	if metadata.Notes != "" {
+		response["notes"] = metadata.Notes
+	}
+
+	if link.UTMTemplate != "" {
+		response["utm_template"] = link.UTMTemplate
+	}
+
+	if len(link.Rules) > 0 {
+		response["rules"] = link.Rules
+	}
+
+	if len(link.Variants) > 0 {
+		response["variants"] = link.Variants
+	}
+
+	if link.Fallback != "" {
+		response["fallback"] = link.Fallback
+	}
+
+	if link.Down {
+		response["down"] = true
+	}
+
+	if !link.Preview.IsZero() {
+		response["preview"] = link.Preview
+	}
+
+	if !link.Health.IsZero() {
+		response["health"] = healthResponse(link.Health)
+	}
+
+	return response
+}
+
+// healthResponse Describes the last check of destination, redirects and error are added when they are present
+func healthResponse(health links.Health) envelope {
+	response := envelope{
+		"status":     health.Status,
+		"broken":     health.IsBroken(),
+		"checked_at": health.CheckedAt,
+	}
+
+	if len(health.Redirects) > 0 {
+		response["redirects"] = health.Redirects
+	}
+
+	if health.Error != "" {
+		response["error"] = health.Error
+	}
+
+	return response
+}
+
+// statsHandler godoc
+// @Summary      Get link statistics
+// @Description  Get total clicks, unique visitors, daily breakdown, top referrers and user agent families of the link. Clicks by key and by alias are counted together, range of days is inclusive and is last 30 days by default.
+// @Description  Clicks and unique visitors of each A/B split variant are returned in "variants" if link has variants
+// @Tags         Link management
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        key   path string true "Short key"
+// @Param        from  query string false "First day of range (YYYY-MM-DD)"
+// @Param        to    query string false "Last day of range (YYYY-MM-DD)"
+// @Success      200  {object}  object{link=string,from=string,to=string,total_clicks=int,unique_visitors=int,daily=[]object{date=string,clicks=int},top_referrers=[]object{name=string,count=int},top_user_agents=[]object{name=string,count=int},variants=[]object{variant=int,clicks=int,unique_visitors=int}}
+// @Failure      400  {object}  object{error=string}
+// @Failure      422  {object}  object{error=string}
+// @Failure      401  {object}  object{error=string}
+// @Failure      403  {object}  object{error=string}
+// @Failure      404  {object}  object{error=string}
+// @Failure      429  {object}  object{error=string}
+// @Failure      500  {object}  object{error=string}
+// @Router       /links/{key}/stats [get]
+func (app *Application) statsHandler(w http.ResponseWriter, r *http.Request) {
+	key := httprouter.ParamsFromContext(r.Context()).ByName("key")
+
+	if err := app.Validator.validateKey(key); err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	query := r.URL.Query()
+	from, to, err := app.Validator.parseStatsRange(query.Get("from"), query.Get("to"), app.Clock.Now())
+
+	if err != nil {
+		app.errorResponse(w, r, http.StatusUnprocessableEntity, err.Error())
+
+		return
+	}
+
+	if _, ok := app.requireOwnLink(w, r, key); !ok {
+		return
+	}
+
+	stats, err := app.Stats.GetStats(key, from, to)
+
+	if errors.Is(err, links.ErrLinkNotFound) {
+		app.errorResponse(w, r, http.StatusNotFound, "Full link not found for key "+key)
+
+		return
+	}
+
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+
+		return
+	}
+
+	daily := make([]envelope, 0, len(stats.Daily))
+
+	for _, day := range stats.Daily {
+		daily = append(daily, envelope{"date": day.Date, "clicks": day.Clicks})
+	}
+
+	result := envelope{
+		"link":            app.composeShortLink(key),
+		"from":            from.Format(time.DateOnly),
+		"to":              to.Format(time.DateOnly),
+		"total_clicks":    stats.TotalClicks,
+		"unique_visitors": stats.UniqueVisitors,
+		"daily":           daily,
+		"top_referrers":   countersResponse(stats.TopReferrers),
+		"top_user_agents": countersResponse(stats.TopUserAgents),
+	}
+
+	if len(stats.Variants) > 0 {
+		variants := make([]envelope, 0, len(stats.Variants))
+
+		for _, variant := range stats.Variants {
+			variants = append(variants, envelope{
+				"variant":         variant.Variant,
+				"clicks":          variant.Clicks,
+				"unique_visitors": variant.UniqueVisitors,
+			})
+		}
+
+		result["variants"] = variants
+	}
+
+	response, err := app.compactGZIP(app.writeJSON)(w, r, result)
+
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(response)
+
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func countersResponse(counters []links.Counter) []envelope {
+	response := make([]envelope, 0, len(counters))
+
+	for _, counter := range counters {
+		response = append(response, envelope{"name": counter.Name, "count": counter.Count})
+	}
+
+	return response
+}
+
+// qrHandler godoc
+// @Summary      Get QR code of short link
+// @Description  Get QR code of short link as PNG or SVG image. Format is chosen by "format" parameter or by "Accept: image/svg+xml" header, PNG by default
+// @Tags         Link management
+// @Produce      png
+// @Produce      image/svg+xml
+// @Security     ApiKeyAuth
+// @Param        key     path string true "Short key"
+// @Param        format  query string false "Image format (png|svg)"
+// @Param        size    query int false "Image width and height in pixels, 256 by default"
+// @Param        margin  query int false "Quiet zone width in modules, 4 by default"
+// @Param        ecc     query string false "Error correction level (L|M|Q|H), M by default"
+// @Success      200  {file}    file
+// @Failure      400  {object}  object{error=string}
+// @Failure      422  {object}  object{error=string}
+// @Failure      401  {object}  object{error=string}
+// @Failure      403  {object}  object{error=string}
+// @Failure      404  {object}  object{error=string}
+// @Failure      429  {object}  object{error=string}
+// @Failure      500  {object}  object{error=string}
+// @Router       /links/{key}/qr [get]
+func (app *Application) qrHandler(w http.ResponseWriter, r *http.Request) {
+	key := httprouter.ParamsFromContext(r.Context()).ByName("key")
+
+	if err := app.Validator.validateKey(key); err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	// QR code of expired or disabled link is still available to its owner
+	if _, ok := app.requireOwnLink(w, r, key); !ok {
+		return
+	}
+
+	options, err := app.Validator.parseQROptions(r.URL.Query(), r.Header.Get("Accept"))
+
+	if err != nil {
+		app.errorResponse(w, r, http.StatusUnprocessableEntity, err.Error())
+
+		return
+	}
+
+	code, err := qr.Encode([]byte(app.composeShortLink(key)), options.level)
+
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+
+		return
+	}
+
+	var image []byte
+
+	if options.format == qrFormatSVG {
+		image, err = code.SVG(options.size, options.margin)
+		w.Header().Set("Content-Type", "image/svg+xml")
+	} else {
+		image, err = code.PNG(options.size, options.margin)
+		w.Header().Set("Content-Type", "image/png")
+	}
+
+	if errors.Is(err, qr.ErrSizeTooSmall) {
+		app.errorResponse(w, r, http.StatusUnprocessableEntity, fmt.Sprintf(
+			"size must be at least %d for margin %d", code.Size()+options.margin*2, options.margin,
+		))
+
+		return
+	}
+
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(image)
+
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteLinkHandler godoc
+// @Summary      Delete link
+// @Description  Delete the link, so it can not be followed anymore
+// @Tags         Link management
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        key   path string true "Short key"
+// @Success      204
+// @Failure      400  {object}  object{error=string}
+// @Failure      401  {object}  object{error=string}
+// @Failure      403  {object}  object{error=string}
+// @Failure      404  {object}  object{error=string}
+// @Failure      429  {object}  object{error=string}
+// @Failure      500  {object}  object{error=string}
+// @Router       /links/{key} [delete]
+func (app *Application) deleteLinkHandler(w http.ResponseWriter, r *http.Request) {
+	key := httprouter.ParamsFromContext(r.Context()).ByName("key")
+
+	if err := app.Validator.validateKey(key); err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	if _, ok := app.requireOwnLink(w, r, key); !ok {
+		return
+	}
+
+	link, err := app.Links.DeleteLink(key)
+
+	if errors.Is(err, links.ErrLinkNotFound) {
+		app.errorResponse(w, r, http.StatusNotFound, "Full link not found for key "+key)
+
+		return
+	}
+
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+
+		return
+	}
+
+	app.publish(webhooks.EventLinkDeleted, app.linkEvent(key, link.URL))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// listLinksHandler godoc
+// @Summary      List links
+// @Description  List links created with API key of request, newest first by default. Links are filtered by destination domain including its subdomains, by tag and by health of destination.
+// @Description  Health is returned for links which destination was checked, status is 0 if request failed
+// @Description  Next page is requested with "next_cursor" of previous one, which is empty on the last page
+// @Tags         Link management
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        limit   query int false "Number of links on page, 20 by default, maximum 100"
+// @Param        cursor  query string false "Cursor of the page"
+// @Param        sort    query string false "Sorting by creation time (created_at|-created_at), -created_at by default"
+// @Param        domain  query string false "Domain of original url"
+// @Param        tag     query string false "Tag of links"
+// @Param        health  query string false "Health of destination (broken|ok)"
+// @Success      200  {object}  object{links=[]object{key=string,alias=string,link=string,url=string,created_at=string,expires_at=string,disabled=bool,protected=bool,title=string,description=string,tags=[]string,notes=string,utm_template=string,rules=[]object,variants=[]object,fallback=string,down=bool,health=object{status=int,broken=bool,checked_at=string,redirects=[]string,error=string},preview=object{title=string,open_graph=object,favicon=string,fetched_at=string}},next_cursor=string}
+// @Failure      401  {object}  object{error=string}
+// @Failure      403  {object}  object{error=string}
+// @Failure      422  {object}  object{error=string}
+// @Failure      429  {object}  object{error=string}
+// @Failure      500  {object}  object{error=string}
+// @Router       /links [get]
+func (app *Application) listLinksHandler(w http.ResponseWriter, r *http.Request) {
+	query, err := app.Validator.parseListQuery(r.URL.Query())
+
+	if err != nil {
+		app.errorResponse(w, r, http.StatusUnprocessableEntity, err.Error())
+
+		return
+	}
+
+	query.Owner = app.contextGetOwner(r)
+	found, next, err := app.Links.ListLinks(query)
+
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+
+		return
+	}
+
+	list := make([]envelope, 0, len(found))
+
+	for _, link := range found {
+		list = append(list, app.linkDetails(link))
+	}
+
+	response, err := app.compactGZIP(app.writeJSON)(w, r, envelope{"links": list, "next_cursor": next})
+
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(response)
+
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+type batchGenerateRequest struct {
+	URLs        []string  `json:"urls"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	UTMTemplate string    `json:"utm_template"`
+}
+
+// UnmarshalJSON Accepts either a plain list of URLs or an object with URLs and options
+func (b *batchGenerateRequest) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+
+	if len(data) > 0 && data[0] == '[' {
+		return json.Unmarshal(data, &b.URLs)
+	}
+
+	type plainRequest batchGenerateRequest
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	return decoder.Decode((*plainRequest)(b))
+}
+
+// batchGenerateHandler godoc
+// @Summary      Generate short links
+// @Description  Provide plenty of links and get short url for each. Plain list of URLs is accepted as well.
+// @Description  Normalized URLs are returned in "urls" by URLs as they were sent.
+// @Description  With DEDUP_ENABLED already shortened URLs get existing links, URLs are listed in "new" and "existing". Links with UTM template are never reused
+// @Tags         Multiple links
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        request body object{urls=[]string,expires_at=string,utm_template=string} true "Original URLs, optional expiration date (RFC 3339) and optional name of UTM template"
+// @Success      200  {object}  object{links=object{key=string},urls=object{key=string},new=[]string,existing=[]string}
+// @Failure      400  {object}  object{error=string}
 // @Failure      422  {object}  object{error=string}
+// @Failure      401  {object}  object{error=string}
+// @Failure      403  {object}  object{error=string}
 // @Failure      404  {object}  object{error=string}
+// @Failure      429  {object}  object{error=string}
 // @Failure      500  {object}  object{error=string}
-// @Router       /generate [post]
-func (app *Application) generateHandler(w http.ResponseWriter, r *http.Request) {
-	data := struct {
-		URL string
-	}{}
+// @Router       /batch/generate [post]
+func (app *Application) batchGenerateHandler(w http.ResponseWriter, r *http.Request) {
+	var data batchGenerateRequest
 
 	err := app.limitMaxBytes(app.extractGZIP(app.readJSON))(w, r, &data)
 
@@ -47,7 +1190,21 @@ func (app *Application) generateHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err = app.Validator.validateURL(data.URL)
+	err = app.Validator.validateURLs(data.URLs)
+
+	if err == nil {
+		err = app.Validator.validateExpiresAt(data.ExpiresAt, app.Clock.Now())
+	}
+
+	if err == nil && data.UTMTemplate != "" {
+		err = app.Validator.validateUTMTemplateName(data.UTMTemplate)
+	}
+
+	var normalizedURLs map[string]string
+
+	if err == nil {
+		normalizedURLs, err = app.normalizeURLs(data.URLs)
+	}
 
 	if err != nil {
 		app.errorResponse(w, r, http.StatusUnprocessableEntity, err.Error())
@@ -55,7 +1212,25 @@ func (app *Application) generateHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	key, err := app.Links.GenerateKey(data.URL)
+	if data.UTMTemplate != "" && !app.requireUTMTemplate(w, r, data.UTMTemplate) {
+		return
+	}
+
+	URLs := make([]string, 0, len(data.URLs))
+
+	for _, URL := range data.URLs {
+		URLs = append(URLs, normalizedURLs[URL])
+	}
+
+	var keys map[string]string
+	var existing map[string]bool
+	options := links.LinkOptions{ExpiresAt: data.ExpiresAt, Owner: app.contextGetOwner(r), UTMTemplate: data.UTMTemplate}
+
+	if app.Config.DedupEnabled && data.UTMTemplate == "" {
+		keys, existing, err = app.Links.GenerateUniqueKeys(URLs, options)
+	} else {
+		keys, err = app.Links.GenerateKeys(URLs, options)
+	}
 
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -63,8 +1238,37 @@ func (app *Application) generateHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	shortLink := app.composeShortLink(key)
-	response, err := app.compactGZIP(app.writeJSON)(w, r, envelope{"link": shortLink})
+	for URL, key := range keys {
+		if !existing[URL] {
+			app.unfurl(key, URL)
+			app.publish(webhooks.EventLinkCreated, app.linkEvent(key, URL))
+		}
+	}
+
+	// links are listed by URLs as they were sent
+	shortLinks := make(map[string]string, len(normalizedURLs))
+	sentExisting := make(map[string]bool, len(existing))
+
+	for sentURL, URL := range normalizedURLs {
+		shortLinks[sentURL] = keys[URL]
+		sentExisting[sentURL] = existing[URL]
+	}
+
+	result := envelope{"links": shortLinks}
+
+	if app.Normalizer != nil {
+		result["urls"] = normalizedURLs
+	}
+
+	if app.Config.DedupEnabled {
+		result["new"], result["existing"] = splitExisting(shortLinks, sentExisting)
+	}
+
+	for URL, key := range shortLinks {
+		shortLinks[URL] = app.composeShortLink(key)
+	}
+
+	response, err := app.compactGZIP(app.writeJSON)(w, r, result)
 
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -80,43 +1284,60 @@ func (app *Application) generateHandler(w http.ResponseWriter, r *http.Request)
 	}
 }
 
-// goHandler godoc
-// @Summary      Get short link
-// @Description  Go by short link and get original url
-// @Tags         Single link
+// batchGoHandler godoc
+// @Summary      Get short links
+// @Description  Provide short keys and get original url for each. Protected links and links with blocked destination are omitted
+// @Tags         Multiple links
 // @Accept       json
 // @Produce      json
-// @Param        key   path string true "Short key"
+// @Security     ApiKeyAuth
+// @Param        request body []string true "Short keys"
 // @Success      200  {object}  object{links=string}
 // @Failure      400  {object}  object{error=string}
 // @Failure      422  {object}  object{error=string}
+// @Failure      401  {object}  object{error=string}
+// @Failure      403  {object}  object{error=string}
 // @Failure      404  {object}  object{error=string}
+// @Failure      429  {object}  object{error=string}
 // @Failure      500  {object}  object{error=string}
-// @Router       /go/{key} [get]
-func (app *Application) goHandler(w http.ResponseWriter, r *http.Request) {
-	key := httprouter.ParamsFromContext(r.Context()).ByName("key")
+// @Router       /batch/go [get]
+func (app *Application) batchGoHandler(w http.ResponseWriter, r *http.Request) {
+	var data []string
 
-	if err := app.Validator.validateKey(key); err != nil {
+	err := app.limitMaxBytes(app.extractGZIP(app.readJSON))(w, r, &data)
+
+	if err != nil {
 		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
 
 		return
 	}
 
-	fullLink, err := app.Links.GetURL(key)
+	err = app.Validator.validateKeys(data)
 
 	if err != nil {
-		app.serverErrorResponse(w, r, err)
+		app.errorResponse(w, r, http.StatusUnprocessableEntity, err.Error())
 
 		return
 	}
 
-	if fullLink == "" {
-		app.errorResponse(w, r, http.StatusNotFound, "Full link not found for key "+key)
+	foundLinks, err := app.Links.GetLinks(data)
+
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
 
 		return
 	}
 
-	response, err := app.compactGZIP(app.writeJSON)(w, r, envelope{"link": fullLink})
+	fullLinks := make(map[string]string, len(foundLinks))
+
+	for key, link := range foundLinks {
+		// protected links are revealed by password only, blocked destinations are not revealed as in goHandler
+		if link.PasswordHash == "" && !app.Validator.isBlocked(link.URL, blockedOnRedirect) {
+			fullLinks[key] = link.URL
+		}
+	}
+
+	response, err := app.compactGZIP(app.writeJSON)(w, r, envelope{"links": fullLinks})
 
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -132,21 +1353,29 @@ func (app *Application) goHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// batchGenerateHandler godoc
-// @Summary      Generate short links
-// @Description  Provide plenty of links and get short url for each
-// @Tags         Multiple links
+// createAPIKeyHandler godoc
+// @Summary      Issue API key
+// @Description  Issue API key with scopes "create", "read" and/or "admin", own rate limit and daily quota (0 means no limit).
+// @Description  Key is returned once, only its hash is stored
+// @Tags         API keys
 // @Accept       json
 // @Produce      json
-// @Param        request body []string true "Original URLs"
-// @Success      200  {object}  object{links=object{key=string}}
+// @Security     ApiKeyAuth
+// @Param        request body object{name=string,scopes=[]string,rps=int,daily_quota=int} true "Name, scopes, requests per second and requests per day"
+// @Success      201  {object}  object{id=string,key=string,name=string,scopes=[]string,rps=int,daily_quota=int,created_at=string}
 // @Failure      400  {object}  object{error=string}
+// @Failure      401  {object}  object{error=string}
+// @Failure      403  {object}  object{error=string}
 // @Failure      422  {object}  object{error=string}
-// @Failure      404  {object}  object{error=string}
 // @Failure      500  {object}  object{error=string}
-// @Router       /batch/generate [post]
-func (app *Application) batchGenerateHandler(w http.ResponseWriter, r *http.Request) {
-	var data []string
+// @Router       /admin/api-keys [post]
+func (app *Application) createAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	data := struct {
+		Name       string
+		Scopes     []string
+		RPS        int
+		DailyQuota int `json:"daily_quota"`
+	}{}
 
 	err := app.limitMaxBytes(app.extractGZIP(app.readJSON))(w, r, &data)
 
@@ -156,7 +1385,7 @@ func (app *Application) batchGenerateHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	err = app.Validator.validateURLs(data)
+	err = app.Validator.validateAPIKey(data.Name, data.Scopes, data.RPS, data.DailyQuota)
 
 	if err != nil {
 		app.errorResponse(w, r, http.StatusUnprocessableEntity, err.Error())
@@ -164,19 +1393,76 @@ func (app *Application) batchGenerateHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	links, err := app.Links.GenerateKeys(data)
+	key, token, err := apikeys.NewKey(data.Name, data.Scopes, data.RPS, data.DailyQuota, app.Clock.Now())
+
+	if err == nil {
+		err = app.APIKeys.StoreKey(key)
+	}
+
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+
+		return
+	}
+
+	response, err := app.compactGZIP(app.writeJSON)(w, r, envelope{
+		"id":          key.ID,
+		"key":         token,
+		"name":        key.Name,
+		"scopes":      key.Scopes,
+		"rps":         key.RPS,
+		"daily_quota": key.DailyQuota,
+		"created_at":  key.CreatedAt,
+	})
+
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	_, err = w.Write(response)
 
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// revokeAPIKeyHandler godoc
+// @Summary      Revoke API key
+// @Description  Revoke API key, so requests with it are rejected
+// @Tags         API keys
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id   path string true "ID of API key"
+// @Success      200  {object}  object{id=string,name=string,revoked_at=string}
+// @Failure      401  {object}  object{error=string}
+// @Failure      403  {object}  object{error=string}
+// @Failure      404  {object}  object{error=string}
+// @Failure      500  {object}  object{error=string}
+// @Router       /admin/api-keys/{id} [delete]
+func (app *Application) revokeAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	id := httprouter.ParamsFromContext(r.Context()).ByName("id")
+	key, err := app.APIKeys.RevokeKey(id, app.Clock.Now())
+
+	if errors.Is(err, apikeys.ErrKeyNotFound) {
+		app.errorResponse(w, r, http.StatusNotFound, "API key not found for id "+id)
 
 		return
 	}
 
-	for URL, key := range links {
-		links[URL] = app.composeShortLink(key)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+
+		return
 	}
 
-	response, err := app.compactGZIP(app.writeJSON)(w, r, envelope{"links": links})
+	response, err := app.compactGZIP(app.writeJSON)(w, r, envelope{
+		"id":         key.ID,
+		"name":       key.Name,
+		"revoked_at": key.RevokedAt,
+	})
 
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -192,21 +1478,190 @@ func (app *Application) batchGenerateHandler(w http.ResponseWriter, r *http.Requ
 	}
 }
 
-// batchGoHandler godoc
-// @Summary      Get short links
-// @Description  Provide short keys and get original url for each
-// @Tags         Multiple links
+func utmTemplateResponse(template utm.Template) envelope {
+	return envelope{
+		"name":     template.Name,
+		"source":   template.Source,
+		"medium":   template.Medium,
+		"campaign": template.Campaign,
+		"content":  template.Content,
+	}
+}
+
+// storeUTMTemplateHandler godoc
+// @Summary      Create or replace UTM template
+// @Description  Save named set of UTM parameters. Empty parameters are not added to original url, template with the same name is replaced
+// @Tags         UTM templates
 // @Accept       json
 // @Produce      json
-// @Param        request body []string true "Short keys"
-// @Success      200  {object}  object{links=string}
+// @Security     ApiKeyAuth
+// @Param        name  path string true "Name of UTM template"
+// @Param        request body object{source=string,medium=string,campaign=string,content=string} true "Values of utm_source, utm_medium, utm_campaign and utm_content"
+// @Success      200  {object}  object{name=string,source=string,medium=string,campaign=string,content=string}
 // @Failure      400  {object}  object{error=string}
+// @Failure      401  {object}  object{error=string}
+// @Failure      403  {object}  object{error=string}
 // @Failure      422  {object}  object{error=string}
+// @Failure      500  {object}  object{error=string}
+// @Router       /utm-templates/{name} [put]
+func (app *Application) storeUTMTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	data := struct {
+		Source   string
+		Medium   string
+		Campaign string
+		Content  string
+	}{}
+
+	err := app.limitMaxBytes(app.extractGZIP(app.readJSON))(w, r, &data)
+
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	template := utm.Template{
+		Name:     httprouter.ParamsFromContext(r.Context()).ByName("name"),
+		Source:   data.Source,
+		Medium:   data.Medium,
+		Campaign: data.Campaign,
+		Content:  data.Content,
+	}
+
+	if err = app.Validator.validateUTMTemplate(template); err != nil {
+		app.errorResponse(w, r, http.StatusUnprocessableEntity, err.Error())
+
+		return
+	}
+
+	if err = app.UTMTemplates.StoreTemplate(template); err != nil {
+		app.serverErrorResponse(w, r, err)
+
+		return
+	}
+
+	response, err := app.compactGZIP(app.writeJSON)(w, r, utmTemplateResponse(template))
+
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(response)
+
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// listUTMTemplatesHandler godoc
+// @Summary      List UTM templates
+// @Description  Get all UTM templates sorted by name
+// @Tags         UTM templates
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Success      200  {object}  object{templates=[]object{name=string,source=string,medium=string,campaign=string,content=string}}
+// @Failure      401  {object}  object{error=string}
+// @Failure      403  {object}  object{error=string}
+// @Failure      500  {object}  object{error=string}
+// @Router       /utm-templates [get]
+func (app *Application) listUTMTemplatesHandler(w http.ResponseWriter, r *http.Request) {
+	templates, err := app.UTMTemplates.ListTemplates()
+
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+
+		return
+	}
+
+	list := make([]envelope, 0, len(templates))
+
+	for _, template := range templates {
+		list = append(list, utmTemplateResponse(template))
+	}
+
+	response, err := app.compactGZIP(app.writeJSON)(w, r, envelope{"templates": list})
+
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(response)
+
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteUTMTemplateHandler godoc
+// @Summary      Delete UTM template
+// @Description  Delete UTM template. Links with deleted template lead to original url as is
+// @Tags         UTM templates
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        name  path string true "Name of UTM template"
+// @Success      204
+// @Failure      401  {object}  object{error=string}
+// @Failure      403  {object}  object{error=string}
 // @Failure      404  {object}  object{error=string}
 // @Failure      500  {object}  object{error=string}
-// @Router       /batch/go [get]
-func (app *Application) batchGoHandler(w http.ResponseWriter, r *http.Request) {
-	var data []string
+// @Router       /utm-templates/{name} [delete]
+func (app *Application) deleteUTMTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	name := httprouter.ParamsFromContext(r.Context()).ByName("name")
+	err := app.UTMTemplates.DeleteTemplate(name)
+
+	if errors.Is(err, utm.ErrTemplateNotFound) {
+		app.errorResponse(w, r, http.StatusNotFound, "UTM template not found: "+name)
+
+		return
+	}
+
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func webhookResponse(subscription webhooks.Subscription) envelope {
+	return envelope{
+		"id":         subscription.ID,
+		"url":        subscription.URL,
+		"events":     subscription.Events,
+		"created_at": subscription.CreatedAt,
+	}
+}
+
+// createWebhookHandler godoc
+// @Summary      Subscribe to events
+// @Description  Subscribe endpoint to events "link.created", "link.updated", "link.deleted" and/or "link.clicked". Events are sent by POST with JSON body {id, event, created_at, data}.
+// @Description  Body is signed by secret of subscription: header X-Webhook-Signature is "sha256=" and hex of HMAC-SHA256 of body. Secret is generated unless provided and is returned once.
+// @Description  Delivery is retried with exponential backoff until endpoint responds with 2xx status or WEBHOOK_MAX_ATTEMPTS are made
+// @Tags         Webhooks
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        request body object{url=string,events=[]string,secret=string} true "Endpoint, events and optional secret (16 to 255 bytes)"
+// @Success      201  {object}  object{id=string,url=string,events=[]string,secret=string,created_at=string}
+// @Failure      400  {object}  object{error=string}
+// @Failure      401  {object}  object{error=string}
+// @Failure      403  {object}  object{error=string}
+// @Failure      422  {object}  object{error=string}
+// @Failure      500  {object}  object{error=string}
+// @Router       /admin/webhooks [post]
+func (app *Application) createWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	data := struct {
+		URL    string
+		Events []string
+		Secret string
+	}{}
 
 	err := app.limitMaxBytes(app.extractGZIP(app.readJSON))(w, r, &data)
 
@@ -216,7 +1671,7 @@ func (app *Application) batchGoHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = app.Validator.validateKeys(data)
+	err = app.Validator.validateWebhook(data.URL, data.Secret, data.Events)
 
 	if err != nil {
 		app.errorResponse(w, r, http.StatusUnprocessableEntity, err.Error())
@@ -224,7 +1679,11 @@ func (app *Application) batchGoHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	fullLinks, err := app.Links.GetURLs(data)
+	subscription, err := webhooks.NewSubscription(data.URL, data.Secret, data.Events, app.Clock.Now())
+
+	if err == nil {
+		err = app.Webhooks.StoreSubscription(subscription)
+	}
 
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -232,7 +1691,51 @@ func (app *Application) batchGoHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response, err := app.compactGZIP(app.writeJSON)(w, r, envelope{"links": fullLinks})
+	result := webhookResponse(subscription)
+	result["secret"] = subscription.Secret
+	response, err := app.compactGZIP(app.writeJSON)(w, r, result)
+
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	_, err = w.Write(response)
+
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// listWebhooksHandler godoc
+// @Summary      List webhook subscriptions
+// @Description  List webhook subscriptions, the oldest first. Secrets are not returned
+// @Tags         Webhooks
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Success      200  {object}  object{webhooks=[]object{id=string,url=string,events=[]string,created_at=string}}
+// @Failure      401  {object}  object{error=string}
+// @Failure      403  {object}  object{error=string}
+// @Failure      500  {object}  object{error=string}
+// @Router       /admin/webhooks [get]
+func (app *Application) listWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	subscriptions, err := app.Webhooks.ListSubscriptions()
+
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+
+		return
+	}
+
+	list := make([]envelope, 0, len(subscriptions))
+
+	for _, subscription := range subscriptions {
+		list = append(list, webhookResponse(subscription))
+	}
+
+	response, err := app.compactGZIP(app.writeJSON)(w, r, envelope{"webhooks": list})
 
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -247,3 +1750,35 @@ func (app *Application) batchGoHandler(w http.ResponseWriter, r *http.Request) {
 		app.serverErrorResponse(w, r, err)
 	}
 }
+
+// deleteWebhookHandler godoc
+// @Summary      Delete webhook subscription
+// @Description  Delete webhook subscription, its deliveries which are not sent yet are dropped
+// @Tags         Webhooks
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id   path string true "ID of subscription"
+// @Success      204
+// @Failure      401  {object}  object{error=string}
+// @Failure      403  {object}  object{error=string}
+// @Failure      404  {object}  object{error=string}
+// @Failure      500  {object}  object{error=string}
+// @Router       /admin/webhooks/{id} [delete]
+func (app *Application) deleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	id := httprouter.ParamsFromContext(r.Context()).ByName("id")
+	_, err := app.Webhooks.DeleteSubscription(id)
+
+	if errors.Is(err, webhooks.ErrSubscriptionNotFound) {
+		app.errorResponse(w, r, http.StatusNotFound, "Webhook subscription not found for id "+id)
+
+		return
+	}
+
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -4,43 +4,93 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
+	"github.com/dzhdmitry/link-shorter/internal/apikeys"
+	"github.com/dzhdmitry/link-shorter/internal/blocklist"
+	"github.com/dzhdmitry/link-shorter/internal/links"
 	"github.com/dzhdmitry/link-shorter/internal/utils"
+	"github.com/dzhdmitry/link-shorter/internal/utm"
+	"github.com/dzhdmitry/link-shorter/internal/webhooks"
+	"github.com/dzhdmitry/link-shorter/test"
 	"github.com/julienschmidt/httprouter"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/require"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 type testLinksCollection struct {
-	links   map[int]string
-	lastKey int
-	maxKey  int
+	links       map[int]string
+	expirations map[int]time.Time
+	aliases     map[string]int
+	disabled    map[int]bool
+	passwords   map[int]string
+	owners      map[int]string
+	metadata    map[int]links.Metadata
+	templates   map[int]string
+	rules       map[int]links.Rules
+	variants    map[int]links.Variants
+	fallbacks   map[int]string
+	down        map[int]bool
+	health      map[int]links.Health
+	previews    map[int]links.Preview
+	listQuery   links.ListQuery
+	lastKey     int
+	maxKey      int
 }
 
-func newTestLinkStorage(maxKey int, links map[int]string) *testLinksCollection {
+func newTestLinkStorage(maxKey int, URLs map[int]string) *testLinksCollection {
 	return &testLinksCollection{
-		links:  links,
-		maxKey: maxKey,
+		links:       URLs,
+		expirations: map[int]time.Time{},
+		aliases:     map[string]int{},
+		disabled:    map[int]bool{},
+		passwords:   map[int]string{},
+		owners:      map[int]string{},
+		metadata:    map[int]links.Metadata{},
+		templates:   map[int]string{},
+		rules:       map[int]links.Rules{},
+		variants:    map[int]links.Variants{},
+		fallbacks:   map[int]string{},
+		down:        map[int]bool{},
+		health:      map[int]links.Health{},
+		previews:    map[int]links.Preview{},
+		maxKey:      maxKey,
 	}
 }
 
-func (t *testLinksCollection) GenerateKey(URL string) (string, error) {
+func (t *testLinksCollection) GenerateKey(URL string, options links.LinkOptions) (string, error) {
 	key := t.lastKey + 1
 	t.links[key] = URL
+	t.expirations[key] = options.ExpiresAt
+	t.owners[key] = options.Owner
+
+	if options.PasswordHash != "" {
+		t.passwords[key] = options.PasswordHash
+	}
+
+	t.metadata[key] = options.Metadata
+	t.templates[key] = options.UTMTemplate
+	t.rules[key] = options.Rules
+	t.variants[key] = options.Variants
+	t.fallbacks[key] = options.Fallback
 	t.lastKey = key
 
 	return strconv.Itoa(key), nil
 }
 
-func (t *testLinksCollection) GenerateKeys(URLs []string) (map[string]string, error) {
+func (t *testLinksCollection) GenerateKeys(URLs []string, options links.LinkOptions) (map[string]string, error) {
 	result := map[string]string{}
 
 	for _, URL := range URLs {
-		r, err := t.GenerateKey(URL)
+		r, err := t.GenerateKey(URL, options)
 
 		if err != nil {
 			return nil, err
@@ -52,23 +102,359 @@ func (t *testLinksCollection) GenerateKeys(URLs []string) (map[string]string, er
 	return result, nil
 }
 
-func (t *testLinksCollection) GetURL(key string) (string, error) {
-	keyInt, _ := strconv.Atoi(key)
+func (t *testLinksCollection) GenerateUniqueKeys(URLs []string, options links.LinkOptions) (map[string]string, map[string]bool, error) {
+	result := map[string]string{}
+	existing := map[string]bool{}
+
+	for _, URL := range URLs {
+		for key, storedURL := range t.links {
+			if storedURL == URL {
+				result[URL] = strconv.Itoa(key)
+				existing[URL] = true
+			}
+		}
+
+		if !existing[URL] {
+			result[URL], _ = t.GenerateKey(URL, options)
+		}
+	}
+
+	return result, existing, nil
+}
+
+func (t *testLinksCollection) GenerateAlias(alias, URL string, options links.LinkOptions) (string, error) {
+	if _, ok := t.aliases[alias]; ok {
+		return "", links.ErrAliasTaken
+	}
+
+	key, _ := t.GenerateKey(URL, options)
+	t.aliases[alias], _ = strconv.Atoi(key)
 
-	return t.links[keyInt], nil
+	return alias, nil
 }
 
-func (t *testLinksCollection) GetURLs(keys []string) (map[string]string, error) {
-	URLs := make(map[string]string, len(keys))
+func (t *testLinksCollection) GetLink(key string) (links.Link, error) {
+	keyInt, err := strconv.Atoi(key)
+
+	if err != nil {
+		keyInt = t.aliases[key]
+	}
+
+	link := links.Link{
+		URL:          t.links[keyInt],
+		ExpiresAt:    t.expirations[keyInt],
+		Disabled:     t.disabled[keyInt],
+		PasswordHash: t.passwords[keyInt],
+		Owner:        t.owners[keyInt],
+		Metadata:     t.metadata[keyInt],
+		UTMTemplate:  t.templates[keyInt],
+		Rules:        t.rules[keyInt],
+		Variants:     t.variants[keyInt],
+		Fallback:     t.fallbacks[keyInt],
+		Down:         t.down[keyInt],
+		Health:       t.health[keyInt],
+		Preview:      t.previews[keyInt],
+	}
+
+	if link.URL != "" && link.IsExpired((&test.Clock{}).Now()) {
+		return link, links.ErrLinkExpired
+	}
+
+	if link.URL != "" && link.Disabled {
+		return link, links.ErrLinkDisabled
+	}
+
+	return link, nil
+}
+
+func (t *testLinksCollection) GetLinks(keys []string) (map[string]links.Link, error) {
+	result := make(map[string]links.Link, len(keys))
 
 	for _, k := range keys {
 		keyInt, _ := strconv.Atoi(k)
 		if URL, ok := t.links[keyInt]; ok {
-			URLs[k] = URL
+			result[k] = links.Link{URL: URL, PasswordHash: t.passwords[keyInt]}
+		}
+	}
+
+	return result, nil
+}
+
+func (t *testLinksCollection) UpdateLink(key string, update links.LinkUpdate) (links.Link, error) {
+	keyInt, _ := strconv.Atoi(key)
+
+	if _, ok := t.links[keyInt]; !ok {
+		return links.Link{}, links.ErrLinkNotFound
+	}
+
+	if update.URL != nil {
+		t.links[keyInt] = *update.URL
+	}
+
+	if update.Disabled != nil {
+		t.disabled[keyInt] = *update.Disabled
+	}
+
+	if update.PasswordHash != nil {
+		t.passwords[keyInt] = *update.PasswordHash
+	}
+
+	metadata := t.metadata[keyInt]
+
+	if update.Title != nil {
+		metadata.Title = *update.Title
+	}
+
+	if update.Tags != nil {
+		metadata.Tags = *update.Tags
+	}
+
+	if update.Notes != nil {
+		metadata.Notes = *update.Notes
+	}
+
+	t.metadata[keyInt] = metadata
+
+	if update.UTMTemplate != nil {
+		t.templates[keyInt] = *update.UTMTemplate
+	}
+
+	if update.Rules != nil {
+		t.rules[keyInt] = *update.Rules
+	}
+
+	if update.Variants != nil {
+		t.variants[keyInt] = *update.Variants
+	}
+
+	if update.Fallback != nil {
+		t.fallbacks[keyInt] = *update.Fallback
+	}
+
+	if update.Down != nil {
+		t.down[keyInt] = *update.Down
+	}
+
+	return links.Link{
+		Key:          key,
+		URL:          t.links[keyInt],
+		Disabled:     t.disabled[keyInt],
+		PasswordHash: t.passwords[keyInt],
+		Metadata:     metadata,
+		UTMTemplate:  t.templates[keyInt],
+		Rules:        t.rules[keyInt],
+		Variants:     t.variants[keyInt],
+		Fallback:     t.fallbacks[keyInt],
+		Down:         t.down[keyInt],
+	}, nil
+}
+
+func (t *testLinksCollection) DeleteLink(key string) (links.Link, error) {
+	keyInt, _ := strconv.Atoi(key)
+	URL, ok := t.links[keyInt]
+
+	if !ok {
+		return links.Link{}, links.ErrLinkNotFound
+	}
+
+	delete(t.links, keyInt)
+
+	return links.Link{Key: key, URL: URL}, nil
+}
+
+type testAPIKeys struct {
+	keys map[string]apikeys.Key
+}
+
+func newTestAPIKeys(keys ...apikeys.Key) *testAPIKeys {
+	t := &testAPIKeys{keys: map[string]apikeys.Key{}}
+
+	for _, key := range keys {
+		t.keys[key.ID] = key
+	}
+
+	return t
+}
+
+func (t *testAPIKeys) StoreKey(key apikeys.Key) error {
+	t.keys[key.ID] = key
+
+	return nil
+}
+
+func (t *testAPIKeys) GetKey(hash string) (apikeys.Key, error) {
+	for _, key := range t.keys {
+		if key.Hash == hash {
+			return key, nil
+		}
+	}
+
+	return apikeys.Key{}, apikeys.ErrKeyNotFound
+}
+
+func (t *testAPIKeys) RevokeKey(id string, revokedAt time.Time) (apikeys.Key, error) {
+	key, ok := t.keys[id]
+
+	if !ok {
+		return apikeys.Key{}, apikeys.ErrKeyNotFound
+	}
+
+	key.RevokedAt = revokedAt
+	t.keys[id] = key
+
+	return key, nil
+}
+
+type testUTMTemplates struct {
+	templates map[string]utm.Template
+}
+
+func newTestUTMTemplates(templates ...utm.Template) *testUTMTemplates {
+	t := &testUTMTemplates{templates: map[string]utm.Template{}}
+
+	for _, template := range templates {
+		t.templates[template.Name] = template
+	}
+
+	return t
+}
+
+func (t *testUTMTemplates) StoreTemplate(template utm.Template) error {
+	t.templates[template.Name] = template
+
+	return nil
+}
+
+func (t *testUTMTemplates) GetTemplate(name string) (utm.Template, error) {
+	template, ok := t.templates[name]
+
+	if !ok {
+		return utm.Template{}, utm.ErrTemplateNotFound
+	}
+
+	return template, nil
+}
+
+func (t *testUTMTemplates) ListTemplates() ([]utm.Template, error) {
+	result := make([]utm.Template, 0, len(t.templates))
+
+	for _, template := range t.templates {
+		result = append(result, template)
+	}
+
+	slices.SortFunc(result, func(a, b utm.Template) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return result, nil
+}
+
+func (t *testUTMTemplates) DeleteTemplate(name string) error {
+	if _, ok := t.templates[name]; !ok {
+		return utm.ErrTemplateNotFound
+	}
+
+	delete(t.templates, name)
+
+	return nil
+}
+
+// ListLinks Returns links of owner by ascending keys, cursor is given to the next page if there are more links
+func (t *testLinksCollection) ListLinks(query links.ListQuery) ([]links.Link, string, error) {
+	t.listQuery = query
+	result := []links.Link{}
+
+	for key := 1; key <= t.lastKey || key <= t.maxKey; key++ {
+		if query.Tag != "" && !slices.Contains(t.metadata[key].Tags, query.Tag) {
+			continue
 		}
+
+		if query.Health == links.HealthBroken && !t.health[key].IsBroken() {
+			continue
+		}
+
+		if URL, ok := t.links[key]; ok && t.owners[key] == query.Owner {
+			result = append(result, links.Link{
+				Key:         strconv.Itoa(key),
+				URL:         URL,
+				Owner:       t.owners[key],
+				Metadata:    t.metadata[key],
+				UTMTemplate: t.templates[key],
+				Rules:       t.rules[key],
+				Variants:    t.variants[key],
+				Health:      t.health[key],
+				Preview:     t.previews[key],
+			})
+		}
+	}
+
+	if len(result) > query.Limit {
+		return result[:query.Limit], "next", nil
+	}
+
+	return result, "", nil
+}
+
+type testClicksRecorder struct {
+	clicks []links.Click
+}
+
+func (t *testClicksRecorder) Record(click links.Click) {
+	t.clicks = append(t.clicks, click)
+}
+
+func (t *testClicksRecorder) Close() {
+	//
+}
+
+type testStats struct {
+	from time.Time
+	to   time.Time
+}
+
+func (t *testStats) GetStats(key string, from, to time.Time) (links.LinkStats, error) {
+	if key != "1" && key != "3" {
+		return links.LinkStats{}, links.ErrLinkNotFound
+	}
+
+	t.from = from
+	t.to = to
+	stats := links.LinkStats{
+		TotalClicks:    3,
+		UniqueVisitors: 2,
+		Daily:          []links.DailyClicks{{Date: "2024-02-06", Clicks: 1}, {Date: "2024-02-07", Clicks: 2}},
+		TopReferrers:   []links.Counter{{Name: "https://example.org", Count: 2}},
+		TopUserAgents:  []links.Counter{{Name: "Chrome", Count: 3}},
+	}
+
+	// link 3 has A/B split
+	if key == "3" {
+		stats.Variants = []links.VariantClicks{{Variant: 1, Clicks: 1, UniqueVisitors: 1}, {Variant: 2, Clicks: 2, UniqueVisitors: 1}}
+	}
+
+	return stats, nil
+}
+
+type testBlocklist struct {
+	list *blocklist.List
+}
+
+func newTestBlocklist(entries string) *testBlocklist {
+	list, err := blocklist.Parse(strings.NewReader(entries))
+
+	if err != nil {
+		panic(err)
 	}
 
-	return URLs, nil
+	return &testBlocklist{list: list}
+}
+
+func (t *testBlocklist) Match(URL string) (string, bool) {
+	return t.list.Match(URL)
+}
+
+func (t *testBlocklist) Close() {
+	//
 }
 
 func TestIndexHandlerOK(t *testing.T) {
@@ -96,6 +482,7 @@ func TestIndexHandlerOK(t *testing.T) {
 func TestGenerateHandlerOK(t *testing.T) {
 	app := Application{
 		Logger: utils.NewLogger(io.Discard, &utils.Clock{}),
+		Clock:  &test.Clock{},
 		Links:  newTestLinkStorage(1, map[int]string{}),
 	}
 	w := httptest.NewRecorder()
@@ -117,71 +504,63 @@ func TestGenerateHandlerOK(t *testing.T) {
 	require.JSONEq(t, `{"link":"http://localhost/go/1"}`+"\n", string(jsonResponse))
 }
 
-func TestGenerateHandlerBadRequest(t *testing.T) {
-	app := Application{
-		Logger: utils.NewLogger(io.Discard, &utils.Clock{}),
-		Links:  newTestLinkStorage(0, map[int]string{}),
-	}
-
+func TestGenerateHandlerDedup(t *testing.T) {
 	tests := []struct {
-		name         string
-		request      any
-		expectedCode int
-		errorMessage string
+		name     string
+		URL      string
+		expected string
 	}{
-		{"Unknown field", envelope{"unknown": "example"}, http.StatusBadRequest, `json: unknown field \"unknown\"`},
-		{"Empty body", envelope{}, http.StatusUnprocessableEntity, "URL must be a valid URL string"},
-		{"Too big", envelope{"url": strings.Repeat("1", 2001)}, http.StatusUnprocessableEntity, "URL must be maximum 2000 letters long"},
-		{"Empty url", envelope{"url": ""}, http.StatusUnprocessableEntity, "URL must be a valid URL string"},
-		{"Invalid url #1", envelope{"url": "http"}, http.StatusUnprocessableEntity, "URL must be an absolute URL"},
-		{"Invalid url #2", envelope{"url": "http://"}, http.StatusUnprocessableEntity, "URL must be an absolute URL"},
-		{"Invalid url #3", envelope{"url": "httpss://exmaple.com"}, http.StatusUnprocessableEntity, "URL must begin with http or https"},
-		{"Invalid url #4", envelope{"url": "exmaple.com"}, http.StatusUnprocessableEntity, "URL must be an absolute URL"},
-		{"Invalid url #5", envelope{"url": "/exmaple.com"}, http.StatusUnprocessableEntity, "URL must be an absolute URL"},
+		{"New URL", "https://example2.org", `{"link":"http://localhost/go/2","existing":false}`},
+		{"Existing URL", "https://example.org", `{"link":"http://localhost/go/1","existing":true}`},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			collection := newTestLinkStorage(2, map[int]string{1: "https://example.org"})
+			collection.lastKey = 1
+			app := Application{
+				Config: Config{DedupEnabled: true},
+				Logger: utils.NewLogger(io.Discard, &utils.Clock{}),
+				Clock:  &test.Clock{},
+				Links:  collection,
+			}
 			w := httptest.NewRecorder()
-			body, _ := json.Marshal(tt.request)
+			body, _ := json.Marshal(envelope{"url": tt.URL})
 			r := httptest.NewRequest(http.MethodPost, "/generate", bytes.NewReader(body))
 
 			app.generateHandler(w, r)
 
 			result := w.Result()
 
-			require.Equal(t, "application/json", result.Header.Get("Content-Type"))
-			require.Equal(t, tt.expectedCode, result.StatusCode)
+			require.Equal(t, http.StatusOK, result.StatusCode)
 
 			jsonResponse, err := io.ReadAll(result.Body)
 
 			defer result.Body.Close()
 
 			require.NoError(t, err)
-			require.JSONEq(t, `{"error":"`+tt.errorMessage+`"}`+"\n", string(jsonResponse))
+			require.JSONEq(t, tt.expected, string(jsonResponse))
 		})
 	}
 }
 
-func TestGoHandlerOK(t *testing.T) {
+func TestGenerateHandlerNormalizes(t *testing.T) {
+	normalizer, _ := links.NewNormalizer([]string{links.NormalizeLowercase, links.NormalizeDefaultPort, links.NormalizeDotSegments}, nil)
+	collection := newTestLinkStorage(1, map[int]string{})
 	app := Application{
-		Logger:    utils.NewLogger(io.Discard, &utils.Clock{}),
-		Validator: *NewValidator("1"),
-		Links: newTestLinkStorage(1, map[int]string{
-			1: "https://example.com",
-		}),
+		Logger:     utils.NewLogger(io.Discard, &utils.Clock{}),
+		Clock:      &test.Clock{},
+		Normalizer: normalizer,
+		Links:      collection,
 	}
-
 	w := httptest.NewRecorder()
-	r := newRequestWithNamedParameter(http.MethodGet, "/go/:key", httprouter.Params{
-		httprouter.Param{Key: "key", Value: "1"},
-	})
+	body, _ := json.Marshal(envelope{"url": "HTTP://Example.com:80/a/../b"})
+	r := httptest.NewRequest(http.MethodPost, "/generate", bytes.NewReader(body))
 
-	app.goHandler(w, r)
+	app.generateHandler(w, r)
 
 	result := w.Result()
 
-	require.Equal(t, "application/json", result.Header.Get("Content-Type"))
 	require.Equal(t, http.StatusOK, result.StatusCode)
 
 	jsonResponse, err := io.ReadAll(result.Body)
@@ -189,99 +568,113 @@ func TestGoHandlerOK(t *testing.T) {
 	defer result.Body.Close()
 
 	require.NoError(t, err)
-	require.JSONEq(t, `{"link":"https://example.com"}`+"\n", string(jsonResponse))
+	require.JSONEq(t, `{"link":"http://localhost/go/1","url":"http://example.com/b"}`, string(jsonResponse))
+	require.Equal(t, "http://example.com/b", collection.links[1])
 }
 
-func TestGoHandlerBadRequest(t *testing.T) {
-	app := Application{
-		Logger: utils.NewLogger(io.Discard, &utils.Clock{}),
-		Links:  newTestLinkStorage(1, map[int]string{}),
-	}
+func TestHandlersValidateNormalizedURL(t *testing.T) {
+	normalizer, _ := links.NewNormalizer([]string{links.NormalizeLowercase, links.NormalizeDefaultPort, links.NormalizeDotSegments}, nil)
+	// only normalized URL is blocked
+	blocked := "HTTP://Example.com:80/a/../blocked"
 
 	tests := []struct {
-		name         string
-		key          string
-		errorMessage string
+		name    string
+		method  string
+		request any
+		handler func(app *Application) http.HandlerFunc
 	}{
-		{"Empty key", "", "key must be at least 1 letter long"},
-		{"Long key", "0123456789a", "key is invalid"},
+		{"Generate", http.MethodPost, envelope{"url": blocked}, func(app *Application) http.HandlerFunc { return app.generateHandler }},
+		{"Batch", http.MethodPost, []string{"https://example.org", blocked}, func(app *Application) http.HandlerFunc { return app.batchGenerateHandler }},
+		{"Update", http.MethodPatch, envelope{"url": blocked}, func(app *Application) http.HandlerFunc { return app.updateLinkHandler }},
+		{"Fullwidth digits", http.MethodPost, envelope{"url": "http://１２７.０.０.１/"}, func(app *Application) http.HandlerFunc { return app.generateHandler }},
+		{"Enclosed digits", http.MethodPost, []string{"http://①②⑦.0.0.1/"}, func(app *Application) http.HandlerFunc { return app.batchGenerateHandler }},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			validator := NewValidator("12")
+			validator.Blocklist = newTestBlocklist(`/^http://example\.com/blocked$/`)
+			collection := newTestLinkStorage(2, map[int]string{1: "https://example.com"})
+			app := Application{
+				Logger:     utils.NewLogger(io.Discard, &utils.Clock{}),
+				Validator:  *validator,
+				Clock:      &test.Clock{},
+				Normalizer: normalizer,
+				Links:      collection,
+			}
 			w := httptest.NewRecorder()
-			r := newRequestWithNamedParameter(http.MethodGet, "/go/:key", httprouter.Params{
-				httprouter.Param{Key: "key", Value: tt.key},
+			body, _ := json.Marshal(tt.request)
+			r := newRequestWithNamedParameter(tt.method, "/links/:key", httprouter.Params{
+				httprouter.Param{Key: "key", Value: "1"},
 			})
+			r.Body = io.NopCloser(bytes.NewReader(body))
 
-			app.goHandler(w, r)
+			tt.handler(&app)(w, r)
 
 			result := w.Result()
 
-			require.Equal(t, "application/json", result.Header.Get("Content-Type"))
-			require.Equal(t, http.StatusBadRequest, result.StatusCode)
-
-			jsonResponse, err := io.ReadAll(result.Body)
-
 			defer result.Body.Close()
 
-			require.NoError(t, err)
-			require.JSONEq(t, `{"error":"`+tt.errorMessage+`"}`+"\n", string(jsonResponse))
+			require.Equal(t, http.StatusUnprocessableEntity, result.StatusCode)
+			require.Equal(t, map[int]string{1: "https://example.com"}, collection.links)
 		})
 	}
 }
 
-func TestBatchGenerateHandlerOK(t *testing.T) {
+func TestGenerateHandlerExpiresAt(t *testing.T) {
+	collection := newTestLinkStorage(1, map[int]string{})
 	app := Application{
 		Logger: utils.NewLogger(io.Discard, &utils.Clock{}),
-		Links:  newTestLinkStorage(2, map[int]string{}),
+		Clock:  &test.Clock{},
+		Links:  collection,
 	}
 	w := httptest.NewRecorder()
-	body, _ := json.Marshal([]string{"https://example.org", "https://example2.org"})
-	r := httptest.NewRequest(http.MethodPost, "/batch/generate", bytes.NewReader(body))
+	body, _ := json.Marshal(envelope{"url": "https://example.org", "expires_at": "2024-02-08T12:00:00Z"})
+	r := httptest.NewRequest(http.MethodPost, "/generate", bytes.NewReader(body))
 
-	app.batchGenerateHandler(w, r)
+	app.generateHandler(w, r)
 
 	result := w.Result()
 
-	require.Equal(t, "application/json", result.Header.Get("Content-Type"))
-	require.Equal(t, http.StatusOK, result.StatusCode)
-
-	jsonResponse, err := io.ReadAll(result.Body)
-
 	defer result.Body.Close()
 
-	require.NoError(t, err)
-	require.JSONEq(t, `{"links":{"https://example.org":"http://localhost/go/1","https://example2.org":"http://localhost/go/2"}}`+"\n", string(jsonResponse))
+	require.Equal(t, http.StatusOK, result.StatusCode)
+	require.Equal(t, time.Date(2024, 2, 8, 12, 0, 0, 0, time.UTC), collection.expirations[1])
 }
 
-func TestBatchGenerateHandlerBadRequest(t *testing.T) {
+func TestGenerateHandlerAlias(t *testing.T) {
+	collection := newTestLinkStorage(1, map[int]string{})
 	app := Application{
-		Logger: utils.NewLogger(io.Discard, &utils.Clock{}),
-		Links:  newTestLinkStorage(2, map[int]string{}),
+		Logger:    utils.NewLogger(io.Discard, &utils.Clock{}),
+		Clock:     &test.Clock{},
+		Validator: *NewValidator(links.Alphabets[links.AlphabetBase36]),
+		Links:     collection,
 	}
 
 	tests := []struct {
-		name         string
-		request      any
-		expectedCode int
-		errorMessage string
+		name             string
+		alias            string
+		expectedCode     int
+		expectedResponse string
 	}{
-		{"Unknown field", envelope{"unknown": "example"}, http.StatusBadRequest, `body contains incorrect JSON type (at character 1)`},
-		{"Empty url", []string{"link"}, http.StatusUnprocessableEntity, `URL must be an absolute URL`},
+		{"New alias", "spring-sale", http.StatusOK, `{"link":"http://localhost/go/spring-sale"}`},
+		{"Taken alias", "spring-sale", http.StatusConflict, `{"error":"alias is already taken"}`},
+		{"Upper case alias", "SpringSale", http.StatusOK, `{"link":"http://localhost/go/SpringSale"}`},
+		{"Alias looks like key", "springsale", http.StatusUnprocessableEntity, `{"error":"alias must contain at least one letter which is not used in generated keys, e.g. \"-\""}`},
+		{"Invalid letter", "spring sale", http.StatusUnprocessableEntity, `{"error":"alias may contain only latin letters, digits, \"-\" and \"_\""}`},
+		{"Too long", "spring-" + strings.Repeat("a", 58), http.StatusUnprocessableEntity, `{"error":"alias must be maximum 64 letters long"}`},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			w := httptest.NewRecorder()
-			body, _ := json.Marshal(tt.request)
-			r := httptest.NewRequest(http.MethodPost, "/batch/generate", bytes.NewReader(body))
+			body, _ := json.Marshal(envelope{"url": "https://example.org", "alias": tt.alias})
+			r := httptest.NewRequest(http.MethodPost, "/generate", bytes.NewReader(body))
 
-			app.batchGenerateHandler(w, r)
+			app.generateHandler(w, r)
 
 			result := w.Result()
 
-			require.Equal(t, "application/json", result.Header.Get("Content-Type"))
 			require.Equal(t, tt.expectedCode, result.StatusCode)
 
 			jsonResponse, err := io.ReadAll(result.Body)
@@ -289,29 +682,84 @@ func TestBatchGenerateHandlerBadRequest(t *testing.T) {
 			defer result.Body.Close()
 
 			require.NoError(t, err)
-			require.JSONEq(t, `{"error":"`+tt.errorMessage+`"}`+"\n", string(jsonResponse))
+			require.JSONEq(t, tt.expectedResponse+"\n", string(jsonResponse))
 		})
 	}
 }
 
-func TestBatchGoHandlerOK(t *testing.T) {
+func TestGenerateHandlerBadRequest(t *testing.T) {
 	app := Application{
-		Logger:    utils.NewLogger(io.Discard, &utils.Clock{}),
-		Validator: *NewValidator("12"),
-		Links: newTestLinkStorage(2, map[int]string{
-			1: "https://example.com",
-			2: "https://example2.com",
-		}),
+		Logger: utils.NewLogger(io.Discard, &utils.Clock{}),
+		Clock:  &test.Clock{},
+		Links:  newTestLinkStorage(0, map[int]string{}),
 	}
-	w := httptest.NewRecorder()
-	body, _ := json.Marshal([]string{"1", "2"})
-	r := httptest.NewRequest(http.MethodPost, "/batch/go", bytes.NewReader(body))
 
-	app.batchGoHandler(w, r)
+	tests := []struct {
+		name         string
+		request      any
+		expectedCode int
+		errorMessage string
+	}{
+		{"Unknown field", envelope{"unknown": "example"}, http.StatusBadRequest, `json: unknown field \"unknown\"`},
+		{"Empty body", envelope{}, http.StatusUnprocessableEntity, "URL must be a valid URL string"},
+		{"Too big", envelope{"url": strings.Repeat("1", 2001)}, http.StatusUnprocessableEntity, "URL must be maximum 2000 letters long"},
+		{"Empty url", envelope{"url": ""}, http.StatusUnprocessableEntity, "URL must be a valid URL string"},
+		{"Invalid url #1", envelope{"url": "http"}, http.StatusUnprocessableEntity, "URL must be an absolute URL"},
+		{"Invalid url #2", envelope{"url": "http://"}, http.StatusUnprocessableEntity, "URL must be an absolute URL"},
+		{"Invalid url #3", envelope{"url": "httpss://exmaple.com"}, http.StatusUnprocessableEntity, "URL must begin with http or https"},
+		{"Invalid url #4", envelope{"url": "exmaple.com"}, http.StatusUnprocessableEntity, "URL must be an absolute URL"},
+		{"Invalid url #5", envelope{"url": "/exmaple.com"}, http.StatusUnprocessableEntity, "URL must be an absolute URL"},
+		{"Internal url", envelope{"url": "http://169.254.169.254/latest/meta-data/"}, http.StatusUnprocessableEntity, "URL must not point to internal address"},
+		{"Past expires_at", envelope{"url": "https://example.org", "expires_at": "2024-02-07T11:00:00Z"}, http.StatusUnprocessableEntity, "expires_at must be in the future"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			body, _ := json.Marshal(tt.request)
+			r := httptest.NewRequest(http.MethodPost, "/generate", bytes.NewReader(body))
+
+			app.generateHandler(w, r)
+
+			result := w.Result()
+
+			require.Equal(t, "application/json", result.Header.Get("Content-Type"))
+			require.Equal(t, tt.expectedCode, result.StatusCode)
+
+			jsonResponse, err := io.ReadAll(result.Body)
+
+			defer result.Body.Close()
+
+			require.NoError(t, err)
+			require.JSONEq(t, `{"error":"`+tt.errorMessage+`"}`+"\n", string(jsonResponse))
+		})
+	}
+}
+
+func TestGoHandlerOK(t *testing.T) {
+	clicks := &testClicksRecorder{}
+	app := Application{
+		Logger:    utils.NewLogger(io.Discard, &utils.Clock{}),
+		Clock:     &test.Clock{},
+		Validator: *NewValidator("1"),
+		Links: newTestLinkStorage(1, map[int]string{
+			1: "https://example.com",
+		}),
+		Clicks: clicks,
+	}
+
+	w := httptest.NewRecorder()
+	r := newRequestWithNamedParameter(http.MethodGet, "/go/:key", httprouter.Params{
+		httprouter.Param{Key: "key", Value: "1"},
+	})
+	r.Header.Set("Accept", "application/json")
+
+	app.goHandler(w, r)
 
 	result := w.Result()
 
 	require.Equal(t, "application/json", result.Header.Get("Content-Type"))
+	require.Equal(t, "Accept", result.Header.Get("Vary"))
 	require.Equal(t, http.StatusOK, result.StatusCode)
 
 	jsonResponse, err := io.ReadAll(result.Body)
@@ -319,40 +767,203 @@ func TestBatchGoHandlerOK(t *testing.T) {
 	defer result.Body.Close()
 
 	require.NoError(t, err)
-	require.JSONEq(t, `{"links":{"1":"https://example.com","2":"https://example2.com"}}`+"\n", string(jsonResponse))
+	require.JSONEq(t, `{"link":"https://example.com"}`+"\n", string(jsonResponse))
+	require.Len(t, clicks.clicks, 1)
 }
 
-func TestBatchGoHandlerBadRequest(t *testing.T) {
+func TestGoHandlerRedirect(t *testing.T) {
+	tests := []struct {
+		name           string
+		redirectStatus int
+		accept         string
+	}{
+		{"Moved permanently", http.StatusMovedPermanently, ""},
+		{"Found", http.StatusFound, ""},
+		{"Temporary redirect", http.StatusTemporaryRedirect, "text/html"},
+		{"Permanent redirect", http.StatusPermanentRedirect, "*/*"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := Application{
+				Config:    Config{RedirectStatus: tt.redirectStatus},
+				Logger:    utils.NewLogger(io.Discard, &utils.Clock{}),
+				Validator: *NewValidator("1"),
+				Clock:     &test.Clock{},
+				Links: newTestLinkStorage(1, map[int]string{
+					1: "https://example.com",
+				}),
+				Clicks: &testClicksRecorder{},
+			}
+
+			w := httptest.NewRecorder()
+			r := newRequestWithNamedParameter(http.MethodGet, "/go/:key", httprouter.Params{
+				httprouter.Param{Key: "key", Value: "1"},
+			})
+			r.Header.Set("Accept", tt.accept)
+
+			app.goHandler(w, r)
+
+			result := w.Result()
+
+			defer result.Body.Close()
+
+			require.Equal(t, tt.redirectStatus, result.StatusCode)
+			require.Equal(t, "https://example.com", result.Header.Get("Location"))
+			require.Equal(t, "Accept", result.Header.Get("Vary"))
+		})
+	}
+}
+
+func TestGoHandlerAlias(t *testing.T) {
+	collection := newTestLinkStorage(1, map[int]string{})
+	_, _ = collection.GenerateAlias("spring-sale", "https://example.com/sale", links.LinkOptions{})
 	app := Application{
+		Config:    Config{RedirectStatus: http.StatusFound},
 		Logger:    utils.NewLogger(io.Discard, &utils.Clock{}),
-		Validator: *NewValidator("1"),
-		Links: newTestLinkStorage(2, map[int]string{
-			1: "http://example.com",
+		Clock:     &test.Clock{},
+		Validator: *NewValidator(links.Alphabets[links.AlphabetBase36]),
+		Links:     collection,
+		Clicks:    &testClicksRecorder{},
+	}
+
+	w := httptest.NewRecorder()
+	r := newRequestWithNamedParameter(http.MethodGet, "/go/:key", httprouter.Params{
+		httprouter.Param{Key: "key", Value: "spring-sale"},
+	})
+
+	app.goHandler(w, r)
+
+	result := w.Result()
+
+	defer result.Body.Close()
+
+	require.Equal(t, http.StatusFound, result.StatusCode)
+	require.Equal(t, "https://example.com/sale", result.Header.Get("Location"))
+}
+
+func TestGoHandlerRecordsClick(t *testing.T) {
+	clicks := &testClicksRecorder{}
+	app := Application{
+		Config:    Config{RedirectStatus: http.StatusFound},
+		Logger:    utils.NewLogger(io.Discard, &utils.Clock{}),
+		Clock:     &test.Clock{},
+		Validator: *NewValidator(links.Alphabets[links.AlphabetBase36]),
+		Links: newTestLinkStorage(1, map[int]string{
+			1: "https://example.com",
+		}),
+		Clicks: clicks,
+	}
+
+	w := httptest.NewRecorder()
+	r := newRequestWithNamedParameter(http.MethodGet, "/go/:key", httprouter.Params{
+		httprouter.Param{Key: "key", Value: "1"},
+	})
+	r.RemoteAddr = "192.168.10.25:54321"
+	r.Header.Set("Referer", "https://news.example.org/post")
+	r.Header.Set("User-Agent", "Mozilla/5.0")
+
+	app.goHandler(w, r)
+
+	require.Equal(t, []links.Click{{
+		Key:       "1",
+		ClickedAt: (&test.Clock{}).Now(),
+		Referrer:  "https://news.example.org/post",
+		UserAgent: "Mozilla/5.0",
+		IP:        "192.168.10.0",
+	}}, clicks.clicks)
+
+	w = httptest.NewRecorder()
+	r = newRequestWithNamedParameter(http.MethodGet, "/go/:key", httprouter.Params{
+		httprouter.Param{Key: "key", Value: "2"},
+	})
+
+	app.goHandler(w, r)
+
+	require.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+	require.Len(t, clicks.clicks, 1)
+}
+
+func TestAnonymizeIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		remoteAddr string
+		expected   string
+	}{
+		{"IPv4 with port", "192.168.10.25:54321", "192.168.10.0"},
+		{"IPv4 without port", "10.1.2.3", "10.1.2.0"},
+		{"IPv6 with port", "[2001:db8:85a3:8d3:1319:8a2e:370:7348]:443", "2001:db8:85a3::"},
+		{"Invalid", "unknown", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, anonymizeIP(tt.remoteAddr))
+		})
+	}
+}
+
+func TestGoHandlerNotFound(t *testing.T) {
+	app := Application{
+		Config:    Config{RedirectStatus: http.StatusFound},
+		Logger:    utils.NewLogger(io.Discard, &utils.Clock{}),
+		Validator: *NewValidator("12"),
+		Links: newTestLinkStorage(1, map[int]string{
+			1: "https://example.com",
 		}),
 	}
 
+	w := httptest.NewRecorder()
+	r := newRequestWithNamedParameter(http.MethodGet, "/go/:key", httprouter.Params{
+		httprouter.Param{Key: "key", Value: "2"},
+	})
+
+	app.goHandler(w, r)
+
+	result := w.Result()
+
+	require.Equal(t, "application/json", result.Header.Get("Content-Type"))
+	require.Equal(t, http.StatusNotFound, result.StatusCode)
+
+	jsonResponse, err := io.ReadAll(result.Body)
+
+	defer result.Body.Close()
+
+	require.NoError(t, err)
+	require.JSONEq(t, `{"error":"Full link not found for key 2"}`+"\n", string(jsonResponse))
+}
+
+func TestGoHandlerBadRequest(t *testing.T) {
+	app := Application{
+		Logger:    utils.NewLogger(io.Discard, &utils.Clock{}),
+		Validator: *NewValidator(links.Alphabets[links.AlphabetBase36]),
+		Links:     newTestLinkStorage(1, map[int]string{}),
+	}
+
 	tests := []struct {
 		name         string
-		request      any
-		expectedCode int
+		key          string
 		errorMessage string
 	}{
-		{"Unknown field", envelope{"unknown": "example"}, http.StatusBadRequest, `body contains incorrect JSON type (at character 1)`},
-		{"Invalid key", []string{"й"}, http.StatusUnprocessableEntity, `invalid letter`},
+		{"Empty key", "", "key must be at least 1 letter long"},
+		{"Long key", "0123456789abcd", "key is invalid"},
+		{"Long alias", "spring-" + strings.Repeat("a", 58), "key is invalid"},
+		{"Invalid letter", "spring.sale", "invalid letter"},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			w := httptest.NewRecorder()
-			body, _ := json.Marshal(tt.request)
-			r := httptest.NewRequest(http.MethodPost, "/batch/go", bytes.NewReader(body))
+			r := newRequestWithNamedParameter(http.MethodGet, "/go/:key", httprouter.Params{
+				httprouter.Param{Key: "key", Value: tt.key},
+			})
 
-			app.batchGoHandler(w, r)
+			app.goHandler(w, r)
 
 			result := w.Result()
 
 			require.Equal(t, "application/json", result.Header.Get("Content-Type"))
-			require.Equal(t, tt.expectedCode, result.StatusCode)
+			require.Equal(t, http.StatusBadRequest, result.StatusCode)
 
 			jsonResponse, err := io.ReadAll(result.Body)
 
@@ -364,13 +975,2389 @@ func TestBatchGoHandlerBadRequest(t *testing.T) {
 	}
 }
 
-func newRequestWithNamedParameter(method, target string, params any) *http.Request {
-	r := httptest.NewRequest(method, target, nil)
-	ctx := r.Context()
-	ctx = context.WithValue(ctx, httprouter.ParamsKey, params)
+func TestGoHandlerGone(t *testing.T) {
+	collection := newTestLinkStorage(1, map[int]string{
+		1: "https://example.com",
+	})
+	collection.expirations[1] = time.Date(2024, 2, 7, 11, 0, 0, 0, time.UTC)
+	app := Application{
+		Config:    Config{RedirectStatus: http.StatusFound},
+		Logger:    utils.NewLogger(io.Discard, &utils.Clock{}),
+		Validator: *NewValidator("1"),
+		Links:     collection,
+	}
 
-	_ = r.WithContext(ctx)
-	r, _ = http.NewRequestWithContext(ctx, method, target, nil)
+	w := httptest.NewRecorder()
+	r := newRequestWithNamedParameter(http.MethodGet, "/go/:key", httprouter.Params{
+		httprouter.Param{Key: "key", Value: "1"},
+	})
 
-	return r
+	app.goHandler(w, r)
+
+	result := w.Result()
+
+	require.Equal(t, "application/json", result.Header.Get("Content-Type"))
+	require.Equal(t, http.StatusGone, result.StatusCode)
+
+	jsonResponse, err := io.ReadAll(result.Body)
+
+	defer result.Body.Close()
+
+	require.NoError(t, err)
+	require.JSONEq(t, `{"error":"Link has expired for key 1"}`+"\n", string(jsonResponse))
+}
+
+func TestGoHandlerDisabled(t *testing.T) {
+	collection := newTestLinkStorage(1, map[int]string{
+		1: "https://example.com",
+	})
+	collection.disabled[1] = true
+	app := Application{
+		Config:    Config{RedirectStatus: http.StatusFound},
+		Logger:    utils.NewLogger(io.Discard, &utils.Clock{}),
+		Validator: *NewValidator("1"),
+		Links:     collection,
+	}
+
+	w := httptest.NewRecorder()
+	r := newRequestWithNamedParameter(http.MethodGet, "/go/:key", httprouter.Params{
+		httprouter.Param{Key: "key", Value: "1"},
+	})
+
+	app.goHandler(w, r)
+
+	result := w.Result()
+
+	require.Equal(t, http.StatusForbidden, result.StatusCode)
+
+	jsonResponse, err := io.ReadAll(result.Body)
+
+	defer result.Body.Close()
+
+	require.NoError(t, err)
+	require.JSONEq(t, `{"error":"Link is disabled for key 1"}`+"\n", string(jsonResponse))
+}
+
+func newProtectedLinkApp(t *testing.T) *Application {
+	passwordHash, err := hashPassword("secret")
+
+	require.NoError(t, err)
+
+	collection := newTestLinkStorage(1, map[int]string{
+		1: "https://example.com",
+	})
+	collection.passwords[1] = passwordHash
+
+	return &Application{
+		Config:    Config{RedirectStatus: http.StatusFound, PasswordAttempts: 2, PasswordInterval: "1m"},
+		Logger:    utils.NewLogger(io.Discard, &utils.Clock{}),
+		Clock:     &test.Clock{},
+		Validator: *NewValidator("1"),
+		Links:     collection,
+		Clicks:    &testClicksRecorder{},
+	}
+}
+
+func TestGoHandlerProtected(t *testing.T) {
+	tests := []struct {
+		name             string
+		method           string
+		accept           string
+		header           string
+		form             string
+		expectedCode     int
+		expectedLocation string
+		expectedBody     string
+	}{
+		{"Form", http.MethodGet, "", "", "", http.StatusUnauthorized, "", `<form method="post">`},
+		{"JSON without password", http.MethodGet, "application/json", "", "", http.StatusUnauthorized, "", `{"error":"password is required"}`},
+		{"JSON invalid password", http.MethodGet, "application/json", "wrong", "", http.StatusUnauthorized, "", `{"error":"invalid password"}`},
+		{"JSON valid password", http.MethodGet, "application/json", "secret", "", http.StatusOK, "", `{"link":"https://example.com"}`},
+		{"Header valid password", http.MethodGet, "", "secret", "", http.StatusFound, "https://example.com", ""},
+		{"Submitted invalid password", http.MethodPost, "", "", "password=wrong", http.StatusUnauthorized, "", "invalid password"},
+		{"Submitted valid password", http.MethodPost, "", "", "password=secret", http.StatusSeeOther, "https://example.com", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newProtectedLinkApp(t)
+			w := httptest.NewRecorder()
+			r := newRequestWithNamedParameter(tt.method, "/go/:key", httprouter.Params{
+				httprouter.Param{Key: "key", Value: "1"},
+			})
+
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+
+			if tt.header != "" {
+				r.Header.Set(PasswordHeader, tt.header)
+			}
+
+			if tt.form != "" {
+				r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				r.Body = io.NopCloser(strings.NewReader(tt.form))
+			}
+
+			app.goHandler(w, r)
+
+			result := w.Result()
+
+			require.Equal(t, tt.expectedCode, result.StatusCode)
+			require.Equal(t, tt.expectedLocation, result.Header.Get("Location"))
+			require.Equal(t, "no-store", result.Header.Get("Cache-Control"))
+
+			body, err := io.ReadAll(result.Body)
+
+			defer result.Body.Close()
+
+			require.NoError(t, err)
+			require.Contains(t, string(body), tt.expectedBody)
+		})
+	}
+}
+
+func TestGoHandlerPasswordAttempts(t *testing.T) {
+	app := newProtectedLinkApp(t)
+	codes := make([]int, 0, 3)
+
+	for _, password := range []string{"wrong", "wrong", "secret"} {
+		w := httptest.NewRecorder()
+		r := newRequestWithNamedParameter(http.MethodGet, "/go/:key", httprouter.Params{
+			httprouter.Param{Key: "key", Value: "1"},
+		})
+		r.Header.Set("Accept", "application/json")
+		r.Header.Set(PasswordHeader, password)
+
+		app.goHandler(w, r)
+
+		codes = append(codes, w.Result().StatusCode)
+	}
+
+	require.Equal(t, []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests}, codes)
+}
+
+func TestGoHandlerParallelPasswordAttempts(t *testing.T) {
+	app := newProtectedLinkApp(t)
+	codes := make(chan int, 10)
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			w := httptest.NewRecorder()
+			r := newRequestWithNamedParameter(http.MethodGet, "/go/:key", httprouter.Params{
+				httprouter.Param{Key: "key", Value: "1"},
+			})
+			r.Header.Set("Accept", "application/json")
+			r.Header.Set(PasswordHeader, "wrong")
+
+			app.goHandler(w, r)
+
+			codes <- w.Result().StatusCode
+		}()
+	}
+
+	wg.Wait()
+	close(codes)
+
+	counts := map[int]int{}
+
+	for code := range codes {
+		counts[code]++
+	}
+
+	require.Equal(t, map[int]int{http.StatusUnauthorized: 2, http.StatusTooManyRequests: 8}, counts)
+}
+
+func TestGoHandlerValidPasswordNotCounted(t *testing.T) {
+	app := newProtectedLinkApp(t)
+	codes := make([]int, 0, 5)
+
+	for _, password := range []string{"secret", "secret", "secret", "wrong", "wrong"} {
+		w := httptest.NewRecorder()
+		r := newRequestWithNamedParameter(http.MethodGet, "/go/:key", httprouter.Params{
+			httprouter.Param{Key: "key", Value: "1"},
+		})
+		r.Header.Set("Accept", "application/json")
+		r.Header.Set(PasswordHeader, password)
+
+		app.goHandler(w, r)
+
+		codes = append(codes, w.Result().StatusCode)
+	}
+
+	require.Equal(t, []int{http.StatusOK, http.StatusOK, http.StatusOK, http.StatusUnauthorized, http.StatusUnauthorized}, codes)
+}
+
+func TestGoHandlerPasswordAttemptsRestored(t *testing.T) {
+	app := newProtectedLinkApp(t)
+	clock := &testDayClock{now: time.Date(2024, 2, 7, 12, 0, 0, 0, time.UTC)}
+	app.Clock = clock
+	attempt := func() int {
+		w := httptest.NewRecorder()
+		r := newRequestWithNamedParameter(http.MethodGet, "/go/:key", httprouter.Params{
+			httprouter.Param{Key: "key", Value: "1"},
+		})
+		r.Header.Set("Accept", "application/json")
+		r.Header.Set(PasswordHeader, "wrong")
+
+		app.goHandler(w, r)
+
+		return w.Result().StatusCode
+	}
+
+	require.Equal(t, []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests}, []int{attempt(), attempt(), attempt()})
+
+	clock.now = clock.now.Add(59 * time.Second)
+
+	require.Equal(t, http.StatusTooManyRequests, attempt())
+
+	clock.now = clock.now.Add(time.Second)
+
+	require.Equal(t, []int{http.StatusUnauthorized, http.StatusTooManyRequests}, []int{attempt(), attempt()})
+}
+
+func TestLinkHandlerProtected(t *testing.T) {
+	app := newProtectedLinkApp(t)
+	w := httptest.NewRecorder()
+	r := newRequestWithNamedParameter(http.MethodGet, "/api/links/:key", httprouter.Params{
+		httprouter.Param{Key: "key", Value: "1"},
+	})
+
+	app.linkHandler(w, r)
+
+	result := w.Result()
+
+	require.Equal(t, http.StatusUnauthorized, result.StatusCode)
+
+	jsonResponse, err := io.ReadAll(result.Body)
+
+	defer result.Body.Close()
+
+	require.NoError(t, err)
+	require.JSONEq(t, `{"error":"password is required"}`+"\n", string(jsonResponse))
+}
+
+func TestLinkHandlerOK(t *testing.T) {
+	app := Application{
+		Config:    Config{RedirectStatus: http.StatusFound},
+		Logger:    utils.NewLogger(io.Discard, &utils.Clock{}),
+		Clock:     &test.Clock{},
+		Validator: *NewValidator("1"),
+		Links: newTestLinkStorage(1, map[int]string{
+			1: "https://example.com",
+		}),
+	}
+
+	w := httptest.NewRecorder()
+	r := newRequestWithNamedParameter(http.MethodGet, "/api/links/:key", httprouter.Params{
+		httprouter.Param{Key: "key", Value: "1"},
+	})
+
+	app.linkHandler(w, r)
+
+	result := w.Result()
+
+	require.Equal(t, "application/json", result.Header.Get("Content-Type"))
+	require.Equal(t, http.StatusOK, result.StatusCode)
+
+	jsonResponse, err := io.ReadAll(result.Body)
+
+	defer result.Body.Close()
+
+	require.NoError(t, err)
+	require.JSONEq(t, `{"link":"https://example.com"}`+"\n", string(jsonResponse))
+}
+
+func TestLinkHandlerDestination(t *testing.T) {
+	tests := []struct {
+		name     string
+		down     bool
+		template string
+		expected string
+	}{
+		{"Original", false, "", "https://example.com"},
+		{"Fallback", true, "", "https://backup.example.com"},
+		{"UTM template", false, "spring", "https://example.com?utm_source=newsletter"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := newTestLinkStorage(1, map[int]string{
+				1: "https://example.com",
+			})
+			storage.down[1] = tt.down
+			storage.fallbacks[1] = "https://backup.example.com"
+			storage.templates[1] = tt.template
+			clicks := &testClicksRecorder{}
+			app := Application{
+				Config:       Config{RedirectStatus: http.StatusFound},
+				Logger:       utils.NewLogger(io.Discard, &utils.Clock{}),
+				Clock:        &test.Clock{},
+				Validator:    *NewValidator("1"),
+				Links:        storage,
+				Clicks:       clicks,
+				UTMTemplates: newTestUTMTemplates(utm.Template{Name: "spring", Source: "newsletter"}),
+			}
+
+			w := httptest.NewRecorder()
+			r := newRequestWithNamedParameter(http.MethodGet, "/api/links/:key", httprouter.Params{
+				httprouter.Param{Key: "key", Value: "1"},
+			})
+
+			app.linkHandler(w, r)
+
+			result := w.Result()
+			jsonResponse, err := io.ReadAll(result.Body)
+
+			defer result.Body.Close()
+
+			require.NoError(t, err)
+			require.Equal(t, http.StatusOK, result.StatusCode)
+			require.JSONEq(t, `{"link":`+strconv.Quote(tt.expected)+`}`, string(jsonResponse))
+			require.Empty(t, clicks.clicks)
+
+			w = httptest.NewRecorder()
+			r = newRequestWithNamedParameter(http.MethodGet, "/go/:key", httprouter.Params{
+				httprouter.Param{Key: "key", Value: "1"},
+			})
+
+			app.goHandler(w, r)
+
+			require.Equal(t, tt.expected, w.Result().Header.Get("Location"))
+		})
+	}
+}
+
+func TestUpdateLinkHandler(t *testing.T) {
+	tests := []struct {
+		name             string
+		key              string
+		request          any
+		expectedCode     int
+		expectedResponse string
+	}{
+		{"Update URL", "1", envelope{"url": "https://example.org"}, http.StatusOK, `{"link":"http://localhost/go/1","url":"https://example.org","disabled":false,"protected":false}`},
+		{"Disable", "1", envelope{"disabled": true}, http.StatusOK, `{"link":"http://localhost/go/1","url":"https://example.com","disabled":true,"protected":false}`},
+		{"Update and enable", "1", envelope{"url": "https://example.org", "disabled": false}, http.StatusOK, `{"link":"http://localhost/go/1","url":"https://example.org","disabled":false,"protected":false}`},
+		{"Set password", "1", envelope{"password": "secret"}, http.StatusOK, `{"link":"http://localhost/go/1","url":"https://example.com","disabled":false,"protected":true}`},
+		{"Remove password", "1", envelope{"password": ""}, http.StatusOK, `{"link":"http://localhost/go/1","url":"https://example.com","disabled":false,"protected":false}`},
+		{"Long password", "1", envelope{"password": strings.Repeat("a", 73)}, http.StatusUnprocessableEntity, `{"error":"password must be maximum 72 bytes long"}`},
+		{"Not found", "2", envelope{"disabled": true}, http.StatusNotFound, `{"error":"Full link not found for key 2"}`},
+		{"Invalid key", "1.", envelope{"disabled": true}, http.StatusBadRequest, `{"error":"invalid letter"}`},
+		{"Set metadata", "1", envelope{"title": "Spring sale", "tags": []string{"Sale", "spring", "sale"}, "notes": "internal"}, http.StatusOK,
+			`{"link":"http://localhost/go/1","url":"https://example.com","disabled":false,"protected":false,` +
+				`"title":"Spring sale","tags":["sale","spring"],"notes":"internal"}`},
+		{"Remove tags", "1", envelope{"tags": []string{}}, http.StatusOK, `{"link":"http://localhost/go/1","url":"https://example.com","disabled":false,"protected":false}`},
+		{"Long title", "1", envelope{"title": strings.Repeat("а", 256)}, http.StatusUnprocessableEntity, `{"error":"title must be maximum 255 letters long"}`},
+		{"Invalid tag", "1", envelope{"tags": []string{"spring sale"}}, http.StatusUnprocessableEntity,
+			`{"error":"tag may contain only letters, digits, \"-\" and \"_\""}`},
+		{"Set rules", "1", envelope{"rules": []envelope{{"url": "https://example.com/night", "time_from": "22:00", "time_to": "06:00"}}}, http.StatusOK,
+			`{"link":"http://localhost/go/1","url":"https://example.com","disabled":false,"protected":false,` +
+				`"rules":[{"url":"https://example.com/night","time_from":"22:00","time_to":"06:00"}]}`},
+		{"Remove rules", "1", envelope{"rules": []envelope{}}, http.StatusOK, `{"link":"http://localhost/go/1","url":"https://example.com","disabled":false,"protected":false}`},
+		{"Invalid rule", "1", envelope{"rules": []envelope{{"url": "https://example.com/de", "languages": []string{"german"}}}}, http.StatusUnprocessableEntity,
+			`{"error":"rule 1: invalid language: german"}`},
+		{"Set variants", "1", envelope{"variants": []envelope{{"url": "https://example.com/a", "weight": 70}, {"url": "https://example.com/b", "weight": 30}}},
+			http.StatusOK, `{"link":"http://localhost/go/1","url":"https://example.com","disabled":false,"protected":false,` +
+				`"variants":[{"url":"https://example.com/a","weight":70},{"url":"https://example.com/b","weight":30}]}`},
+		{"Remove variants", "1", envelope{"variants": []envelope{}}, http.StatusOK, `{"link":"http://localhost/go/1","url":"https://example.com","disabled":false,"protected":false}`},
+		{"Single variant", "1", envelope{"variants": []envelope{{"url": "https://example.com/a", "weight": 1}}}, http.StatusUnprocessableEntity,
+			`{"error":"from 2 to 10 variants must be provided"}`},
+		{"Set fallback and mark down", "1", envelope{"fallback": "https://example.com/maintenance", "down": true}, http.StatusOK,
+			`{"link":"http://localhost/go/1","url":"https://example.com","disabled":false,"protected":false,` +
+				`"fallback":"https://example.com/maintenance","down":true}`},
+		{"Remove fallback and mark up", "1", envelope{"fallback": "", "down": false}, http.StatusOK,
+			`{"link":"http://localhost/go/1","url":"https://example.com","disabled":false,"protected":false}`},
+		{"Invalid fallback", "1", envelope{"fallback": "maintenance"}, http.StatusUnprocessableEntity,
+			`{"error":"fallback: URL must be an absolute URL"}`},
+		{"Empty update", "1", envelope{}, http.StatusUnprocessableEntity, `{"error":"URL, disabled, password, title, description, tags, notes, utm_template, rules, variants, fallback or down must be provided"}`},
+		{"Invalid URL", "1", envelope{"url": "example.org"}, http.StatusUnprocessableEntity, `{"error":"URL must be an absolute URL"}`},
+		{"Unknown field", "1", envelope{"unknown": "example"}, http.StatusBadRequest, `{"error":"json: unknown field \"unknown\""}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := Application{
+				Logger:    utils.NewLogger(io.Discard, &utils.Clock{}),
+				Validator: *NewValidator("12"),
+				Clock:     &test.Clock{},
+				Links: newTestLinkStorage(1, map[int]string{
+					1: "https://example.com",
+				}),
+				Clicks: &testClicksRecorder{},
+			}
+
+			w := httptest.NewRecorder()
+			body, _ := json.Marshal(tt.request)
+			r := newRequestWithNamedParameter(http.MethodPatch, "/links/:key", httprouter.Params{
+				httprouter.Param{Key: "key", Value: tt.key},
+			})
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			app.updateLinkHandler(w, r)
+
+			result := w.Result()
+
+			require.Equal(t, "application/json", result.Header.Get("Content-Type"))
+			require.Equal(t, tt.expectedCode, result.StatusCode)
+
+			jsonResponse, err := io.ReadAll(result.Body)
+
+			defer result.Body.Close()
+
+			require.NoError(t, err)
+			require.JSONEq(t, tt.expectedResponse+"\n", string(jsonResponse))
+		})
+	}
+}
+
+func TestDeleteLinkHandler(t *testing.T) {
+	collection := newTestLinkStorage(1, map[int]string{
+		1: "https://example.com",
+	})
+	app := Application{
+		Logger:    utils.NewLogger(io.Discard, &utils.Clock{}),
+		Validator: *NewValidator("12"),
+		Links:     collection,
+	}
+
+	w := httptest.NewRecorder()
+	r := newRequestWithNamedParameter(http.MethodDelete, "/links/:key", httprouter.Params{
+		httprouter.Param{Key: "key", Value: "1"},
+	})
+
+	app.deleteLinkHandler(w, r)
+
+	result := w.Result()
+
+	require.Equal(t, http.StatusNoContent, result.StatusCode)
+	require.Equal(t, map[int]string{}, collection.links)
+
+	w = httptest.NewRecorder()
+
+	app.deleteLinkHandler(w, r)
+
+	result = w.Result()
+
+	require.Equal(t, http.StatusNotFound, result.StatusCode)
+
+	jsonResponse, err := io.ReadAll(result.Body)
+
+	defer result.Body.Close()
+
+	require.NoError(t, err)
+	require.JSONEq(t, `{"error":"Full link not found for key 1"}`+"\n", string(jsonResponse))
+}
+
+func TestStatsHandler(t *testing.T) {
+	tests := []struct {
+		name             string
+		target           string
+		key              string
+		expectedStatus   int
+		expectedResponse string
+		expectedFrom     time.Time
+		expectedTo       time.Time
+	}{
+		{"Default range", "/links/1/stats", "1", http.StatusOK,
+			`{"link":"http://localhost/go/1","from":"2024-01-09","to":"2024-02-07","total_clicks":3,"unique_visitors":2,` +
+				`"daily":[{"date":"2024-02-06","clicks":1},{"date":"2024-02-07","clicks":2}],` +
+				`"top_referrers":[{"name":"https://example.org","count":2}],"top_user_agents":[{"name":"Chrome","count":3}]}`,
+			time.Date(2024, 1, 9, 12, 0, 0, 0, time.UTC), time.Date(2024, 2, 7, 12, 0, 0, 0, time.UTC)},
+		{"Requested range", "/links/1/stats?from=2024-02-06&to=2024-02-07", "1", http.StatusOK,
+			`{"link":"http://localhost/go/1","from":"2024-02-06","to":"2024-02-07","total_clicks":3,"unique_visitors":2,` +
+				`"daily":[{"date":"2024-02-06","clicks":1},{"date":"2024-02-07","clicks":2}],` +
+				`"top_referrers":[{"name":"https://example.org","count":2}],"top_user_agents":[{"name":"Chrome","count":3}]}`,
+			time.Date(2024, 2, 6, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 7, 0, 0, 0, 0, time.UTC)},
+		{"With variants", "/links/3/stats?from=2024-02-06&to=2024-02-07", "3", http.StatusOK,
+			`{"link":"http://localhost/go/3","from":"2024-02-06","to":"2024-02-07","total_clicks":3,"unique_visitors":2,` +
+				`"daily":[{"date":"2024-02-06","clicks":1},{"date":"2024-02-07","clicks":2}],` +
+				`"top_referrers":[{"name":"https://example.org","count":2}],"top_user_agents":[{"name":"Chrome","count":3}],` +
+				`"variants":[{"variant":1,"clicks":1,"unique_visitors":1},{"variant":2,"clicks":2,"unique_visitors":1}]}`,
+			time.Date(2024, 2, 6, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 7, 0, 0, 0, 0, time.UTC)},
+		{"Invalid key", "/links/1./stats", "1.", http.StatusBadRequest, `{"error":"invalid letter"}`, time.Time{}, time.Time{}},
+		{"Invalid from", "/links/1/stats?from=06.02.2024", "1", http.StatusUnprocessableEntity,
+			`{"error":"from must be a date in format YYYY-MM-DD"}`, time.Time{}, time.Time{}},
+		{"Invalid to", "/links/1/stats?to=tomorrow", "1", http.StatusUnprocessableEntity,
+			`{"error":"to must be a date in format YYYY-MM-DD"}`, time.Time{}, time.Time{}},
+		{"From after to", "/links/1/stats?from=2024-02-08&to=2024-02-07", "1", http.StatusUnprocessableEntity,
+			`{"error":"from must not be after to"}`, time.Time{}, time.Time{}},
+		{"Too long range", "/links/1/stats?from=2023-01-01&to=2024-02-07", "1", http.StatusUnprocessableEntity,
+			`{"error":"range must be maximum 366 days long"}`, time.Time{}, time.Time{}},
+		{"Not found", "/links/2/stats", "2", http.StatusNotFound, `{"error":"Full link not found for key 2"}`, time.Time{}, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := &testStats{}
+			app := Application{
+				Logger:    utils.NewLogger(io.Discard, &utils.Clock{}),
+				Clock:     &test.Clock{},
+				Validator: *NewValidator(links.Alphabets[links.AlphabetBase36]),
+				Links:     newTestLinkStorage(3, map[int]string{1: "https://example1.com", 3: "https://example3.com"}),
+				Stats:     stats,
+			}
+
+			w := httptest.NewRecorder()
+			r := newRequestWithNamedParameter(http.MethodGet, tt.target, httprouter.Params{
+				httprouter.Param{Key: "key", Value: tt.key},
+			})
+
+			app.statsHandler(w, r)
+
+			result := w.Result()
+			jsonResponse, err := io.ReadAll(result.Body)
+
+			defer result.Body.Close()
+
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedStatus, result.StatusCode)
+			require.JSONEq(t, tt.expectedResponse, string(jsonResponse))
+			require.True(t, tt.expectedFrom.Equal(stats.from))
+			require.True(t, tt.expectedTo.Equal(stats.to))
+		})
+	}
+}
+
+func TestQRHandler(t *testing.T) {
+	tests := []struct {
+		name                string
+		target              string
+		accept              string
+		key                 string
+		expectedStatus      int
+		expectedContentType string
+		expectedError       string
+	}{
+		{"PNG by default", "/links/1/qr", "", "1", http.StatusOK, "image/png", ""},
+		{"SVG by header", "/links/1/qr", "image/svg+xml", "1", http.StatusOK, "image/svg+xml", ""},
+		{"PNG by parameter", "/links/1/qr?format=png&size=64&margin=0&ecc=h", "image/svg+xml", "1", http.StatusOK, "image/png", ""},
+		{"Unknown format", "/links/1/qr?format=gif", "", "1", http.StatusUnprocessableEntity, "application/json",
+			`format must be "png" or "svg"`},
+		{"Invalid size", "/links/1/qr?size=big", "", "1", http.StatusUnprocessableEntity, "application/json",
+			"size must be an integer from 1 to 4096"},
+		{"Invalid margin", "/links/1/qr?margin=-1", "", "1", http.StatusUnprocessableEntity, "application/json",
+			"margin must be an integer from 0 to 32"},
+		{"Invalid level", "/links/1/qr?ecc=X", "", "1", http.StatusUnprocessableEntity, "application/json",
+			`ecc must be one of "L", "M", "Q" or "H"`},
+		{"Too small size", "/links/1/qr?size=32", "", "1", http.StatusUnprocessableEntity, "application/json",
+			"size must be at least 33 for margin 4"},
+		{"Not found", "/links/2/qr", "", "2", http.StatusNotFound, "application/json",
+			"Full link not found for key 2"},
+		{"Expired", "/links/3/qr", "", "3", http.StatusOK, "image/png", ""},
+		{"Disabled", "/links/4/qr", "", "4", http.StatusOK, "image/png", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := newTestLinkStorage(4, map[int]string{
+				1: "https://example.com",
+				3: "https://example.com/expired",
+				4: "https://example.com/disabled",
+			})
+			storage.expirations[3] = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			storage.disabled[4] = true
+			app := Application{
+				Logger:    utils.NewLogger(io.Discard, &utils.Clock{}),
+				Validator: *NewValidator(links.Alphabets[links.AlphabetBase36]),
+				Links:     storage,
+			}
+
+			w := httptest.NewRecorder()
+			r := newRequestWithNamedParameter(http.MethodGet, tt.target, httprouter.Params{
+				httprouter.Param{Key: "key", Value: tt.key},
+			})
+			r.Header.Set("Accept", tt.accept)
+
+			app.qrHandler(w, r)
+
+			result := w.Result()
+			body, err := io.ReadAll(result.Body)
+
+			defer result.Body.Close()
+
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedStatus, result.StatusCode)
+			require.Equal(t, tt.expectedContentType, result.Header.Get("Content-Type"))
+
+			if tt.expectedError != "" {
+				require.JSONEq(t, `{"error":`+strconv.Quote(tt.expectedError)+`}`, string(body))
+			} else if tt.expectedContentType == "image/png" {
+				require.True(t, bytes.HasPrefix(body, []byte("\x89PNG")))
+			} else {
+				require.Contains(t, string(body), "<svg ")
+			}
+		})
+	}
+}
+
+func TestListLinksHandler(t *testing.T) {
+	cursor := links.ListCursor{CreatedAt: time.Date(2024, 2, 7, 12, 0, 0, 0, time.UTC), Key: "2"}.String()
+	aliasCursor := links.ListCursor{CreatedAt: time.Date(2024, 2, 7, 12, 0, 0, 0, time.UTC), Key: "spring.sale"}.String()
+
+	tests := []struct {
+		name             string
+		target           string
+		owner            string
+		expectedStatus   int
+		expectedResponse string
+		expectedQuery    links.ListQuery
+	}{
+		{"Default query", "/links", "client", http.StatusOK,
+			`{"links":[{"key":"1","link":"http://localhost/go/1","url":"https://example1.com","disabled":false,"protected":false,` +
+				`"health":{"status":404,"broken":true,"checked_at":"2024-02-07T12:00:00Z","redirects":["https://example1.com/moved"]}},` +
+				`{"key":"3","link":"http://localhost/go/3","url":"https://example3.com","disabled":false,"protected":false,` +
+				`"title":"Spring sale","tags":["sale"],"health":{"status":200,"broken":false,"checked_at":"2024-02-07T12:00:00Z"},` +
+				`"preview":{"title":"Example 3","favicon":"https://example3.com/favicon.ico","fetched_at":"2024-02-07T12:00:00Z"}}],"next_cursor":""}`,
+			links.ListQuery{Owner: "client", Sort: links.SortCreatedDesc, Limit: 20}},
+		{"Page", "/links?limit=1&sort=created_at&domain=Example.COM.&cursor=" + cursor, "client", http.StatusOK,
+			`{"links":[{"key":"1","link":"http://localhost/go/1","url":"https://example1.com","disabled":false,"protected":false,` +
+				`"health":{"status":404,"broken":true,"checked_at":"2024-02-07T12:00:00Z","redirects":["https://example1.com/moved"]}}],"next_cursor":"next"}`,
+			links.ListQuery{
+				Owner:  "client",
+				Domain: "example.com",
+				Sort:   links.SortCreatedAsc,
+				Cursor: links.ListCursor{CreatedAt: time.Date(2024, 2, 7, 12, 0, 0, 0, time.UTC), Key: "2"},
+				Limit:  1,
+			}},
+		{"Unauthenticated", "/links", "", http.StatusOK,
+			`{"links":[{"key":"2","link":"http://localhost/go/2","url":"https://example2.com","disabled":false,"protected":false}],"next_cursor":""}`,
+			links.ListQuery{Sort: links.SortCreatedDesc, Limit: 20}},
+		{"Invalid limit", "/links?limit=101", "client", http.StatusUnprocessableEntity,
+			`{"error":"limit must be an integer from 1 to 100"}`, links.ListQuery{}},
+		{"Invalid sort", "/links?sort=url", "client", http.StatusUnprocessableEntity,
+			`{"error":"sort must be \"created_at\" or \"-created_at\""}`, links.ListQuery{}},
+		{"Invalid domain", "/links?domain=https://example.com", "client", http.StatusUnprocessableEntity,
+			`{"error":"domain is invalid"}`, links.ListQuery{}},
+		{"Tag", "/links?tag=Sale", "client", http.StatusOK,
+			`{"links":[{"key":"3","link":"http://localhost/go/3","url":"https://example3.com","disabled":false,"protected":false,` +
+				`"title":"Spring sale","tags":["sale"],"health":{"status":200,"broken":false,"checked_at":"2024-02-07T12:00:00Z"},` +
+				`"preview":{"title":"Example 3","favicon":"https://example3.com/favicon.ico","fetched_at":"2024-02-07T12:00:00Z"}}],"next_cursor":""}`,
+			links.ListQuery{Owner: "client", Tag: "sale", Sort: links.SortCreatedDesc, Limit: 20}},
+		{"Invalid tag", "/links?tag=a,b", "client", http.StatusUnprocessableEntity,
+			`{"error":"tag may contain only letters, digits, \"-\" and \"_\""}`, links.ListQuery{}},
+		{"Invalid cursor", "/links?cursor=abc", "client", http.StatusUnprocessableEntity,
+			`{"error":"cursor is invalid"}`, links.ListQuery{}},
+		{"Alias cursor", "/links?cursor=" + aliasCursor, "client", http.StatusUnprocessableEntity,
+			`{"error":"cursor is invalid"}`, links.ListQuery{}},
+		{"Broken", "/links?health=broken", "client", http.StatusOK,
+			`{"links":[{"key":"1","link":"http://localhost/go/1","url":"https://example1.com","disabled":false,"protected":false,` +
+				`"health":{"status":404,"broken":true,"checked_at":"2024-02-07T12:00:00Z","redirects":["https://example1.com/moved"]}}],"next_cursor":""}`,
+			links.ListQuery{Owner: "client", Health: links.HealthBroken, Sort: links.SortCreatedDesc, Limit: 20}},
+		{"Invalid health", "/links?health=dead", "client", http.StatusUnprocessableEntity,
+			`{"error":"health must be \"broken\" or \"ok\""}`, links.ListQuery{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := newTestLinkStorage(3, map[int]string{
+				1: "https://example1.com",
+				2: "https://example2.com",
+				3: "https://example3.com",
+			})
+			storage.owners = map[int]string{1: "client", 3: "client"}
+			storage.metadata = map[int]links.Metadata{3: {Title: "Spring sale", Tags: []string{"sale"}}}
+			storage.health = map[int]links.Health{
+				1: {Status: 404, Redirects: []string{"https://example1.com/moved"}, CheckedAt: time.Date(2024, 2, 7, 12, 0, 0, 0, time.UTC)},
+				3: {Status: 200, CheckedAt: time.Date(2024, 2, 7, 12, 0, 0, 0, time.UTC)},
+			}
+			storage.previews = map[int]links.Preview{
+				3: {Title: "Example 3", Favicon: "https://example3.com/favicon.ico", FetchedAt: time.Date(2024, 2, 7, 12, 0, 0, 0, time.UTC)},
+			}
+			app := Application{
+				Logger:    utils.NewLogger(io.Discard, &utils.Clock{}),
+				Validator: *NewValidator(links.Alphabets[links.AlphabetBase36]),
+				Links:     storage,
+			}
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+
+			if tt.owner != "" {
+				r = app.contextSetAPIKey(r, apikeys.Key{ID: tt.owner, Scopes: []string{apikeys.ScopeRead}})
+			}
+
+			app.listLinksHandler(w, r)
+
+			result := w.Result()
+			jsonResponse, err := io.ReadAll(result.Body)
+
+			defer result.Body.Close()
+
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedStatus, result.StatusCode)
+			require.JSONEq(t, tt.expectedResponse, string(jsonResponse))
+			require.Equal(t, tt.expectedQuery, storage.listQuery)
+		})
+	}
+}
+
+func TestLinkDetailsHandler(t *testing.T) {
+	tests := []struct {
+		name             string
+		key              string
+		owner            string
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{"With preview", "1", "client", http.StatusOK,
+			`{"key":"1","link":"http://localhost/go/1","url":"https://example1.com","disabled":false,"protected":false,"title":"Spring sale",` +
+				`"preview":{"title":"Example","open_graph":{"title":"Example, Inc.","image":"https://example1.com/logo.png"},` +
+				`"favicon":"https://example1.com/favicon.ico","fetched_at":"2024-02-07T12:00:00Z"}}`},
+		{"Disabled", "2", "client", http.StatusOK,
+			`{"key":"2","link":"http://localhost/go/2","url":"https://example2.com","disabled":true,"protected":false}`},
+		{"Link of other owner", "3", "client", http.StatusNotFound, `{"error":"Full link not found for key 3"}`},
+		{"Unauthenticated", "1", "", http.StatusNotFound, `{"error":"Full link not found for key 1"}`},
+		{"Not found", "4", "client", http.StatusNotFound, `{"error":"Full link not found for key 4"}`},
+		{"Invalid key", "1.", "client", http.StatusBadRequest, `{"error":"invalid letter"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := newTestLinkStorage(3, map[int]string{
+				1: "https://example1.com",
+				2: "https://example2.com",
+				3: "https://example3.com",
+			})
+			storage.owners = map[int]string{1: "client", 2: "client", 3: "other"}
+			storage.disabled = map[int]bool{2: true}
+			storage.metadata = map[int]links.Metadata{1: {Title: "Spring sale"}}
+			storage.previews = map[int]links.Preview{1: {
+				Title:     "Example",
+				OpenGraph: map[string]string{"title": "Example, Inc.", "image": "https://example1.com/logo.png"},
+				Favicon:   "https://example1.com/favicon.ico",
+				FetchedAt: time.Date(2024, 2, 7, 12, 0, 0, 0, time.UTC),
+			}}
+			app := Application{
+				Logger:    utils.NewLogger(io.Discard, &utils.Clock{}),
+				Validator: *NewValidator(links.Alphabets[links.AlphabetBase36]),
+				Links:     storage,
+			}
+
+			w := httptest.NewRecorder()
+			r := newRequestWithNamedParameter(http.MethodGet, "/links/:key", httprouter.Params{
+				httprouter.Param{Key: "key", Value: tt.key},
+			})
+
+			if tt.owner != "" {
+				r = app.contextSetAPIKey(r, apikeys.Key{ID: tt.owner, Scopes: []string{apikeys.ScopeRead}})
+			}
+
+			app.linkDetailsHandler(w, r)
+
+			result := w.Result()
+			jsonResponse, err := io.ReadAll(result.Body)
+
+			defer result.Body.Close()
+
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedStatus, result.StatusCode)
+			require.JSONEq(t, tt.expectedResponse, string(jsonResponse))
+		})
+	}
+}
+
+func TestHandlersRequireOwner(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		target         string
+		key            string
+		owner          string
+		body           string
+		handler        func(*Application, http.ResponseWriter, *http.Request)
+		expectedStatus int
+	}{
+		{"Update own link", http.MethodPatch, "/links/1", "1", "client", `{"disabled":true}`,
+			(*Application).updateLinkHandler, http.StatusOK},
+		{"Update link of other owner", http.MethodPatch, "/links/1", "1", "other", `{"disabled":true}`,
+			(*Application).updateLinkHandler, http.StatusNotFound},
+		{"Delete own link", http.MethodDelete, "/links/1", "1", "client", "",
+			(*Application).deleteLinkHandler, http.StatusNoContent},
+		{"Delete link of other owner", http.MethodDelete, "/links/1", "1", "other", "",
+			(*Application).deleteLinkHandler, http.StatusNotFound},
+		{"Stats of own link", http.MethodGet, "/links/1/stats", "1", "client", "",
+			(*Application).statsHandler, http.StatusOK},
+		{"Stats of link of other owner", http.MethodGet, "/links/1/stats", "1", "other", "",
+			(*Application).statsHandler, http.StatusNotFound},
+		{"QR code of own link", http.MethodGet, "/links/1/qr", "1", "client", "",
+			(*Application).qrHandler, http.StatusOK},
+		{"QR code of link of other owner", http.MethodGet, "/links/1/qr", "1", "other", "",
+			(*Application).qrHandler, http.StatusNotFound},
+		{"Unauthenticated", http.MethodDelete, "/links/1", "1", "", "",
+			(*Application).deleteLinkHandler, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := newTestLinkStorage(2, map[int]string{
+				1: "https://example1.com",
+				2: "https://example2.com",
+			})
+			storage.owners = map[int]string{1: "client", 2: "other"}
+			app := Application{
+				Logger:    utils.NewLogger(io.Discard, &utils.Clock{}),
+				Clock:     &test.Clock{},
+				Validator: *NewValidator(links.Alphabets[links.AlphabetBase36]),
+				Links:     storage,
+				Stats:     &testStats{},
+			}
+
+			w := httptest.NewRecorder()
+			r := newRequestWithNamedParameter(tt.method, tt.target, httprouter.Params{
+				httprouter.Param{Key: "key", Value: tt.key},
+			})
+			r.Body = io.NopCloser(strings.NewReader(tt.body))
+
+			if tt.owner != "" {
+				r = app.contextSetAPIKey(r, apikeys.Key{ID: tt.owner, Scopes: []string{apikeys.ScopeCreate, apikeys.ScopeRead}})
+			}
+
+			tt.handler(&app, w, r)
+
+			result := w.Result()
+			jsonResponse, err := io.ReadAll(result.Body)
+
+			defer result.Body.Close()
+
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedStatus, result.StatusCode)
+
+			if tt.expectedStatus == http.StatusNotFound {
+				require.JSONEq(t, `{"error":"Full link not found for key 1"}`, string(jsonResponse))
+				require.Equal(t, map[int]string{1: "https://example1.com", 2: "https://example2.com"}, storage.links)
+				require.Empty(t, storage.disabled[1])
+			}
+		})
+	}
+}
+
+type testUnfurler struct {
+	unfurled map[string]string
+}
+
+func (u *testUnfurler) Unfurl(key, URL string) {
+	u.unfurled[key] = URL
+}
+
+func (u *testUnfurler) Close() {
+	//
+}
+
+func TestUnfurlNewLinks(t *testing.T) {
+	tests := []struct {
+		name             string
+		handler          func(*Application, http.ResponseWriter, *http.Request)
+		request          any
+		expectedUnfurled map[string]string
+	}{
+		{"Generate", (*Application).generateHandler, envelope{"url": "https://example.org"}, map[string]string{"2": "https://example.org"}},
+		{"Generate existing", (*Application).generateHandler, envelope{"url": "https://example.com"}, map[string]string{}},
+		{"Generate alias", (*Application).generateHandler, envelope{"url": "https://example.org", "alias": "spring-sale"},
+			map[string]string{"spring-sale": "https://example.org"}},
+		{"Batch generate", (*Application).batchGenerateHandler, []string{"https://example.com", "https://example.org"},
+			map[string]string{"2": "https://example.org"}},
+		{"Update URL", (*Application).updateLinkHandler, envelope{"url": "https://example.org"}, map[string]string{"1": "https://example.org"}},
+		{"Update title", (*Application).updateLinkHandler, envelope{"title": "Example"}, map[string]string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unfurler := &testUnfurler{unfurled: map[string]string{}}
+			storage := newTestLinkStorage(1, map[int]string{1: "https://example.com"})
+			storage.lastKey = 1
+			app := Application{
+				Config:    Config{DedupEnabled: true},
+				Logger:    utils.NewLogger(io.Discard, &utils.Clock{}),
+				Validator: *NewValidator(links.Alphabets[links.AlphabetBase36]),
+				Clock:     &test.Clock{},
+				Links:     storage,
+				Unfurler:  unfurler,
+			}
+
+			w := httptest.NewRecorder()
+			body, _ := json.Marshal(tt.request)
+			r := newRequestWithNamedParameter(http.MethodPost, "/", httprouter.Params{
+				httprouter.Param{Key: "key", Value: "1"},
+			})
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			tt.handler(&app, w, r)
+
+			result := w.Result()
+
+			defer result.Body.Close()
+
+			require.Equal(t, http.StatusOK, result.StatusCode)
+			require.Equal(t, tt.expectedUnfurled, unfurler.unfurled)
+		})
+	}
+}
+
+func TestGenerateHandlerMetadata(t *testing.T) {
+	tests := []struct {
+		name             string
+		request          envelope
+		expectedCode     int
+		expectedResponse string
+		expectedMetadata map[int]links.Metadata
+	}{
+		{"Metadata", envelope{
+			"url":         "https://example.org",
+			"title":       "Spring sale",
+			"description": "Sale of spring",
+			"tags":        []string{" Sale", "SPRING"},
+			"notes":       "internal",
+		}, http.StatusOK, `{"link":"http://localhost/go/2","existing":false}`, map[int]links.Metadata{
+			1: {},
+			2: {Title: "Spring sale", Description: "Sale of spring", Tags: []string{"sale", "spring"}, Notes: "internal"},
+		}},
+		{"Without metadata is deduplicated", envelope{"url": "https://example.org"}, http.StatusOK,
+			`{"link":"http://localhost/go/1","existing":true}`, map[int]links.Metadata{1: {}}},
+		{"Long description", envelope{"url": "https://example.org", "description": strings.Repeat("a", 1001)}, http.StatusUnprocessableEntity,
+			`{"error":"description must be maximum 1000 letters long"}`, map[int]links.Metadata{1: {}}},
+		{"Too many tags", envelope{"url": "https://example.org", "tags": strings.Split("abcdefghijklmnopqrstu", "")}, http.StatusUnprocessableEntity,
+			`{"error":"maximum 20 tags are allowed"}`, map[int]links.Metadata{1: {}}},
+		{"Empty tag", envelope{"url": "https://example.org", "tags": []string{" "}}, http.StatusUnprocessableEntity,
+			`{"error":"tag must be from 1 to 32 letters long"}`, map[int]links.Metadata{1: {}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := newTestLinkStorage(1, map[int]string{})
+			_, _ = storage.GenerateKey("https://example.org", links.LinkOptions{})
+			app := Application{
+				Logger: utils.NewLogger(io.Discard, &utils.Clock{}),
+				Clock:  &test.Clock{},
+				Config: Config{DedupEnabled: true},
+				Links:  storage,
+			}
+			w := httptest.NewRecorder()
+			body, _ := json.Marshal(tt.request)
+			r := httptest.NewRequest(http.MethodPost, "/generate", bytes.NewReader(body))
+
+			app.generateHandler(w, r)
+
+			result := w.Result()
+			jsonResponse, err := io.ReadAll(result.Body)
+
+			defer result.Body.Close()
+
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedCode, result.StatusCode)
+			require.JSONEq(t, tt.expectedResponse, string(jsonResponse))
+			require.Equal(t, tt.expectedMetadata, storage.metadata)
+		})
+	}
+}
+
+func TestGenerateHandlerRecordsOwner(t *testing.T) {
+	storage := newTestLinkStorage(1, map[int]string{})
+	app := Application{
+		Logger:    utils.NewLogger(io.Discard, &utils.Clock{}),
+		Clock:     &test.Clock{},
+		Validator: *NewValidator(links.Alphabets[links.AlphabetBase36]),
+		Links:     storage,
+	}
+	key := apikeys.Key{ID: "client", Scopes: []string{apikeys.ScopeCreate}}
+
+	body, _ := json.Marshal(envelope{"url": "https://example.org"})
+	r := app.contextSetAPIKey(httptest.NewRequest(http.MethodPost, "/generate", bytes.NewReader(body)), key)
+	w := httptest.NewRecorder()
+
+	app.generateHandler(w, r)
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+	body, _ = json.Marshal([]string{"https://example.com"})
+	r = app.contextSetAPIKey(httptest.NewRequest(http.MethodPost, "/batch/generate", bytes.NewReader(body)), key)
+	w = httptest.NewRecorder()
+
+	app.batchGenerateHandler(w, r)
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+	body, _ = json.Marshal(envelope{"url": "https://example.net"})
+	w = httptest.NewRecorder()
+
+	app.generateHandler(w, httptest.NewRequest(http.MethodPost, "/generate", bytes.NewReader(body)))
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+	require.Equal(t, map[int]string{1: "client", 2: "client", 3: ""}, storage.owners)
+}
+
+func TestBatchGenerateHandlerOK(t *testing.T) {
+	app := Application{
+		Logger: utils.NewLogger(io.Discard, &utils.Clock{}),
+		Clock:  &test.Clock{},
+		Links:  newTestLinkStorage(2, map[int]string{}),
+	}
+	w := httptest.NewRecorder()
+	body, _ := json.Marshal([]string{"https://example.org", "https://example2.org"})
+	r := httptest.NewRequest(http.MethodPost, "/batch/generate", bytes.NewReader(body))
+
+	app.batchGenerateHandler(w, r)
+
+	result := w.Result()
+
+	require.Equal(t, "application/json", result.Header.Get("Content-Type"))
+	require.Equal(t, http.StatusOK, result.StatusCode)
+
+	jsonResponse, err := io.ReadAll(result.Body)
+
+	defer result.Body.Close()
+
+	require.NoError(t, err)
+	require.JSONEq(t, `{"links":{"https://example.org":"http://localhost/go/1","https://example2.org":"http://localhost/go/2"}}`+"\n", string(jsonResponse))
+}
+
+func TestBatchGenerateHandlerObjectOK(t *testing.T) {
+	collection := newTestLinkStorage(2, map[int]string{})
+	app := Application{
+		Logger: utils.NewLogger(io.Discard, &utils.Clock{}),
+		Clock:  &test.Clock{},
+		Links:  collection,
+	}
+	w := httptest.NewRecorder()
+	body, _ := json.Marshal(envelope{
+		"urls":       []string{"https://example.org", "https://example2.org"},
+		"expires_at": "2024-02-08T12:00:00Z",
+	})
+	r := httptest.NewRequest(http.MethodPost, "/batch/generate", bytes.NewReader(body))
+
+	app.batchGenerateHandler(w, r)
+
+	result := w.Result()
+
+	require.Equal(t, "application/json", result.Header.Get("Content-Type"))
+	require.Equal(t, http.StatusOK, result.StatusCode)
+
+	jsonResponse, err := io.ReadAll(result.Body)
+
+	defer result.Body.Close()
+
+	require.NoError(t, err)
+	require.JSONEq(t, `{"links":{"https://example.org":"http://localhost/go/1","https://example2.org":"http://localhost/go/2"}}`+"\n", string(jsonResponse))
+	require.Equal(t, time.Date(2024, 2, 8, 12, 0, 0, 0, time.UTC), collection.expirations[1])
+	require.Equal(t, time.Date(2024, 2, 8, 12, 0, 0, 0, time.UTC), collection.expirations[2])
+}
+
+func TestBatchGenerateHandlerDedup(t *testing.T) {
+	collection := newTestLinkStorage(3, map[int]string{1: "https://example.org"})
+	collection.lastKey = 1
+	app := Application{
+		Config: Config{DedupEnabled: true},
+		Logger: utils.NewLogger(io.Discard, &utils.Clock{}),
+		Clock:  &test.Clock{},
+		Links:  collection,
+	}
+	w := httptest.NewRecorder()
+	body, _ := json.Marshal([]string{"https://example.org", "https://example2.org", "https://example3.org"})
+	r := httptest.NewRequest(http.MethodPost, "/batch/generate", bytes.NewReader(body))
+
+	app.batchGenerateHandler(w, r)
+
+	result := w.Result()
+
+	require.Equal(t, http.StatusOK, result.StatusCode)
+
+	jsonResponse, err := io.ReadAll(result.Body)
+
+	defer result.Body.Close()
+
+	require.NoError(t, err)
+	require.JSONEq(t, `{"links":{"https://example.org":"http://localhost/go/1","https://example2.org":"http://localhost/go/2",`+
+		`"https://example3.org":"http://localhost/go/3"},"new":["https://example2.org","https://example3.org"],`+
+		`"existing":["https://example.org"]}`+"\n", string(jsonResponse))
+}
+
+func TestBatchGenerateHandlerNormalizes(t *testing.T) {
+	normalizer, _ := links.NewNormalizer([]string{links.NormalizeLowercase, links.NormalizeStripTracking}, []string{"utm_*"})
+	collection := newTestLinkStorage(2, map[int]string{1: "https://example.org"})
+	collection.lastKey = 1
+	app := Application{
+		Config:     Config{DedupEnabled: true},
+		Logger:     utils.NewLogger(io.Discard, &utils.Clock{}),
+		Clock:      &test.Clock{},
+		Normalizer: normalizer,
+		Links:      collection,
+	}
+	w := httptest.NewRecorder()
+	body, _ := json.Marshal([]string{"https://Example.org?utm_source=x", "https://example2.org"})
+	r := httptest.NewRequest(http.MethodPost, "/batch/generate", bytes.NewReader(body))
+
+	app.batchGenerateHandler(w, r)
+
+	result := w.Result()
+
+	require.Equal(t, http.StatusOK, result.StatusCode)
+
+	jsonResponse, err := io.ReadAll(result.Body)
+
+	defer result.Body.Close()
+
+	require.NoError(t, err)
+	require.JSONEq(t, `{"links":{"https://Example.org?utm_source=x":"http://localhost/go/1","https://example2.org":"http://localhost/go/2"},`+
+		`"urls":{"https://Example.org?utm_source=x":"https://example.org","https://example2.org":"https://example2.org"},`+
+		`"new":["https://example2.org"],"existing":["https://Example.org?utm_source=x"]}`, string(jsonResponse))
+}
+
+func TestBatchGenerateHandlerBadRequest(t *testing.T) {
+	app := Application{
+		Logger: utils.NewLogger(io.Discard, &utils.Clock{}),
+		Clock:  &test.Clock{},
+		Links:  newTestLinkStorage(2, map[int]string{}),
+	}
+
+	tests := []struct {
+		name         string
+		request      any
+		expectedCode int
+		errorMessage string
+	}{
+		{"Unknown field", envelope{"unknown": "example"}, http.StatusBadRequest, `json: unknown field \"unknown\"`},
+		{"Incorrect type", "https://example.org", http.StatusBadRequest, `body contains incorrect JSON type (at character 21)`},
+		{"Empty url", []string{"link"}, http.StatusUnprocessableEntity, `URL must be an absolute URL`},
+		{"Empty url in object", envelope{"urls": []string{"link"}}, http.StatusUnprocessableEntity, `URL must be an absolute URL`},
+		{"Past expires_at", envelope{"urls": []string{"https://example.org"}, "expires_at": "2024-02-07T11:00:00Z"}, http.StatusUnprocessableEntity, "expires_at must be in the future"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			body, _ := json.Marshal(tt.request)
+			r := httptest.NewRequest(http.MethodPost, "/batch/generate", bytes.NewReader(body))
+
+			app.batchGenerateHandler(w, r)
+
+			result := w.Result()
+
+			require.Equal(t, "application/json", result.Header.Get("Content-Type"))
+			require.Equal(t, tt.expectedCode, result.StatusCode)
+
+			jsonResponse, err := io.ReadAll(result.Body)
+
+			defer result.Body.Close()
+
+			require.NoError(t, err)
+			require.JSONEq(t, `{"error":"`+tt.errorMessage+`"}`+"\n", string(jsonResponse))
+		})
+	}
+}
+
+func TestBatchGoHandlerOK(t *testing.T) {
+	app := Application{
+		Logger:    utils.NewLogger(io.Discard, &utils.Clock{}),
+		Validator: *NewValidator("12"),
+		Links: newTestLinkStorage(2, map[int]string{
+			1: "https://example.com",
+			2: "https://example2.com",
+		}),
+	}
+	w := httptest.NewRecorder()
+	body, _ := json.Marshal([]string{"1", "2"})
+	r := httptest.NewRequest(http.MethodPost, "/batch/go", bytes.NewReader(body))
+
+	app.batchGoHandler(w, r)
+
+	result := w.Result()
+
+	require.Equal(t, "application/json", result.Header.Get("Content-Type"))
+	require.Equal(t, http.StatusOK, result.StatusCode)
+
+	jsonResponse, err := io.ReadAll(result.Body)
+
+	defer result.Body.Close()
+
+	require.NoError(t, err)
+	require.JSONEq(t, `{"links":{"1":"https://example.com","2":"https://example2.com"}}`+"\n", string(jsonResponse))
+}
+
+func TestBatchGoHandlerBadRequest(t *testing.T) {
+	app := Application{
+		Logger:    utils.NewLogger(io.Discard, &utils.Clock{}),
+		Validator: *NewValidator("1"),
+		Links: newTestLinkStorage(2, map[int]string{
+			1: "http://example.com",
+		}),
+	}
+
+	tests := []struct {
+		name         string
+		request      any
+		expectedCode int
+		errorMessage string
+	}{
+		{"Unknown field", envelope{"unknown": "example"}, http.StatusBadRequest, `body contains incorrect JSON type (at character 1)`},
+		{"Invalid key", []string{"й"}, http.StatusUnprocessableEntity, `invalid letter`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			body, _ := json.Marshal(tt.request)
+			r := httptest.NewRequest(http.MethodPost, "/batch/go", bytes.NewReader(body))
+
+			app.batchGoHandler(w, r)
+
+			result := w.Result()
+
+			require.Equal(t, "application/json", result.Header.Get("Content-Type"))
+			require.Equal(t, tt.expectedCode, result.StatusCode)
+
+			jsonResponse, err := io.ReadAll(result.Body)
+
+			defer result.Body.Close()
+
+			require.NoError(t, err)
+			require.JSONEq(t, `{"error":"`+tt.errorMessage+`"}`+"\n", string(jsonResponse))
+		})
+	}
+}
+
+func newRequestWithNamedParameter(method, target string, params any) *http.Request {
+	r := httptest.NewRequest(method, target, nil)
+	ctx := r.Context()
+	ctx = context.WithValue(ctx, httprouter.ParamsKey, params)
+
+	_ = r.WithContext(ctx)
+	r, _ = http.NewRequestWithContext(ctx, method, target, nil)
+
+	return r
+}
+
+func TestCreateAPIKeyHandler(t *testing.T) {
+	keys := newTestAPIKeys()
+	app := Application{
+		Logger:  utils.NewLogger(io.Discard, &utils.Clock{}),
+		Clock:   &test.Clock{},
+		APIKeys: keys,
+	}
+
+	w := httptest.NewRecorder()
+	body, _ := json.Marshal(envelope{"name": "client", "scopes": []string{"create", "read"}, "rps": 5, "daily_quota": 100})
+	r := httptest.NewRequest(http.MethodPost, "/admin/api-keys", bytes.NewReader(body))
+
+	app.createAPIKeyHandler(w, r)
+
+	result := w.Result()
+
+	require.Equal(t, http.StatusCreated, result.StatusCode)
+
+	var response struct {
+		ID         string
+		Key        string
+		Name       string
+		Scopes     []string
+		RPS        int
+		DailyQuota int       `json:"daily_quota"`
+		CreatedAt  time.Time `json:"created_at"`
+	}
+
+	defer result.Body.Close()
+
+	require.NoError(t, json.NewDecoder(result.Body).Decode(&response))
+	require.Equal(t, "client", response.Name)
+	require.Equal(t, []string{"create", "read"}, response.Scopes)
+	require.Equal(t, 5, response.RPS)
+	require.Equal(t, 100, response.DailyQuota)
+	require.True(t, response.CreatedAt.Equal((&test.Clock{}).Now()))
+
+	stored, err := keys.GetKey(apikeys.Hash(response.Key))
+
+	require.NoError(t, err)
+	require.Equal(t, response.ID, stored.ID)
+}
+
+func TestCreateAPIKeyHandlerInvalid(t *testing.T) {
+	tests := []struct {
+		name             string
+		request          any
+		expectedCode     int
+		expectedResponse string
+	}{
+		{"No scopes", envelope{"name": "client"}, http.StatusUnprocessableEntity, `{"error":"at least one scope must be provided"}`},
+		{"Unknown scope", envelope{"scopes": []string{"write"}}, http.StatusUnprocessableEntity, `{"error":"unknown scope: write"}`},
+		{"Negative quota", envelope{"scopes": []string{"read"}, "daily_quota": -1}, http.StatusUnprocessableEntity, `{"error":"rps and daily_quota must not be negative"}`},
+		{"Long name", envelope{"name": strings.Repeat("a", 256), "scopes": []string{"read"}}, http.StatusUnprocessableEntity, `{"error":"name must be maximum 255 letters long"}`},
+		{"Unknown field", envelope{"scopes": []string{"read"}, "owner": "me"}, http.StatusBadRequest, `{"error":"json: unknown field \"owner\""}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := Application{
+				Logger:  utils.NewLogger(io.Discard, &utils.Clock{}),
+				Clock:   &test.Clock{},
+				APIKeys: newTestAPIKeys(),
+			}
+
+			w := httptest.NewRecorder()
+			body, _ := json.Marshal(tt.request)
+			r := httptest.NewRequest(http.MethodPost, "/admin/api-keys", bytes.NewReader(body))
+
+			app.createAPIKeyHandler(w, r)
+
+			result := w.Result()
+
+			require.Equal(t, tt.expectedCode, result.StatusCode)
+
+			jsonResponse, err := io.ReadAll(result.Body)
+
+			defer result.Body.Close()
+
+			require.NoError(t, err)
+			require.JSONEq(t, tt.expectedResponse+"\n", string(jsonResponse))
+		})
+	}
+}
+
+func TestRevokeAPIKeyHandler(t *testing.T) {
+	tests := []struct {
+		name             string
+		id               string
+		expectedCode     int
+		expectedResponse string
+	}{
+		{"Revoke", "a1b2c3", http.StatusOK, `{"id":"a1b2c3","name":"client","revoked_at":"2024-02-07T12:00:00Z"}`},
+		{"Not found", "missing", http.StatusNotFound, `{"error":"API key not found for id missing"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := Application{
+				Logger:  utils.NewLogger(io.Discard, &utils.Clock{}),
+				Clock:   &test.Clock{},
+				APIKeys: newTestAPIKeys(apikeys.Key{ID: "a1b2c3", Name: "client", Scopes: []string{"read"}}),
+			}
+
+			w := httptest.NewRecorder()
+			r := newRequestWithNamedParameter(http.MethodDelete, "/admin/api-keys/:id", httprouter.Params{
+				httprouter.Param{Key: "id", Value: tt.id},
+			})
+
+			app.revokeAPIKeyHandler(w, r)
+
+			result := w.Result()
+
+			require.Equal(t, tt.expectedCode, result.StatusCode)
+
+			jsonResponse, err := io.ReadAll(result.Body)
+
+			defer result.Body.Close()
+
+			require.NoError(t, err)
+			require.JSONEq(t, tt.expectedResponse+"\n", string(jsonResponse))
+		})
+	}
+}
+
+func TestGoHandlerUTMTemplate(t *testing.T) {
+	tests := []struct {
+		name             string
+		URL              string
+		template         string
+		accept           string
+		expectedLocation string
+	}{
+		{"Parameters are added", "https://example.com/sale", "spring", "",
+			"https://example.com/sale?utm_source=newsletter&utm_medium=email&utm_campaign=spring+sale"},
+		{"Existing parameters are kept", "https://example.com/sale?utm_source=ads&b=1#top", "spring", "",
+			"https://example.com/sale?utm_source=ads&b=1&utm_medium=email&utm_campaign=spring+sale#top"},
+		{"JSON", "https://example.com", "spring", "application/json",
+			"https://example.com?utm_source=newsletter&utm_medium=email&utm_campaign=spring+sale"},
+		{"Deleted template", "https://example.com", "deleted", "", "https://example.com"},
+		{"Without template", "https://example.com", "", "", "https://example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := newTestLinkStorage(0, map[int]string{})
+			_, _ = storage.GenerateKey(tt.URL, links.LinkOptions{UTMTemplate: tt.template})
+			app := Application{
+				Config:    Config{RedirectStatus: http.StatusFound},
+				Logger:    utils.NewLogger(io.Discard, &utils.Clock{}),
+				Validator: *NewValidator("1"),
+				Clock:     &test.Clock{},
+				Links:     storage,
+				Clicks:    &testClicksRecorder{},
+				UTMTemplates: newTestUTMTemplates(utm.Template{
+					Name:     "spring",
+					Source:   "newsletter",
+					Medium:   "email",
+					Campaign: "spring sale",
+				}),
+			}
+
+			w := httptest.NewRecorder()
+			r := newRequestWithNamedParameter(http.MethodGet, "/go/:key", httprouter.Params{
+				httprouter.Param{Key: "key", Value: "1"},
+			})
+			r.Header.Set("Accept", tt.accept)
+
+			app.goHandler(w, r)
+
+			result := w.Result()
+			response, err := io.ReadAll(result.Body)
+
+			defer result.Body.Close()
+
+			require.NoError(t, err)
+
+			if tt.accept == "application/json" {
+				require.Equal(t, http.StatusOK, result.StatusCode)
+				require.JSONEq(t, `{"link":"`+tt.expectedLocation+`"}`, string(response))
+			} else {
+				require.Equal(t, http.StatusFound, result.StatusCode)
+				require.Equal(t, tt.expectedLocation, result.Header.Get("Location"))
+			}
+		})
+	}
+}
+
+func TestGenerateHandlerUTMTemplate(t *testing.T) {
+	tests := []struct {
+		name              string
+		request           envelope
+		expectedCode      int
+		expectedResponse  string
+		expectedTemplates map[int]string
+	}{
+		{"Template", envelope{"url": "https://example.org", "utm_template": "spring"}, http.StatusOK,
+			`{"link":"http://localhost/go/2","existing":false}`, map[int]string{1: "", 2: "spring"}},
+		{"Unknown template", envelope{"url": "https://example.org", "utm_template": "autumn"}, http.StatusUnprocessableEntity,
+			`{"error":"UTM template not found: autumn"}`, map[int]string{1: ""}},
+		{"Invalid name", envelope{"url": "https://example.org", "utm_template": "spring sale"}, http.StatusUnprocessableEntity,
+			`{"error":"name of UTM template may contain only latin letters, digits, \"-\" and \"_\""}`, map[int]string{1: ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := newTestLinkStorage(1, map[int]string{})
+			_, _ = storage.GenerateKey("https://example.org", links.LinkOptions{})
+			app := Application{
+				Logger:       utils.NewLogger(io.Discard, &utils.Clock{}),
+				Clock:        &test.Clock{},
+				Config:       Config{DedupEnabled: true},
+				Links:        storage,
+				UTMTemplates: newTestUTMTemplates(utm.Template{Name: "spring", Source: "newsletter"}),
+			}
+			w := httptest.NewRecorder()
+			body, _ := json.Marshal(tt.request)
+			r := httptest.NewRequest(http.MethodPost, "/generate", bytes.NewReader(body))
+
+			app.generateHandler(w, r)
+
+			result := w.Result()
+			jsonResponse, err := io.ReadAll(result.Body)
+
+			defer result.Body.Close()
+
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedCode, result.StatusCode)
+			require.JSONEq(t, tt.expectedResponse, string(jsonResponse))
+			require.Equal(t, tt.expectedTemplates, storage.templates)
+		})
+	}
+}
+
+func TestStoreUTMTemplateHandler(t *testing.T) {
+	tests := []struct {
+		name             string
+		templateName     string
+		request          envelope
+		expectedCode     int
+		expectedResponse string
+	}{
+		{"Create", "autumn", envelope{"source": "newsletter", "campaign": "autumn sale"}, http.StatusOK,
+			`{"name":"autumn","source":"newsletter","medium":"","campaign":"autumn sale","content":""}`},
+		{"Replace", "spring", envelope{"source": "ads", "medium": "cpc"}, http.StatusOK,
+			`{"name":"spring","source":"ads","medium":"cpc","campaign":"","content":""}`},
+		{"Empty", "spring", envelope{}, http.StatusUnprocessableEntity,
+			`{"error":"source, medium, campaign or content must be provided"}`},
+		{"Long name", strings.Repeat("a", 65), envelope{"source": "ads"}, http.StatusUnprocessableEntity,
+			`{"error":"name of UTM template must be from 1 to 64 letters long"}`},
+		{"Long parameter", "spring", envelope{"content": strings.Repeat("a", 256)}, http.StatusUnprocessableEntity,
+			`{"error":"utm_content must be maximum 255 letters long"}`},
+		{"Unknown field", "spring", envelope{"term": "shoes"}, http.StatusBadRequest,
+			`{"error":"json: unknown field \"term\""}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			templates := newTestUTMTemplates(utm.Template{Name: "spring", Source: "newsletter"})
+			app := Application{
+				Logger:       utils.NewLogger(io.Discard, &utils.Clock{}),
+				Clock:        &test.Clock{},
+				UTMTemplates: templates,
+			}
+			w := httptest.NewRecorder()
+			body, _ := json.Marshal(tt.request)
+			r := newRequestWithNamedParameter(http.MethodPut, "/utm-templates/:name", httprouter.Params{
+				httprouter.Param{Key: "name", Value: tt.templateName},
+			})
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			app.storeUTMTemplateHandler(w, r)
+
+			result := w.Result()
+			jsonResponse, err := io.ReadAll(result.Body)
+
+			defer result.Body.Close()
+
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedCode, result.StatusCode)
+			require.JSONEq(t, tt.expectedResponse, string(jsonResponse))
+
+			if tt.expectedCode == http.StatusOK {
+				stored, err := templates.GetTemplate(tt.templateName)
+
+				require.NoError(t, err)
+				require.Equal(t, tt.templateName, stored.Name)
+			}
+		})
+	}
+}
+
+func TestListUTMTemplatesHandler(t *testing.T) {
+	app := Application{
+		Logger: utils.NewLogger(io.Discard, &utils.Clock{}),
+		Clock:  &test.Clock{},
+		UTMTemplates: newTestUTMTemplates(
+			utm.Template{Name: "spring", Source: "newsletter"},
+			utm.Template{Name: "autumn", Medium: "email"},
+		),
+	}
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/utm-templates", nil)
+
+	app.listUTMTemplatesHandler(w, r)
+
+	result := w.Result()
+	jsonResponse, err := io.ReadAll(result.Body)
+
+	defer result.Body.Close()
+
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, result.StatusCode)
+	require.JSONEq(t, `{"templates":[`+
+		`{"name":"autumn","source":"","medium":"email","campaign":"","content":""},`+
+		`{"name":"spring","source":"newsletter","medium":"","campaign":"","content":""}`+
+		`]}`, string(jsonResponse))
+}
+
+func TestDeleteUTMTemplateHandler(t *testing.T) {
+	tests := []struct {
+		name             string
+		templateName     string
+		expectedCode     int
+		expectedResponse string
+		expectedLeft     int
+	}{
+		{"Delete", "spring", http.StatusNoContent, "", 0},
+		{"Not found", "autumn", http.StatusNotFound, `{"error":"UTM template not found: autumn"}` + "\n", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			templates := newTestUTMTemplates(utm.Template{Name: "spring", Source: "newsletter"})
+			app := Application{
+				Logger:       utils.NewLogger(io.Discard, &utils.Clock{}),
+				Clock:        &test.Clock{},
+				UTMTemplates: templates,
+			}
+			w := httptest.NewRecorder()
+			r := newRequestWithNamedParameter(http.MethodDelete, "/utm-templates/:name", httprouter.Params{
+				httprouter.Param{Key: "name", Value: tt.templateName},
+			})
+
+			app.deleteUTMTemplateHandler(w, r)
+
+			result := w.Result()
+			response, err := io.ReadAll(result.Body)
+
+			defer result.Body.Close()
+
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedCode, result.StatusCode)
+			require.Equal(t, tt.expectedResponse, string(response))
+			require.Len(t, templates.templates, tt.expectedLeft)
+		})
+	}
+}
+
+func TestGoHandlerRules(t *testing.T) {
+	rules := links.Rules{
+		{URL: "https://apps.apple.com/app", Platforms: []string{links.PlatformIOS}},
+		{URL: "https://play.google.com/app", Platforms: []string{links.PlatformAndroid}},
+		{URL: "https://example.com/de", Languages: []string{"de"}},
+		{URL: "https://example.com/lunch", TimeFrom: "11:00", TimeTo: "14:00"},
+		{URL: "https://example.com/promo", Query: map[string]string{"ref": "promo"}},
+	}
+
+	tests := []struct {
+		name             string
+		rules            links.Rules
+		userAgent        string
+		acceptLanguage   string
+		query            string
+		expectedLocation string
+	}{
+		{"iOS", rules[:3], "Mozilla/5.0 (iPhone; CPU iPhone OS 17_2 like Mac OS X)", "de", "",
+			"https://apps.apple.com/app?utm_source=newsletter"},
+		{"Android", rules[:3], "Mozilla/5.0 (Linux; Android 14; Pixel 8)", "", "", "https://play.google.com/app?utm_source=newsletter"},
+		{"German", rules[:3], "Mozilla/5.0 (Windows NT 10.0; Win64; x64)", "de-DE,en;q=0.8", "", "https://example.com/de?utm_source=newsletter"},
+		{"Time", rules[3:], "", "", "", "https://example.com/lunch?utm_source=newsletter"},
+		{"Query", rules[4:], "", "", "?ref=promo", "https://example.com/promo?utm_source=newsletter"},
+		{"Default", rules[:3], "Mozilla/5.0 (Windows NT 10.0; Win64; x64)", "en", "", "https://example.com?utm_source=newsletter"},
+		{"Without rules", nil, "Mozilla/5.0 (iPhone; CPU iPhone OS 17_2 like Mac OS X)", "", "", "https://example.com?utm_source=newsletter"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := newTestLinkStorage(0, map[int]string{})
+			_, _ = storage.GenerateKey("https://example.com", links.LinkOptions{UTMTemplate: "spring", Rules: tt.rules})
+			app := Application{
+				Config:       Config{RedirectStatus: http.StatusFound},
+				Logger:       utils.NewLogger(io.Discard, &utils.Clock{}),
+				Validator:    *NewValidator("1"),
+				Clock:        &test.Clock{},
+				Links:        storage,
+				Clicks:       &testClicksRecorder{},
+				UTMTemplates: newTestUTMTemplates(utm.Template{Name: "spring", Source: "newsletter"}),
+			}
+
+			w := httptest.NewRecorder()
+			r := newRequestWithNamedParameter(http.MethodGet, "/go/:key"+tt.query, httprouter.Params{
+				httprouter.Param{Key: "key", Value: "1"},
+			})
+			r.Header.Set("User-Agent", tt.userAgent)
+			r.Header.Set("Accept-Language", tt.acceptLanguage)
+
+			app.goHandler(w, r)
+
+			result := w.Result()
+
+			defer result.Body.Close()
+
+			require.Equal(t, http.StatusFound, result.StatusCode)
+			require.Equal(t, tt.expectedLocation, result.Header.Get("Location"))
+		})
+	}
+}
+
+func TestGenerateHandlerRules(t *testing.T) {
+	tests := []struct {
+		name             string
+		request          envelope
+		expectedCode     int
+		expectedResponse string
+		expectedRules    map[int]links.Rules
+	}{
+		{"Rules", envelope{"url": "https://example.org", "rules": []envelope{
+			{"url": "https://example.org/de", "languages": []string{"de"}},
+		}}, http.StatusOK, `{"link":"http://localhost/go/2","existing":false}`, map[int]links.Rules{
+			1: nil,
+			2: {{URL: "https://example.org/de", Languages: []string{"de"}}},
+		}},
+		{"Invalid URL", envelope{"url": "https://example.org", "rules": []envelope{{"url": "example", "platforms": []string{"ios"}}}},
+			http.StatusUnprocessableEntity, `{"error":"rule 1: URL must be an absolute URL"}`, map[int]links.Rules{1: nil}},
+		{"Without conditions", envelope{"url": "https://example.org", "rules": []envelope{
+			{"url": "https://example.org/ios", "platforms": []string{"ios"}},
+			{"url": "https://example.org/de"},
+		}}, http.StatusUnprocessableEntity, `{"error":"rule 2: rule must have at least one condition"}`, map[int]links.Rules{1: nil}},
+		{"Unknown condition", envelope{"url": "https://example.org", "rules": []envelope{{"url": "https://example.org/de", "country": "de"}}},
+			http.StatusBadRequest, `{"error":"json: unknown field \"country\""}`, map[int]links.Rules{1: nil}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := newTestLinkStorage(1, map[int]string{})
+			_, _ = storage.GenerateKey("https://example.org", links.LinkOptions{})
+			app := Application{
+				Logger: utils.NewLogger(io.Discard, &utils.Clock{}),
+				Clock:  &test.Clock{},
+				Config: Config{DedupEnabled: true},
+				Links:  storage,
+			}
+			w := httptest.NewRecorder()
+			body, _ := json.Marshal(tt.request)
+			r := httptest.NewRequest(http.MethodPost, "/generate", bytes.NewReader(body))
+
+			app.generateHandler(w, r)
+
+			result := w.Result()
+			jsonResponse, err := io.ReadAll(result.Body)
+
+			defer result.Body.Close()
+
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedCode, result.StatusCode)
+			require.JSONEq(t, tt.expectedResponse, string(jsonResponse))
+			require.Equal(t, tt.expectedRules, storage.rules)
+		})
+	}
+}
+
+func TestGoHandlerVariants(t *testing.T) {
+	variants := links.Variants{{URL: "https://example.com/a", Weight: 70}, {URL: "https://example.com/b", Weight: 30}}
+
+	tests := []struct {
+		name             string
+		sticky           string
+		random           uint64
+		cookie           string
+		rules            links.Rules
+		expectedLocation string
+		expectedVariant  int
+		expectedCookie   string
+	}{
+		{"Random first variant", SplitStickyCookie, 69, "", nil, "https://example.com/a?utm_source=newsletter", 1, "ab_1=1"},
+		{"Random second variant", SplitStickyCookie, 170, "", nil, "https://example.com/b?utm_source=newsletter", 2, "ab_1=2"},
+		{"Variant of cookie", SplitStickyCookie, 0, "2", nil, "https://example.com/b?utm_source=newsletter", 2, ""},
+		{"Invalid cookie", SplitStickyCookie, 0, "3", nil, "https://example.com/a?utm_source=newsletter", 1, "ab_1=1"},
+		{"Hash", SplitStickyHash, 0, "", nil, "https://example.com/a?utm_source=newsletter", 1, ""},
+		{"Rule matches", SplitStickyCookie, 0, "", links.Rules{{URL: "https://example.com/promo", Query: map[string]string{"ref": ""}}},
+			"https://example.com/promo?utm_source=newsletter", 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := newTestLinkStorage(0, map[int]string{})
+			_, _ = storage.GenerateKey("https://example.com", links.LinkOptions{UTMTemplate: "spring", Rules: tt.rules, Variants: variants})
+			clicks := &testClicksRecorder{}
+			app := Application{
+				Config:       Config{RedirectStatus: http.StatusFound, SplitSticky: tt.sticky},
+				Logger:       utils.NewLogger(io.Discard, &utils.Clock{}),
+				Validator:    *NewValidator("1"),
+				Clock:        &test.Clock{},
+				Random:       &test.Random{Value: tt.random},
+				Links:        storage,
+				Clicks:       clicks,
+				UTMTemplates: newTestUTMTemplates(utm.Template{Name: "spring", Source: "newsletter"}),
+			}
+
+			w := httptest.NewRecorder()
+			r := newRequestWithNamedParameter(http.MethodGet, "/go/1?ref=mail", httprouter.Params{
+				httprouter.Param{Key: "key", Value: "1"},
+			})
+			r.RemoteAddr = "192.168.10.15:1234"
+			r.Header.Set("User-Agent", "curl/8.5.0")
+
+			if tt.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: "ab_1", Value: tt.cookie})
+			}
+
+			app.goHandler(w, r)
+
+			result := w.Result()
+
+			defer result.Body.Close()
+
+			require.Equal(t, http.StatusFound, result.StatusCode)
+			require.Equal(t, tt.expectedLocation, result.Header.Get("Location"))
+			require.Len(t, clicks.clicks, 1)
+			require.Equal(t, tt.expectedVariant, clicks.clicks[0].Variant)
+
+			if tt.expectedCookie == "" {
+				require.Empty(t, result.Cookies())
+			} else {
+				require.Len(t, result.Cookies(), 1)
+				require.Equal(t, tt.expectedCookie+"; Path=/go/1; Max-Age=2592000; HttpOnly; SameSite=Lax", result.Cookies()[0].String())
+			}
+		})
+	}
+}
+
+func TestGenerateHandlerVariants(t *testing.T) {
+	tests := []struct {
+		name             string
+		request          envelope
+		expectedCode     int
+		expectedResponse string
+		expectedVariants map[int]links.Variants
+	}{
+		{"Variants", envelope{"url": "https://example.org", "variants": []envelope{
+			{"url": "https://example.org/a", "weight": 70},
+			{"url": "https://example.org/b", "weight": 30},
+		}}, http.StatusOK, `{"link":"http://localhost/go/2","existing":false}`, map[int]links.Variants{
+			1: nil,
+			2: {{URL: "https://example.org/a", Weight: 70}, {URL: "https://example.org/b", Weight: 30}},
+		}},
+		{"Invalid URL", envelope{"url": "https://example.org", "variants": []envelope{
+			{"url": "https://example.org/a", "weight": 70},
+			{"url": "example", "weight": 30},
+		}}, http.StatusUnprocessableEntity, `{"error":"variant 2: URL must be an absolute URL"}`, map[int]links.Variants{1: nil}},
+		{"Invalid weight", envelope{"url": "https://example.org", "variants": []envelope{
+			{"url": "https://example.org/a", "weight": 70},
+			{"url": "https://example.org/b", "weight": 0},
+		}}, http.StatusUnprocessableEntity, `{"error":"weight of variant must be from 1 to 1000"}`, map[int]links.Variants{1: nil}},
+		{"Empty variants", envelope{"url": "https://example.org", "variants": []envelope{}},
+			http.StatusUnprocessableEntity, `{"error":"from 2 to 10 variants must be provided"}`, map[int]links.Variants{1: nil}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := newTestLinkStorage(1, map[int]string{})
+			_, _ = storage.GenerateKey("https://example.org", links.LinkOptions{})
+			app := Application{
+				Logger: utils.NewLogger(io.Discard, &utils.Clock{}),
+				Clock:  &test.Clock{},
+				Config: Config{DedupEnabled: true},
+				Links:  storage,
+			}
+			w := httptest.NewRecorder()
+			body, _ := json.Marshal(tt.request)
+			r := httptest.NewRequest(http.MethodPost, "/generate", bytes.NewReader(body))
+
+			app.generateHandler(w, r)
+
+			result := w.Result()
+			jsonResponse, err := io.ReadAll(result.Body)
+
+			defer result.Body.Close()
+
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedCode, result.StatusCode)
+			require.JSONEq(t, tt.expectedResponse, string(jsonResponse))
+			require.Equal(t, tt.expectedVariants, storage.variants)
+		})
+	}
+}
+
+func TestGoHandlerFallback(t *testing.T) {
+	broken := links.Health{Status: http.StatusNotFound, CheckedAt: (&test.Clock{}).Now()}
+	healthy := links.Health{Status: http.StatusOK, CheckedAt: (&test.Clock{}).Now()}
+
+	tests := []struct {
+		name             string
+		fallback         string
+		globalFallback   string
+		down             bool
+		health           links.Health
+		expectedLocation string
+	}{
+		{"Healthy", "https://example.com/maintenance", "https://example.org", false, healthy, "https://example.com/a?utm_source=newsletter"},
+		{"Not checked", "https://example.com/maintenance", "", false, links.Health{}, "https://example.com/a?utm_source=newsletter"},
+		{"Broken with fallback", "https://example.com/maintenance", "https://example.org", false, broken,
+			"https://example.com/maintenance?utm_source=newsletter"},
+		{"Broken with global fallback", "", "https://example.org", false, broken, "https://example.org?utm_source=newsletter"},
+		{"Marked down", "https://example.com/maintenance", "", true, healthy, "https://example.com/maintenance?utm_source=newsletter"},
+		{"No fallback", "", "", true, broken, "https://example.com/a?utm_source=newsletter"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := newTestLinkStorage(0, map[int]string{})
+			_, _ = storage.GenerateKey("https://example.com", links.LinkOptions{
+				UTMTemplate: "spring",
+				Rules:       links.Rules{{URL: "https://example.com/a", Query: map[string]string{"ref": ""}}},
+				Fallback:    tt.fallback,
+			})
+			storage.down[1] = tt.down
+			storage.health[1] = tt.health
+			clicks := &testClicksRecorder{}
+			app := Application{
+				Config:       Config{RedirectStatus: http.StatusFound, FallbackURL: tt.globalFallback},
+				Logger:       utils.NewLogger(io.Discard, &utils.Clock{}),
+				Validator:    *NewValidator("1"),
+				Clock:        &test.Clock{},
+				Links:        storage,
+				Clicks:       clicks,
+				UTMTemplates: newTestUTMTemplates(utm.Template{Name: "spring", Source: "newsletter"}),
+			}
+
+			w := httptest.NewRecorder()
+			r := newRequestWithNamedParameter(http.MethodGet, "/go/1?ref=mail", httprouter.Params{
+				httprouter.Param{Key: "key", Value: "1"},
+			})
+
+			app.goHandler(w, r)
+
+			result := w.Result()
+
+			defer result.Body.Close()
+
+			require.Equal(t, http.StatusFound, result.StatusCode)
+			require.Equal(t, tt.expectedLocation, result.Header.Get("Location"))
+			require.Len(t, clicks.clicks, 1)
+		})
+	}
+}
+
+func TestGenerateHandlerFallback(t *testing.T) {
+	tests := []struct {
+		name              string
+		request           envelope
+		expectedCode      int
+		expectedResponse  string
+		expectedFallbacks map[int]string
+	}{
+		{"Fallback", envelope{"url": "https://example.org", "fallback": "https://example.org/maintenance"}, http.StatusOK,
+			`{"link":"http://localhost/go/2","existing":false}`, map[int]string{1: "", 2: "https://example.org/maintenance"}},
+		{"Without fallback", envelope{"url": "https://example.org"}, http.StatusOK,
+			`{"link":"http://localhost/go/1","existing":true}`, map[int]string{1: ""}},
+		{"Invalid fallback", envelope{"url": "https://example.org", "fallback": "maintenance"}, http.StatusUnprocessableEntity,
+			`{"error":"fallback: URL must be an absolute URL"}`, map[int]string{1: ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := newTestLinkStorage(1, map[int]string{})
+			_, _ = storage.GenerateKey("https://example.org", links.LinkOptions{})
+			app := Application{
+				Logger: utils.NewLogger(io.Discard, &utils.Clock{}),
+				Clock:  &test.Clock{},
+				Config: Config{DedupEnabled: true},
+				Links:  storage,
+			}
+			w := httptest.NewRecorder()
+			body, _ := json.Marshal(tt.request)
+			r := httptest.NewRequest(http.MethodPost, "/generate", bytes.NewReader(body))
+
+			app.generateHandler(w, r)
+
+			result := w.Result()
+			jsonResponse, err := io.ReadAll(result.Body)
+
+			defer result.Body.Close()
+
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedCode, result.StatusCode)
+			require.JSONEq(t, tt.expectedResponse, string(jsonResponse))
+			require.Equal(t, tt.expectedFallbacks, storage.fallbacks)
+		})
+	}
+}
+
+func TestGenerateHandlersBlocked(t *testing.T) {
+	validator := NewValidator("1")
+	validator.Blocklist = newTestBlocklist("phishing.example\n.spam.example\n")
+
+	tests := []struct {
+		name             string
+		target           string
+		request          any
+		expectedCode     int
+		expectedResponse string
+	}{
+		{"Generate", "/generate", envelope{"url": "https://login.spam.example/account"}, http.StatusUnprocessableEntity, `{"error":"URL is blocked"}`},
+		{"Generate with rule", "/generate", envelope{"url": "https://example.com", "rules": []envelope{
+			{"url": "https://phishing.example", "platforms": []string{"ios"}},
+		}}, http.StatusUnprocessableEntity, `{"error":"rule 1: URL is blocked"}`},
+		{"Batch generate", "/batch/generate", []string{"https://example.com", "https://PHISHING.example"}, http.StatusUnprocessableEntity,
+			`{"error":"URL is blocked"}`},
+		{"Allowed", "/generate", envelope{"url": "https://example.com"}, http.StatusOK, `{"link":"http://localhost/go/1"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := Application{
+				Logger:    utils.NewLogger(io.Discard, &utils.Clock{}),
+				Clock:     &test.Clock{},
+				Validator: *validator,
+				Links:     newTestLinkStorage(1, map[int]string{}),
+			}
+			w := httptest.NewRecorder()
+			body, _ := json.Marshal(tt.request)
+			r := httptest.NewRequest(http.MethodPost, tt.target, bytes.NewReader(body))
+
+			if tt.target == "/generate" {
+				app.generateHandler(w, r)
+			} else {
+				app.batchGenerateHandler(w, r)
+			}
+
+			result := w.Result()
+			jsonResponse, err := io.ReadAll(result.Body)
+
+			defer result.Body.Close()
+
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedCode, result.StatusCode)
+			require.JSONEq(t, tt.expectedResponse, string(jsonResponse))
+		})
+	}
+}
+
+func TestGoHandlerBlocked(t *testing.T) {
+	clicks := &testClicksRecorder{}
+	validator := NewValidator("1")
+	validator.Blocklist = newTestBlocklist("phishing.example\n")
+	app := Application{
+		Config:    Config{RedirectStatus: http.StatusFound},
+		Logger:    utils.NewLogger(io.Discard, &utils.Clock{}),
+		Clock:     &test.Clock{},
+		Validator: *validator,
+		Links: newTestLinkStorage(2, map[int]string{
+			1: "https://phishing.example/login",
+			2: "https://example.com",
+		}),
+		Clicks: clicks,
+	}
+	blocked := testutil.ToFloat64(MetricBlockedURLs.WithLabelValues(blocklist.RuleDomain, blockedOnRedirect))
+
+	w := httptest.NewRecorder()
+	r := newRequestWithNamedParameter(http.MethodGet, "/go/:key", httprouter.Params{
+		httprouter.Param{Key: "key", Value: "1"},
+	})
+
+	app.goHandler(w, r)
+
+	result := w.Result()
+	jsonResponse, err := io.ReadAll(result.Body)
+
+	defer result.Body.Close()
+
+	require.NoError(t, err)
+	require.Equal(t, http.StatusForbidden, result.StatusCode)
+	require.JSONEq(t, `{"error":"Destination of the link is blocked"}`, string(jsonResponse))
+	require.Empty(t, clicks.clicks)
+	require.Equal(t, blocked+1, testutil.ToFloat64(MetricBlockedURLs.WithLabelValues(blocklist.RuleDomain, blockedOnRedirect)))
+
+	w = httptest.NewRecorder()
+	r = newRequestWithNamedParameter(http.MethodGet, "/go/:key", httprouter.Params{
+		httprouter.Param{Key: "key", Value: "2"},
+	})
+
+	app.goHandler(w, r)
+
+	require.Equal(t, http.StatusFound, w.Result().StatusCode)
+	require.Len(t, clicks.clicks, 1)
+}
+
+func TestLinkHandlersBlocked(t *testing.T) {
+	validator := NewValidator("1")
+	validator.Blocklist = newTestBlocklist("phishing.example\n")
+	app := Application{
+		Logger:    utils.NewLogger(io.Discard, &utils.Clock{}),
+		Clock:     &test.Clock{},
+		Validator: *validator,
+		Links: newTestLinkStorage(2, map[int]string{
+			1: "https://phishing.example/login",
+			2: "https://example.com",
+		}),
+	}
+	blocked := testutil.ToFloat64(MetricBlockedURLs.WithLabelValues(blocklist.RuleDomain, blockedOnRedirect))
+
+	w := httptest.NewRecorder()
+	r := newRequestWithNamedParameter(http.MethodGet, "/api/links/:key", httprouter.Params{
+		httprouter.Param{Key: "key", Value: "1"},
+	})
+
+	app.linkHandler(w, r)
+
+	result := w.Result()
+	jsonResponse, err := io.ReadAll(result.Body)
+
+	defer result.Body.Close()
+
+	require.NoError(t, err)
+	require.Equal(t, http.StatusForbidden, result.StatusCode)
+	require.JSONEq(t, `{"error":"Destination of the link is blocked"}`, string(jsonResponse))
+
+	w = httptest.NewRecorder()
+	r = newRequestWithNamedParameter(http.MethodGet, "/batch/go", nil)
+	r.Body = io.NopCloser(strings.NewReader(`["1","2"]`))
+
+	app.batchGoHandler(w, r)
+
+	result = w.Result()
+	jsonResponse, err = io.ReadAll(result.Body)
+
+	defer result.Body.Close()
+
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, result.StatusCode)
+	require.JSONEq(t, `{"links":{"2":"https://example.com"}}`, string(jsonResponse))
+	require.Equal(t, blocked+2, testutil.ToFloat64(MetricBlockedURLs.WithLabelValues(blocklist.RuleDomain, blockedOnRedirect)))
+}
+
+type testDispatcher struct {
+	published []string
+}
+
+// Publish Records event as "event key"
+func (d *testDispatcher) Publish(event string, data any) {
+	d.published = append(d.published, event+" "+fmt.Sprint(data.(envelope)["key"]))
+}
+
+func (d *testDispatcher) Close() {
+	//
+}
+
+func TestPublishLinkEvents(t *testing.T) {
+	tests := []struct {
+		name              string
+		method            string
+		handler           func(*Application, http.ResponseWriter, *http.Request)
+		request           any
+		expectedCode      int
+		expectedPublished []string
+	}{
+		{"Generate", http.MethodPost, (*Application).generateHandler, envelope{"url": "https://example.org"}, http.StatusOK, []string{"link.created 2"}},
+		{"Generate existing", http.MethodPost, (*Application).generateHandler, envelope{"url": "https://example.com"}, http.StatusOK, nil},
+		{"Generate invalid", http.MethodPost, (*Application).generateHandler, envelope{"url": "example.org"}, http.StatusUnprocessableEntity, nil},
+		{"Batch generate", http.MethodPost, (*Application).batchGenerateHandler, []string{"https://example.com", "https://example.org"}, http.StatusOK,
+			[]string{"link.created 2"}},
+		{"Update", http.MethodPatch, (*Application).updateLinkHandler, envelope{"title": "Example"}, http.StatusOK, []string{"link.updated 1"}},
+		{"Delete", http.MethodDelete, (*Application).deleteLinkHandler, nil, http.StatusNoContent, []string{"link.deleted 1"}},
+		{"Click", http.MethodGet, (*Application).goHandler, nil, http.StatusFound, []string{"link.clicked 1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dispatcher := &testDispatcher{}
+			storage := newTestLinkStorage(1, map[int]string{1: "https://example.com"})
+			storage.lastKey = 1
+			app := Application{
+				Config:     Config{DedupEnabled: true, RedirectStatus: http.StatusFound},
+				Logger:     utils.NewLogger(io.Discard, &utils.Clock{}),
+				Validator:  *NewValidator(links.Alphabets[links.AlphabetBase36]),
+				Clock:      &test.Clock{},
+				Links:      storage,
+				Clicks:     &testClicksRecorder{},
+				Dispatcher: dispatcher,
+			}
+
+			w := httptest.NewRecorder()
+			body, _ := json.Marshal(tt.request)
+			r := newRequestWithNamedParameter(tt.method, "/", httprouter.Params{
+				httprouter.Param{Key: "key", Value: "1"},
+			})
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			tt.handler(&app, w, r)
+
+			result := w.Result()
+
+			defer result.Body.Close()
+
+			require.Equal(t, tt.expectedCode, result.StatusCode)
+			require.Equal(t, tt.expectedPublished, dispatcher.published)
+		})
+	}
+}
+
+type testWebhooks struct {
+	subscriptions []webhooks.Subscription
+}
+
+func (t *testWebhooks) StoreSubscription(subscription webhooks.Subscription) error {
+	t.subscriptions = append(t.subscriptions, subscription)
+
+	return nil
+}
+
+func (t *testWebhooks) ListSubscriptions() ([]webhooks.Subscription, error) {
+	return slices.Clone(t.subscriptions), nil
+}
+
+func (t *testWebhooks) DeleteSubscription(id string) (webhooks.Subscription, error) {
+	for i, subscription := range t.subscriptions {
+		if subscription.ID == id {
+			t.subscriptions = slices.Delete(t.subscriptions, i, i+1)
+
+			return subscription, nil
+		}
+	}
+
+	return webhooks.Subscription{}, webhooks.ErrSubscriptionNotFound
+}
+
+func TestCreateWebhookHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		secret         string
+		expectedSecret string
+	}{
+		{"Secret", "0123456789abcdef", "0123456789abcdef"},
+		{"Generated secret", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &testWebhooks{}
+			app := Application{
+				Logger:   utils.NewLogger(io.Discard, &utils.Clock{}),
+				Clock:    &test.Clock{},
+				Webhooks: storage,
+			}
+
+			w := httptest.NewRecorder()
+			body, _ := json.Marshal(envelope{
+				"url":    "https://hooks.example.com/links",
+				"events": []string{"link.created", "link.clicked"},
+				"secret": tt.secret,
+			})
+			r := httptest.NewRequest(http.MethodPost, "/admin/webhooks", bytes.NewReader(body))
+
+			app.createWebhookHandler(w, r)
+
+			result := w.Result()
+
+			require.Equal(t, http.StatusCreated, result.StatusCode)
+
+			var response struct {
+				ID        string
+				URL       string
+				Events    []string
+				Secret    string
+				CreatedAt time.Time `json:"created_at"`
+			}
+
+			defer result.Body.Close()
+
+			require.NoError(t, json.NewDecoder(result.Body).Decode(&response))
+			require.Equal(t, "https://hooks.example.com/links", response.URL)
+			require.Equal(t, []string{"link.created", "link.clicked"}, response.Events)
+			require.True(t, response.CreatedAt.Equal((&test.Clock{}).Now()))
+
+			if tt.expectedSecret == "" {
+				require.Len(t, response.Secret, 48)
+			} else {
+				require.Equal(t, tt.expectedSecret, response.Secret)
+			}
+
+			require.Len(t, storage.subscriptions, 1)
+			require.Equal(t, response.ID, storage.subscriptions[0].ID)
+			require.Equal(t, response.Secret, storage.subscriptions[0].Secret)
+		})
+	}
+}
+
+func TestCreateWebhookHandlerInvalid(t *testing.T) {
+	tests := []struct {
+		name             string
+		request          any
+		expectedCode     int
+		expectedResponse string
+	}{
+		{"No events", envelope{"url": "https://hooks.example.com"}, http.StatusUnprocessableEntity,
+			`{"error":"at least one event must be provided"}`},
+		{"Unknown event", envelope{"url": "https://hooks.example.com", "events": []string{"link.viewed"}}, http.StatusUnprocessableEntity,
+			`{"error":"unknown event: link.viewed"}`},
+		{"Not http", envelope{"url": "ftp://hooks.example.com", "events": []string{"link.created"}}, http.StatusUnprocessableEntity,
+			`{"error":"URL must be an absolute http or https URL"}`},
+		{"Relative", envelope{"url": "/hooks", "events": []string{"link.created"}}, http.StatusUnprocessableEntity,
+			`{"error":"URL must be an absolute http or https URL"}`},
+		{"Internal", envelope{"url": "http://127.0.0.1:8080/hooks", "events": []string{"link.created"}}, http.StatusUnprocessableEntity,
+			`{"error":"URL must not point to internal address"}`},
+		{"Link-local", envelope{"url": "http://169.254.169.254/latest", "events": []string{"link.created"}}, http.StatusUnprocessableEntity,
+			`{"error":"URL must not point to internal address"}`},
+		{"Localhost", envelope{"url": "https://localhost/hooks", "events": []string{"link.created"}}, http.StatusUnprocessableEntity,
+			`{"error":"URL must not point to internal address"}`},
+		{"Short secret", envelope{"url": "https://hooks.example.com", "events": []string{"link.created"}, "secret": "short"},
+			http.StatusUnprocessableEntity, `{"error":"secret must be from 16 to 255 bytes long"}`},
+		{"Unknown field", envelope{"url": "https://hooks.example.com", "events": []string{"link.created"}, "owner": "me"},
+			http.StatusBadRequest, `{"error":"json: unknown field \"owner\""}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &testWebhooks{}
+			app := Application{
+				Logger:   utils.NewLogger(io.Discard, &utils.Clock{}),
+				Clock:    &test.Clock{},
+				Webhooks: storage,
+			}
+
+			w := httptest.NewRecorder()
+			body, _ := json.Marshal(tt.request)
+			r := httptest.NewRequest(http.MethodPost, "/admin/webhooks", bytes.NewReader(body))
+
+			app.createWebhookHandler(w, r)
+
+			result := w.Result()
+
+			require.Equal(t, tt.expectedCode, result.StatusCode)
+
+			jsonResponse, err := io.ReadAll(result.Body)
+
+			defer result.Body.Close()
+
+			require.NoError(t, err)
+			require.JSONEq(t, tt.expectedResponse+"\n", string(jsonResponse))
+			require.Empty(t, storage.subscriptions)
+		})
+	}
+}
+
+func TestListWebhooksHandler(t *testing.T) {
+	createdAt := time.Date(2024, 2, 7, 12, 0, 0, 0, time.UTC)
+	app := Application{
+		Logger: utils.NewLogger(io.Discard, &utils.Clock{}),
+		Webhooks: &testWebhooks{subscriptions: []webhooks.Subscription{
+			{ID: "a1", URL: "https://hooks.example.com", Secret: "secret", Events: []string{"link.created"}, CreatedAt: createdAt},
+			{ID: "b2", URL: "https://example.org/hook", Secret: "secret", Events: []string{"link.clicked"}, CreatedAt: createdAt},
+		}},
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/admin/webhooks", nil)
+
+	app.listWebhooksHandler(w, r)
+
+	result := w.Result()
+
+	require.Equal(t, http.StatusOK, result.StatusCode)
+
+	jsonResponse, err := io.ReadAll(result.Body)
+
+	defer result.Body.Close()
+
+	require.NoError(t, err)
+	require.JSONEq(t, `{"webhooks":[`+
+		`{"id":"a1","url":"https://hooks.example.com","events":["link.created"],"created_at":"2024-02-07T12:00:00Z"},`+
+		`{"id":"b2","url":"https://example.org/hook","events":["link.clicked"],"created_at":"2024-02-07T12:00:00Z"}]}`,
+		string(jsonResponse))
+}
+
+func TestDeleteWebhookHandler(t *testing.T) {
+	tests := []struct {
+		name             string
+		id               string
+		expectedCode     int
+		expectedResponse string
+	}{
+		{"Delete", "a1", http.StatusNoContent, ""},
+		{"Not found", "missing", http.StatusNotFound, `{"error":"Webhook subscription not found for id missing"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &testWebhooks{subscriptions: []webhooks.Subscription{{ID: "a1", URL: "https://hooks.example.com"}}}
+			app := Application{
+				Logger:   utils.NewLogger(io.Discard, &utils.Clock{}),
+				Webhooks: storage,
+			}
+
+			w := httptest.NewRecorder()
+			r := newRequestWithNamedParameter(http.MethodDelete, "/admin/webhooks/:id", httprouter.Params{
+				httprouter.Param{Key: "id", Value: tt.id},
+			})
+
+			app.deleteWebhookHandler(w, r)
+
+			result := w.Result()
+
+			require.Equal(t, tt.expectedCode, result.StatusCode)
+
+			jsonResponse, err := io.ReadAll(result.Body)
+
+			defer result.Body.Close()
+
+			require.NoError(t, err)
+
+			if tt.expectedResponse == "" {
+				require.Empty(t, jsonResponse)
+				require.Empty(t, storage.subscriptions)
+			} else {
+				require.JSONEq(t, tt.expectedResponse+"\n", string(jsonResponse))
+				require.Len(t, storage.subscriptions, 1)
+			}
+		})
+	}
 }
@@ -4,8 +4,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/dzhdmitry/link-shorter/internal/links"
+	"github.com/dzhdmitry/link-shorter/internal/webhooks"
+	"github.com/julienschmidt/httprouter"
+	"hash/fnv"
 	"io"
+	"net"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -51,6 +57,132 @@ func (app *Application) writeJSON(w http.ResponseWriter, r *http.Request, data i
 	return js, nil
 }
 
+func (app *Application) acceptsJSON(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "application/json")
+}
+
+// anonymizeIP Zeroes last octet of IPv4 and last 80 bits of IPv6 address
+func anonymizeIP(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+
+	if err != nil {
+		host = remoteAddr
+	}
+
+	ip := net.ParseIP(host)
+
+	if ip == nil {
+		return ""
+	}
+
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4.Mask(net.CIDRMask(24, 32)).String()
+	}
+
+	return ip.Mask(net.CIDRMask(48, 128)).String()
+}
+
+// visitorHash Returns hash of key, IP and user agent of visitor, which is the same for all requests of visitor to the link
+func visitorHash(key string, r *http.Request) uint64 {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(key + "|" + host + "|" + r.UserAgent()))
+
+	return hash.Sum64()
+}
+
+func (app *Application) recordClick(r *http.Request, variant int) {
+	click := links.Click{
+		Key:       httprouter.ParamsFromContext(r.Context()).ByName("key"),
+		ClickedAt: app.Clock.Now(),
+		Referrer:  r.Referer(),
+		UserAgent: r.UserAgent(),
+		IP:        anonymizeIP(r.RemoteAddr),
+		Variant:   variant,
+	}
+
+	app.Clicks.Record(click)
+	app.publish(webhooks.EventLinkClicked, envelope{
+		"key":        click.Key,
+		"clicked_at": click.ClickedAt,
+		"referrer":   click.Referrer,
+		"user_agent": click.UserAgent,
+		"variant":    click.Variant,
+	})
+}
+
+// publish Sends event to webhook subscriptions in background, if webhooks are enabled
+func (app *Application) publish(event string, data envelope) {
+	if app.Dispatcher != nil {
+		app.Dispatcher.Publish(event, data)
+	}
+}
+
+// linkEvent Makes data of event about link which is created or deleted
+func (app *Application) linkEvent(key, URL string) envelope {
+	return envelope{"key": key, "link": app.composeShortLink(key), "url": URL}
+}
+
+// normalizeURL Returns canonical form of URL to store, URL is kept as is without normalizer.
+// Changed URL is validated again, as normalization may turn it into internal or blocked one
+func (app *Application) normalizeURL(URL string) (string, error) {
+	if app.Normalizer == nil {
+		return URL, nil
+	}
+
+	normalized, err := app.Normalizer.Normalize(URL)
+
+	if err != nil || normalized == URL {
+		return normalized, err
+	}
+
+	if err = app.Validator.validateURL(normalized); err != nil {
+		return "", err
+	}
+
+	return normalized, nil
+}
+
+// normalizeURLs Returns map with key=URL, value=normalized URL
+func (app *Application) normalizeURLs(URLs []string) (map[string]string, error) {
+	normalizedURLs := make(map[string]string, len(URLs))
+
+	for _, URL := range URLs {
+		normalized, err := app.normalizeURL(URL)
+
+		if err != nil {
+			return nil, err
+		}
+
+		normalizedURLs[URL] = normalized
+	}
+
+	return normalizedURLs, nil
+}
+
+// splitExisting Returns sorted lists of newly shortened URLs and URLs which got key of existing link
+func splitExisting(keysByURLs map[string]string, existing map[string]bool) ([]string, []string) {
+	created, reused := []string{}, []string{}
+
+	for URL := range keysByURLs {
+		if existing[URL] {
+			reused = append(reused, URL)
+		} else {
+			created = append(created, URL)
+		}
+	}
+
+	sort.Strings(created)
+	sort.Strings(reused)
+
+	return created, reused
+}
+
 func (app *Application) readJSON(w http.ResponseWriter, r *http.Request, destination interface{}) error {
 	decoder := json.NewDecoder(r.Body)
 
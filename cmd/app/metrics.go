@@ -14,3 +14,13 @@ var MetricRequestsTotal = promauto.NewSummaryVec(
 	},
 	[]string{"status"},
 )
+
+// MetricBlockedURLs Counts URLs matched by blocklist, by kind of matched entry and by action: "create" or "redirect"
+var MetricBlockedURLs = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "shorter",
+		Subsystem: "blocklist",
+		Name:      "matches_total",
+	},
+	[]string{"rule", "action"},
+)
@@ -3,7 +3,11 @@ package app
 import (
 	"bytes"
 	"compress/gzip"
+	"crypto/subtle"
+	"errors"
 	"fmt"
+	"github.com/dzhdmitry/link-shorter/internal/apikeys"
+	"github.com/dzhdmitry/link-shorter/internal/utils"
 	"github.com/felixge/httpsnoop"
 	"golang.org/x/time/rate"
 	"io"
@@ -119,32 +123,59 @@ func (app *Application) recoverPanic(next http.Handler) http.Handler {
 	})
 }
 
-func (app *Application) rateLimit(next http.Handler) http.Handler {
-	type client struct {
-		limiter  *rate.Limiter
-		lastSeen time.Time
-	}
+// limiters Keeps rate limiter of every client, clients which are not seen for 3 minutes are forgotten
+type limiters struct {
+	limit   rate.Limit
+	burst   int
+	clock   utils.ClockInterface
+	mu      sync.Mutex
+	clients map[string]*limiterClient
+}
 
-	var (
-		mu      sync.Mutex
-		clients = make(map[string]*client)
-	)
+type limiterClient struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+func newLimiters(limit rate.Limit, burst int, clock utils.ClockInterface) *limiters {
+	l := limiters{limit: limit, burst: burst, clock: clock, clients: make(map[string]*limiterClient)}
 
 	go func() {
 		for {
 			time.Sleep(time.Minute)
-			mu.Lock()
+			l.mu.Lock()
 
-			for ip, c := range clients {
-				if time.Since(c.lastSeen) > 3*time.Minute {
-					delete(clients, ip)
+			for name, c := range l.clients {
+				if l.clock.Now().Sub(c.lastSeen) > 3*time.Minute {
+					delete(l.clients, name)
 				}
 			}
 
-			mu.Unlock()
+			l.mu.Unlock()
 		}
 	}()
 
+	return &l
+}
+
+// get Returns limiter of client, limiter is created on first request
+func (l *limiters) get(name string) *rate.Limiter {
+	l.mu.Lock()
+
+	defer l.mu.Unlock()
+
+	if _, found := l.clients[name]; !found {
+		l.clients[name] = &limiterClient{limiter: rate.NewLimiter(l.limit, l.burst)}
+	}
+
+	l.clients[name].lastSeen = l.clock.Now()
+
+	return l.clients[name].limiter
+}
+
+func (app *Application) rateLimit(next http.Handler) http.Handler {
+	clients := newLimiters(rate.Limit(app.Config.LimiterRPS), app.Config.LimiterBurst, app.Clock)
+
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if app.Config.LimiterEnabled {
 			ip, _, err := net.SplitHostPort(r.RemoteAddr)
@@ -155,31 +186,146 @@ func (app *Application) rateLimit(next http.Handler) http.Handler {
 				return
 			}
 
-			mu.Lock()
+			if !clients.get(ip).AllowN(app.Clock.Now(), 1) {
+				app.rateLimitExceededResponse(w, r)
 
-			if _, found := clients[ip]; !found {
-				clients[ip] = &client{
-					limiter: rate.NewLimiter(
-						rate.Limit(app.Config.LimiterRPS),
-						app.Config.LimiterBurst,
-					),
-				}
+				return
 			}
+		}
 
-			clients[ip].lastSeen = time.Now()
+		next.ServeHTTP(w, r)
+	})
+}
 
-			if !clients[ip].limiter.Allow() {
-				mu.Unlock()
-				app.rateLimitExceededResponse(w, r)
+// keyUsage Counts requests of every API key: limiter by RPS of the key and number of requests made in UTC day
+type keyUsage struct {
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+	days     map[string]string
+	requests map[string]int
+}
 
-				return
-			}
+func newKeyUsage() *keyUsage {
+	return &keyUsage{limiters: map[string]*rate.Limiter{}, days: map[string]string{}, requests: map[string]int{}}
+}
+
+// allow Returns whether request of key fits its rate limit and whether it fits daily quota.
+// Zero RPS or quota means no limit, rejected requests are not counted to quota
+func (u *keyUsage) allow(key apikeys.Key, now time.Time) (bool, bool) {
+	u.mu.Lock()
 
-			mu.Unlock()
+	defer u.mu.Unlock()
+
+	if key.RPS > 0 {
+		if _, found := u.limiters[key.ID]; !found {
+			u.limiters[key.ID] = rate.NewLimiter(rate.Limit(key.RPS), key.RPS)
 		}
 
-		next.ServeHTTP(w, r)
+		if !u.limiters[key.ID].Allow() {
+			return false, true
+		}
+	}
+
+	if key.DailyQuota == 0 {
+		return true, true
+	}
+
+	day := now.UTC().Format(time.DateOnly)
+
+	if u.days[key.ID] != day {
+		u.days[key.ID] = day
+		u.requests[key.ID] = 0
+	}
+
+	if u.requests[key.ID] >= key.DailyQuota {
+		return true, false
+	}
+
+	u.requests[key.ID]++
+
+	return true, true
+}
+
+func (app *Application) keyUsage() *keyUsage {
+	app.apiKeyUsageOnce.Do(func() {
+		app.apiKeyUsage = newKeyUsage()
 	})
+
+	return app.apiKeyUsage
+}
+
+// findAPIKey Returns key by token from "Authorization: Bearer" header. ADMIN_API_KEY is admin key without limits
+func (app *Application) findAPIKey(token string) (apikeys.Key, error) {
+	admin := app.Config.AdminAPIKey
+
+	if admin != "" && subtle.ConstantTimeCompare([]byte(token), []byte(admin)) == 1 {
+		return apikeys.Key{ID: "admin", Name: "ADMIN_API_KEY", Scopes: []string{apikeys.ScopeAdmin}}, nil
+	}
+
+	if app.APIKeys == nil {
+		return apikeys.Key{}, apikeys.ErrKeyNotFound
+	}
+
+	return app.APIKeys.GetKey(apikeys.Hash(token))
+}
+
+// authenticate Requires API key with given scope. Unless AUTH_ENABLED is set, only admin endpoints require a key
+func (app *Application) authenticate(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !app.Config.AuthEnabled && scope != apikeys.ScopeAdmin {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		w.Header().Add("Vary", "Authorization")
+
+		token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+		if !found || token == "" {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			app.errorResponse(w, r, http.StatusUnauthorized, "API key is required")
+
+			return
+		}
+
+		key, err := app.findAPIKey(token)
+
+		if errors.Is(err, apikeys.ErrKeyNotFound) || (err == nil && key.IsRevoked()) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			app.errorResponse(w, r, http.StatusUnauthorized, "invalid or revoked API key")
+
+			return
+		}
+
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+
+			return
+		}
+
+		if !key.HasScope(scope) {
+			app.errorResponse(w, r, http.StatusForbidden, fmt.Sprintf("API key has no %q scope", scope))
+
+			return
+		}
+
+		withinRate, withinQuota := app.keyUsage().allow(key, app.Clock.Now())
+
+		if !withinRate {
+			app.rateLimitExceededResponse(w, r)
+
+			return
+		}
+
+		if !withinQuota {
+			app.errorResponse(w, r, http.StatusTooManyRequests, "daily quota of API key exceeded")
+
+			return
+		}
+
+		next.ServeHTTP(w, app.contextSetAPIKey(r, key))
+	}
 }
 
 func (app *Application) metricsMiddleware(next http.HandlerFunc) http.HandlerFunc {
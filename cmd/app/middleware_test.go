@@ -2,6 +2,7 @@ package app
 
 import (
 	"bytes"
+	"github.com/dzhdmitry/link-shorter/internal/apikeys"
 	"github.com/dzhdmitry/link-shorter/internal/utils"
 	"github.com/dzhdmitry/link-shorter/test"
 	"github.com/stretchr/testify/assert"
@@ -10,7 +11,9 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
+	"time"
 )
 
 var testdata = "./../../test/testdata"
@@ -73,3 +76,106 @@ func TestLogRequest(t *testing.T) {
 	app.logRequest(testLog)(httptest.NewRecorder(), r)
 	assert.Equal(t, "INFO: [2024-02-07T12:00:00Z] 127.0.0.1:1234 - HTTP/1.1 GET /some_url \n", w.Messages[0])
 }
+
+func testAuthenticated(w http.ResponseWriter, r *http.Request) {
+	key, _ := (&Application{}).contextGetAPIKey(r)
+
+	_, _ = w.Write([]byte(key.ID))
+}
+
+func TestAuthenticate(t *testing.T) {
+	keys := newTestAPIKeys(
+		apikeys.Key{ID: "creator", Hash: apikeys.Hash("lsk_creator"), Scopes: []string{apikeys.ScopeCreate}},
+		apikeys.Key{ID: "revoked", Hash: apikeys.Hash("lsk_revoked"), Scopes: []string{apikeys.ScopeCreate}, RevokedAt: time.Now()},
+	)
+
+	tests := []struct {
+		name          string
+		enabled       bool
+		scope         string
+		authorization string
+		expectedCode  int
+		expectedBody  string
+	}{
+		{"Disabled", false, apikeys.ScopeCreate, "", http.StatusOK, ""},
+		{"Disabled admin", false, apikeys.ScopeAdmin, "", http.StatusUnauthorized, `{"error":"API key is required"}`},
+		{"Missing", true, apikeys.ScopeCreate, "", http.StatusUnauthorized, `{"error":"API key is required"}`},
+		{"Not bearer", true, apikeys.ScopeCreate, "Basic lsk_creator", http.StatusUnauthorized, `{"error":"API key is required"}`},
+		{"Unknown", true, apikeys.ScopeCreate, "Bearer lsk_unknown", http.StatusUnauthorized, `{"error":"invalid or revoked API key"}`},
+		{"Revoked", true, apikeys.ScopeCreate, "Bearer lsk_revoked", http.StatusUnauthorized, `{"error":"invalid or revoked API key"}`},
+		{"No scope", true, apikeys.ScopeRead, "Bearer lsk_creator", http.StatusForbidden, `{"error":"API key has no \"read\" scope"}`},
+		{"Scope granted", true, apikeys.ScopeCreate, "Bearer lsk_creator", http.StatusOK, "creator"},
+		{"Admin key", false, apikeys.ScopeAdmin, "Bearer admin-secret", http.StatusOK, "admin"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := Application{
+				Config:  Config{AuthEnabled: tt.enabled, AdminAPIKey: "admin-secret"},
+				Logger:  utils.NewLogger(io.Discard, &utils.Clock{}),
+				Clock:   &test.Clock{},
+				APIKeys: keys,
+			}
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/generate", nil)
+
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+
+			app.authenticate(tt.scope, testAuthenticated)(w, r)
+
+			result := w.Result()
+			body, err := io.ReadAll(result.Body)
+
+			defer result.Body.Close()
+
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedCode, result.StatusCode)
+			require.Equal(t, tt.expectedBody, strings.TrimSpace(string(body)))
+		})
+	}
+}
+
+type testDayClock struct {
+	now time.Time
+}
+
+func (c *testDayClock) Now() time.Time {
+	return c.now
+}
+
+func TestAuthenticateLimits(t *testing.T) {
+	clock := &testDayClock{now: time.Date(2024, 2, 7, 23, 0, 0, 0, time.UTC)}
+	app := Application{
+		Config: Config{AuthEnabled: true},
+		Logger: utils.NewLogger(io.Discard, &utils.Clock{}),
+		Clock:  clock,
+		APIKeys: newTestAPIKeys(
+			apikeys.Key{ID: "limited", Hash: apikeys.Hash("lsk_limited"), Scopes: []string{apikeys.ScopeRead}, RPS: 1},
+			apikeys.Key{ID: "quoted", Hash: apikeys.Hash("lsk_quoted"), Scopes: []string{apikeys.ScopeRead}, DailyQuota: 2},
+		),
+	}
+
+	request := func(token string) int {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/api/links/1", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+
+		app.authenticate(apikeys.ScopeRead, testAuthenticated)(w, r)
+
+		return w.Result().StatusCode
+	}
+
+	require.Equal(t, http.StatusOK, request("lsk_limited"))
+	require.Equal(t, http.StatusTooManyRequests, request("lsk_limited"))
+
+	require.Equal(t, http.StatusOK, request("lsk_quoted"))
+	require.Equal(t, http.StatusOK, request("lsk_quoted"))
+	require.Equal(t, http.StatusTooManyRequests, request("lsk_quoted"))
+
+	clock.now = clock.now.Add(time.Hour)
+
+	require.Equal(t, http.StatusOK, request("lsk_quoted"))
+}
@@ -0,0 +1,146 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"github.com/dzhdmitry/link-shorter/internal/links"
+	"github.com/julienschmidt/httprouter"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/time/rate"
+	"html/template"
+	"net/http"
+	"time"
+)
+
+// PasswordHeader Header of password of protected link for API clients
+const PasswordHeader = "X-Link-Password"
+
+const passwordMaxLength = 72
+
+var passwordForm = template.Must(template.New("password").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <title>Password required</title>
+</head>
+<body>
+<form method="post">
+    <p>The link is protected by password.</p>
+    {{if .}}<p style="color: #c00">{{.}}</p>{{end}}
+    <input type="password" name="password" autofocus required>
+    <button type="submit">Open</button>
+</form>
+</body>
+</html>
+`))
+
+// hashPassword Returns slow hash of password to store, empty password gives empty hash
+func hashPassword(password string) (string, error) {
+	if password == "" {
+		return "", nil
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+// passwordLimiters Returns limiters of failed password attempts by link key
+func (app *Application) passwordLimiters() *limiters {
+	app.passwordLimitersOnce.Do(func() {
+		interval, _ := time.ParseDuration(app.Config.PasswordInterval)
+		app.passwordAttempts = newLimiters(rate.Every(interval), app.Config.PasswordAttempts, app.Clock)
+	})
+
+	return app.passwordAttempts
+}
+
+// authorizeLink Checks password of protected link given in header or in submitted form. Unless password is correct,
+// responds with password form or JSON error and returns false
+func (app *Application) authorizeLink(w http.ResponseWriter, r *http.Request, link links.Link, form bool) bool {
+	if link.PasswordHash == "" {
+		return true
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+
+	password := r.Header.Get(PasswordHeader)
+
+	if password == "" && form && r.Method == http.MethodPost {
+		r.Body = http.MaxBytesReader(w, r.Body, 1_048_576)
+		password = r.PostFormValue("password")
+	}
+
+	if password == "" {
+		app.passwordRequiredResponse(w, r, form, "password is required")
+
+		return false
+	}
+
+	key := link.Key
+
+	if key == "" {
+		key = httprouter.ParamsFromContext(r.Context()).ByName("key")
+	}
+
+	// attempt is counted before comparison, so parallel attempts can not exceed the limit, and is given back unless it failed
+	now := app.Clock.Now()
+	attempt := app.passwordLimiters().get(key).ReserveN(now, 1)
+
+	if !attempt.OK() || attempt.DelayFrom(now) > 0 {
+		attempt.CancelAt(now)
+		app.errorResponse(w, r, http.StatusTooManyRequests, "too many failed password attempts, try again later")
+
+		return false
+	}
+
+	err := bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(password))
+
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		app.passwordRequiredResponse(w, r, form, "invalid password")
+
+		return false
+	}
+
+	attempt.CancelAt(now)
+
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+
+		return false
+	}
+
+	return true
+}
+
+func (app *Application) passwordRequiredResponse(w http.ResponseWriter, r *http.Request, form bool, message string) {
+	if !form {
+		app.errorResponse(w, r, http.StatusUnauthorized, message)
+
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		message = ""
+	}
+
+	var buf bytes.Buffer
+
+	if err := passwordForm.Execute(&buf, message); err != nil {
+		app.serverErrorResponse(w, r, err)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusUnauthorized)
+
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		app.Logger.LogError(err)
+	}
+}
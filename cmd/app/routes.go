@@ -1,6 +1,7 @@
 package app
 
 import (
+	"github.com/dzhdmitry/link-shorter/internal/apikeys"
 	"github.com/julienschmidt/httprouter"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	httpSwagger "github.com/swaggo/http-swagger"
@@ -12,10 +13,26 @@ func (app *Application) routes() http.Handler {
 	router := httprouter.New()
 
 	router.HandlerFunc(http.MethodGet, "/", app.indexHandler)
-	router.HandlerFunc(http.MethodPost, "/generate", app.metricsMiddleware(app.logRequest(app.generateHandler)))
+	router.HandlerFunc(http.MethodPost, "/generate", app.metricsMiddleware(app.logRequest(app.authenticate(apikeys.ScopeCreate, app.generateHandler))))
 	router.HandlerFunc(http.MethodGet, "/go/:key", app.metricsMiddleware(app.logRequest(app.goHandler)))
-	router.HandlerFunc(http.MethodPost, "/batch/generate", app.metricsMiddleware(app.logRequest(app.batchGenerateHandler)))
-	router.HandlerFunc(http.MethodPost, "/batch/go", app.metricsMiddleware(app.logRequest(app.batchGoHandler)))
+	router.HandlerFunc(http.MethodPost, "/go/:key", app.metricsMiddleware(app.logRequest(app.goHandler)))
+	router.HandlerFunc(http.MethodGet, "/api/links/:key", app.metricsMiddleware(app.logRequest(app.authenticate(apikeys.ScopeRead, app.linkHandler))))
+	router.HandlerFunc(http.MethodGet, "/links/:key", app.metricsMiddleware(app.logRequest(app.authenticate(apikeys.ScopeRead, app.linkDetailsHandler))))
+	router.HandlerFunc(http.MethodGet, "/links", app.metricsMiddleware(app.logRequest(app.authenticate(apikeys.ScopeRead, app.listLinksHandler))))
+	router.HandlerFunc(http.MethodPatch, "/links/:key", app.metricsMiddleware(app.logRequest(app.authenticate(apikeys.ScopeCreate, app.updateLinkHandler))))
+	router.HandlerFunc(http.MethodDelete, "/links/:key", app.metricsMiddleware(app.logRequest(app.authenticate(apikeys.ScopeCreate, app.deleteLinkHandler))))
+	router.HandlerFunc(http.MethodGet, "/links/:key/stats", app.metricsMiddleware(app.logRequest(app.authenticate(apikeys.ScopeRead, app.statsHandler))))
+	router.HandlerFunc(http.MethodGet, "/links/:key/qr", app.metricsMiddleware(app.logRequest(app.authenticate(apikeys.ScopeRead, app.qrHandler))))
+	router.HandlerFunc(http.MethodPost, "/batch/generate", app.metricsMiddleware(app.logRequest(app.authenticate(apikeys.ScopeCreate, app.batchGenerateHandler))))
+	router.HandlerFunc(http.MethodPost, "/batch/go", app.metricsMiddleware(app.logRequest(app.authenticate(apikeys.ScopeRead, app.batchGoHandler))))
+	router.HandlerFunc(http.MethodPost, "/admin/api-keys", app.metricsMiddleware(app.logRequest(app.authenticate(apikeys.ScopeAdmin, app.createAPIKeyHandler))))
+	router.HandlerFunc(http.MethodDelete, "/admin/api-keys/:id", app.metricsMiddleware(app.logRequest(app.authenticate(apikeys.ScopeAdmin, app.revokeAPIKeyHandler))))
+	router.HandlerFunc(http.MethodPost, "/admin/webhooks", app.metricsMiddleware(app.logRequest(app.authenticate(apikeys.ScopeAdmin, app.createWebhookHandler))))
+	router.HandlerFunc(http.MethodGet, "/admin/webhooks", app.metricsMiddleware(app.logRequest(app.authenticate(apikeys.ScopeAdmin, app.listWebhooksHandler))))
+	router.HandlerFunc(http.MethodDelete, "/admin/webhooks/:id", app.metricsMiddleware(app.logRequest(app.authenticate(apikeys.ScopeAdmin, app.deleteWebhookHandler))))
+	router.HandlerFunc(http.MethodGet, "/utm-templates", app.metricsMiddleware(app.logRequest(app.authenticate(apikeys.ScopeRead, app.listUTMTemplatesHandler))))
+	router.HandlerFunc(http.MethodPut, "/utm-templates/:name", app.metricsMiddleware(app.logRequest(app.authenticate(apikeys.ScopeAdmin, app.storeUTMTemplateHandler))))
+	router.HandlerFunc(http.MethodDelete, "/utm-templates/:name", app.metricsMiddleware(app.logRequest(app.authenticate(apikeys.ScopeAdmin, app.deleteUTMTemplateHandler))))
 
 	router.HandlerFunc(http.MethodGet, "/swagger/:any", httpSwagger.Handler(
 		httpSwagger.URL("http://localhost:8080/swagger/doc.json"), //The url pointing to API definition
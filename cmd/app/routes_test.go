@@ -1,12 +1,84 @@
 package app
 
 import (
+	"github.com/dzhdmitry/link-shorter/internal/apikeys"
+	"github.com/dzhdmitry/link-shorter/internal/links"
+	"github.com/dzhdmitry/link-shorter/internal/utils"
+	"github.com/dzhdmitry/link-shorter/test"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
 func TestRoutes(t *testing.T) {
-	app := Application{}
+	app := Application{Clock: &test.Clock{}}
 
 	assert.NotNil(t, app.routes())
 }
+
+func TestRoutesManageLinks(t *testing.T) {
+	keys := newTestAPIKeys(
+		apikeys.Key{ID: "client", Hash: apikeys.Hash("lsk_client"), Scopes: []string{apikeys.ScopeCreate, apikeys.ScopeRead}},
+		apikeys.Key{ID: "other", Hash: apikeys.Hash("lsk_other"), Scopes: []string{apikeys.ScopeCreate, apikeys.ScopeRead}},
+		apikeys.Key{ID: "reader", Hash: apikeys.Hash("lsk_reader"), Scopes: []string{apikeys.ScopeRead}},
+	)
+
+	tests := []struct {
+		name           string
+		enabled        bool
+		method         string
+		target         string
+		authorization  string
+		expectedStatus int
+	}{
+		{"Update own link", true, http.MethodPatch, "/links/1", "Bearer lsk_client", http.StatusOK},
+		{"Delete own link", true, http.MethodDelete, "/links/1", "Bearer lsk_client", http.StatusNoContent},
+		{"Delete link of other owner", true, http.MethodDelete, "/links/1", "Bearer lsk_other", http.StatusNotFound},
+		{"Delete without create scope", true, http.MethodDelete, "/links/1", "Bearer lsk_reader", http.StatusForbidden},
+		{"Delete without key", true, http.MethodDelete, "/links/1", "", http.StatusUnauthorized},
+		{"Update by admin", true, http.MethodPatch, "/links/1", "Bearer admin-secret", http.StatusOK},
+		{"Delete by admin", true, http.MethodDelete, "/links/1", "Bearer admin-secret", http.StatusNoContent},
+		{"Details by admin", true, http.MethodGet, "/links/1", "Bearer admin-secret", http.StatusOK},
+		{"Delete anonymous link without auth", false, http.MethodDelete, "/links/2", "", http.StatusNoContent},
+		{"Update anonymous link without auth", false, http.MethodPatch, "/links/2", "", http.StatusOK},
+		{"Delete anonymous link by admin without auth", false, http.MethodDelete, "/links/2", "Bearer admin-secret", http.StatusNoContent},
+		{"Delete owned link without auth", false, http.MethodDelete, "/links/1", "", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := newTestLinkStorage(2, map[int]string{
+				1: "https://example1.com",
+				2: "https://example2.com",
+			})
+			storage.owners = map[int]string{1: "client", 2: ""}
+			app := Application{
+				Config:    Config{AuthEnabled: tt.enabled, AdminAPIKey: "admin-secret"},
+				Logger:    utils.NewLogger(io.Discard, &utils.Clock{}),
+				Clock:     &test.Clock{},
+				Validator: *NewValidator(links.Alphabets[links.AlphabetBase36]),
+				Links:     storage,
+				APIKeys:   keys,
+			}
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(tt.method, tt.target, strings.NewReader(`{"disabled":true}`))
+
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+
+			app.routes().ServeHTTP(w, r)
+
+			result := w.Result()
+
+			defer result.Body.Close()
+
+			require.Equal(t, tt.expectedStatus, result.StatusCode)
+		})
+	}
+}
@@ -0,0 +1,260 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golang.org/x/net/idna"
+	"net"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// defaultSchemes Schemes of URLs which are always allowed, other schemes are allowed by URL_SCHEMES
+var defaultSchemes = []string{"http", "https"}
+
+// forbiddenSchemes Schemes which run code or read files in browser, they can not be allowed
+var forbiddenSchemes = []string{"javascript", "data", "vbscript", "file", "blob"}
+
+var schemePattern = regexp.MustCompile(`^[a-z][a-z0-9+.\-]*$`)
+
+const resolveTimeout = 2 * time.Second
+
+// maxClientRedirects Number of redirects followed by client of NewHTTPClient, the same as default one of http.Client
+const maxClientRedirects = 10
+
+type ResolverInterface interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// URLPolicy Decides which destinations may be shortened. Loopback, link-local, private and unique local addresses
+// are rejected unless host or network is an exception. Hostnames are resolved and checked only if resolver is set
+type URLPolicy struct {
+	schemes       []string
+	allowedHosts  map[string]bool
+	allowedIPNets []*net.IPNet
+	resolver      ResolverInterface
+}
+
+// NewURLPolicy Makes policy allowing extra schemes besides http and https, exceptions are hostnames, IP addresses
+// and CIDRs. Resolver is nil if hostnames should not be resolved
+func NewURLPolicy(extraSchemes, exceptions []string, resolver ResolverInterface) (*URLPolicy, error) {
+	p := URLPolicy{
+		schemes:      slices.Clone(defaultSchemes),
+		allowedHosts: map[string]bool{},
+		resolver:     resolver,
+	}
+
+	for _, scheme := range extraSchemes {
+		scheme = strings.TrimSuffix(strings.ToLower(scheme), ":")
+
+		if !schemePattern.MatchString(scheme) {
+			return nil, fmt.Errorf("invalid URL scheme: %s", scheme)
+		}
+
+		if slices.Contains(forbiddenSchemes, scheme) {
+			return nil, fmt.Errorf("URL scheme can not be allowed: %s", scheme)
+		}
+
+		if !slices.Contains(p.schemes, scheme) {
+			p.schemes = append(p.schemes, scheme)
+		}
+	}
+
+	for _, exception := range exceptions {
+		if _, network, err := net.ParseCIDR(exception); err == nil {
+			p.allowedIPNets = append(p.allowedIPNets, network)
+		} else if ip := net.ParseIP(exception); ip != nil {
+			p.allowedIPNets = append(p.allowedIPNets, &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)})
+		} else {
+			p.allowedHosts[strings.TrimSuffix(strings.ToLower(exception), ".")] = true
+		}
+	}
+
+	return &p, nil
+}
+
+// allowsScheme Scheme of URL is case-insensitive
+func (p *URLPolicy) allowsScheme(scheme string) bool {
+	return slices.Contains(p.schemes, strings.ToLower(scheme))
+}
+
+// schemesList Returns "http, https or mailto" for error messages
+func (p *URLPolicy) schemesList() string {
+	last := len(p.schemes) - 1
+
+	return strings.Join(p.schemes[:last], ", ") + " or " + p.schemes[last]
+}
+
+// checkHost Returns error if host is internal and is not an exception. Host is checked after UTS46 mapping,
+// which browsers apply too, so "１２７.０.０.１" and "①②⑦.0.0.1" are checked as "127.0.0.1"
+func (p *URLPolicy) checkHost(host string) error {
+	// mapping of invalid name returns error, but mapped name is still returned and checked
+	if mapped, _ := idna.Lookup.ToASCII(host); mapped != "" {
+		host = mapped
+	}
+
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+
+	if p.allowedHosts[host] {
+		return nil
+	}
+
+	if ip := parseHostIP(host); ip != nil {
+		return p.checkIP(ip)
+	}
+
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return errors.New("URL must not point to internal address")
+	}
+
+	if p.resolver == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+
+	defer cancel()
+
+	addresses, err := p.resolver.LookupIPAddr(ctx, host)
+
+	if err != nil || len(addresses) == 0 {
+		return errors.New("host of URL can not be resolved")
+	}
+
+	for _, address := range addresses {
+		if err = p.checkIP(address.IP); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// NewHTTPClient Returns client for requests to destinations of links, which connects to internal addresses only
+// if they are exceptions. Address is checked when it is dialed, so names resolved to internal addresses are refused
+// regardless of resolver, and every redirect is checked as a new destination. Proxy is not used, as it would be dialed instead
+func (p *URLPolicy) NewHTTPClient(timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = p.dialContext
+
+	return &http.Client{Timeout: timeout, Transport: transport, CheckRedirect: p.checkRedirect}
+}
+
+// dialContext Dials any address of exception host, addresses of other hosts are checked by Control hook
+func (p *URLPolicy) dialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	dialer := net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	host, _, err := net.SplitHostPort(address)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.allowedHosts[strings.TrimSuffix(strings.ToLower(host), ".")] {
+		dialer.Control = p.controlDial
+	}
+
+	return dialer.DialContext(ctx, network, address)
+}
+
+// controlDial Refuses connection to internal IP address which is not an exception
+func (p *URLPolicy) controlDial(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+
+	if ip == nil {
+		return fmt.Errorf("invalid dialed address: %s", address)
+	}
+
+	return p.checkIP(ip)
+}
+
+// checkRedirect Follows redirects to http and https URLs which are not internal
+func (p *URLPolicy) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxClientRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxClientRedirects)
+	}
+
+	if !slices.Contains(defaultSchemes, strings.ToLower(req.URL.Scheme)) {
+		return fmt.Errorf("redirect to %s URL is not followed", req.URL.Scheme)
+	}
+
+	return p.checkHost(req.URL.Hostname())
+}
+
+func (p *URLPolicy) checkIP(ip net.IP) error {
+	for _, network := range p.allowedIPNets {
+		if network.Contains(ip) {
+			return nil
+		}
+	}
+
+	if isInternalIP(ip) {
+		return errors.New("URL must not point to internal address")
+	}
+
+	return nil
+}
+
+// isInternalIP Reports whether IP is loopback, link-local, private (including IPv6 unique local) or unspecified
+func isInternalIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		ip.IsPrivate() || ip.IsUnspecified()
+}
+
+// parseHostIP Parses IP address of host including IPv4 forms which browsers accept: "2130706433", "0x7f.1", "0177.0.0.1".
+// Returns nil if host is a name
+func parseHostIP(host string) net.IP {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip
+	}
+
+	parts := strings.Split(host, ".")
+
+	if len(parts) > 4 {
+		return nil
+	}
+
+	numbers := make([]uint64, 0, len(parts))
+
+	for _, part := range parts {
+		number, err := strconv.ParseUint(part, 0, 32)
+
+		if err != nil {
+			return nil
+		}
+
+		numbers = append(numbers, number)
+	}
+
+	// the last part fills all remaining bytes of address
+	last := numbers[len(numbers)-1]
+	address := uint64(0)
+
+	for i, number := range numbers[:len(numbers)-1] {
+		if number > 255 {
+			return nil
+		}
+
+		address |= number << (24 - 8*i)
+	}
+
+	if last >= 1<<(8*(5-len(numbers))) {
+		return nil
+	}
+
+	address |= last
+
+	return net.IPv4(byte(address>>24), byte(address>>16), byte(address>>8), byte(address))
+}
@@ -0,0 +1,182 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+type testResolver struct {
+	addresses map[string][]string
+}
+
+func (t *testResolver) LookupIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	ips, ok := t.addresses[host]
+
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+
+	addresses := make([]net.IPAddr, 0, len(ips))
+
+	for _, ip := range ips {
+		addresses = append(addresses, net.IPAddr{IP: net.ParseIP(ip)})
+	}
+
+	return addresses, nil
+}
+
+func TestValidateURLInternal(t *testing.T) {
+	resolver := &testResolver{addresses: map[string][]string{
+		"example.com":          {"93.184.216.34", "2606:2800:220:1:248:1893:25c8:1946"},
+		"internal.example.com": {"93.184.216.34", "10.0.0.5"},
+		"metadata.example.com": {"169.254.169.254"},
+		"intranet.example.com": {"10.1.0.7"},
+	}}
+
+	tests := []struct {
+		name          string
+		resolver      ResolverInterface
+		URL           string
+		expectedError string
+	}{
+		{"Public IP", nil, "http://93.184.216.34/", ""},
+		{"Loopback", nil, "http://127.0.0.1/", "URL must not point to internal address"},
+		{"Loopback with port", nil, "http://127.0.0.2:6379", "URL must not point to internal address"},
+		{"Localhost", nil, "http://localhost:6379", "URL must not point to internal address"},
+		{"Subdomain of localhost", nil, "http://app.LOCALHOST./", "URL must not point to internal address"},
+		{"Link-local", nil, "http://169.254.169.254/latest/meta-data/", "URL must not point to internal address"},
+		{"Private", nil, "https://192.168.1.1/admin", "URL must not point to internal address"},
+		{"Unspecified", nil, "http://0.0.0.0:8080", "URL must not point to internal address"},
+		{"Decimal IPv4", nil, "http://2130706433/", "URL must not point to internal address"},
+		{"Hexadecimal IPv4", nil, "http://0x7f.1/", "URL must not point to internal address"},
+		{"Octal IPv4", nil, "http://0251.0376.0251.0376/", "URL must not point to internal address"},
+		{"Fullwidth digits", nil, "http://１２７.０.０.１/", "URL must not point to internal address"},
+		{"Enclosed digits", nil, "http://①②⑦.0.0.1/", "URL must not point to internal address"},
+		{"Fullwidth localhost", nil, "http://ｌｏｃａｌｈｏｓｔ/", "URL must not point to internal address"},
+		{"IPv6 loopback", nil, "http://[::1]/", "URL must not point to internal address"},
+		{"IPv6 unique local", nil, "http://[fd12:3456::1]/", "URL must not point to internal address"},
+		{"IPv6 link-local", nil, "http://[fe80::1]/", "URL must not point to internal address"},
+		{"IPv4-mapped IPv6", nil, "http://[::ffff:10.0.0.1]/", "URL must not point to internal address"},
+		{"Exception host", nil, "http://intranet.example.com/", ""},
+		{"Exception network", nil, "http://10.1.2.3/", ""},
+		{"Name is not resolved without resolver", nil, "http://internal.example.com/", ""},
+		{"Resolved public", resolver, "https://example.com/", ""},
+		{"Resolved to private", resolver, "https://internal.example.com/", "URL must not point to internal address"},
+		{"Resolved to link-local", resolver, "https://metadata.example.com/", "URL must not point to internal address"},
+		{"Resolved exception host", resolver, "https://intranet.example.com/", ""},
+		{"Not resolved", resolver, "https://unknown.example.com/", "host of URL can not be resolved"},
+		{"IP is not resolved", resolver, "https://93.184.216.34/", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := NewURLPolicy(nil, []string{"intranet.example.com", "10.1.0.0/16"}, tt.resolver)
+
+			require.NoError(t, err)
+
+			validator := NewValidator("1")
+			validator.URLPolicy = policy
+			err = validator.validateURL(tt.URL)
+
+			if tt.expectedError == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tt.expectedError)
+			}
+		})
+	}
+}
+
+func TestValidateURLSchemes(t *testing.T) {
+	policy, err := NewURLPolicy([]string{"mailto", "MyApp:"}, nil, nil)
+
+	require.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		policy        *URLPolicy
+		URL           string
+		expectedError string
+	}{
+		{"Mailto", policy, "mailto:info@example.com?subject=Hi", ""},
+		{"Deep link", policy, "myapp://open/item?id=1", ""},
+		{"Uppercase scheme", policy, "MAILTO:info@example.com", ""},
+		{"Empty mailto", policy, "mailto:", "URL must be an absolute URL"},
+		{"Deep link to localhost", policy, "myapp://localhost/", "URL must not point to internal address"},
+		{"Not allowed scheme", policy, "ftp://example.com/file", "URL must begin with http, https, mailto or myapp"},
+		{"Javascript", policy, "javascript:alert(1)", "URL must begin with http, https, mailto or myapp"},
+		{"Default policy", nil, "mailto:info@example.com", "URL must begin with http or https"},
+		{"Default policy internal", nil, "http://10.0.0.1/", "URL must not point to internal address"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := NewValidator("1")
+			validator.URLPolicy = tt.policy
+
+			err := validator.validateURL(tt.URL)
+
+			if tt.expectedError == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tt.expectedError)
+			}
+		})
+	}
+}
+
+func TestURLPolicyHTTPClient(t *testing.T) {
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	defer internal.Close()
+
+	internalURL, _ := url.Parse(internal.URL)
+	// the same server under exception host redirects to its own internal address
+	redirecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, internal.URL+"/secret", http.StatusFound)
+	}))
+
+	defer redirecting.Close()
+
+	redirectingURL, _ := url.Parse(redirecting.URL)
+
+	tests := []struct {
+		name          string
+		exceptions    []string
+		URL           string
+		expectedError string
+	}{
+		{"Internal address", nil, internal.URL, "URL must not point to internal address"},
+		{"Name resolved to internal address", nil, "http://localhost:" + internalURL.Port(), "URL must not point to internal address"},
+		{"Exception network", []string{"127.0.0.0/8"}, internal.URL, ""},
+		{"Exception host", []string{"localhost"}, "http://localhost:" + internalURL.Port(), ""},
+		{"Redirect to internal address", []string{"localhost"}, "http://localhost:" + redirectingURL.Port(), "URL must not point to internal address"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := NewURLPolicy(nil, tt.exceptions, nil)
+
+			require.NoError(t, err)
+
+			response, err := policy.NewHTTPClient(time.Second).Get(tt.URL)
+
+			if tt.expectedError == "" {
+				require.NoError(t, err)
+				require.Equal(t, http.StatusOK, response.StatusCode)
+				require.NoError(t, response.Body.Close())
+			} else {
+				require.ErrorContains(t, err, tt.expectedError)
+			}
+		})
+	}
+}
@@ -2,45 +2,303 @@ package app
 
 import (
 	"errors"
+	"fmt"
+	"github.com/dzhdmitry/link-shorter/internal/apikeys"
+	"github.com/dzhdmitry/link-shorter/internal/links"
+	"github.com/dzhdmitry/link-shorter/internal/qr"
+	"github.com/dzhdmitry/link-shorter/internal/utm"
+	"github.com/dzhdmitry/link-shorter/internal/webhooks"
 	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
+const aliasMaxLength = 64
+const aliasLetters = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ-_"
+
 type Validator struct {
 	KeyMaxLength   int
+	Blocklist      BlocklistInterface
+	URLPolicy      *URLPolicy
 	allowedLetters string
 }
 
+// defaultURLPolicy Policy of validator without configured one: http and https only, internal addresses are rejected
+var defaultURLPolicy, _ = NewURLPolicy(nil, nil, nil)
+
+// NewValidator Makes validator of keys generated from allowedLetters, longer keys would exceed range of ids
 func NewValidator(allowedLetters string) *Validator {
 	return &Validator{
+		KeyMaxLength:   links.MaxKeyLength(allowedLetters),
 		allowedLetters: allowedLetters,
 	}
 }
 
+// isAlias Reports whether key contains letters which are never used in generated keys
+func (v *Validator) isAlias(key string) bool {
+	for _, letter := range key {
+		if !strings.ContainsRune(v.allowedLetters, letter) {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (v *Validator) validateKey(key string) error {
 	if key == "" {
 		return errors.New("key must be at least 1 letter long")
 	}
 
-	if len(key) > 10 {
+	if v.isAlias(key) {
+		return v.validateAliasLetters(key)
+	}
+
+	if len(key) > v.KeyMaxLength {
 		return errors.New("key is invalid")
 	}
 
-	for _, keyLetter := range key {
-		found := false
+	return nil
+}
 
-		for _, generatorLetter := range v.allowedLetters {
-			if keyLetter == generatorLetter {
-				found = true
+func (v *Validator) validateAliasLetters(alias string) error {
+	if len(alias) > aliasMaxLength {
+		return errors.New("key is invalid")
+	}
 
-				break
-			}
+	for _, letter := range alias {
+		if !strings.ContainsRune(aliasLetters, letter) {
+			return errors.New("invalid letter")
 		}
+	}
 
-		if !found {
-			return errors.New("invalid letter")
+	return nil
+}
+
+func (v *Validator) validateAlias(alias string) error {
+	if len(alias) > aliasMaxLength {
+		return errors.New("alias must be maximum 64 letters long")
+	}
+
+	for _, letter := range alias {
+		if !strings.ContainsRune(aliasLetters, letter) {
+			return errors.New("alias may contain only latin letters, digits, \"-\" and \"_\"")
 		}
 	}
 
+	if !v.isAlias(alias) {
+		return errors.New("alias must contain at least one letter which is not used in generated keys, e.g. \"-\"")
+	}
+
+	return nil
+}
+
+func (v *Validator) validatePassword(password string) error {
+	if len(password) > passwordMaxLength {
+		return fmt.Errorf("password must be maximum %d bytes long", passwordMaxLength)
+	}
+
+	return nil
+}
+
+const apiKeyNameMaxLength = 255
+
+func (v *Validator) validateAPIKey(name string, scopes []string, rps, dailyQuota int) error {
+	if len(name) > apiKeyNameMaxLength {
+		return fmt.Errorf("name must be maximum %d letters long", apiKeyNameMaxLength)
+	}
+
+	if err := apikeys.ValidateScopes(scopes); err != nil {
+		return err
+	}
+
+	if rps < 0 || dailyQuota < 0 {
+		return errors.New("rps and daily_quota must not be negative")
+	}
+
+	return nil
+}
+
+const webhookSecretMinLength = 16
+const webhookSecretMaxLength = 255
+
+// validateWebhook Endpoint must be http or https URL which is not internal unless allowed, secret is optional
+func (v *Validator) validateWebhook(URL, secret string, events []string) error {
+	if len(URL) > 2000 {
+		return errors.New("URL must be maximum 2000 letters long")
+	}
+
+	parsedURL, err := url.Parse(URL)
+
+	if err != nil || parsedURL.Host == "" || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") {
+		return errors.New("URL must be an absolute http or https URL")
+	}
+
+	policy := v.URLPolicy
+
+	if policy == nil {
+		policy = defaultURLPolicy
+	}
+
+	if err = policy.checkHost(parsedURL.Hostname()); err != nil {
+		return err
+	}
+
+	if secret != "" && (len(secret) < webhookSecretMinLength || len(secret) > webhookSecretMaxLength) {
+		return fmt.Errorf("secret must be from %d to %d bytes long", webhookSecretMinLength, webhookSecretMaxLength)
+	}
+
+	return webhooks.ValidateEvents(events)
+}
+
+const titleMaxLength = 255
+const descriptionMaxLength = 1000
+const notesMaxLength = 10000
+const tagMaxLength = 32
+const tagsMaxCount = 20
+
+// validateMetadata Checks lengths of metadata, tags are validated by parseTags
+func (v *Validator) validateMetadata(metadata links.Metadata) error {
+	if utf8.RuneCountInString(metadata.Title) > titleMaxLength {
+		return fmt.Errorf("title must be maximum %d letters long", titleMaxLength)
+	}
+
+	if utf8.RuneCountInString(metadata.Description) > descriptionMaxLength {
+		return fmt.Errorf("description must be maximum %d letters long", descriptionMaxLength)
+	}
+
+	if utf8.RuneCountInString(metadata.Notes) > notesMaxLength {
+		return fmt.Errorf("notes must be maximum %d letters long", notesMaxLength)
+	}
+
+	return nil
+}
+
+// parseTags Lowercases tags and removes repeated ones keeping order
+func (v *Validator) parseTags(tags []string) ([]string, error) {
+	if len(tags) > tagsMaxCount {
+		return nil, fmt.Errorf("maximum %d tags are allowed", tagsMaxCount)
+	}
+
+	parsed := make([]string, 0, len(tags))
+
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+
+		if err := v.validateTag(tag); err != nil {
+			return nil, err
+		}
+
+		if !slices.Contains(parsed, tag) {
+			parsed = append(parsed, tag)
+		}
+	}
+
+	return parsed, nil
+}
+
+func (v *Validator) validateTag(tag string) error {
+	if tag == "" || utf8.RuneCountInString(tag) > tagMaxLength {
+		return fmt.Errorf("tag must be from 1 to %d letters long", tagMaxLength)
+	}
+
+	for _, letter := range tag {
+		if !unicode.IsLetter(letter) && !unicode.IsDigit(letter) && letter != '-' && letter != '_' {
+			return errors.New("tag may contain only letters, digits, \"-\" and \"_\"")
+		}
+	}
+
+	return nil
+}
+
+const rulesMaxCount = 20
+
+// validateRules Checks conditions and URLs of rules, errors are prefixed with number of the rule
+func (v *Validator) validateRules(rules links.Rules) error {
+	if len(rules) > rulesMaxCount {
+		return fmt.Errorf("maximum %d rules are allowed", rulesMaxCount)
+	}
+
+	for i, rule := range rules {
+		err := v.validateURL(rule.URL)
+
+		if err == nil {
+			err = rule.Validate()
+		}
+
+		if err != nil {
+			return fmt.Errorf("rule %d: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+// validateVariants Checks number, weights and URLs of A/B split variants, errors of URLs are prefixed with number of the variant
+func (v *Validator) validateVariants(variants links.Variants) error {
+	if err := variants.Validate(); err != nil {
+		return err
+	}
+
+	for i, variant := range variants {
+		if err := v.validateURL(variant.URL); err != nil {
+			return fmt.Errorf("variant %d: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+// validateFallback Fallback is checked as any destination of the link
+func (v *Validator) validateFallback(URL string) error {
+	if err := v.validateURL(URL); err != nil {
+		return fmt.Errorf("fallback: %w", err)
+	}
+
+	return nil
+}
+
+const utmTemplateNameMaxLength = 64
+const utmParamMaxLength = 255
+
+func (v *Validator) validateUTMTemplateName(name string) error {
+	if name == "" || len(name) > utmTemplateNameMaxLength {
+		return fmt.Errorf("name of UTM template must be from 1 to %d letters long", utmTemplateNameMaxLength)
+	}
+
+	for _, letter := range name {
+		if !strings.ContainsRune(aliasLetters, letter) {
+			return errors.New("name of UTM template may contain only latin letters, digits, \"-\" and \"_\"")
+		}
+	}
+
+	return nil
+}
+
+// validateUTMTemplate Checks name and lengths of parameters, at least one parameter must be set
+func (v *Validator) validateUTMTemplate(template utm.Template) error {
+	if err := v.validateUTMTemplateName(template.Name); err != nil {
+		return err
+	}
+
+	empty := true
+
+	for _, param := range template.Params() {
+		if utf8.RuneCountInString(param[1]) > utmParamMaxLength {
+			return fmt.Errorf("%s must be maximum %d letters long", param[0], utmParamMaxLength)
+		}
+
+		empty = empty && param[1] == ""
+	}
+
+	if empty {
+		return errors.New("source, medium, campaign or content must be provided")
+	}
+
 	return nil
 }
 
@@ -64,18 +322,58 @@ func (v *Validator) validateURL(URL string) error {
 	}
 
 	parsedURL, err := url.Parse(URL)
+	policy := v.URLPolicy
+
+	if policy == nil {
+		policy = defaultURLPolicy
+	}
 
 	if err != nil {
 		return errors.New("URL must be a valid URL string")
-	} else if parsedURL.Scheme == "" || parsedURL.Host == "" {
+	} else if parsedURL.Scheme == "" {
 		return errors.New("URL must be an absolute URL")
-	} else if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
-		return errors.New("URL must begin with http or https")
+	} else if !policy.allowsScheme(parsedURL.Scheme) {
+		return fmt.Errorf("URL must begin with %s", policy.schemesList())
+	}
+
+	// other schemes, e.g. "mailto:" or deep links of apps, may have no host
+	isWeb := slices.Contains(defaultSchemes, strings.ToLower(parsedURL.Scheme))
+
+	if (isWeb && parsedURL.Host == "") || (parsedURL.Host == "" && parsedURL.Opaque == "" && parsedURL.Path == "") {
+		return errors.New("URL must be an absolute URL")
+	}
+
+	if parsedURL.Host != "" {
+		if err = policy.checkHost(parsedURL.Hostname()); err != nil {
+			return err
+		}
+	}
+
+	if v.isBlocked(URL, blockedOnCreate) {
+		return errors.New("URL is blocked")
 	}
 
 	return nil
 }
 
+const blockedOnCreate = "create"
+const blockedOnRedirect = "redirect"
+
+// isBlocked Reports whether URL matches blocklist, matches are counted in metrics by action
+func (v *Validator) isBlocked(URL, action string) bool {
+	if v.Blocklist == nil {
+		return false
+	}
+
+	rule, blocked := v.Blocklist.Match(URL)
+
+	if blocked {
+		MetricBlockedURLs.WithLabelValues(rule, action).Inc()
+	}
+
+	return blocked
+}
+
 func (v *Validator) validateURLs(URLs []string) error {
 	uniqueURLs := make(map[string]bool, len(URLs))
 
@@ -95,3 +393,178 @@ func (v *Validator) validateURLs(URLs []string) error {
 
 	return nil
 }
+
+func (v *Validator) validateExpiresAt(expiresAt, now time.Time) error {
+	if !expiresAt.IsZero() && !expiresAt.After(now) {
+		return errors.New("expires_at must be in the future")
+	}
+
+	return nil
+}
+
+const statsMaxDays = 366
+
+// parseStatsRange Parses inclusive range of days "YYYY-MM-DD", by default range is last 30 days until today
+func (v *Validator) parseStatsRange(fromRaw, toRaw string, now time.Time) (time.Time, time.Time, error) {
+	to := now.UTC()
+	from := to.AddDate(0, 0, -29)
+	var err error
+
+	if toRaw != "" {
+		if to, err = time.Parse(time.DateOnly, toRaw); err != nil {
+			return time.Time{}, time.Time{}, errors.New("to must be a date in format YYYY-MM-DD")
+		}
+
+		if fromRaw == "" {
+			from = to.AddDate(0, 0, -29)
+		}
+	}
+
+	if fromRaw != "" {
+		if from, err = time.Parse(time.DateOnly, fromRaw); err != nil {
+			return time.Time{}, time.Time{}, errors.New("from must be a date in format YYYY-MM-DD")
+		}
+	}
+
+	if from.After(to) {
+		return time.Time{}, time.Time{}, errors.New("from must not be after to")
+	}
+
+	if to.Sub(from) >= statsMaxDays*24*time.Hour {
+		return time.Time{}, time.Time{}, fmt.Errorf("range must be maximum %d days long", statsMaxDays)
+	}
+
+	return from, to, nil
+}
+
+const qrFormatPNG = "png"
+const qrFormatSVG = "svg"
+const qrDefaultSize = 256
+const qrMaxSize = 4096
+const qrDefaultMargin = 4
+const qrMaxMargin = 32
+
+type qrOptions struct {
+	format string
+	size   int
+	margin int
+	level  qr.Level
+}
+
+// parseQROptions Parses query parameters of QR code, format is taken from Accept header unless "format" is given
+func (v *Validator) parseQROptions(query url.Values, accept string) (qrOptions, error) {
+	options := qrOptions{format: qrFormatPNG, size: qrDefaultSize, margin: qrDefaultMargin, level: qr.LevelM}
+	var err error
+
+	if strings.Contains(accept, "image/svg+xml") {
+		options.format = qrFormatSVG
+	}
+
+	if format := query.Get("format"); format != "" {
+		if format != qrFormatPNG && format != qrFormatSVG {
+			return qrOptions{}, errors.New(`format must be "png" or "svg"`)
+		}
+
+		options.format = format
+	}
+
+	if size := query.Get("size"); size != "" {
+		options.size, err = strconv.Atoi(size)
+
+		if err != nil || options.size < 1 || options.size > qrMaxSize {
+			return qrOptions{}, fmt.Errorf("size must be an integer from 1 to %d", qrMaxSize)
+		}
+	}
+
+	if margin := query.Get("margin"); margin != "" {
+		options.margin, err = strconv.Atoi(margin)
+
+		if err != nil || options.margin < 0 || options.margin > qrMaxMargin {
+			return qrOptions{}, fmt.Errorf("margin must be an integer from 0 to %d", qrMaxMargin)
+		}
+	}
+
+	if ecc := query.Get("ecc"); ecc != "" {
+		options.level, err = qr.ParseLevel(ecc)
+
+		if err != nil {
+			return qrOptions{}, errors.New(`ecc must be one of "L", "M", "Q" or "H"`)
+		}
+	}
+
+	return options, nil
+}
+
+const listDefaultLimit = 20
+const listMaxLimit = 100
+
+// parseListQuery Parses query parameters of links listing, newest links go first by default
+func (v *Validator) parseListQuery(query url.Values) (links.ListQuery, error) {
+	listQuery := links.ListQuery{Sort: links.SortCreatedDesc, Limit: listDefaultLimit}
+	var err error
+
+	if limit := query.Get("limit"); limit != "" {
+		listQuery.Limit, err = strconv.Atoi(limit)
+
+		if err != nil || listQuery.Limit < 1 || listQuery.Limit > listMaxLimit {
+			return links.ListQuery{}, fmt.Errorf("limit must be an integer from 1 to %d", listMaxLimit)
+		}
+	}
+
+	if sort := query.Get("sort"); sort != "" {
+		if sort != links.SortCreatedAsc && sort != links.SortCreatedDesc {
+			return links.ListQuery{}, fmt.Errorf(`sort must be "%s" or "%s"`, links.SortCreatedAsc, links.SortCreatedDesc)
+		}
+
+		listQuery.Sort = sort
+	}
+
+	if tag := query.Get("tag"); tag != "" {
+		listQuery.Tag = strings.ToLower(strings.TrimSpace(tag))
+
+		if err = v.validateTag(listQuery.Tag); err != nil {
+			return links.ListQuery{}, err
+		}
+	}
+
+	if domain := query.Get("domain"); domain != "" {
+		listQuery.Domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+
+		if !isDomain(listQuery.Domain) {
+			return links.ListQuery{}, errors.New("domain is invalid")
+		}
+	}
+
+	if health := query.Get("health"); health != "" {
+		if health != links.HealthBroken && health != links.HealthOK {
+			return links.ListQuery{}, fmt.Errorf(`health must be "%s" or "%s"`, links.HealthBroken, links.HealthOK)
+		}
+
+		listQuery.Health = health
+	}
+
+	if cursor := query.Get("cursor"); cursor != "" {
+		listQuery.Cursor, err = links.ParseListCursor(cursor)
+
+		// cursor always points to generated key, aliases are never used there
+		if err != nil || v.isAlias(listQuery.Cursor.Key) || len(listQuery.Cursor.Key) > v.KeyMaxLength {
+			return links.ListQuery{}, errors.New("cursor is invalid")
+		}
+	}
+
+	return listQuery, nil
+}
+
+func isDomain(domain string) bool {
+	if domain == "" || strings.ContainsAny(domain, "/:@?# ") {
+		return false
+	}
+
+	for _, label := range strings.Split(domain, ".") {
+		if label == "" {
+			return false
+		}
+	}
+
+	return true
+}
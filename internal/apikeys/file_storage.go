@@ -0,0 +1,208 @@
+package apikeys
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const recordKey = "key"
+const recordRevoke = "revoke"
+
+// FileStorage Keeps keys in memory and appends changes to CSV file: "key,id,hash,name,scopes,RPS,dailyQuota,createdAt"
+// on issue and "revoke,id,revokedAt" on revoke, scopes are separated by space
+type FileStorage struct {
+	filename string
+	keys     map[string]Key
+	hashes   map[string]string
+	mu       sync.Mutex
+}
+
+func NewFileStorage(filename string) (*FileStorage, error) {
+	s := FileStorage{filename: filename, keys: map[string]Key{}, hashes: map[string]string{}}
+
+	if err := s.restore(); err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+func (fs *FileStorage) persist(record []string) error {
+	file, err := os.OpenFile(fs.filename, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	w := csv.NewWriter(file)
+	defer w.Flush()
+
+	return w.Write(record)
+}
+
+func (fs *FileStorage) StoreKey(key Key) error {
+	fs.mu.Lock()
+
+	defer fs.mu.Unlock()
+
+	err := fs.persist([]string{
+		recordKey,
+		key.ID,
+		key.Hash,
+		key.Name,
+		strings.Join(key.Scopes, " "),
+		strconv.Itoa(key.RPS),
+		strconv.Itoa(key.DailyQuota),
+		key.CreatedAt.UTC().Format(time.RFC3339),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	fs.keys[key.ID] = key
+	fs.hashes[key.Hash] = key.ID
+
+	return nil
+}
+
+func (fs *FileStorage) GetKey(hash string) (Key, error) {
+	fs.mu.Lock()
+
+	defer fs.mu.Unlock()
+
+	id, ok := fs.hashes[hash]
+
+	if !ok {
+		return Key{}, ErrKeyNotFound
+	}
+
+	return fs.keys[id], nil
+}
+
+// RevokeKey Marks key as revoked, already revoked key keeps its original revocation time
+func (fs *FileStorage) RevokeKey(id string, revokedAt time.Time) (Key, error) {
+	fs.mu.Lock()
+
+	defer fs.mu.Unlock()
+
+	key, ok := fs.keys[id]
+
+	if !ok {
+		return Key{}, ErrKeyNotFound
+	}
+
+	if key.IsRevoked() {
+		return key, nil
+	}
+
+	key.RevokedAt = revokedAt.UTC()
+
+	if err := fs.persist([]string{recordRevoke, id, key.RevokedAt.Format(time.RFC3339)}); err != nil {
+		return Key{}, err
+	}
+
+	fs.keys[id] = key
+
+	return key, nil
+}
+
+func (fs *FileStorage) restore() error {
+	file, err := os.Open(fs.filename)
+
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		return err
+	}
+
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+
+	for {
+		record, err := reader.Read()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if err = fs.restoreRecord(record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (fs *FileStorage) restoreRecord(record []string) error {
+	if record[0] == recordRevoke && len(record) == 3 {
+		key, ok := fs.keys[record[1]]
+
+		if !ok {
+			return errors.New("file has malformed data")
+		}
+
+		revokedAt, err := time.Parse(time.RFC3339, record[2])
+
+		if err != nil {
+			return err
+		}
+
+		key.RevokedAt = revokedAt
+		fs.keys[key.ID] = key
+
+		return nil
+	}
+
+	if record[0] != recordKey || len(record) != 8 {
+		return errors.New("file has malformed data")
+	}
+
+	rps, err := strconv.Atoi(record[5])
+
+	if err != nil {
+		return err
+	}
+
+	dailyQuota, err := strconv.Atoi(record[6])
+
+	if err != nil {
+		return err
+	}
+
+	createdAt, err := time.Parse(time.RFC3339, record[7])
+
+	if err != nil {
+		return err
+	}
+
+	key := Key{
+		ID:         record[1],
+		Hash:       record[2],
+		Name:       record[3],
+		Scopes:     strings.Fields(record[4]),
+		RPS:        rps,
+		DailyQuota: dailyQuota,
+		CreatedAt:  createdAt,
+	}
+	fs.keys[key.ID] = key
+	fs.hashes[key.Hash] = key.ID
+
+	return nil
+}
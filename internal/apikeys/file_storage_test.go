@@ -0,0 +1,72 @@
+package apikeys
+
+import (
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestFileStorage(t *testing.T) {
+	filename := t.TempDir() + "/api_keys.csv"
+	s, err := NewFileStorage(filename)
+
+	require.NoError(t, err)
+
+	key := Key{
+		ID:         "a1b2c3",
+		Name:       "client, inc",
+		Hash:       "hash",
+		Scopes:     []string{ScopeCreate, ScopeRead},
+		RPS:        5,
+		DailyQuota: 100,
+		CreatedAt:  time.Date(2024, 2, 7, 12, 0, 0, 0, time.UTC),
+	}
+
+	require.NoError(t, s.StoreKey(key))
+
+	found, err := s.GetKey("hash")
+
+	require.NoError(t, err)
+	require.Equal(t, key, found)
+
+	_, err = s.GetKey("other")
+
+	require.ErrorIs(t, err, ErrKeyNotFound)
+
+	revoked, err := s.RevokeKey("a1b2c3", time.Date(2024, 2, 8, 12, 0, 0, 0, time.UTC))
+
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2024, 2, 8, 12, 0, 0, 0, time.UTC), revoked.RevokedAt)
+
+	revoked, err = s.RevokeKey("a1b2c3", time.Date(2024, 2, 9, 12, 0, 0, 0, time.UTC))
+
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2024, 2, 8, 12, 0, 0, 0, time.UTC), revoked.RevokedAt)
+
+	_, err = s.RevokeKey("missing", time.Date(2024, 2, 9, 12, 0, 0, 0, time.UTC))
+
+	require.ErrorIs(t, err, ErrKeyNotFound)
+
+	data, err := os.ReadFile(filename)
+
+	require.NoError(t, err)
+	require.Equal(t, "key,a1b2c3,hash,\"client, inc\",create read,5,100,2024-02-07T12:00:00Z\n"+
+		"revoke,a1b2c3,2024-02-08T12:00:00Z\n", string(data))
+
+	restored, err := NewFileStorage(filename)
+
+	require.NoError(t, err)
+	require.Equal(t, s.keys, restored.keys)
+	require.Equal(t, s.hashes, restored.hashes)
+}
+
+func TestFileStorageMalformed(t *testing.T) {
+	filename := t.TempDir() + "/api_keys.csv"
+
+	require.NoError(t, os.WriteFile(filename, []byte("revoke,a1b2c3,2024-02-08T12:00:00Z\n"), 0600))
+
+	_, err := NewFileStorage(filename)
+
+	require.EqualError(t, err, "file has malformed data")
+}
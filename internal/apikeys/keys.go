@@ -0,0 +1,110 @@
+package apikeys
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+const ScopeCreate = "create"
+const ScopeRead = "read"
+const ScopeAdmin = "admin"
+
+// Scopes All known scopes of API keys
+var Scopes = []string{ScopeCreate, ScopeRead, ScopeAdmin}
+
+var ErrKeyNotFound = errors.New("API key not found")
+
+const tokenPrefix = "lsk_"
+
+// Key Issued API key. Token itself is shown once on issue, only its hash is stored
+type Key struct {
+	ID         string
+	Name       string
+	Hash       string
+	Scopes     []string
+	RPS        int
+	DailyQuota int
+	CreatedAt  time.Time
+	RevokedAt  time.Time
+}
+
+// HasScope Returns true if key is granted the scope, admin scope grants all others
+func (k Key) HasScope(scope string) bool {
+	return slices.Contains(k.Scopes, scope) || slices.Contains(k.Scopes, ScopeAdmin)
+}
+
+func (k Key) IsRevoked() bool {
+	return !k.RevokedAt.IsZero()
+}
+
+type StorageInterface interface {
+	StoreKey(key Key) error
+	GetKey(hash string) (Key, error)
+	RevokeKey(id string, revokedAt time.Time) (Key, error)
+}
+
+// ValidateScopes Returns error if list is empty or contains unknown scope
+func ValidateScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return errors.New("at least one scope must be provided")
+	}
+
+	for _, scope := range scopes {
+		if !slices.Contains(Scopes, scope) {
+			return fmt.Errorf("unknown scope: %s", scope)
+		}
+	}
+
+	return nil
+}
+
+// Hash Returns hash of token to store and look up by. Tokens are random, so fast hash is enough
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
+
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// NewKey Generates key with random ID and returns it together with secret token
+func NewKey(name string, scopes []string, rps, dailyQuota int, createdAt time.Time) (Key, string, error) {
+	id, err := randomBytes(6)
+
+	if err != nil {
+		return Key{}, "", err
+	}
+
+	secret, err := randomBytes(24)
+
+	if err != nil {
+		return Key{}, "", err
+	}
+
+	token := tokenPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	key := Key{
+		ID:         hex.EncodeToString(id),
+		Name:       name,
+		Hash:       Hash(token),
+		Scopes:     scopes,
+		RPS:        rps,
+		DailyQuota: dailyQuota,
+		CreatedAt:  createdAt.UTC(),
+	}
+
+	return key, token, nil
+}
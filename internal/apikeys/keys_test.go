@@ -0,0 +1,60 @@
+package apikeys
+
+import (
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewKey(t *testing.T) {
+	createdAt := time.Date(2024, 2, 7, 12, 0, 0, 0, time.UTC)
+	key, token, err := NewKey("client", []string{ScopeCreate}, 5, 100, createdAt)
+
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(token, tokenPrefix))
+	require.Len(t, key.ID, 12)
+	require.Equal(t, Hash(token), key.Hash)
+	require.NotContains(t, key.Hash, token)
+	require.Equal(t, Key{
+		ID:         key.ID,
+		Name:       "client",
+		Hash:       key.Hash,
+		Scopes:     []string{ScopeCreate},
+		RPS:        5,
+		DailyQuota: 100,
+		CreatedAt:  createdAt,
+	}, key)
+
+	other, otherToken, err := NewKey("client", []string{ScopeCreate}, 5, 100, createdAt)
+
+	require.NoError(t, err)
+	require.NotEqual(t, key.ID, other.ID)
+	require.NotEqual(t, token, otherToken)
+}
+
+func TestHasScope(t *testing.T) {
+	tests := []struct {
+		name     string
+		scopes   []string
+		scope    string
+		expected bool
+	}{
+		{"Granted", []string{ScopeCreate, ScopeRead}, ScopeRead, true},
+		{"Not granted", []string{ScopeCreate}, ScopeRead, false},
+		{"Admin grants all", []string{ScopeAdmin}, ScopeCreate, true},
+		{"Admin is not granted", []string{ScopeCreate, ScopeRead}, ScopeAdmin, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, Key{Scopes: tt.scopes}.HasScope(tt.scope))
+		})
+	}
+}
+
+func TestValidateScopes(t *testing.T) {
+	require.NoError(t, ValidateScopes([]string{ScopeCreate, ScopeRead, ScopeAdmin}))
+	require.EqualError(t, ValidateScopes(nil), "at least one scope must be provided")
+	require.EqualError(t, ValidateScopes([]string{ScopeRead, "write"}), "unknown scope: write")
+}
@@ -0,0 +1,87 @@
+package apikeys
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/lib/pq"
+	"time"
+)
+
+const keyColumns = "id, hash, name, scopes, rps, daily_quota, created_at, revoked_at"
+
+type SQLStorage struct {
+	db      *sql.DB
+	timeout time.Duration
+}
+
+func NewSQLStorage(db *sql.DB, timeout int) *SQLStorage {
+	return &SQLStorage{
+		db:      db,
+		timeout: time.Second * time.Duration(timeout),
+	}
+}
+
+func (s *SQLStorage) scanKey(row *sql.Row) (Key, error) {
+	var key Key
+	var revokedAt sql.NullTime
+
+	err := row.Scan(
+		&key.ID,
+		&key.Hash,
+		&key.Name,
+		pq.Array(&key.Scopes),
+		&key.RPS,
+		&key.DailyQuota,
+		&key.CreatedAt,
+		&revokedAt,
+	)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return Key{}, ErrKeyNotFound
+	}
+
+	if err != nil {
+		return Key{}, err
+	}
+
+	key.CreatedAt = key.CreatedAt.UTC()
+
+	if revokedAt.Valid {
+		key.RevokedAt = revokedAt.Time.UTC()
+	}
+
+	return key, nil
+}
+
+func (s *SQLStorage) StoreKey(key Key) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+
+	defer cancel()
+
+	query := "INSERT INTO api_keys(id, hash, name, scopes, rps, daily_quota, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7)"
+	_, err := s.db.ExecContext(ctx, query, key.ID, key.Hash, key.Name, pq.Array(key.Scopes), key.RPS, key.DailyQuota, key.CreatedAt)
+
+	return err
+}
+
+func (s *SQLStorage) GetKey(hash string) (Key, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+
+	defer cancel()
+
+	query := "SELECT " + keyColumns + " FROM api_keys WHERE hash = $1"
+
+	return s.scanKey(s.db.QueryRowContext(ctx, query, hash))
+}
+
+// RevokeKey Marks key as revoked, already revoked key keeps its original revocation time
+func (s *SQLStorage) RevokeKey(id string, revokedAt time.Time) (Key, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+
+	defer cancel()
+
+	query := "UPDATE api_keys SET revoked_at = COALESCE(revoked_at, $2) WHERE id = $1 RETURNING " + keyColumns
+
+	return s.scanKey(s.db.QueryRowContext(ctx, query, id, revokedAt))
+}
@@ -0,0 +1,86 @@
+package apikeys
+
+import (
+	"database/sql"
+	"github.com/dzhdmitry/link-shorter/internal/db"
+	"github.com/dzhdmitry/link-shorter/test"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+type SQLStorageSuite struct {
+	suite.Suite
+	db *sql.DB
+}
+
+func (s *SQLStorageSuite) SetupSuite() {
+	openDB, err := db.OpenPostgres(test.PrepareTestDB(), 25, 25, "15m")
+
+	if err != nil {
+		panic(err)
+	}
+
+	s.db = openDB
+}
+
+func (s *SQLStorageSuite) SetupTest() {
+	_, _ = s.db.Exec("TRUNCATE api_keys")
+}
+
+func (s *SQLStorageSuite) TearDownSuite() {
+	if s.db != nil {
+		s.db.Close()
+	}
+}
+
+func (s *SQLStorageSuite) TestStoreKey() {
+	storage := NewSQLStorage(s.db, 1)
+	key := Key{
+		ID:         "a1b2c3",
+		Name:       "client",
+		Hash:       Hash("token"),
+		Scopes:     []string{ScopeCreate, ScopeRead},
+		RPS:        5,
+		DailyQuota: 100,
+		CreatedAt:  time.Date(2024, 2, 7, 12, 0, 0, 0, time.UTC),
+	}
+
+	s.NoError(storage.StoreKey(key))
+
+	found, err := storage.GetKey(Hash("token"))
+
+	s.NoError(err)
+	s.Equal(key, found)
+
+	_, err = storage.GetKey(Hash("other"))
+
+	s.ErrorIs(err, ErrKeyNotFound)
+}
+
+func (s *SQLStorageSuite) TestRevokeKey() {
+	storage := NewSQLStorage(s.db, 1)
+	key := Key{ID: "a1b2c3", Hash: Hash("token"), Scopes: []string{ScopeRead}, CreatedAt: time.Date(2024, 2, 7, 12, 0, 0, 0, time.UTC)}
+
+	s.NoError(storage.StoreKey(key))
+
+	revoked, err := storage.RevokeKey("a1b2c3", time.Date(2024, 2, 8, 12, 0, 0, 0, time.UTC))
+
+	s.NoError(err)
+	s.Equal(time.Date(2024, 2, 8, 12, 0, 0, 0, time.UTC), revoked.RevokedAt)
+
+	revoked, err = storage.RevokeKey("a1b2c3", time.Date(2024, 2, 9, 12, 0, 0, 0, time.UTC))
+
+	s.NoError(err)
+	s.Equal(time.Date(2024, 2, 8, 12, 0, 0, 0, time.UTC), revoked.RevokedAt)
+
+	_, err = storage.RevokeKey("missing", time.Date(2024, 2, 9, 12, 0, 0, 0, time.UTC))
+
+	s.ErrorIs(err, ErrKeyNotFound)
+}
+
+func TestSQLStorage(t *testing.T) {
+	suite.Run(t, new(SQLStorageSuite))
+}
@@ -0,0 +1,159 @@
+package blocklist
+
+import (
+	"errors"
+	"fmt"
+	"github.com/dzhdmitry/link-shorter/internal/utils"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Blocklist Deny list loaded from file. File is reloaded in background when its modification time or size changes
+// and on SIGHUP, invalid file is reported and the previous list is kept
+type Blocklist struct {
+	filename       string
+	logger         *utils.Logger
+	reloadInterval time.Duration
+	list           *List
+	modTime        time.Time
+	size           int64
+	stop           chan struct{}
+	closed         bool
+	mu             sync.RWMutex
+}
+
+func NewBlocklist(filename string, logger *utils.Logger, background *utils.Background, reloadInterval time.Duration) (*Blocklist, error) {
+	b := Blocklist{
+		filename:       filename,
+		logger:         logger,
+		reloadInterval: reloadInterval,
+		stop:           make(chan struct{}),
+	}
+
+	if err := b.Reload(); err != nil {
+		return nil, err
+	}
+
+	background.Run(b.run)
+
+	return &b, nil
+}
+
+// Match Returns kind of the entry which matches URL, false if URL is allowed
+func (b *Blocklist) Match(URL string) (string, bool) {
+	b.mu.RLock()
+
+	defer b.mu.RUnlock()
+
+	return b.list.Match(URL)
+}
+
+// Reload Reads file and replaces the list, the list is kept if file is invalid
+func (b *Blocklist) Reload() error {
+	file, err := os.Open(b.filename)
+
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	stat, err := file.Stat()
+
+	if err != nil {
+		return err
+	}
+
+	list, err := Parse(file)
+
+	if err != nil {
+		return fmt.Errorf("blocklist %s: %w", b.filename, err)
+	}
+
+	b.mu.Lock()
+
+	defer b.mu.Unlock()
+
+	b.list = list
+	b.modTime = stat.ModTime()
+	b.size = stat.Size()
+
+	return nil
+}
+
+// changed Reports whether modification time or size of file differ from loaded ones
+func (b *Blocklist) changed() (bool, error) {
+	stat, err := os.Stat(b.filename)
+
+	if err != nil {
+		return false, err
+	}
+
+	b.mu.RLock()
+
+	defer b.mu.RUnlock()
+
+	return !stat.ModTime().Equal(b.modTime) || stat.Size() != b.size, nil
+}
+
+// Close Stops watching file, the list is kept
+func (b *Blocklist) Close() {
+	b.mu.Lock()
+
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+
+	b.closed = true
+	close(b.stop)
+}
+
+func (b *Blocklist) run() {
+	hangup := make(chan os.Signal, 1)
+
+	signal.Notify(hangup, syscall.SIGHUP)
+
+	defer signal.Stop(hangup)
+
+	ticker := time.NewTicker(b.reloadInterval)
+
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.stop:
+			return
+		case <-hangup:
+			b.reload()
+		case <-ticker.C:
+			changed, err := b.changed()
+
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				b.logger.LogError(err)
+			}
+
+			if changed {
+				b.reload()
+			}
+		}
+	}
+}
+
+func (b *Blocklist) reload() {
+	if err := b.Reload(); err != nil {
+		b.logger.LogError(err)
+
+		return
+	}
+
+	b.mu.RLock()
+
+	defer b.mu.RUnlock()
+
+	b.logger.LogInfo(fmt.Sprintf("blocklist %s reloaded: %d entries", b.filename, b.list.Len()))
+}
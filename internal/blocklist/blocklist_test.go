@@ -0,0 +1,55 @@
+package blocklist
+
+import (
+	"github.com/dzhdmitry/link-shorter/internal/utils"
+	"github.com/dzhdmitry/link-shorter/test"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestBlocklistReload(t *testing.T) {
+	filename := t.TempDir() + "/blocklist.txt"
+
+	require.NoError(t, os.WriteFile(filename, []byte("phishing.example\n"), 0600))
+
+	w := &test.Writer{Messages: []string{}}
+	background := &utils.Background{}
+	b, err := NewBlocklist(filename, utils.NewLogger(w, &test.Clock{}), background, 10*time.Millisecond)
+
+	require.NoError(t, err)
+
+	_, ok := b.Match("https://phishing.example")
+
+	require.True(t, ok)
+
+	require.NoError(t, os.WriteFile(filename, []byte("spam.example\n10.0.0.0/8\n"), 0600))
+	require.Eventually(t, func() bool {
+		_, ok := b.Match("https://spam.example")
+
+		return ok
+	}, time.Second, 10*time.Millisecond)
+
+	_, ok = b.Match("https://phishing.example")
+
+	require.False(t, ok)
+
+	// invalid file is reported and the previous list is kept
+	require.NoError(t, os.WriteFile(filename, []byte("/[a-/\n"), 0600))
+	require.EqualError(t, b.Reload(), "blocklist "+filename+": line 1: invalid regular expression: error parsing regexp: missing closing ]: `[a-`")
+
+	_, ok = b.Match("https://spam.example")
+
+	require.True(t, ok)
+
+	b.Close()
+	b.Close()
+	background.Wait()
+}
+
+func TestNewBlocklistMissingFile(t *testing.T) {
+	_, err := NewBlocklist(t.TempDir()+"/blocklist.txt", utils.NewLogger(&test.Writer{}, &test.Clock{}), &utils.Background{}, time.Second)
+
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -0,0 +1,150 @@
+package blocklist
+
+import (
+	"bufio"
+	"fmt"
+	"golang.org/x/net/idna"
+	"io"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const RuleDomain = "domain"
+const RuleSuffix = "suffix"
+const RuleRegex = "regex"
+const RuleCIDR = "cidr"
+
+// List Parsed deny list. Line of the file is one entry, kind of entry is chosen by its form:
+// "/pattern/" is regular expression matched against whole URL, IP address or CIDR matches host which is IP address,
+// ".example.com" or "*.example.com" is suffix matching domain and all its subdomains, other entries are exact domains.
+// Empty lines and lines starting with "#" are skipped
+type List struct {
+	domains  map[string]bool
+	suffixes []string
+	patterns []*regexp.Regexp
+	networks []*net.IPNet
+}
+
+// Parse Reads list, error reports number of the first invalid line
+func Parse(r io.Reader) (*List, error) {
+	l := List{domains: map[string]bool{}}
+	scanner := bufio.NewScanner(r)
+	number := 0
+
+	for scanner.Scan() {
+		number++
+		entry := strings.TrimSpace(scanner.Text())
+
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+
+		if err := l.add(entry); err != nil {
+			return nil, fmt.Errorf("line %d: %w", number, err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return &l, nil
+}
+
+func (l *List) add(entry string) error {
+	if len(entry) > 1 && strings.HasPrefix(entry, "/") && strings.HasSuffix(entry, "/") {
+		pattern, err := regexp.Compile(entry[1 : len(entry)-1])
+
+		if err != nil {
+			return fmt.Errorf("invalid regular expression: %w", err)
+		}
+
+		l.patterns = append(l.patterns, pattern)
+
+		return nil
+	}
+
+	if _, network, err := net.ParseCIDR(entry); err == nil {
+		l.networks = append(l.networks, network)
+
+		return nil
+	}
+
+	if ip := net.ParseIP(entry); ip != nil {
+		l.networks = append(l.networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)})
+
+		return nil
+	}
+
+	suffix, isSuffix := strings.CutPrefix(strings.TrimPrefix(entry, "*"), ".")
+	domain, err := normalizeDomain(suffix)
+
+	if err != nil || domain == "" {
+		return fmt.Errorf("invalid domain: %s", entry)
+	}
+
+	if isSuffix {
+		l.suffixes = append(l.suffixes, domain)
+	} else {
+		l.domains[domain] = true
+	}
+
+	return nil
+}
+
+// normalizeDomain Returns lowercase ASCII form of domain, the same as URL normalizer makes
+func normalizeDomain(domain string) (string, error) {
+	return idna.Lookup.ToASCII(strings.TrimSuffix(strings.ToLower(domain), "."))
+}
+
+// Len Returns number of entries in the list
+func (l *List) Len() int {
+	return len(l.domains) + len(l.suffixes) + len(l.patterns) + len(l.networks)
+}
+
+// Match Returns kind of the first entry which matches URL, false if URL is allowed or is not a valid URL
+func (l *List) Match(URL string) (string, bool) {
+	parsed, err := url.Parse(URL)
+
+	if err != nil {
+		return "", false
+	}
+
+	for _, pattern := range l.patterns {
+		if pattern.MatchString(URL) {
+			return RuleRegex, true
+		}
+	}
+
+	host := parsed.Hostname()
+
+	if ip := net.ParseIP(host); ip != nil {
+		for _, network := range l.networks {
+			if network.Contains(ip) {
+				return RuleCIDR, true
+			}
+		}
+
+		return "", false
+	}
+
+	host, err = normalizeDomain(host)
+
+	if err != nil {
+		return "", false
+	}
+
+	if l.domains[host] {
+		return RuleDomain, true
+	}
+
+	for _, suffix := range l.suffixes {
+		if host == suffix || strings.HasSuffix(host, "."+suffix) {
+			return RuleSuffix, true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,76 @@
+package blocklist
+
+import (
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+const testList = `# phishing
+phishing.example
+.spam.example
+*.bad.example
+/^https?://[^/]+/login\.php/
+10.0.0.0/8
+203.0.113.7
+2001:db8::/32
+пример.рф
+`
+
+func TestMatch(t *testing.T) {
+	l, err := Parse(strings.NewReader(testList))
+
+	require.NoError(t, err)
+	require.Equal(t, 8, l.Len())
+
+	tests := []struct {
+		name         string
+		URL          string
+		expectedRule string
+	}{
+		{"Domain", "https://phishing.example/pay", RuleDomain},
+		{"Domain with uppercase letters", "https://PHISHING.example./pay", RuleDomain},
+		{"Subdomain of domain", "https://www.phishing.example", ""},
+		{"Suffix", "https://spam.example", RuleSuffix},
+		{"Subdomain of suffix", "https://a.b.spam.example/x", RuleSuffix},
+		{"Suffix with asterisk", "http://www.bad.example", RuleSuffix},
+		{"Suffix is not part of name", "https://notspam.example", ""},
+		{"Regex", "https://example.org/login.php?next=/", RuleRegex},
+		{"CIDR", "http://10.1.2.3:8080/", RuleCIDR},
+		{"IP", "http://203.0.113.7/", RuleCIDR},
+		{"Other IP", "http://203.0.113.8/", ""},
+		{"IPv6", "http://[2001:db8::1]/", RuleCIDR},
+		{"International domain", "https://xn--e1afmkfd.xn--p1ai/", RuleDomain},
+		{"International domain in unicode", "https://пример.рф/", RuleDomain},
+		{"Allowed", "https://example.com", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, ok := l.Match(tt.URL)
+
+			require.Equal(t, tt.expectedRule, rule)
+			require.Equal(t, tt.expectedRule != "", ok)
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		name          string
+		data          string
+		expectedError string
+	}{
+		{"Regex", "example.com\n/[a-/\n", "line 2: invalid regular expression: error parsing regexp: missing closing ]: `[a-`"},
+		{"Domain", "exa mple.com\n", "line 1: invalid domain: exa mple.com"},
+		{"Empty suffix", "*.\n", "line 1: invalid domain: *."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tt.data))
+
+			require.EqualError(t, err, tt.expectedError)
+		})
+	}
+}
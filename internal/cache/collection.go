@@ -1,13 +1,144 @@
 package cache
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/dzhdmitry/link-shorter/cmd/app"
+	"github.com/dzhdmitry/link-shorter/internal/links"
+	"strings"
+	"time"
 )
 
 type LinksCacheInterface interface {
 	Get(string) (interface{}, bool, error)
-	Put(string, interface{}) error
+	// Put Stores value until expiresAt, zero expiresAt means forever
+	Put(string, interface{}, time.Time) error
+	Delete(string) error
+}
+
+// linkRecord Link kept in cache as JSON. Password hash and redirects of health are never cached
+type linkRecord struct {
+	Key         string         `json:"key,omitempty"`
+	Alias       string         `json:"alias,omitempty"`
+	URL         string         `json:"url"`
+	ExpiresAt   *time.Time     `json:"expires_at,omitempty"`
+	Owner       string         `json:"owner,omitempty"`
+	CreatedAt   *time.Time     `json:"created_at,omitempty"`
+	Title       string         `json:"title,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Tags        []string       `json:"tags,omitempty"`
+	Notes       string         `json:"notes,omitempty"`
+	UTMTemplate string         `json:"utm_template,omitempty"`
+	Rules       links.Rules    `json:"rules,omitempty"`
+	Variants    links.Variants `json:"variants,omitempty"`
+	Fallback    string         `json:"fallback,omitempty"`
+	Down        bool           `json:"down,omitempty"`
+	Health      *healthRecord  `json:"health,omitempty"`
+	Preview     *links.Preview `json:"preview,omitempty"`
+}
+
+type healthRecord struct {
+	Status    int       `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+func healthOrNil(health links.Health) *healthRecord {
+	if health.IsZero() {
+		return nil
+	}
+
+	return &healthRecord{Status: health.Status, Error: health.Error, CheckedAt: health.CheckedAt}
+}
+
+func previewOrNil(preview links.Preview) *links.Preview {
+	if preview.IsZero() {
+		return nil
+	}
+
+	return &preview
+}
+
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
+}
+
+func encodeLink(link links.Link) (string, error) {
+	record, err := json.Marshal(linkRecord{
+		Key:         link.Key,
+		Alias:       link.Alias,
+		URL:         link.URL,
+		ExpiresAt:   timeOrNil(link.ExpiresAt),
+		Owner:       link.Owner,
+		CreatedAt:   timeOrNil(link.CreatedAt),
+		Title:       link.Metadata.Title,
+		Description: link.Metadata.Description,
+		Tags:        link.Metadata.Tags,
+		Notes:       link.Metadata.Notes,
+		UTMTemplate: link.UTMTemplate,
+		Rules:       link.Rules,
+		Variants:    link.Variants,
+		Fallback:    link.Fallback,
+		Down:        link.Down,
+		Health:      healthOrNil(link.Health),
+		Preview:     previewOrNil(link.Preview),
+	})
+
+	return string(record), err
+}
+
+// decodeLink Makes link of cached value. Values cached by previous versions are bare URLs
+func decodeLink(value string) (links.Link, error) {
+	if !strings.HasPrefix(value, "{") {
+		return links.Link{URL: value}, nil
+	}
+
+	var record linkRecord
+
+	if err := json.Unmarshal([]byte(value), &record); err != nil {
+		return links.Link{}, err
+	}
+
+	link := links.Link{
+		Key:   record.Key,
+		Alias: record.Alias,
+		URL:   record.URL,
+		Owner: record.Owner,
+		Metadata: links.Metadata{
+			Title:       record.Title,
+			Description: record.Description,
+			Tags:        record.Tags,
+			Notes:       record.Notes,
+		},
+		UTMTemplate: record.UTMTemplate,
+		Rules:       record.Rules,
+		Variants:    record.Variants,
+		Fallback:    record.Fallback,
+		Down:        record.Down,
+	}
+
+	if record.Health != nil {
+		link.Health = links.Health{Status: record.Health.Status, Error: record.Health.Error, CheckedAt: record.Health.CheckedAt}
+	}
+
+	if record.Preview != nil {
+		link.Preview = *record.Preview
+	}
+
+	if record.ExpiresAt != nil {
+		link.ExpiresAt = *record.ExpiresAt
+	}
+
+	if record.CreatedAt != nil {
+		link.CreatedAt = *record.CreatedAt
+	}
+
+	return link, nil
 }
 
 type CachedCollection struct {
@@ -22,50 +153,109 @@ func NewCachedCollection(collection app.LinksCollectionInterface, cache LinksCac
 	}
 }
 
-func (c *CachedCollection) GenerateKey(URL string) (string, error) {
-	return c.collection.GenerateKey(URL)
+func (c *CachedCollection) GenerateKey(URL string, options links.LinkOptions) (string, error) {
+	return c.collection.GenerateKey(URL, options)
 }
 
-func (c *CachedCollection) GenerateKeys(URLs []string) (map[string]string, error) {
-	return c.collection.GenerateKeys(URLs)
+func (c *CachedCollection) GenerateKeys(URLs []string, options links.LinkOptions) (map[string]string, error) {
+	return c.collection.GenerateKeys(URLs, options)
 }
 
-func (c *CachedCollection) GetURL(key string) (string, error) {
-	cachedURL, ok, err := c.cache.Get(key)
+func (c *CachedCollection) GenerateUniqueKeys(URLs []string, options links.LinkOptions) (map[string]string, map[string]bool, error) {
+	return c.collection.GenerateUniqueKeys(URLs, options)
+}
+
+func (c *CachedCollection) GenerateAlias(alias, URL string, options links.LinkOptions) (string, error) {
+	return c.collection.GenerateAlias(alias, URL, options)
+}
+
+func (c *CachedCollection) GetLink(key string) (links.Link, error) {
+	cached, ok, err := c.cache.Get(key)
 
 	if err != nil {
-		return "", err
+		return links.Link{}, err
 	}
 
 	if ok {
-		return fmt.Sprintf("%s", cachedURL), nil
+		return decodeLink(fmt.Sprintf("%s", cached))
 	}
 
-	URL, err := c.collection.GetURL(key)
+	link, err := c.collection.GetLink(key)
 
 	if err != nil {
-		return "", err
+		return link, err
 	}
 
-	if URL != "" {
-		err = c.cache.Put(key, URL)
+	// password hash is not cached, so protected link would lose protection
+	if link.URL == "" || link.PasswordHash != "" {
+		return link, nil
 	}
 
-	return URL, err
+	record, err := encodeLink(link)
+
+	if err != nil {
+		return link, err
+	}
+
+	return link, c.cache.Put(key, record, link.ExpiresAt)
 }
 
-func (c *CachedCollection) GetURLs(keys []string) (map[string]string, error) {
-	URLs := make(map[string]string, len(keys))
+// GetLinks Returns map with key=key, value=link, expired and disabled links are omitted
+func (c *CachedCollection) GetLinks(keys []string) (map[string]links.Link, error) {
+	result := make(map[string]links.Link, len(keys))
 
 	for _, key := range keys {
-		URL, err := c.GetURL(key)
+		link, err := c.GetLink(key)
+
+		if errors.Is(err, links.ErrLinkExpired) || errors.Is(err, links.ErrLinkDisabled) {
+			continue
+		}
 
 		if err != nil {
 			return nil, err
 		}
 
-		URLs[key] = URL
+		result[key] = link
+	}
+
+	return result, nil
+}
+
+func (c *CachedCollection) UpdateLink(key string, update links.LinkUpdate) (links.Link, error) {
+	link, err := c.collection.UpdateLink(key, update)
+
+	if err != nil {
+		return link, err
+	}
+
+	return link, c.invalidate(key, link)
+}
+
+func (c *CachedCollection) DeleteLink(key string) (links.Link, error) {
+	link, err := c.collection.DeleteLink(key)
+
+	if err != nil {
+		return link, err
+	}
+
+	return link, c.invalidate(key, link)
+}
+
+func (c *CachedCollection) ListLinks(query links.ListQuery) ([]links.Link, string, error) {
+	return c.collection.ListLinks(query)
+}
+
+// invalidate Removes link cached by any of its keys
+func (c *CachedCollection) invalidate(key string, link links.Link) error {
+	for _, k := range []string{key, link.Key, link.Alias} {
+		if k == "" {
+			continue
+		}
+
+		if err := c.cache.Delete(k); err != nil {
+			return err
+		}
 	}
 
-	return URLs, nil
+	return nil
 }
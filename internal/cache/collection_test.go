@@ -2,32 +2,97 @@ package cache
 
 import (
 	"fmt"
+	"github.com/dzhdmitry/link-shorter/internal/links"
 	"github.com/stretchr/testify/require"
 	"testing"
+	"time"
 )
 
 type testCollection struct {
 	//
 }
 
-func (c *testCollection) GenerateKey(URL string) (string, error) {
+func (c *testCollection) GenerateKey(URL string, options links.LinkOptions) (string, error) {
 	return "key", nil
 }
 
-func (c *testCollection) GenerateKeys(URLs []string) (map[string]string, error) {
+func (c *testCollection) GenerateKeys(URLs []string, options links.LinkOptions) (map[string]string, error) {
 	return map[string]string{}, nil
 }
 
-func (c *testCollection) GetURL(key string) (string, error) {
-	return "url", nil
+func (c *testCollection) GenerateUniqueKeys(URLs []string, options links.LinkOptions) (map[string]string, map[string]bool, error) {
+	return map[string]string{}, map[string]bool{}, nil
 }
 
-func (c *testCollection) GetURLs(keys []string) (map[string]string, error) {
-	return map[string]string{}, nil
+func (c *testCollection) GenerateAlias(alias, URL string, options links.LinkOptions) (string, error) {
+	return alias, nil
+}
+
+func (c *testCollection) GetLink(key string) (links.Link, error) {
+	if key == "expired" {
+		return links.Link{URL: "url"}, links.ErrLinkExpired
+	}
+
+	if key == "disabled" {
+		return links.Link{URL: "url", Disabled: true}, links.ErrLinkDisabled
+	}
+
+	if key == "protected" {
+		return links.Link{URL: "url", PasswordHash: "hash"}, nil
+	}
+
+	if key == "described" {
+		return links.Link{
+			Key:         "7",
+			URL:         "url",
+			ExpiresAt:   time.Date(2024, 2, 8, 12, 0, 0, 0, time.UTC),
+			Owner:       "client",
+			Metadata:    links.Metadata{Title: "Spring sale", Tags: []string{"sale", "spring"}, Notes: "internal"},
+			UTMTemplate: "spring",
+			Rules:       links.Rules{{URL: "https://example.com/de", Languages: []string{"de"}}},
+			Variants:    links.Variants{{URL: "https://example.com/a", Weight: 7}, {URL: "https://example.com/b", Weight: 3}},
+			Fallback:    "https://example.com/sale",
+			Down:        true,
+			Health:      links.Health{Status: 503, CheckedAt: time.Date(2024, 2, 7, 12, 0, 0, 0, time.UTC)},
+			Preview: links.Preview{
+				Title:     "Sale",
+				OpenGraph: map[string]string{"title": "Spring sale"},
+				Favicon:   "https://example.com/favicon.ico",
+				FetchedAt: time.Date(2024, 2, 7, 12, 0, 0, 0, time.UTC),
+			},
+		}, nil
+	}
+
+	if key == "expiring" {
+		return links.Link{URL: "url", ExpiresAt: time.Date(2024, 2, 8, 12, 0, 0, 0, time.UTC)}, nil
+	}
+
+	return links.Link{URL: "url"}, nil
+}
+
+func (c *testCollection) GetLinks(keys []string) (map[string]links.Link, error) {
+	return map[string]links.Link{}, nil
+}
+
+func (c *testCollection) UpdateLink(key string, update links.LinkUpdate) (links.Link, error) {
+	if key == "missing" {
+		return links.Link{}, links.ErrLinkNotFound
+	}
+
+	return links.Link{Key: "5", Alias: "spring-sale", URL: *update.URL}, nil
+}
+
+func (c *testCollection) DeleteLink(key string) (links.Link, error) {
+	return links.Link{Key: "5", Alias: "spring-sale", URL: "url"}, nil
+}
+
+func (c *testCollection) ListLinks(query links.ListQuery) ([]links.Link, string, error) {
+	return []links.Link{{Key: "5", URL: "url"}}, "", nil
 }
 
 type testCache struct {
-	data map[string]string
+	data        map[string]string
+	expirations map[string]time.Time
 }
 
 func (c *testCache) Get(key string) (interface{}, bool, error) {
@@ -36,8 +101,19 @@ func (c *testCache) Get(key string) (interface{}, bool, error) {
 	return v, ok, nil
 }
 
-func (c *testCache) Put(key string, URL interface{}) error {
+func (c *testCache) Put(key string, URL interface{}, expiresAt time.Time) error {
 	c.data[key] = fmt.Sprintf("%s", URL)
+
+	if c.expirations != nil {
+		c.expirations[key] = expiresAt
+	}
+
+	return nil
+}
+
+func (c *testCache) Delete(key string) error {
+	delete(c.data, key)
+
 	return nil
 }
 
@@ -49,10 +125,10 @@ func TestGetURL(t *testing.T) {
 		},
 	)
 
-	url, err := c.GetURL("a")
+	link, err := c.GetLink("a")
 
 	require.NoError(t, err)
-	require.Equal(t, "url", url)
+	require.Equal(t, "url", link.URL)
 }
 
 func TestGetURLs(t *testing.T) {
@@ -66,10 +142,10 @@ func TestGetURLs(t *testing.T) {
 		},
 	)
 
-	URLs, err := c.GetURLs([]string{"a", "b", "c"})
+	result, err := c.GetLinks([]string{"a", "b", "c", "expired", "disabled"})
 
 	require.NoError(t, err)
-	require.Equal(t, map[string]string{"a": "url1", "b": "url2", "c": "url"}, URLs)
+	require.Equal(t, map[string]links.Link{"a": {URL: "url1"}, "b": {URL: "url2"}, "c": {URL: "url"}}, result)
 }
 
 func TestGetURLPut(t *testing.T) {
@@ -81,11 +157,140 @@ func TestGetURLPut(t *testing.T) {
 		cache,
 	)
 
-	url, err := c.GetURL("a")
+	link, err := c.GetLink("a")
 
 	require.NoError(t, err)
-	require.Equal(t, "url", url)
+	require.Equal(t, "url", link.URL)
 	require.Equal(t, map[string]string{
-		"a": "url",
+		"a": `{"url":"url"}`,
 	}, cache.data)
 }
+
+func TestGetURLRecord(t *testing.T) {
+	cache := &testCache{
+		data: map[string]string{},
+	}
+	c := NewCachedCollection(
+		&testCollection{},
+		cache,
+	)
+
+	link, err := c.GetLink("described")
+
+	require.NoError(t, err)
+	require.Equal(t, `{"key":"7","url":"url","expires_at":"2024-02-08T12:00:00Z","owner":"client",`+
+		`"title":"Spring sale","tags":["sale","spring"],"notes":"internal","utm_template":"spring",`+
+		`"rules":[{"url":"https://example.com/de","languages":["de"]}],`+
+		`"variants":[{"url":"https://example.com/a","weight":7},{"url":"https://example.com/b","weight":3}],`+
+		`"fallback":"https://example.com/sale","down":true,"health":{"status":503,"checked_at":"2024-02-07T12:00:00Z"},`+
+		`"preview":{"title":"Sale","open_graph":{"title":"Spring sale"},"favicon":"https://example.com/favicon.ico","fetched_at":"2024-02-07T12:00:00Z"}}`,
+		cache.data["described"])
+
+	cached, err := c.GetLink("described")
+
+	require.NoError(t, err)
+	require.Equal(t, link, cached)
+}
+
+func TestGetURLPutExpiration(t *testing.T) {
+	cache := &testCache{
+		data:        map[string]string{},
+		expirations: map[string]time.Time{},
+	}
+	c := NewCachedCollection(
+		&testCollection{},
+		cache,
+	)
+
+	_, err := c.GetLink("expiring")
+
+	require.NoError(t, err)
+	require.Equal(t, map[string]time.Time{
+		"expiring": time.Date(2024, 2, 8, 12, 0, 0, 0, time.UTC),
+	}, cache.expirations)
+}
+
+func TestGetURLExpiredNotCached(t *testing.T) {
+	cache := &testCache{
+		data: map[string]string{},
+	}
+	c := NewCachedCollection(
+		&testCollection{},
+		cache,
+	)
+
+	_, err := c.GetLink("expired")
+
+	require.ErrorIs(t, err, links.ErrLinkExpired)
+	require.Equal(t, map[string]string{}, cache.data)
+}
+
+func TestGetURLProtectedNotCached(t *testing.T) {
+	cache := &testCache{
+		data: map[string]string{},
+	}
+	c := NewCachedCollection(
+		&testCollection{},
+		cache,
+	)
+
+	link, err := c.GetLink("protected")
+
+	require.NoError(t, err)
+	require.Equal(t, "hash", link.PasswordHash)
+	require.Equal(t, map[string]string{}, cache.data)
+}
+
+func TestUpdateLinkInvalidates(t *testing.T) {
+	cache := &testCache{
+		data: map[string]string{
+			"5":           "url",
+			"spring-sale": "url",
+			"6":           "url6",
+		},
+	}
+	c := NewCachedCollection(
+		&testCollection{},
+		cache,
+	)
+
+	URL := "new-url"
+	link, err := c.UpdateLink("spring-sale", links.LinkUpdate{URL: &URL})
+
+	require.NoError(t, err)
+	require.Equal(t, "new-url", link.URL)
+	require.Equal(t, map[string]string{"6": "url6"}, cache.data)
+}
+
+func TestUpdateLinkNotFound(t *testing.T) {
+	cache := &testCache{
+		data: map[string]string{"missing": "url"},
+	}
+	c := NewCachedCollection(
+		&testCollection{},
+		cache,
+	)
+
+	URL := "new-url"
+	_, err := c.UpdateLink("missing", links.LinkUpdate{URL: &URL})
+
+	require.ErrorIs(t, err, links.ErrLinkNotFound)
+}
+
+func TestDeleteLinkInvalidates(t *testing.T) {
+	cache := &testCache{
+		data: map[string]string{
+			"5":           "url",
+			"spring-sale": "url",
+		},
+	}
+	c := NewCachedCollection(
+		&testCollection{},
+		cache,
+	)
+
+	_, err := c.DeleteLink("5")
+
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{}, cache.data)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"github.com/redis/go-redis/v9"
+	"time"
 )
 
 type RedisCache struct {
@@ -29,9 +30,15 @@ func (c *RedisCache) Get(key string) (interface{}, bool, error) {
 	return result, true, nil
 }
 
-func (c *RedisCache) Put(key string, value interface{}) error {
+func (c *RedisCache) Put(key string, value interface{}, expiresAt time.Time) error {
 	ctx := context.Background()
-	err := c.rdb.Set(ctx, key, value, 0).Err()
+	err := c.rdb.SetArgs(ctx, key, value, redis.SetArgs{ExpireAt: expiresAt}).Err()
 
 	return err
 }
+
+func (c *RedisCache) Delete(key string) error {
+	ctx := context.Background()
+
+	return c.rdb.Del(ctx, key).Err()
+}
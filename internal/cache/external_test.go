@@ -6,6 +6,7 @@ import (
 	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/suite"
 	"testing"
+	"time"
 )
 
 type RedisSuite struct {
@@ -56,7 +57,7 @@ func (s *RedisSuite) TestRedisGetExisting() {
 
 func (s *RedisSuite) TestRedisPut() {
 	c := NewRedisCache(s.rdb)
-	err := c.Put("test-put", "put-value")
+	err := c.Put("test-put", "put-value", time.Time{})
 
 	s.NoError(err)
 
@@ -65,6 +66,52 @@ func (s *RedisSuite) TestRedisPut() {
 
 	s.NoError(err)
 	s.Equal("put-value", result)
+
+	ttl, err := c.rdb.TTL(ctx, "test-put").Result()
+
+	s.NoError(err)
+	s.Equal(time.Duration(-1), ttl)
+}
+
+func (s *RedisSuite) TestRedisPutExpiration() {
+	c := NewRedisCache(s.rdb)
+	err := c.Put("test-put-exp", "put-value", time.Now().Add(time.Hour))
+
+	s.NoError(err)
+
+	ctx := context.Background()
+	ttl, err := c.rdb.TTL(ctx, "test-put-exp").Result()
+
+	s.NoError(err)
+	s.Greater(ttl, 59*time.Minute)
+	s.LessOrEqual(ttl, time.Hour)
+}
+
+func (s *RedisSuite) TestRedisPutExpired() {
+	c := NewRedisCache(s.rdb)
+	err := c.Put("test-put-expired", "put-value", time.Now().Add(-time.Hour))
+
+	s.NoError(err)
+
+	result, exists, err := c.Get("test-put-expired")
+
+	s.NoError(err)
+	s.False(exists)
+	s.Nil(result)
+}
+
+func (s *RedisSuite) TestRedisDelete() {
+	c := NewRedisCache(s.rdb)
+	ctx := context.Background()
+	_ = s.rdb.Set(ctx, "test-delete", "value", 0)
+
+	s.NoError(c.Delete("test-delete"))
+	s.NoError(c.Delete("test-delete-non-existing"))
+
+	_, exists, err := c.Get("test-delete")
+
+	s.NoError(err)
+	s.False(exists)
 }
 
 func TestSQLStorage(t *testing.T) {
@@ -0,0 +1,43 @@
+package cache
+
+import (
+	"github.com/dzhdmitry/link-shorter/internal/links"
+	"time"
+)
+
+// HealthStorage Records health of links and removes link from cache when its destination breaks or recovers,
+// so redirects follow fallback without waiting for cached link to expire
+type HealthStorage struct {
+	storage links.HealthStorageInterface
+	cache   LinksCacheInterface
+}
+
+func NewHealthStorage(storage links.HealthStorageInterface, cache LinksCacheInterface) *HealthStorage {
+	return &HealthStorage{storage: storage, cache: cache}
+}
+
+func (s *HealthStorage) LinksToCheck(now, checkedBefore time.Time, limit int) ([]links.Link, error) {
+	return s.storage.LinksToCheck(now, checkedBefore, limit)
+}
+
+func (s *HealthStorage) StoreHealth(link links.Link, health links.Health) error {
+	if err := s.storage.StoreHealth(link, health); err != nil {
+		return err
+	}
+
+	if link.Health.IsBroken() == health.IsBroken() {
+		return nil
+	}
+
+	for _, key := range []string{link.Key, link.Alias} {
+		if key == "" {
+			continue
+		}
+
+		if err := s.cache.Delete(key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
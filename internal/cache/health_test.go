@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"github.com/dzhdmitry/link-shorter/internal/links"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+type testHealthStorage struct {
+	stored map[string]links.Health
+}
+
+func (s *testHealthStorage) LinksToCheck(_, _ time.Time, _ int) ([]links.Link, error) {
+	return nil, nil
+}
+
+func (s *testHealthStorage) StoreHealth(link links.Link, health links.Health) error {
+	s.stored[link.Key] = health
+
+	return nil
+}
+
+func TestHealthStorageInvalidates(t *testing.T) {
+	checkedAt := time.Date(2024, 2, 7, 12, 0, 0, 0, time.UTC)
+	healthy := links.Health{Status: 200, CheckedAt: checkedAt}
+	broken := links.Health{Status: 503, CheckedAt: checkedAt}
+
+	tests := []struct {
+		name     string
+		previous links.Health
+		health   links.Health
+		expected map[string]string
+	}{
+		{"First check is healthy", links.Health{}, healthy, map[string]string{"5": "url", "spring-sale": "url", "6": "url6"}},
+		{"Still healthy", healthy, healthy, map[string]string{"5": "url", "spring-sale": "url", "6": "url6"}},
+		{"Breaks", healthy, broken, map[string]string{"6": "url6"}},
+		{"First check is broken", links.Health{}, broken, map[string]string{"6": "url6"}},
+		{"Still broken", broken, broken, map[string]string{"5": "url", "spring-sale": "url", "6": "url6"}},
+		{"Recovers", broken, healthy, map[string]string{"6": "url6"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := &testCache{data: map[string]string{"5": "url", "spring-sale": "url", "6": "url6"}}
+			storage := &testHealthStorage{stored: map[string]links.Health{}}
+			s := NewHealthStorage(storage, cache)
+			link := links.Link{Key: "5", Alias: "spring-sale", URL: "url", Health: tt.previous}
+
+			require.NoError(t, s.StoreHealth(link, tt.health))
+			require.Equal(t, tt.health, storage.stored["5"])
+			require.Equal(t, tt.expected, cache.data)
+		})
+	}
+}
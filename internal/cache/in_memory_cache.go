@@ -2,13 +2,20 @@ package cache
 
 import (
 	"container/list"
+	"github.com/dzhdmitry/link-shorter/internal/utils"
 	"slices"
 	"sync"
+	"time"
 )
 
 type CachedEntry struct {
-	value   interface{}
-	freqRef *list.Element
+	value     interface{}
+	expiresAt time.Time
+	freqRef   *list.Element
+}
+
+func (e *CachedEntry) isExpired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
 }
 
 type FrequencyEntry struct {
@@ -28,15 +35,17 @@ type LFUCache struct {
 	frequencies   *list.List // list of *FrequencyEntry
 	length        int
 	capacity      int
+	clock         utils.ClockInterface
 	mu            sync.Mutex
 }
 
-func NewLFUCache(capacity int) *LFUCache {
+func NewLFUCache(capacity int, clock utils.ClockInterface) *LFUCache {
 	return &LFUCache{
 		cachedEntries: make(map[string]*CachedEntry, capacity),
 		frequencies:   list.New(),
 		length:        0,
 		capacity:      capacity,
+		clock:         clock,
 	}
 }
 
@@ -67,29 +76,60 @@ func (c *LFUCache) incrementFrequency(key string) {
 	}
 }
 
-func (c *LFUCache) Get(key string) (interface{}, bool, error) {
+// remove Deletes entry of key, missing key is ignored
+func (c *LFUCache) remove(key string) {
 	entry, ok := c.cachedEntries[key]
 
 	if !ok {
-		return "", false, nil
+		return
 	}
 
+	freqRef := entry.freqRef
+	keyPosition := slices.Index(freqRef.Value.(*FrequencyEntry).keys, key)
+
+	if keyPosition != -1 {
+		freqRef.Value.(*FrequencyEntry).keys = slices.Delete(freqRef.Value.(*FrequencyEntry).keys, keyPosition, keyPosition+1)
+	}
+
+	if len(freqRef.Value.(*FrequencyEntry).keys) == 0 {
+		c.frequencies.Remove(freqRef)
+	}
+
+	delete(c.cachedEntries, key)
+	c.length--
+}
+
+func (c *LFUCache) Get(key string) (interface{}, bool, error) {
 	c.mu.Lock()
 
 	defer c.mu.Unlock()
 
+	entry, ok := c.cachedEntries[key]
+
+	if !ok {
+		return "", false, nil
+	}
+
+	if entry.isExpired(c.clock.Now()) {
+		c.remove(key)
+
+		return "", false, nil
+	}
+
 	c.incrementFrequency(key)
 
 	return entry.value, ok, nil
 }
 
-func (c *LFUCache) Put(key string, value interface{}) error {
+func (c *LFUCache) Put(key string, value interface{}, expiresAt time.Time) error {
 	c.mu.Lock()
 
 	defer c.mu.Unlock()
 
-	if _, ok := c.cachedEntries[key]; ok {
+	if entry, ok := c.cachedEntries[key]; ok {
 		// what if parallel task has already set key?
+		entry.value = value
+		entry.expiresAt = expiresAt
 		c.incrementFrequency(key)
 
 		return nil
@@ -119,7 +159,17 @@ func (c *LFUCache) Put(key string, value interface{}) error {
 	}
 
 	c.frequencies.Front().Value.(*FrequencyEntry).keys = append(c.frequencies.Front().Value.(*FrequencyEntry).keys, key)
-	c.cachedEntries[key] = &CachedEntry{value: value, freqRef: c.frequencies.Front()}
+	c.cachedEntries[key] = &CachedEntry{value: value, expiresAt: expiresAt, freqRef: c.frequencies.Front()}
+
+	return nil
+}
+
+func (c *LFUCache) Delete(key string) error {
+	c.mu.Lock()
+
+	defer c.mu.Unlock()
+
+	c.remove(key)
 
 	return nil
 }
@@ -3,9 +3,12 @@ package cache
 import (
 	"container/list"
 	"fmt"
+	"github.com/dzhdmitry/link-shorter/test"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"sync"
 	"testing"
+	"time"
 )
 
 func collectCachedEntries(cachedEntries map[string]*CachedEntry) map[string]string {
@@ -35,7 +38,7 @@ func collectFrequencies(frequencies *list.List) map[int][]string {
 }
 
 func TestGetNonExisting(t *testing.T) {
-	cache := NewLFUCache(5)
+	cache := NewLFUCache(5, &test.Clock{})
 	URL, ok, _ := cache.Get("a")
 
 	require.False(t, ok)
@@ -43,9 +46,9 @@ func TestGetNonExisting(t *testing.T) {
 }
 
 func TestGetExisting(t *testing.T) {
-	cache := NewLFUCache(5)
+	cache := NewLFUCache(5, &test.Clock{})
 
-	_ = cache.Put("a", "url")
+	_ = cache.Put("a", "url", time.Time{})
 
 	URL, ok, _ := cache.Get("a")
 
@@ -57,7 +60,7 @@ func TestGetExisting(t *testing.T) {
 }
 
 func TestPut(t *testing.T) {
-	cache := NewLFUCache(5)
+	cache := NewLFUCache(5, &test.Clock{})
 	links := [][]string{
 		{"a", "url1"},
 		{"b", "url2"},
@@ -67,7 +70,7 @@ func TestPut(t *testing.T) {
 	for _, keyURL := range links {
 		key, URL := keyURL[0], keyURL[1]
 
-		_ = cache.Put(key, URL)
+		_ = cache.Put(key, URL, time.Time{})
 
 		require.Equal(t, URL, cache.cachedEntries[key].value)
 
@@ -83,14 +86,14 @@ func TestPut(t *testing.T) {
 }
 
 func TestPutEvict(t *testing.T) {
-	cache := NewLFUCache(3)
+	cache := NewLFUCache(3, &test.Clock{})
 
-	_ = cache.Put("a", "url1")
-	_ = cache.Put("b", "url2")
-	_ = cache.Put("c", "url3")
+	_ = cache.Put("a", "url1", time.Time{})
+	_ = cache.Put("b", "url2", time.Time{})
+	_ = cache.Put("c", "url3", time.Time{})
 	_, _, _ = cache.Get("b")
 	_, _, _ = cache.Get("c")
-	_ = cache.Put("d", "url4")
+	_ = cache.Put("d", "url4", time.Time{})
 
 	require.Equal(t, map[string]string{
 		"b": "url2",
@@ -105,18 +108,18 @@ func TestPutEvict(t *testing.T) {
 }
 
 func TestPutEvictEmptyFirstFrequency(t *testing.T) {
-	cache := NewLFUCache(3)
+	cache := NewLFUCache(3, &test.Clock{})
 
-	_ = cache.Put("a", "url1")
-	_ = cache.Put("b", "url2")
-	_ = cache.Put("c", "url3")
+	_ = cache.Put("a", "url1", time.Time{})
+	_ = cache.Put("b", "url2", time.Time{})
+	_ = cache.Put("c", "url3", time.Time{})
 	_, _, _ = cache.Get("a")
 	_, _, _ = cache.Get("b")
 	_, _, _ = cache.Get("c")
 	_, _, _ = cache.Get("a")
 	_, _, _ = cache.Get("b")
 	_, _, _ = cache.Get("c")
-	_ = cache.Put("d", "url4")
+	_ = cache.Put("d", "url4", time.Time{})
 
 	require.Equal(t, map[string]string{
 		"b": "url2",
@@ -131,7 +134,7 @@ func TestPutEvictEmptyFirstFrequency(t *testing.T) {
 }
 
 func TestPutEvictFirstFrequency(t *testing.T) {
-	cache := NewLFUCache(6)
+	cache := NewLFUCache(6, &test.Clock{})
 	links := [][]string{
 		{"a", "url1"},
 		{"b", "url2"},
@@ -143,11 +146,11 @@ func TestPutEvictFirstFrequency(t *testing.T) {
 
 	for i := 0; i < len(links); i++ {
 		for j := 0; j < i+1; j++ {
-			_ = cache.Put(links[i][0], links[i][1])
+			_ = cache.Put(links[i][0], links[i][1], time.Time{})
 		}
 	}
 
-	_ = cache.Put("g", "url7")
+	_ = cache.Put("g", "url7", time.Time{})
 
 	assert.Equal(t, map[string]string{
 		"b": "url2",
@@ -169,17 +172,17 @@ func TestPutEvictFirstFrequency(t *testing.T) {
 }
 
 func TestPutEvictMultiple(t *testing.T) {
-	cache := NewLFUCache(3)
+	cache := NewLFUCache(3, &test.Clock{})
 
-	_ = cache.Put("a", "url1")
-	_ = cache.Put("b", "url2")
-	_ = cache.Put("c", "url3")
+	_ = cache.Put("a", "url1", time.Time{})
+	_ = cache.Put("b", "url2", time.Time{})
+	_ = cache.Put("c", "url3", time.Time{})
 	_, _, _ = cache.Get("a")
 	_, _, _ = cache.Get("b")
 	_, _, _ = cache.Get("c")
-	_ = cache.Put("d", "url4")
-	_ = cache.Put("e", "url5")
-	_ = cache.Put("f", "url6")
+	_ = cache.Put("d", "url4", time.Time{})
+	_ = cache.Put("e", "url5", time.Time{})
+	_ = cache.Put("f", "url6", time.Time{})
 
 	assert.Equal(t, map[string]string{
 		"b": "url2",
@@ -194,12 +197,12 @@ func TestPutEvictMultiple(t *testing.T) {
 }
 
 func TestPutRangeBetweenFrequencies(t *testing.T) {
-	cache := NewLFUCache(5)
+	cache := NewLFUCache(5, &test.Clock{})
 
-	_ = cache.Put("a", "url1")
-	_ = cache.Put("b", "url2")
-	_ = cache.Put("c", "url3")
-	_ = cache.Put("d", "url4")
+	_ = cache.Put("a", "url1", time.Time{})
+	_ = cache.Put("b", "url2", time.Time{})
+	_ = cache.Put("c", "url3", time.Time{})
+	_ = cache.Put("d", "url4", time.Time{})
 
 	_, _, _ = cache.Get("a")
 	_, _, _ = cache.Get("a")
@@ -218,3 +221,86 @@ func TestPutRangeBetweenFrequencies(t *testing.T) {
 		3: {"a"},
 	}, collectFrequencies(cache.frequencies))
 }
+
+func TestGetExpired(t *testing.T) {
+	cache := NewLFUCache(5, &test.Clock{})
+
+	_ = cache.Put("a", "url1", time.Date(2024, 2, 7, 12, 0, 0, 0, time.UTC))
+	_ = cache.Put("b", "url2", time.Date(2024, 2, 7, 13, 0, 0, 0, time.UTC))
+
+	URL, ok, _ := cache.Get("a")
+
+	require.False(t, ok)
+	require.Equal(t, "", URL)
+
+	URL, ok, _ = cache.Get("b")
+
+	require.True(t, ok)
+	require.Equal(t, "url2", URL)
+	assert.Equal(t, map[string]string{
+		"b": "url2",
+	}, collectCachedEntries(cache.cachedEntries))
+	assert.Equal(t, map[int][]string{
+		2: {"b"},
+	}, collectFrequencies(cache.frequencies))
+	assert.Equal(t, 1, cache.length)
+}
+
+func TestDelete(t *testing.T) {
+	cache := NewLFUCache(5, &test.Clock{})
+
+	_ = cache.Put("a", "url1", time.Time{})
+	_ = cache.Put("b", "url2", time.Time{})
+	_, _, _ = cache.Get("b")
+
+	require.NoError(t, cache.Delete("b"))
+	require.NoError(t, cache.Delete("c"))
+
+	_, ok, _ := cache.Get("b")
+
+	require.False(t, ok)
+	assert.Equal(t, map[string]string{
+		"a": "url1",
+	}, collectCachedEntries(cache.cachedEntries))
+	assert.Equal(t, map[int][]string{
+		1: {"a"},
+	}, collectFrequencies(cache.frequencies))
+	assert.Equal(t, 1, cache.length)
+}
+
+func TestConcurrentAccess(t *testing.T) {
+	cache := NewLFUCache(10, &test.Clock{})
+	expired := time.Date(2024, 2, 7, 12, 0, 0, 0, time.UTC)
+	var wg sync.WaitGroup
+
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				key := fmt.Sprintf("key%d", j%5)
+
+				switch (i + j) % 4 {
+				case 0:
+					_ = cache.Put(key, "url", time.Time{})
+				case 1:
+					_ = cache.Put(key, "url", expired)
+				case 2:
+					_ = cache.Delete(key)
+				default:
+					_, _, _ = cache.Get(key)
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	require.Equal(t, len(cache.cachedEntries), cache.length)
+
+	for key, entry := range cache.cachedEntries {
+		assert.Contains(t, entry.freqRef.Value.(*FrequencyEntry).keys, key)
+	}
+}
@@ -0,0 +1,34 @@
+package cache
+
+import (
+	"github.com/dzhdmitry/link-shorter/internal/links"
+)
+
+// PreviewStorage Records previews of links and removes link from cache, so link cached before its destination
+// was fetched gets the preview
+type PreviewStorage struct {
+	storage links.PreviewStorageInterface
+	cache   LinksCacheInterface
+}
+
+func NewPreviewStorage(storage links.PreviewStorageInterface, cache LinksCacheInterface) *PreviewStorage {
+	return &PreviewStorage{storage: storage, cache: cache}
+}
+
+func (s *PreviewStorage) StorePreview(link links.Link, preview links.Preview) error {
+	if err := s.storage.StorePreview(link, preview); err != nil {
+		return err
+	}
+
+	for _, key := range []string{link.Key, link.Alias} {
+		if key == "" {
+			continue
+		}
+
+		if err := s.cache.Delete(key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package cache
+
+import (
+	"github.com/dzhdmitry/link-shorter/internal/links"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+type testPreviewStorage struct {
+	stored map[string]links.Preview
+}
+
+func (s *testPreviewStorage) StorePreview(link links.Link, preview links.Preview) error {
+	s.stored[link.Key] = preview
+
+	return nil
+}
+
+func TestPreviewStorageInvalidates(t *testing.T) {
+	tests := []struct {
+		name     string
+		link     links.Link
+		expected map[string]string
+	}{
+		{"Key", links.Link{Key: "5", URL: "url"}, map[string]string{"spring-sale": "url", "6": "url6"}},
+		{"Alias", links.Link{Key: "spring-sale", URL: "url"}, map[string]string{"5": "url", "6": "url6"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := &testCache{data: map[string]string{"5": "url", "spring-sale": "url", "6": "url6"}}
+			storage := &testPreviewStorage{stored: map[string]links.Preview{}}
+			s := NewPreviewStorage(storage, cache)
+			preview := links.Preview{Title: "Spring sale", FetchedAt: time.Date(2024, 2, 7, 12, 0, 0, 0, time.UTC)}
+
+			require.NoError(t, s.StorePreview(tt.link, preview))
+			require.Equal(t, preview, storage.stored[tt.link.Key])
+			require.Equal(t, tt.expected, cache.data)
+		})
+	}
+}
@@ -4,35 +4,62 @@ import (
 	"database/sql"
 	"errors"
 	"github.com/dzhdmitry/link-shorter/cmd/app"
+	"github.com/dzhdmitry/link-shorter/internal/apikeys"
+	"github.com/dzhdmitry/link-shorter/internal/blocklist"
 	"github.com/dzhdmitry/link-shorter/internal/cache"
 	"github.com/dzhdmitry/link-shorter/internal/db"
 	"github.com/dzhdmitry/link-shorter/internal/links"
 	"github.com/dzhdmitry/link-shorter/internal/utils"
+	"github.com/dzhdmitry/link-shorter/internal/utm"
+	"github.com/dzhdmitry/link-shorter/internal/webhooks"
 	"github.com/redis/go-redis/v9"
+	"net"
+	"time"
 )
 
 const storageFilename = "tmp/storage.csv"
+const clicksFilename = "tmp/clicks.csv"
+const apiKeysFilename = "tmp/api_keys.csv"
+const utmTemplatesFilename = "tmp/utm_templates.csv"
+const webhooksFilename = "tmp/webhooks.csv"
 
 type Container struct {
 	Logger     *utils.Logger
 	Background *utils.Background
+	Clock      utils.ClockInterface
+
+	linksStorage  links.StorageInterface
+	linksCache    cache.LinksCacheInterface
+	clicksStorage links.ClicksStorageInterface
+	webhooks      webhooks.StorageInterface
+	urlPolicy     *app.URLPolicy
+}
+
+// usesFiles Returns true if data other than links is kept in files: SQLite storage keeps links only
+func usesFiles(config app.Config) bool {
+	return config.ProjectStorageType == app.StorageTypeFile || config.ProjectStorageType == app.StorageTypeSQLite
 }
 
-func (c *Container) createFileStorage(async bool) (links.StorageInterface, error) {
+func (c *Container) createFileStorage(async bool, converter *links.KeyConverter) (links.StorageInterface, error) {
 	if async {
-		return links.NewFileStorageAsync(c.Logger, c.Background, storageFilename)
+		return links.NewFileStorageAsync(c.Logger, c.Background, storageFilename, converter)
 	}
 
-	return links.NewFileStorage(storageFilename)
+	return links.NewFileStorage(storageFilename, converter)
 }
 
 func (c *Container) createStorage(config app.Config) (links.StorageInterface, *sql.DB, error) {
 	var storage links.StorageInterface
 	var dbConn *sql.DB
 	var err error
+	converter, err := links.NewKeyConverter(config.KeyAlphabet, config.KeySecret, config.KeySequentialMaxID)
+
+	if err != nil {
+		return nil, nil, err
+	}
 
 	if config.ProjectStorageType == app.StorageTypeFile {
-		storage, err = c.createFileStorage(config.FileAsync)
+		storage, err = c.createFileStorage(config.FileAsync, converter)
 	} else if config.ProjectStorageType == app.StorageTypePostgres {
 		dbConn, err = db.OpenPostgres(config.DbDSN, config.DbMaxOpenConns, config.DbMaxIdleConns, config.DbMaxIdleTime)
 
@@ -40,7 +67,25 @@ func (c *Container) createStorage(config app.Config) (links.StorageInterface, *s
 			return nil, dbConn, err
 		}
 
-		storage = links.NewSQLStorage(dbConn, config.DbTimeout)
+		sqlStorage := links.NewSQLStorage(dbConn, config.DbTimeout, converter)
+		storage, err = sqlStorage, sqlStorage.CheckAlphabet()
+
+		if err == nil {
+			err = sqlStorage.CheckSequentialMaxID()
+		}
+	} else if config.ProjectStorageType == app.StorageTypeSQLite {
+		dbConn, err = db.OpenSQLite(config.SQLitePath)
+
+		if err != nil {
+			return nil, dbConn, err
+		}
+
+		sqliteStorage := links.NewSQLiteStorage(dbConn, config.DbTimeout, converter)
+		storage, err = sqliteStorage, sqliteStorage.CheckAlphabet()
+
+		if err == nil {
+			err = sqliteStorage.CheckSequentialMaxID()
+		}
 	} else {
 		return nil, nil, errors.New("unknown storage type: " + config.ProjectStorageType)
 	}
@@ -58,7 +103,7 @@ func (c *Container) createCache(config app.Config) (cache.LinksCacheInterface, *
 	var err error
 
 	if config.CacheType == app.CacheTypeInMemory {
-		linksCache = cache.NewLFUCache(config.CacheCapacity)
+		linksCache = cache.NewLFUCache(config.CacheCapacity, c.Clock)
 	} else if config.CacheType == app.CacheTypeRedis {
 		rdb, err = db.OpenRedis(config.CacheRedisDSN)
 
@@ -81,8 +126,10 @@ func (c *Container) CreateLinksCollection(config app.Config) (app.LinksCollectio
 		return nil, dbConn, nil, err
 	}
 
+	c.linksStorage = storage
+
 	var linksCollection app.LinksCollectionInterface
-	linksCollection = links.NewCollection(storage)
+	linksCollection = links.NewCollection(storage, c.Clock)
 
 	if config.CacheType == app.CacheTypeDisabled {
 		return linksCollection, dbConn, nil, nil
@@ -94,7 +141,257 @@ func (c *Container) CreateLinksCollection(config app.Config) (app.LinksCollectio
 		return nil, dbConn, rdb, err
 	}
 
+	c.linksCache = linksCache
 	linksCollection = cache.NewCachedCollection(linksCollection, linksCache)
 
 	return linksCollection, dbConn, rdb, nil
 }
+
+func (c *Container) createClicksStorage(config app.Config, dbConn *sql.DB) (links.ClicksStorageInterface, error) {
+	if c.clicksStorage != nil {
+		return c.clicksStorage, nil
+	}
+
+	if usesFiles(config) {
+		c.clicksStorage = links.NewFileClicksStorage(clicksFilename)
+	} else if config.ProjectStorageType == app.StorageTypePostgres {
+		c.clicksStorage = links.NewSQLClicksStorage(dbConn, config.DbTimeout)
+	} else {
+		return nil, errors.New("unknown storage type: " + config.ProjectStorageType)
+	}
+
+	return c.clicksStorage, nil
+}
+
+func (c *Container) CreateClicksRecorder(config app.Config, dbConn *sql.DB) (app.ClicksRecorderInterface, error) {
+	storage, err := c.createClicksStorage(config, dbConn)
+
+	if err != nil {
+		return nil, err
+	}
+
+	flushTime, err := time.ParseDuration(config.ClicksFlushTime)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return links.NewClicksRecorder(
+		storage,
+		c.Logger,
+		c.Background,
+		config.ClicksBufferSize,
+		config.ClicksBatchSize,
+		flushTime,
+	), nil
+}
+
+// CreateStats Must be called after CreateLinksCollection, stats are computed over the same links storage
+func (c *Container) CreateStats(config app.Config, dbConn *sql.DB) (app.StatsInterface, error) {
+	if c.linksStorage == nil {
+		return nil, errors.New("links storage is not created")
+	}
+
+	clicksStorage, err := c.createClicksStorage(config, dbConn)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return links.NewStats(c.linksStorage, clicksStorage), nil
+}
+
+func (c *Container) CreateAPIKeys(config app.Config, dbConn *sql.DB) (app.APIKeysInterface, error) {
+	if usesFiles(config) {
+		return apikeys.NewFileStorage(apiKeysFilename)
+	} else if config.ProjectStorageType == app.StorageTypePostgres {
+		return apikeys.NewSQLStorage(dbConn, config.DbTimeout), nil
+	}
+
+	return nil, errors.New("unknown storage type: " + config.ProjectStorageType)
+}
+
+// CreateBlocklist Returns nil if blocklist file is not configured
+func (c *Container) CreateBlocklist(config app.Config) (app.BlocklistInterface, error) {
+	if config.BlocklistFile == "" {
+		return nil, nil
+	}
+
+	reloadInterval, err := time.ParseDuration(config.BlocklistReload)
+
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := blocklist.NewBlocklist(config.BlocklistFile, c.Logger, c.Background, reloadInterval)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (c *Container) CreateURLPolicy(config app.Config) (*app.URLPolicy, error) {
+	var resolver app.ResolverInterface
+
+	if config.URLResolveHosts {
+		resolver = net.DefaultResolver
+	}
+
+	policy, err := app.NewURLPolicy(config.ExtraSchemes(), config.InternalExceptions(), resolver)
+
+	if err != nil {
+		return nil, err
+	}
+
+	c.urlPolicy = policy
+
+	return policy, nil
+}
+
+// CreateHealthChecker Must be called after CreateLinksCollection and CreateURLPolicy, destinations of the same
+// links storage are checked by client which refuses internal addresses. Returns nil if health check is disabled
+func (c *Container) CreateHealthChecker(config app.Config) (app.HealthCheckerInterface, error) {
+	if !config.HealthCheck {
+		return nil, nil
+	}
+
+	if c.linksStorage == nil {
+		return nil, errors.New("links storage is not created")
+	}
+
+	if c.urlPolicy == nil {
+		return nil, errors.New("URL policy is not created")
+	}
+
+	interval, err := time.ParseDuration(config.HealthInterval)
+
+	if err != nil {
+		return nil, err
+	}
+
+	timeout, err := time.ParseDuration(config.HealthTimeout)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var storage links.HealthStorageInterface = c.linksStorage
+
+	// cached links keep health, so they are removed when destination breaks or recovers
+	if c.linksCache != nil {
+		storage = cache.NewHealthStorage(c.linksStorage, c.linksCache)
+	}
+
+	return links.NewHealthChecker(
+		storage,
+		c.urlPolicy.NewHTTPClient(timeout),
+		c.Clock,
+		c.Logger,
+		c.Background,
+		interval,
+		config.HealthBatchSize,
+	), nil
+}
+
+// CreateUnfurler Must be called after CreateLinksCollection and CreateURLPolicy, previews are stored to the same
+// links storage and pages are fetched by client which refuses internal addresses. Returns nil if unfurling is disabled
+func (c *Container) CreateUnfurler(config app.Config) (app.UnfurlerInterface, error) {
+	if !config.Unfurl {
+		return nil, nil
+	}
+
+	if c.linksStorage == nil {
+		return nil, errors.New("links storage is not created")
+	}
+
+	if c.urlPolicy == nil {
+		return nil, errors.New("URL policy is not created")
+	}
+
+	timeout, err := time.ParseDuration(config.UnfurlTimeout)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var storage links.PreviewStorageInterface = c.linksStorage
+
+	// link could be cached before its destination was fetched
+	if c.linksCache != nil {
+		storage = cache.NewPreviewStorage(c.linksStorage, c.linksCache)
+	}
+
+	return links.NewUnfurler(
+		storage,
+		c.urlPolicy.NewHTTPClient(timeout),
+		c.Clock,
+		c.Logger,
+		c.Background,
+		int64(config.UnfurlMaxSize),
+	), nil
+}
+
+func (c *Container) CreateUTMTemplates(config app.Config, dbConn *sql.DB) (app.UTMTemplatesInterface, error) {
+	if usesFiles(config) {
+		return utm.NewFileStorage(utmTemplatesFilename)
+	} else if config.ProjectStorageType == app.StorageTypePostgres {
+		return utm.NewSQLStorage(dbConn, config.DbTimeout), nil
+	}
+
+	return nil, errors.New("unknown storage type: " + config.ProjectStorageType)
+}
+
+func (c *Container) CreateWebhooks(config app.Config, dbConn *sql.DB) (app.WebhooksInterface, error) {
+	var err error
+
+	if usesFiles(config) {
+		c.webhooks, err = webhooks.NewFileStorage(webhooksFilename)
+	} else if config.ProjectStorageType == app.StorageTypePostgres {
+		c.webhooks = webhooks.NewSQLStorage(dbConn, config.DbTimeout)
+	} else {
+		err = errors.New("unknown storage type: " + config.ProjectStorageType)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return c.webhooks, nil
+}
+
+// CreateDispatcher Must be called after CreateWebhooks and CreateURLPolicy, deliveries are queued in the same storage
+// as subscriptions and are sent by client which refuses internal addresses
+func (c *Container) CreateDispatcher(config app.Config) (app.DispatcherInterface, error) {
+	if c.webhooks == nil {
+		return nil, errors.New("webhooks storage is not created")
+	}
+
+	if c.urlPolicy == nil {
+		return nil, errors.New("URL policy is not created")
+	}
+
+	timeout, err := time.ParseDuration(config.WebhookTimeout)
+
+	if err != nil {
+		return nil, err
+	}
+
+	retryDelay, err := time.ParseDuration(config.WebhookRetryDelay)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return webhooks.NewDispatcher(
+		c.webhooks,
+		c.urlPolicy.NewHTTPClient(timeout),
+		c.Clock,
+		c.Logger,
+		c.Background,
+		config.WebhookBufferSize,
+		config.WebhookMaxAttempts,
+		retryDelay,
+	), nil
+}
@@ -3,11 +3,20 @@ package db
 import (
 	"context"
 	"database/sql"
+	"embed"
+	"errors"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
 	_ "github.com/lib/pq"
 	"github.com/redis/go-redis/v9"
+	_ "modernc.org/sqlite"
 	"time"
 )
 
+//go:embed sqlite_migrations/*.sql
+var sqliteMigrations embed.FS
+
 func OpenPostgres(dsn string, maxOpenConns int, maxIdleConns int, maxIdleTime string) (*sql.DB, error) {
 	db, err := sql.Open("postgres", dsn)
 
@@ -39,6 +48,52 @@ func OpenPostgres(dsn string, maxOpenConns int, maxIdleConns int, maxIdleTime st
 	return db, nil
 }
 
+// OpenSQLite Opens SQLite database file, which is created if it does not exist, and applies embedded migrations to it
+func OpenSQLite(path string) (*sql.DB, error) {
+	// times are stored as text in UTC, which keeps them comparable; writers wait for each other instead of failing
+	dsn := "file:" + path + "?_time_format=sqlite&_txlock=immediate" +
+		"&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)"
+	db, err := sql.Open("sqlite", dsn)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = migrateSQLite(db); err != nil {
+		db.Close()
+
+		return nil, err
+	}
+
+	return db, nil
+}
+
+func migrateSQLite(db *sql.DB) error {
+	source, err := iofs.New(sqliteMigrations, "sqlite_migrations")
+
+	if err != nil {
+		return err
+	}
+
+	driver, err := sqlite.WithInstance(db, &sqlite.Config{})
+
+	if err != nil {
+		return err
+	}
+
+	m, err := migrate.NewWithInstance("iofs", source, "sqlite", driver)
+
+	if err != nil {
+		return err
+	}
+
+	if err = m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+
+	return nil
+}
+
 func OpenRedis(DSN string) (*redis.Client, error) {
 	opts, err := redis.ParseURL(DSN)
 
@@ -0,0 +1,36 @@
+-- links and settings tables of postgreSQL migrations in one, lists are stored as JSON and times as text in UTC
+CREATE TABLE IF NOT EXISTS links (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    url varchar(2000) NOT NULL,
+    expires_at datetime NULL,
+    alias varchar(64) NULL UNIQUE,
+    disabled boolean NOT NULL DEFAULT false,
+    deleted_at datetime NULL,
+    url_hash blob NULL UNIQUE,
+    password_hash varchar(255) NULL,
+    owner varchar(32) NOT NULL DEFAULT '',
+    created_at datetime NULL,
+    title varchar(255) NOT NULL DEFAULT '',
+    description text NOT NULL DEFAULT '',
+    tags text NOT NULL DEFAULT '[]',
+    notes text NOT NULL DEFAULT '',
+    utm_template varchar(64) NOT NULL DEFAULT '',
+    rules text NOT NULL DEFAULT '[]',
+    variants text NOT NULL DEFAULT '[]',
+    health_status integer NOT NULL DEFAULT 0,
+    health_redirects text NOT NULL DEFAULT '[]',
+    health_error text NOT NULL DEFAULT '',
+    health_checked_at datetime NULL,
+    fallback text NOT NULL DEFAULT '',
+    down boolean NOT NULL DEFAULT false,
+    preview text NULL
+);
+
+CREATE INDEX IF NOT EXISTS links_owner_created_at_idx ON links (owner, COALESCE(created_at, '0001-01-01 00:00:00+00:00'), id)
+    WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS links_health_checked_at_idx ON links (health_checked_at, id) WHERE deleted_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS settings (
+    name varchar(64) PRIMARY KEY,
+    value text NOT NULL
+);
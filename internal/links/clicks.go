@@ -0,0 +1,129 @@
+package links
+
+import (
+	"errors"
+	"github.com/dzhdmitry/link-shorter/internal/utils"
+	"sync"
+	"time"
+)
+
+var ErrClicksBufferFull = errors.New("clicks buffer is full, click is dropped")
+
+// Click Variant is number of A/B split variant visitor is sent to, 0 if link has no variants
+type Click struct {
+	Key       string
+	ClickedAt time.Time
+	Referrer  string
+	UserAgent string
+	IP        string
+	Variant   int
+}
+
+type ClicksStorageInterface interface {
+	StoreClicks(clicks []Click) error
+	GetStats(keys []string, from, to time.Time) (LinkStats, error)
+}
+
+// ClicksRecorder Buffers clicks and flushes them to storage in batches in background
+type ClicksRecorder struct {
+	storage       ClicksStorageInterface
+	logger        *utils.Logger
+	clicks        chan Click
+	batchSize     int
+	flushInterval time.Duration
+	closed        bool
+	mu            sync.RWMutex
+}
+
+func NewClicksRecorder(
+	storage ClicksStorageInterface,
+	logger *utils.Logger,
+	background *utils.Background,
+	bufferSize int,
+	batchSize int,
+	flushInterval time.Duration,
+) *ClicksRecorder {
+	r := ClicksRecorder{
+		storage:       storage,
+		logger:        logger,
+		clicks:        make(chan Click, bufferSize),
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+	}
+
+	background.Run(r.run)
+
+	return &r
+}
+
+// Record Puts click to buffer without blocking, click is dropped if buffer is full or recorder is closed
+func (r *ClicksRecorder) Record(click Click) {
+	r.mu.RLock()
+
+	defer r.mu.RUnlock()
+
+	if r.closed {
+		return
+	}
+
+	select {
+	case r.clicks <- click:
+	default:
+		r.logger.LogError(ErrClicksBufferFull)
+	}
+}
+
+// Close Stops accepting clicks, buffered clicks are flushed in background
+func (r *ClicksRecorder) Close() {
+	r.mu.Lock()
+
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return
+	}
+
+	r.closed = true
+	close(r.clicks)
+}
+
+func (r *ClicksRecorder) run() {
+	ticker := time.NewTicker(r.flushInterval)
+
+	defer ticker.Stop()
+
+	batch := make([]Click, 0, r.batchSize)
+
+	for {
+		select {
+		case click, ok := <-r.clicks:
+			if !ok {
+				r.flush(batch)
+
+				return
+			}
+
+			batch = append(batch, click)
+
+			if len(batch) >= r.batchSize {
+				r.flush(batch)
+				batch = make([]Click, 0, r.batchSize)
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				r.flush(batch)
+				batch = make([]Click, 0, r.batchSize)
+			}
+		}
+	}
+}
+
+func (r *ClicksRecorder) flush(batch []Click) {
+	if len(batch) == 0 {
+		return
+	}
+
+	if err := r.storage.StoreClicks(batch); err != nil {
+		r.logger.LogError(err)
+	}
+}
@@ -0,0 +1,127 @@
+package links
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// FileClicksStorage Appends clicks to CSV file, one record "key,clickedAt,referrer,userAgent,IP[,variant]" per click,
+// variant is written for links with A/B split only
+type FileClicksStorage struct {
+	filename string
+	mu       sync.Mutex
+}
+
+func NewFileClicksStorage(filename string) *FileClicksStorage {
+	return &FileClicksStorage{filename: filename}
+}
+
+func (s *FileClicksStorage) StoreClicks(clicks []Click) error {
+	if len(clicks) == 0 {
+		return nil
+	}
+
+	records := make([][]string, 0, len(clicks))
+
+	for _, click := range clicks {
+		record := []string{
+			click.Key,
+			click.ClickedAt.UTC().Format(time.RFC3339),
+			click.Referrer,
+			click.UserAgent,
+			click.IP,
+		}
+
+		if click.Variant > 0 {
+			record = append(record, strconv.Itoa(click.Variant))
+		}
+
+		records = append(records, record)
+	}
+
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	file, err := os.OpenFile(s.filename, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	w := csv.NewWriter(file)
+	defer w.Flush()
+
+	return w.WriteAll(records)
+}
+
+// GetStats Reads whole file and aggregates clicks of given keys made in range [from, to)
+func (s *FileClicksStorage) GetStats(keys []string, from, to time.Time) (LinkStats, error) {
+	collector := newStatsCollector(keys, from, to)
+
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	file, err := os.Open(s.filename)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return collector.stats(), nil
+	}
+
+	if err != nil {
+		return LinkStats{}, err
+	}
+
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+
+	for {
+		record, err := reader.Read()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return LinkStats{}, err
+		}
+
+		if len(record) != 5 && len(record) != 6 {
+			return LinkStats{}, errors.New("file has malformed data")
+		}
+
+		clickedAt, err := time.Parse(time.RFC3339, record[1])
+
+		if err != nil {
+			return LinkStats{}, err
+		}
+
+		click := Click{
+			Key:       record[0],
+			ClickedAt: clickedAt,
+			Referrer:  record[2],
+			UserAgent: record[3],
+			IP:        record[4],
+		}
+
+		if len(record) == 6 {
+			if click.Variant, err = strconv.Atoi(record[5]); err != nil {
+				return LinkStats{}, err
+			}
+		}
+
+		collector.add(click)
+	}
+
+	return collector.stats(), nil
+}
@@ -0,0 +1,49 @@
+package links
+
+import (
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestStoreClicks(t *testing.T) {
+	_ = os.Remove(testdata + "/results/test_clicks.csv")
+	s := NewFileClicksStorage(testdata + "/results/test_clicks.csv")
+
+	err := s.StoreClicks([]Click{
+		{Key: "1", ClickedAt: time.Date(2024, 2, 7, 12, 0, 0, 0, time.UTC), IP: "192.168.10.0"},
+		{Key: "spring-sale", ClickedAt: time.Date(2024, 2, 7, 13, 0, 0, 0, time.UTC), Referrer: "https://example.org", UserAgent: "Mozilla/5.0, Linux"},
+	})
+
+	require.NoError(t, err)
+
+	err = s.StoreClicks([]Click{
+		{Key: "2", ClickedAt: time.Date(2024, 2, 8, 12, 0, 0, 0, time.UTC)},
+		{Key: "3", ClickedAt: time.Date(2024, 2, 8, 13, 0, 0, 0, time.UTC), Variant: 2},
+	})
+
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(testdata + "/results/test_clicks.csv")
+
+	require.NoError(t, err)
+	require.Equal(t, "1,2024-02-07T12:00:00Z,,,192.168.10.0\n"+
+		"spring-sale,2024-02-07T13:00:00Z,https://example.org,\"Mozilla/5.0, Linux\",\n"+
+		"2,2024-02-08T12:00:00Z,,,\n"+
+		"3,2024-02-08T13:00:00Z,,,,2\n", string(data))
+}
+
+type FileClicksStorageSuite struct {
+	clicksStorageSuite
+}
+
+func (s *FileClicksStorageSuite) SetupTest() {
+	_ = os.Remove(testdata + "/results/test_clicks.csv")
+	s.storage = NewFileClicksStorage(testdata + "/results/test_clicks.csv")
+}
+
+func TestFileClicksStorage(t *testing.T) {
+	suite.Run(t, new(FileClicksStorageSuite))
+}
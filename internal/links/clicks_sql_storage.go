@@ -0,0 +1,176 @@
+package links
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type SQLClicksStorage struct {
+	db      *sql.DB
+	timeout time.Duration
+}
+
+func NewSQLClicksStorage(db *sql.DB, timeout int) *SQLClicksStorage {
+	return &SQLClicksStorage{
+		db:      db,
+		timeout: time.Second * time.Duration(timeout),
+	}
+}
+
+func (s *SQLClicksStorage) StoreClicks(clicks []Click) error {
+	if len(clicks) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+
+	defer cancel()
+
+	var placeholders []string
+	var values []interface{}
+	n := 1
+
+	for _, click := range clicks {
+		placeholders = append(placeholders, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d)", n, n+1, n+2, n+3, n+4, n+5))
+		values = append(values, click.Key, click.ClickedAt, click.Referrer, click.UserAgent, click.IP, click.Variant)
+		n += 6
+	}
+
+	query := "INSERT INTO clicks(key, clicked_at, referrer, user_agent, ip, variant) VALUES " + strings.Join(placeholders, ", ")
+	_, err := s.db.ExecContext(ctx, query, values...)
+
+	return err
+}
+
+// clicksCondition Makes condition selecting clicks of given keys made in range [from, to)
+func (s *SQLClicksStorage) clicksCondition(keys []string, from, to time.Time) (string, []interface{}) {
+	placeholders := make([]string, 0, len(keys))
+	values := []interface{}{from, to}
+
+	for i, key := range keys {
+		placeholders = append(placeholders, fmt.Sprintf("$%d", i+3))
+		values = append(values, key)
+	}
+
+	return "key IN (" + strings.Join(placeholders, ", ") + ") AND clicked_at >= $1 AND clicked_at < $2", values
+}
+
+func (s *SQLClicksStorage) countBy(ctx context.Context, query string, values []interface{}) (map[string]int, error) {
+	rows, err := s.db.QueryContext(ctx, query, values...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	counts := map[string]int{}
+
+	for rows.Next() {
+		var name string
+		var count int
+
+		if err = rows.Scan(&name, &count); err != nil {
+			return nil, err
+		}
+
+		counts[name] += count
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+func (s *SQLClicksStorage) GetStats(keys []string, from, to time.Time) (LinkStats, error) {
+	if len(keys) == 0 {
+		return newStatsCollector(keys, from, to).stats(), nil
+	}
+
+	var stats LinkStats
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+
+	defer cancel()
+
+	condition, values := s.clicksCondition(keys, from, to)
+	query := "SELECT count(*), count(DISTINCT (ip, user_agent)) FROM clicks WHERE " + condition
+	err := s.db.QueryRowContext(ctx, query, values...).Scan(&stats.TotalClicks, &stats.UniqueVisitors)
+
+	if err != nil {
+		return LinkStats{}, err
+	}
+
+	query = "SELECT to_char(clicked_at AT TIME ZONE 'UTC', 'YYYY-MM-DD'), count(*) FROM clicks WHERE " + condition + " GROUP BY 1"
+	daily, err := s.countBy(ctx, query, values)
+
+	if err != nil {
+		return LinkStats{}, err
+	}
+
+	query = "SELECT referrer, count(*) FROM clicks WHERE " + condition + " AND referrer <> '' GROUP BY referrer"
+	referrers, err := s.countBy(ctx, query, values)
+
+	if err != nil {
+		return LinkStats{}, err
+	}
+
+	query = "SELECT user_agent, count(*) FROM clicks WHERE " + condition + " GROUP BY user_agent"
+	userAgents, err := s.countBy(ctx, query, values)
+
+	if err != nil {
+		return LinkStats{}, err
+	}
+
+	families := make(map[string]int, len(userAgents))
+
+	for userAgent, count := range userAgents {
+		families[userAgentFamily(userAgent)] += count
+	}
+
+	query = "SELECT variant, count(*), count(DISTINCT (ip, user_agent)) FROM clicks WHERE " + condition +
+		" AND variant > 0 GROUP BY variant ORDER BY variant"
+
+	if stats.Variants, err = s.variantClicks(ctx, query, values); err != nil {
+		return LinkStats{}, err
+	}
+
+	stats.Daily = dailyBreakdown(daily, from, to)
+	stats.TopReferrers = topCounters(referrers, statsTopLimit)
+	stats.TopUserAgents = topCounters(families, statsTopLimit)
+
+	return stats, nil
+}
+
+func (s *SQLClicksStorage) variantClicks(ctx context.Context, query string, values []interface{}) ([]VariantClicks, error) {
+	rows, err := s.db.QueryContext(ctx, query, values...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var variants []VariantClicks
+
+	for rows.Next() {
+		var variant VariantClicks
+
+		if err = rows.Scan(&variant.Variant, &variant.Clicks, &variant.UniqueVisitors); err != nil {
+			return nil, err
+		}
+
+		variants = append(variants, variant)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return variants, nil
+}
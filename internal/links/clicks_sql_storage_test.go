@@ -0,0 +1,69 @@
+package links
+
+import (
+	"database/sql"
+	"github.com/dzhdmitry/link-shorter/internal/db"
+	"github.com/dzhdmitry/link-shorter/test"
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+type SQLClicksStorageSuite struct {
+	clicksStorageSuite
+	db *sql.DB
+}
+
+func (s *SQLClicksStorageSuite) SetupSuite() {
+	openDB, err := db.OpenPostgres(test.PrepareTestDB(), 25, 25, "15m")
+
+	if err != nil {
+		panic(err)
+	}
+
+	s.db = openDB
+}
+
+func (s *SQLClicksStorageSuite) SetupTest() {
+	_, _ = s.db.Exec("TRUNCATE clicks")
+	s.storage = NewSQLClicksStorage(s.db, 1)
+}
+
+func (s *SQLClicksStorageSuite) TearDownSuite() {
+	if s.db != nil {
+		s.db.Close()
+	}
+}
+
+func (s *SQLClicksStorageSuite) TestStoreClicks() {
+	clicks := []Click{
+		{Key: "1", ClickedAt: time.Date(2024, 2, 7, 12, 0, 0, 0, time.UTC), IP: "192.168.10.0"},
+		{Key: "spring-sale", ClickedAt: time.Date(2024, 2, 7, 13, 0, 0, 0, time.UTC), Referrer: "https://example.org", UserAgent: "Mozilla/5.0", Variant: 2},
+	}
+
+	s.NoError(s.storage.StoreClicks([]Click{}))
+	s.NoError(s.storage.StoreClicks(clicks))
+
+	rows, err := s.db.Query("SELECT key, clicked_at, referrer, user_agent, ip, variant FROM clicks ORDER BY id")
+
+	s.Require().NoError(err)
+
+	defer rows.Close()
+
+	var stored []Click
+
+	for rows.Next() {
+		var click Click
+
+		s.Require().NoError(rows.Scan(&click.Key, &click.ClickedAt, &click.Referrer, &click.UserAgent, &click.IP, &click.Variant))
+
+		click.ClickedAt = click.ClickedAt.UTC()
+		stored = append(stored, click)
+	}
+
+	s.Equal(clicks, stored)
+}
+
+func TestSQLClicksStorage(t *testing.T) {
+	suite.Run(t, new(SQLClicksStorageSuite))
+}
@@ -0,0 +1,112 @@
+package links
+
+import (
+	"github.com/stretchr/testify/suite"
+	"time"
+)
+
+// clicksStorageSuite Shared tests of ClicksStorageInterface implementations, storage must be empty before each test
+type clicksStorageSuite struct {
+	suite.Suite
+	storage ClicksStorageInterface
+}
+
+func (s *clicksStorageSuite) TestGetStats() {
+	chrome := "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/121.0.0.0 Safari/537.36"
+	firefox := "Mozilla/5.0 (X11; Linux x86_64; rv:122.0) Gecko/20100101 Firefox/122.0"
+
+	err := s.storage.StoreClicks([]Click{
+		{Key: "1", ClickedAt: time.Date(2024, 2, 4, 23, 59, 59, 0, time.UTC), UserAgent: chrome, IP: "192.168.10.0"},
+		{Key: "1", ClickedAt: time.Date(2024, 2, 5, 10, 0, 0, 0, time.UTC), Referrer: "https://news.example.org", UserAgent: chrome, IP: "192.168.10.0"},
+		{Key: "1", ClickedAt: time.Date(2024, 2, 5, 11, 0, 0, 0, time.UTC), Referrer: "https://news.example.org", UserAgent: chrome, IP: "192.168.10.0"},
+		{Key: "spring-sale", ClickedAt: time.Date(2024, 2, 6, 9, 0, 0, 0, time.UTC), Referrer: "https://mail.example.org", UserAgent: firefox, IP: "10.1.2.0"},
+		{Key: "2", ClickedAt: time.Date(2024, 2, 6, 10, 0, 0, 0, time.UTC), Referrer: "https://mail.example.org", UserAgent: firefox, IP: "10.1.2.0"},
+	})
+
+	s.Require().NoError(err)
+
+	err = s.storage.StoreClicks([]Click{
+		{Key: "1", ClickedAt: time.Date(2024, 2, 7, 23, 59, 59, 0, time.UTC), UserAgent: "curl/8.5.0", IP: "10.1.3.0"},
+		{Key: "1", ClickedAt: time.Date(2024, 2, 8, 0, 0, 0, 0, time.UTC), UserAgent: chrome, IP: "10.1.4.0"},
+	})
+
+	s.Require().NoError(err)
+
+	tests := []struct {
+		name     string
+		keys     []string
+		expected LinkStats
+	}{
+		{"Key and alias", []string{"1", "spring-sale"}, LinkStats{
+			TotalClicks:    4,
+			UniqueVisitors: 3,
+			Daily: []DailyClicks{
+				{Date: "2024-02-05", Clicks: 2},
+				{Date: "2024-02-06", Clicks: 1},
+				{Date: "2024-02-07", Clicks: 1},
+			},
+			TopReferrers: []Counter{
+				{Name: "https://news.example.org", Count: 2},
+				{Name: "https://mail.example.org", Count: 1},
+			},
+			TopUserAgents: []Counter{
+				{Name: "Chrome", Count: 2},
+				{Name: "Firefox", Count: 1},
+				{Name: "curl", Count: 1},
+			},
+		}},
+		{"Without clicks", []string{"3"}, LinkStats{
+			Daily: []DailyClicks{
+				{Date: "2024-02-05", Clicks: 0},
+				{Date: "2024-02-06", Clicks: 0},
+				{Date: "2024-02-07", Clicks: 0},
+			},
+			TopReferrers:  []Counter{},
+			TopUserAgents: []Counter{},
+		}},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			from := time.Date(2024, 2, 5, 0, 0, 0, 0, time.UTC)
+			to := time.Date(2024, 2, 8, 0, 0, 0, 0, time.UTC)
+			stats, err := s.storage.GetStats(tt.keys, from, to)
+
+			s.NoError(err)
+			s.Equal(tt.expected, stats)
+		})
+	}
+}
+
+func (s *clicksStorageSuite) TestGetStatsEmpty() {
+	from := time.Date(2024, 2, 7, 0, 0, 0, 0, time.UTC)
+	stats, err := s.storage.GetStats([]string{"1"}, from, from.AddDate(0, 0, 1))
+
+	s.NoError(err)
+	s.Equal(LinkStats{
+		Daily:         []DailyClicks{{Date: "2024-02-07", Clicks: 0}},
+		TopReferrers:  []Counter{},
+		TopUserAgents: []Counter{},
+	}, stats)
+}
+
+func (s *clicksStorageSuite) TestGetStatsVariants() {
+	err := s.storage.StoreClicks([]Click{
+		{Key: "1", ClickedAt: time.Date(2024, 2, 7, 10, 0, 0, 0, time.UTC), UserAgent: "curl/8.5.0", IP: "10.1.2.0", Variant: 2},
+		{Key: "1", ClickedAt: time.Date(2024, 2, 7, 11, 0, 0, 0, time.UTC), UserAgent: "curl/8.5.0", IP: "10.1.2.0", Variant: 2},
+		{Key: "1", ClickedAt: time.Date(2024, 2, 7, 12, 0, 0, 0, time.UTC), UserAgent: "curl/8.5.0", IP: "10.1.3.0", Variant: 1},
+		{Key: "1", ClickedAt: time.Date(2024, 2, 7, 13, 0, 0, 0, time.UTC), UserAgent: "curl/8.5.0", IP: "10.1.4.0", Variant: 2},
+		{Key: "1", ClickedAt: time.Date(2024, 2, 7, 14, 0, 0, 0, time.UTC), UserAgent: "curl/8.5.0", IP: "10.1.5.0"},
+	})
+
+	s.Require().NoError(err)
+
+	from := time.Date(2024, 2, 7, 0, 0, 0, 0, time.UTC)
+	stats, err := s.storage.GetStats([]string{"1"}, from, from.AddDate(0, 0, 1))
+
+	s.NoError(err)
+	s.Equal([]VariantClicks{
+		{Variant: 1, Clicks: 1, UniqueVisitors: 1},
+		{Variant: 2, Clicks: 3, UniqueVisitors: 2},
+	}, stats.Variants)
+}
@@ -0,0 +1,99 @@
+package links
+
+import (
+	"errors"
+	"github.com/dzhdmitry/link-shorter/internal/utils"
+	"github.com/dzhdmitry/link-shorter/test"
+	"github.com/stretchr/testify/require"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testClicksStorage struct {
+	batches [][]Click
+	err     error
+	mu      sync.Mutex
+}
+
+func (s *testClicksStorage) StoreClicks(clicks []Click) error {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	s.batches = append(s.batches, clicks)
+
+	return s.err
+}
+
+func (s *testClicksStorage) GetStats(keys []string, from, to time.Time) (LinkStats, error) {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	collector := newStatsCollector(keys, from, to)
+
+	for _, batch := range s.batches {
+		for _, click := range batch {
+			collector.add(click)
+		}
+	}
+
+	return collector.stats(), s.err
+}
+
+func TestClicksRecorderBatches(t *testing.T) {
+	storage := &testClicksStorage{}
+	background := &utils.Background{}
+	r := NewClicksRecorder(storage, utils.NewLogger(io.Discard, &test.Clock{}), background, 10, 2, time.Hour)
+	clicks := []Click{
+		{Key: "1", ClickedAt: (&test.Clock{}).Now(), IP: "192.168.10.0"},
+		{Key: "2", ClickedAt: (&test.Clock{}).Now(), Referrer: "https://example.org"},
+		{Key: "spring-sale", ClickedAt: (&test.Clock{}).Now(), UserAgent: "Mozilla/5.0"},
+	}
+
+	for _, click := range clicks {
+		r.Record(click)
+	}
+
+	r.Close()
+	r.Record(Click{Key: "3"})
+	background.Wait()
+
+	require.Equal(t, [][]Click{clicks[:2], clicks[2:]}, storage.batches)
+}
+
+func TestClicksRecorderFlushInterval(t *testing.T) {
+	storage := &testClicksStorage{}
+	background := &utils.Background{}
+	r := NewClicksRecorder(storage, utils.NewLogger(io.Discard, &test.Clock{}), background, 10, 100, time.Millisecond)
+
+	r.Record(Click{Key: "1"})
+
+	require.Eventually(t, func() bool {
+		storage.mu.Lock()
+
+		defer storage.mu.Unlock()
+
+		return len(storage.batches) == 1
+	}, time.Second, time.Millisecond)
+
+	r.Close()
+	background.Wait()
+
+	require.Equal(t, [][]Click{{{Key: "1"}}}, storage.batches)
+}
+
+func TestClicksRecorderStorageError(t *testing.T) {
+	w := &test.Writer{}
+	storage := &testClicksStorage{err: errors.New("storage is unavailable")}
+	background := &utils.Background{}
+	r := NewClicksRecorder(storage, utils.NewLogger(w, &test.Clock{}), background, 10, 100, time.Hour)
+
+	r.Record(Click{Key: "1"})
+	r.Close()
+	background.Wait()
+
+	require.Equal(t, []string{"ERROR: [2024-02-07T12:00:00Z] storage is unavailable \n"}, w.Messages)
+}
@@ -1,21 +1,254 @@
 package links
 
+import (
+	"encoding/base64"
+	"errors"
+	"github.com/dzhdmitry/link-shorter/internal/utils"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var ErrLinkExpired = errors.New("link has expired")
+var ErrLinkDisabled = errors.New("link is disabled")
+var ErrLinkNotFound = errors.New("link not found")
+var ErrAliasTaken = errors.New("alias is already taken")
+
+type Link struct {
+	Key          string
+	Alias        string
+	URL          string
+	ExpiresAt    time.Time
+	Disabled     bool
+	PasswordHash string
+	Owner        string
+	CreatedAt    time.Time
+	Metadata     Metadata
+	UTMTemplate  string
+	Rules        Rules
+	Variants     Variants
+	Fallback     string
+	Down         bool
+	Health       Health
+	Preview      Preview
+}
+
+// Metadata Describes link for its owner, it is never used to follow the link
+type Metadata struct {
+	Title       string
+	Description string
+	Tags        []string
+	Notes       string
+}
+
+func (m Metadata) IsZero() bool {
+	return m.Title == "" && m.Description == "" && len(m.Tags) == 0 && m.Notes == ""
+}
+
+// LinkOptions Attributes of links being stored besides URL. Owner is ID of API key which creates links,
+// UTMTemplate is name of template which parameters are added to URL on redirect, Rules and Variants choose other URL on redirect,
+// Fallback is URL used instead when destination is down
+type LinkOptions struct {
+	ExpiresAt    time.Time
+	Owner        string
+	CreatedAt    time.Time
+	PasswordHash string
+	Metadata     Metadata
+	UTMTemplate  string
+	Rules        Rules
+	Variants     Variants
+	Fallback     string
+}
+
+// LinkUpdate Contains fields to change, nil fields are left as is. Empty password hash removes password,
+// empty list of tags removes all tags, empty UTM template removes template, empty list of rules or variants removes them,
+// empty fallback removes it. Down marks destination as down regardless of its health
+type LinkUpdate struct {
+	URL          *string
+	Disabled     *bool
+	PasswordHash *string
+	Title        *string
+	Description  *string
+	Tags         *[]string
+	Notes        *string
+	UTMTemplate  *string
+	Rules        *Rules
+	Variants     *Variants
+	Fallback     *string
+	Down         *bool
+}
+
+// apply Changes metadata by update
+func (u LinkUpdate) apply(metadata Metadata) Metadata {
+	if u.Title != nil {
+		metadata.Title = *u.Title
+	}
+
+	if u.Description != nil {
+		metadata.Description = *u.Description
+	}
+
+	if u.Tags != nil {
+		metadata.Tags = nilIfEmpty(*u.Tags)
+	}
+
+	if u.Notes != nil {
+		metadata.Notes = *u.Notes
+	}
+
+	return metadata
+}
+
+// ChangesMetadata Reports whether any of title, description, tags or notes is changed
+func (u LinkUpdate) ChangesMetadata() bool {
+	return u.Title != nil || u.Description != nil || u.Tags != nil || u.Notes != nil
+}
+
+// nilIfEmpty Makes empty list of tags, rules or variants nil, so links without them are equal however they were restored
+func nilIfEmpty[S ~[]E, E any](items S) S {
+	if len(items) == 0 {
+		return nil
+	}
+
+	return items
+}
+
+func (l Link) IsExpired(now time.Time) bool {
+	return !l.ExpiresAt.IsZero() && !now.Before(l.ExpiresAt)
+}
+
+// IsDown Reports whether destination is marked as down or the last check found it broken
+func (l Link) IsDown() bool {
+	return l.Down || l.Health.IsBroken()
+}
+
+// dedupKey Identifies URL with expiration and owner, links are reused only when all of them are the same
+func dedupKey(URL string, expiresAt time.Time, owner string) string {
+	key := URL + "\n"
+
+	if !expiresAt.IsZero() {
+		key += expiresAt.UTC().Format(time.RFC3339)
+	}
+
+	if owner != "" {
+		key += "\n" + owner
+	}
+
+	return key
+}
+
 type StorageInterface interface {
-	StoreURLs(URLs []string) (map[string]string, error)
-	GetURL(string) (string, error)
-	GetURLs([]string) (map[string]string, error)
+	StoreURLs(URLs []string, options LinkOptions) (map[string]string, error)
+	// StoreUniqueURLs Works as StoreURLs, but reuses active links of URLs which were stored with the same expiration
+	// by the same owner. Returned set contains such URLs
+	StoreUniqueURLs(URLs []string, options LinkOptions) (map[string]string, map[string]bool, error)
+	StoreAlias(alias, URL string, options LinkOptions) error
+	GetLink(key string) (Link, error)
+	GetLinks(keys []string) (map[string]Link, error)
+	// UpdateLink Returns ErrLinkNotFound if there is no link by key
+	UpdateLink(key string, update LinkUpdate) (Link, error)
+	// DeleteLink Returns ErrLinkNotFound if there is no link by key
+	DeleteLink(key string) (Link, error)
+	ListerInterface
+	HealthStorageInterface
+	PreviewStorageInterface
+}
+
+const SortCreatedAsc = "created_at"
+const SortCreatedDesc = "-created_at"
+
+// ListQuery Selects links of owner, optionally by destination domain and its subdomains, by tag and by health state
+// (HealthBroken|HealthOK). Links are sorted by creation time, cursor is the last link of previous page
+type ListQuery struct {
+	Owner  string
+	Domain string
+	Tag    string
+	Health string
+	Sort   string
+	Cursor ListCursor
+	Limit  int
+}
+
+// ListCursor Position in listing, zero cursor is the beginning
+type ListCursor struct {
+	CreatedAt time.Time
+	Key       string
+}
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// String Encodes cursor to opaque string passed by clients
+func (c ListCursor) String() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "," + c.Key
+
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func (c ListCursor) IsZero() bool {
+	return c.Key == ""
+}
+
+func ParseListCursor(cursor string) (ListCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+
+	if err != nil {
+		return ListCursor{}, ErrInvalidCursor
+	}
+
+	createdAtRaw, key, found := strings.Cut(string(raw), ",")
+
+	if !found || key == "" {
+		return ListCursor{}, ErrInvalidCursor
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, createdAtRaw)
+
+	if err != nil {
+		return ListCursor{}, ErrInvalidCursor
+	}
+
+	return ListCursor{CreatedAt: createdAt, Key: key}, nil
+}
+
+// ListerInterface Lists links page by page. Deleted links are omitted, expired and disabled ones are listed
+type ListerInterface interface {
+	// ListLinks Returns up to query.Limit links following query.Cursor
+	ListLinks(query ListQuery) ([]Link, error)
+}
+
+// matchesDomain Reports whether host of URL is domain or its subdomain
+func matchesDomain(URL, domain string) bool {
+	u, err := url.Parse(URL)
+
+	if err != nil {
+		return false
+	}
+
+	host := strings.ToLower(u.Hostname())
+
+	return host == domain || strings.HasSuffix(host, "."+domain)
 }
 
 type Collection struct {
 	storage StorageInterface
+	clock   utils.ClockInterface
 }
 
-func NewCollection(storage StorageInterface) *Collection {
-	return &Collection{storage: storage}
+func NewCollection(storage StorageInterface, clock utils.ClockInterface) *Collection {
+	return &Collection{storage: storage, clock: clock}
 }
 
-func (c *Collection) GenerateKey(URL string) (string, error) {
-	keys, err := c.GenerateKeys([]string{URL})
+// withCreatedAt Sets creation time of links unless it is given
+func (c *Collection) withCreatedAt(options LinkOptions) LinkOptions {
+	if options.CreatedAt.IsZero() {
+		options.CreatedAt = c.clock.Now().UTC().Truncate(time.Second)
+	}
+
+	return options
+}
+
+func (c *Collection) GenerateKey(URL string, options LinkOptions) (string, error) {
+	keys, err := c.GenerateKeys([]string{URL}, options)
 
 	if err != nil {
 		return "", err
@@ -24,14 +257,90 @@ func (c *Collection) GenerateKey(URL string) (string, error) {
 	return keys[URL], nil
 }
 
-func (c *Collection) GenerateKeys(URLs []string) (map[string]string, error) {
-	return c.storage.StoreURLs(URLs)
+func (c *Collection) GenerateKeys(URLs []string, options LinkOptions) (map[string]string, error) {
+	return c.storage.StoreURLs(URLs, c.withCreatedAt(options))
+}
+
+// GenerateUniqueKeys Returns keys of URLs and set of URLs which got key of already existing link
+func (c *Collection) GenerateUniqueKeys(URLs []string, options LinkOptions) (map[string]string, map[string]bool, error) {
+	return c.storage.StoreUniqueURLs(URLs, c.withCreatedAt(options))
+}
+
+// GenerateAlias Returns ErrAliasTaken if alias is already in use
+func (c *Collection) GenerateAlias(alias, URL string, options LinkOptions) (string, error) {
+	if err := c.storage.StoreAlias(alias, URL, c.withCreatedAt(options)); err != nil {
+		return "", err
+	}
+
+	return alias, nil
+}
+
+// GetLink Returns ErrLinkExpired or ErrLinkDisabled along with the link if it can not be followed
+func (c *Collection) GetLink(key string) (Link, error) {
+	link, err := c.storage.GetLink(key)
+
+	if err != nil {
+		return Link{}, err
+	}
+
+	if link.URL == "" {
+		return link, nil
+	}
+
+	if link.IsExpired(c.clock.Now()) {
+		return link, ErrLinkExpired
+	}
+
+	if link.Disabled {
+		return link, ErrLinkDisabled
+	}
+
+	return link, nil
+}
+
+// GetLinks Returns map with key=key, value=link, expired and disabled links are omitted
+func (c *Collection) GetLinks(keys []string) (map[string]Link, error) {
+	links, err := c.storage.GetLinks(keys)
+
+	if err != nil {
+		return nil, err
+	}
+
+	now := c.clock.Now()
+
+	for key, link := range links {
+		if link.IsExpired(now) || link.Disabled {
+			delete(links, key)
+		}
+	}
+
+	return links, nil
+}
+
+func (c *Collection) UpdateLink(key string, update LinkUpdate) (Link, error) {
+	return c.storage.UpdateLink(key, update)
 }
 
-func (c *Collection) GetURL(key string) (string, error) {
-	return c.storage.GetURL(key)
+func (c *Collection) DeleteLink(key string) (Link, error) {
+	return c.storage.DeleteLink(key)
 }
 
-func (c *Collection) GetURLs(keys []string) (map[string]string, error) {
-	return c.storage.GetURLs(keys)
+// ListLinks Returns page of links and cursor of the next page, empty if there are no more links
+func (c *Collection) ListLinks(query ListQuery) ([]Link, string, error) {
+	limit := query.Limit
+	query.Limit++
+	links, err := c.storage.ListLinks(query)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(links) <= limit {
+		return links, "", nil
+	}
+
+	links = links[:limit]
+	last := links[limit-1]
+
+	return links, ListCursor{CreatedAt: last.CreatedAt, Key: last.Key}.String(), nil
 }
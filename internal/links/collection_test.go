@@ -1,17 +1,21 @@
 package links
 
 import (
+	"github.com/dzhdmitry/link-shorter/test"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"strconv"
 	"testing"
+	"time"
 )
 
 type testStorage struct {
-	//
+	options   LinkOptions
+	listQuery ListQuery
 }
 
-func (t *testStorage) StoreURLs(URLs []string) (map[string]string, error) {
+func (t *testStorage) StoreURLs(URLs []string, options LinkOptions) (map[string]string, error) {
+	t.options = options
 	result := map[string]string{}
 	i := 0
 
@@ -23,24 +27,79 @@ func (t *testStorage) StoreURLs(URLs []string) (map[string]string, error) {
 	return result, nil
 }
 
+func (t *testStorage) StoreUniqueURLs(URLs []string, options LinkOptions) (map[string]string, map[string]bool, error) {
+	result, _ := t.StoreURLs(URLs, options)
+
+	return result, map[string]bool{}, nil
+}
+
+func (t *testStorage) StoreAlias(alias, URL string, options LinkOptions) error {
+	if alias == "taken-alias" {
+		return ErrAliasTaken
+	}
+
+	return nil
+}
+
+func (t *testStorage) UpdateLink(key string, update LinkUpdate) (Link, error) {
+	return Link{Key: key, URL: *update.URL}, nil
+}
+
+func (t *testStorage) DeleteLink(key string) (Link, error) {
+	return Link{Key: key, URL: "http://example.com"}, nil
+}
+
 func (t *testStorage) Restore() error {
 	return nil
 }
 
-func (t *testStorage) GetURL(key string) (string, error) {
-	return "http://example.com", nil
+func (t *testStorage) GetLink(key string) (Link, error) {
+	if key == "expired" {
+		return Link{URL: "http://example.com", ExpiresAt: time.Date(2024, 2, 7, 11, 0, 0, 0, time.UTC)}, nil
+	}
+
+	if key == "disabled" {
+		return Link{URL: "http://example.com", Disabled: true}, nil
+	}
+
+	return Link{URL: "http://example.com"}, nil
 }
 
-func (t *testStorage) GetURLs(keys []string) (map[string]string, error) {
-	return map[string]string{
-		"key1": "http://example.com",
-		"key2": "http://example.com",
+func (t *testStorage) GetLinks(keys []string) (map[string]Link, error) {
+	return map[string]Link{
+		"key1":     {URL: "http://example.com"},
+		"key2":     {URL: "http://example.com", ExpiresAt: time.Date(2024, 2, 7, 13, 0, 0, 0, time.UTC)},
+		"expired":  {URL: "http://example.com", ExpiresAt: time.Date(2024, 2, 7, 12, 0, 0, 0, time.UTC)},
+		"disabled": {URL: "http://example.com", Disabled: true},
 	}, nil
 }
 
+func (t *testStorage) LinksToCheck(_, _ time.Time, _ int) ([]Link, error) {
+	return nil, nil
+}
+
+func (t *testStorage) StoreHealth(_ Link, _ Health) error {
+	return nil
+}
+
+func (t *testStorage) StorePreview(_ Link, _ Preview) error {
+	return nil
+}
+
+func (t *testStorage) ListLinks(query ListQuery) ([]Link, error) {
+	t.listQuery = query
+	links := []Link{
+		{Key: "1", URL: "http://example.com", CreatedAt: time.Date(2024, 2, 7, 10, 0, 0, 0, time.UTC)},
+		{Key: "2", URL: "http://example.com", CreatedAt: time.Date(2024, 2, 7, 11, 0, 0, 0, time.UTC)},
+		{Key: "3", URL: "http://example.com", CreatedAt: time.Date(2024, 2, 7, 12, 0, 0, 0, time.UTC)},
+	}
+
+	return links[:min(query.Limit, len(links))], nil
+}
+
 func TestGenerateKey(t *testing.T) {
-	collection := NewCollection(&testStorage{})
-	key, err := collection.GenerateKey("http://links.ru")
+	collection := NewCollection(&testStorage{}, &test.Clock{})
+	key, err := collection.GenerateKey("http://links.ru", LinkOptions{})
 
 	require.NoError(t, err)
 	assert.Equal(t, "1", key)
@@ -61,8 +120,8 @@ func TestGenerateKeys(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			collection := NewCollection(&testStorage{})
-			keys, err := collection.GenerateKeys(tt.key)
+			collection := NewCollection(&testStorage{}, &test.Clock{})
+			keys, err := collection.GenerateKeys(tt.key, LinkOptions{})
 
 			require.NoError(t, err)
 			assert.Equal(t, tt.expected, keys)
@@ -70,10 +129,104 @@ func TestGenerateKeys(t *testing.T) {
 	}
 }
 
+func TestGenerateAlias(t *testing.T) {
+	collection := NewCollection(&testStorage{}, &test.Clock{})
+	key, err := collection.GenerateAlias("spring-sale", "http://links.ru", LinkOptions{})
+
+	require.NoError(t, err)
+	assert.Equal(t, "spring-sale", key)
+
+	_, err = collection.GenerateAlias("taken-alias", "http://links.ru", LinkOptions{})
+
+	require.ErrorIs(t, err, ErrAliasTaken)
+}
+
 func TestGetLink(t *testing.T) {
-	collection := NewCollection(&testStorage{})
-	keys, err := collection.GetURL("2")
+	collection := NewCollection(&testStorage{}, &test.Clock{})
+	link, err := collection.GetLink("2")
+
+	require.NoError(t, err)
+	assert.Equal(t, "http://example.com", link.URL)
+}
+
+func TestGetLinkExpired(t *testing.T) {
+	collection := NewCollection(&testStorage{}, &test.Clock{})
+	link, err := collection.GetLink("expired")
+
+	require.ErrorIs(t, err, ErrLinkExpired)
+	assert.Equal(t, "http://example.com", link.URL)
+}
+
+func TestGetLinkDisabled(t *testing.T) {
+	collection := NewCollection(&testStorage{}, &test.Clock{})
+	link, err := collection.GetLink("disabled")
+
+	require.ErrorIs(t, err, ErrLinkDisabled)
+	assert.Equal(t, "http://example.com", link.URL)
+}
+
+func TestGetLinksOmitsUnavailable(t *testing.T) {
+	collection := NewCollection(&testStorage{}, &test.Clock{})
+	links, err := collection.GetLinks([]string{"key1", "key2", "expired", "disabled"})
 
 	require.NoError(t, err)
-	assert.Equal(t, "http://example.com", keys)
+	assert.Equal(t, map[string]Link{
+		"key1": {URL: "http://example.com"},
+		"key2": {URL: "http://example.com", ExpiresAt: time.Date(2024, 2, 7, 13, 0, 0, 0, time.UTC)},
+	}, links)
+}
+
+func TestGenerateKeySetsCreatedAt(t *testing.T) {
+	storage := &testStorage{}
+	collection := NewCollection(storage, &test.Clock{})
+	_, err := collection.GenerateKey("http://links.ru", LinkOptions{Owner: "a1b2c3"})
+
+	require.NoError(t, err)
+	assert.Equal(t, LinkOptions{Owner: "a1b2c3", CreatedAt: time.Date(2024, 2, 7, 12, 0, 0, 0, time.UTC)}, storage.options)
+}
+
+func TestListLinks(t *testing.T) {
+	tests := []struct {
+		name         string
+		limit        int
+		expectedKeys []string
+		expectedNext string
+	}{
+		{"More links", 2, []string{"1", "2"}, ListCursor{CreatedAt: time.Date(2024, 2, 7, 11, 0, 0, 0, time.UTC), Key: "2"}.String()},
+		{"Last page", 3, []string{"1", "2", "3"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &testStorage{}
+			collection := NewCollection(storage, &test.Clock{})
+			links, next, err := collection.ListLinks(ListQuery{Owner: "a1b2c3", Limit: tt.limit})
+
+			require.NoError(t, err)
+
+			var keys []string
+
+			for _, link := range links {
+				keys = append(keys, link.Key)
+			}
+
+			assert.Equal(t, tt.expectedKeys, keys)
+			assert.Equal(t, tt.expectedNext, next)
+			assert.Equal(t, tt.limit+1, storage.listQuery.Limit)
+		})
+	}
+}
+
+func TestListCursor(t *testing.T) {
+	cursor := ListCursor{CreatedAt: time.Date(2024, 2, 7, 12, 0, 0, 0, time.UTC), Key: "7ps"}
+	parsed, err := ParseListCursor(cursor.String())
+
+	require.NoError(t, err)
+	assert.Equal(t, cursor, parsed)
+
+	for _, invalid := range []string{"!", "bm8tY29tbWE", "eCw3cHM", "MjAyNC0wMi0wN1QxMjowMDowMFos"} {
+		_, err = ParseListCursor(invalid)
+
+		assert.ErrorIs(t, err, ErrInvalidCursor, invalid)
+	}
 }
@@ -1,48 +1,207 @@
 package links
 
 import (
+	"errors"
+	"fmt"
 	"math"
 	"slices"
 	"strings"
 )
 
-var Letters = "0123456789abcdefghijklmnopqrstuvwxyz"
-var alphabet = strings.Split(Letters, "")
+const AlphabetBase36 = "base36"
+const AlphabetBase62 = "base62"
+const AlphabetHumanSafe = "human-safe"
 
-func convertNumberToKey(number int64) string {
+// Alphabets Letters of generated keys by alphabet name. Human-safe alphabet has no look-alike letters 0, O, 1, l and I
+var Alphabets = map[string]string{
+	AlphabetBase36:    "0123456789abcdefghijklmnopqrstuvwxyz",
+	AlphabetBase62:    "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	AlphabetHumanSafe: "23456789abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ",
+}
+
+var ErrAlphabetMismatch = errors.New("configured key alphabet does not match alphabet of stored links")
+var ErrSequentialMaxIDMismatch = errors.New("configured last id with sequential key does not match recorded one")
+var ErrKeysExhausted = errors.New("id of new link exceeds range of non-enumerable keys")
+
+// MaxKeyLength Returns length of key of the largest id, which can be generated with given letters
+func MaxKeyLength(letters string) int {
+	base := int64(len(letters))
+	length := 1
+
+	for number := int64(math.MaxInt64); base > 1 && number >= base; number /= base {
+		length++
+	}
+
+	return length
+}
+
+func pow(x int64, y int) int64 {
+	result := int64(1)
+
+	for i := 0; i < y; i++ {
+		result *= x
+	}
+
+	return result
+}
+
+// permutedKeyLength Length of non-enumerable keys, sequential keys of this length are never generated before ~78 billion links
+const permutedKeyLength = 8
+
+// KeyConverter Converts ids of links to keys and back. With a secret, ids are put through keyed permutation,
+// so keys do not reveal neighbour links. Ids up to sequentialMaxID, created before the secret was set, keep sequential keys
+type KeyConverter struct {
+	alphabet        string
+	letters         []string
+	permutation     *permutation
+	sequentialMaxID int64
+}
+
+func NewKeyConverter(alphabet, secret string, sequentialMaxID int64) (*KeyConverter, error) {
+	letters, ok := Alphabets[alphabet]
+
+	if !ok {
+		return nil, fmt.Errorf("unknown key alphabet: %s", alphabet)
+	}
+
+	c := KeyConverter{alphabet: alphabet, letters: strings.Split(letters, ""), sequentialMaxID: sequentialMaxID}
+
+	if secret != "" {
+		c.permutation = newPermutation(secret, pow(int64(len(c.letters)), permutedKeyLength))
+	}
+
+	return &c, nil
+}
+
+// Alphabet Returns name of alphabet of generated keys
+func (c *KeyConverter) Alphabet() string {
+	return c.alphabet
+}
+
+// HasSecret Reports whether keys of new links are non-enumerable
+func (c *KeyConverter) HasSecret() bool {
+	return c.permutation != nil
+}
+
+// SequentialMaxID Returns last id which keeps sequential key
+func (c *KeyConverter) SequentialMaxID() int64 {
+	return c.sequentialMaxID
+}
+
+// applySequentialMaxID Sets last id with sequential key recorded by storage when the secret was enabled,
+// configured id must match it unless it is not set
+func (c *KeyConverter) applySequentialMaxID(recorded int64) error {
+	if c.sequentialMaxID != 0 && c.sequentialMaxID != recorded {
+		return fmt.Errorf("%w: %d is stored, %d is configured", ErrSequentialMaxIDMismatch, recorded, c.sequentialMaxID)
+	}
+
+	c.sequentialMaxID = recorded
+
+	return nil
+}
+
+func (c *KeyConverter) encode(number int64) string {
 	var digits []int64
+	base := int64(len(c.letters))
 
 	for {
 		if number == 0 {
 			break
 		}
 
-		reminder := number % int64(len(alphabet))
-		number = number / int64(len(alphabet))
+		reminder := number % base
+		number = number / base
 		digits = append(digits, reminder)
 	}
 
 	key := make([]string, len(digits))
 
 	for i, digit := range digits {
-		key[len(digits)-i-1] = alphabet[digit]
+		key[len(digits)-i-1] = c.letters[digit]
 	}
 
 	return strings.Join(key, "")
 }
 
-func pow(x float64, y int) int64 {
-	return int64(math.Pow(x, float64(y)))
-}
-
-func convertKeyToNumber(key string) int64 {
+// decode Returns number of key, 0 is returned if key has letters out of alphabet or its number overflows int64
+func (c *KeyConverter) decode(key string) int64 {
 	number := int64(0)
-	alphabetLen := float64(len(alphabet))
+	base := int64(len(c.letters))
+
+	for _, letter := range strings.Split(key, "") {
+		digit := int64(slices.Index(c.letters, letter))
+
+		if digit == -1 || number > (math.MaxInt64-digit)/base {
+			return 0
+		}
 
-	for i, letter := range strings.Split(key, "") {
-		index := int64(slices.Index(alphabet, letter))
-		number += index * pow(alphabetLen, len(key)-i-1)
+		number = number*base + digit
 	}
 
 	return number
 }
+
+func (c *KeyConverter) isSequential(id int64) bool {
+	return c.permutation == nil || id <= c.sequentialMaxID
+}
+
+// checkNewID Returns error if non-enumerable key can not be produced for id of new link, as it is out of range of the permutation
+func (c *KeyConverter) checkNewID(id int64) error {
+	if !c.isSequential(id) && id >= c.permutation.domain {
+		return fmt.Errorf("%w: %d is greater than %d", ErrKeysExhausted, id, c.permutation.domain-1)
+	}
+
+	return nil
+}
+
+// newKey Returns key of new link id, ids out of range of the permutation are refused instead of getting longer keys
+func (c *KeyConverter) newKey(id int64) (string, error) {
+	if err := c.checkNewID(id); err != nil {
+		return "", err
+	}
+
+	return c.Key(id), nil
+}
+
+// Key Returns key of link id, id of non-enumerable key must be checked by checkNewID when link is created
+func (c *KeyConverter) Key(id int64) string {
+	if c.isSequential(id) {
+		return c.encode(id)
+	}
+
+	key := c.encode(c.permutation.encrypt(id))
+
+	return strings.Repeat(c.letters[0], permutedKeyLength-len(key)) + key
+}
+
+// ID Returns id of link by key, 0 is returned if key can not be produced by Key
+func (c *KeyConverter) ID(key string) int64 {
+	if c.permutation != nil && len(key) == permutedKeyLength {
+		id := c.permutation.decrypt(c.decode(key))
+
+		if c.isSequential(id) {
+			return 0
+		}
+
+		return id
+	}
+
+	id := c.decode(key)
+
+	if !c.isSequential(id) {
+		return 0
+	}
+
+	return id
+}
+
+// IsAlias Reports whether key contains letters which are never produced by Key
+func (c *KeyConverter) IsAlias(key string) bool {
+	for _, letter := range key {
+		if !strings.ContainsRune(Alphabets[c.alphabet], letter) {
+			return true
+		}
+	}
+
+	return false
+}
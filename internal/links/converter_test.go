@@ -2,9 +2,21 @@ package links
 
 import (
 	"github.com/stretchr/testify/assert"
+	"math"
+	"strings"
 	"testing"
 )
 
+func newTestConverter(alphabet, secret string, sequentialMaxID int64) *KeyConverter {
+	c, err := NewKeyConverter(alphabet, secret, sequentialMaxID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return c
+}
+
 func TestNumberToKey(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -27,7 +39,7 @@ func TestNumberToKey(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			key := convertNumberToKey(int64(tt.number))
+			key := newTestConverter(AlphabetBase36, "", 0).encode(int64(tt.number))
 
 			assert.Equal(t, tt.expectedKey, key)
 		})
@@ -38,7 +50,7 @@ func TestKeyToNumber(t *testing.T) {
 	tests := []struct {
 		name           string
 		key            string
-		expectedNumber int
+		expectedNumber int64
 	}{
 		{"Zero", "", 0},
 		{"#1", "1", 1},
@@ -52,13 +64,211 @@ func TestKeyToNumber(t *testing.T) {
 		{"#9", "1z", 71},
 		{"#10", "20", 72},
 		{"#11", "21", 73},
+		{"Maximum", "1y2p0ij32e8e7", math.MaxInt64},
+		{"Overflow", "1y2p0ij32e8e8", 0},
+		{"Overflow to small number", "3w5e11264sgsh", 0},
+		{"Invalid letter", "1-", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			number := newTestConverter(AlphabetBase36, "", 0).decode(tt.key)
+
+			assert.Equal(t, tt.expectedNumber, number)
+		})
+	}
+}
+
+func TestIsAlias(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      string
+		expected bool
+	}{
+		{"Empty", "", false},
+		{"Generated key", "7ps", false},
+		{"Hyphen", "spring-sale", true},
+		{"Underscore", "spring_sale", true},
+		{"Upper case", "Spring", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, newTestConverter(AlphabetBase36, "", 0).IsAlias(tt.key))
+		})
+	}
+}
+
+func TestKeyConverterSequential(t *testing.T) {
+	c := newTestConverter(AlphabetBase36, "", 0)
+
+	assert.Equal(t, "7ps", c.Key(10000))
+	assert.Equal(t, int64(10000), c.ID("7ps"))
+	assert.Equal(t, int64(22011420), c.ID("d3s4c"))
+	assert.Equal(t, int64(0), c.ID("3w5e11264sgsh"))
+}
+
+func TestKeyConverterPermuted(t *testing.T) {
+	c := newTestConverter(AlphabetBase36, "secret", 3)
+
+	tests := []struct {
+		name       string
+		id         int64
+		sequential bool
+	}{
+		{"Sequential", 1, true},
+		{"Last sequential", 3, true},
+		{"First permuted", 4, false},
+		{"Permuted", 10000, false},
+		{"Largest with short sequential key", 78364164095, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := c.Key(tt.id)
+
+			if tt.sequential {
+				assert.Equal(t, c.encode(tt.id), key)
+			} else {
+				assert.Len(t, key, permutedKeyLength)
+				assert.Equal(t, int64(0), c.ID(c.encode(tt.id)), "sequential key of new link is not resolvable")
+			}
+
+			assert.Equal(t, tt.id, c.ID(key))
+		})
+	}
+
+	assert.NotEqual(t, newTestConverter(AlphabetBase36, "another secret", 3).Key(4), c.Key(4))
+	oldKey := c.encode(c.permutation.encrypt(2))
+	oldKey = strings.Repeat("0", permutedKeyLength-len(oldKey)) + oldKey
+
+	assert.Equal(t, int64(0), c.ID(oldKey), "permuted key of old link is not resolvable")
+}
+
+func TestKeyConverterNewKey(t *testing.T) {
+	domain := pow(36, permutedKeyLength)
+	c := newTestConverter(AlphabetBase36, "secret", 3)
+
+	tests := []struct {
+		name          string
+		converter     *KeyConverter
+		id            int64
+		expectedError error
+	}{
+		{"Sequential", c, 3, nil},
+		{"Permuted", c, 4, nil},
+		{"Last in range", c, domain - 1, nil},
+		{"Out of range", c, domain, ErrKeysExhausted},
+		{"Without secret", newTestConverter(AlphabetBase36, "", 0), domain, nil},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			number := convertKeyToNumber(tt.key)
+			key, err := tt.converter.newKey(tt.id)
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				assert.Empty(t, key)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.id, tt.converter.ID(key))
+		})
+	}
+
+	key, _ := c.newKey(domain - 1)
+
+	assert.Len(t, key, permutedKeyLength)
+}
+
+func TestKeyConverterUnique(t *testing.T) {
+	c := newTestConverter(AlphabetBase36, "secret", 0)
+	keys := map[string]bool{}
+
+	for id := int64(1); id <= 10000; id++ {
+		key := c.Key(id)
+
+		assert.False(t, keys[key], "key %s is duplicated", key)
+
+		keys[key] = true
+	}
+}
+
+func TestPermutation(t *testing.T) {
+	p := newPermutation("secret", 1000)
+	values := map[int64]bool{}
+
+	for number := int64(0); number < 1000; number++ {
+		value := p.encrypt(number)
+
+		assert.True(t, value >= 0 && value < 1000)
+		assert.False(t, values[value])
+		assert.Equal(t, number, p.decrypt(value))
+
+		values[value] = true
+	}
+}
+
+func TestKeyConverterAlphabets(t *testing.T) {
+	tests := []struct {
+		name        string
+		alphabet    string
+		id          int64
+		expectedKey string
+		alias       string
+	}{
+		{"Base36", AlphabetBase36, 10000, "7ps", "Spring"},
+		{"Base62", AlphabetBase62, 10000, "2Bi", "Spring-sale"},
+		{"Human-safe", AlphabetHumanSafe, 10000, "56s", "spring1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestConverter(tt.alphabet, "", 0)
+
+			assert.Equal(t, tt.expectedKey, c.Key(tt.id))
+			assert.Equal(t, tt.id, c.ID(tt.expectedKey))
+			assert.False(t, c.IsAlias(tt.expectedKey))
+			assert.True(t, c.IsAlias(tt.alias))
+
+			permuted := newTestConverter(tt.alphabet, "secret", 0)
+			key := permuted.Key(tt.id)
+
+			assert.Len(t, key, permutedKeyLength)
+			assert.False(t, permuted.IsAlias(key))
+			assert.Equal(t, tt.id, permuted.ID(key))
+		})
+	}
+
+	_, err := NewKeyConverter("base64", "", 0)
+
+	assert.EqualError(t, err, "unknown key alphabet: base64")
+}
+
+func TestMaxKeyLength(t *testing.T) {
+	tests := []struct {
+		name     string
+		letters  string
+		expected int
+	}{
+		{"Base36", Alphabets[AlphabetBase36], 13},
+		{"Base62", Alphabets[AlphabetBase62], 11},
+		{"Human-safe", Alphabets[AlphabetHumanSafe], 11},
+		{"Binary", "01", 63},
+		{"Single letter", "1", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, MaxKeyLength(tt.letters))
+
+			if len(tt.letters) > 1 {
+				c := KeyConverter{letters: strings.Split(tt.letters, "")}
 
-			assert.Equal(t, int64(tt.expectedNumber), number)
+				assert.Len(t, c.encode(math.MaxInt64), tt.expected)
+			}
 		})
 	}
 }
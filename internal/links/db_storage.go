@@ -0,0 +1,386 @@
+package links
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Query building shared by SQLStorage and SQLiteStorage, which differ in placeholders of long lists, encoding of tags,
+// time handling and casts only
+
+const linkColumns = "id, url, expires_at, alias, disabled, password_hash, owner, created_at, title, description, tags, notes, utm_template, rules, variants, " +
+	"fallback, down, health_status, health_redirects, health_error, health_checked_at, preview"
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// placeholdersFunc Returns count placeholders separated by comma, numbered from first in dialects with numbered ones
+type placeholdersFunc func(first, count int) string
+
+// tagsScanFunc Returns destination which scans tags column to tags
+type tagsScanFunc func(tags *[]string) sql.Scanner
+
+// checkAlphabet Records configured alphabet of keys if none is recorded yet and compares it with recorded one
+func checkAlphabet(ctx context.Context, db *sql.DB, converter *KeyConverter) error {
+	query := "INSERT INTO settings(name, value) VALUES ('alphabet', $1) ON CONFLICT (name) DO NOTHING"
+
+	if _, err := db.ExecContext(ctx, query, converter.Alphabet()); err != nil {
+		return err
+	}
+
+	var alphabet string
+	err := db.QueryRowContext(ctx, "SELECT value FROM settings WHERE name = 'alphabet'").Scan(&alphabet)
+
+	if err != nil {
+		return err
+	}
+
+	if alphabet != converter.Alphabet() {
+		return fmt.Errorf("%w: %s is stored, %s is configured", ErrAlphabetMismatch, alphabet, converter.Alphabet())
+	}
+
+	return nil
+}
+
+// checkSequentialMaxID Records last id with sequential key by insertQuery, which takes configured id, and applies
+// the one selected by selectQuery to converter
+func checkSequentialMaxID(ctx context.Context, db *sql.DB, converter *KeyConverter, insertQuery, selectQuery string) error {
+	if _, err := db.ExecContext(ctx, insertQuery, converter.SequentialMaxID()); err != nil {
+		return err
+	}
+
+	var recorded int64
+
+	if err := db.QueryRowContext(ctx, selectQuery).Scan(&recorded); err != nil {
+		return err
+	}
+
+	return converter.applySequentialMaxID(recorded)
+}
+
+// keyCondition Returns condition to find a link by generated key or alias
+func keyCondition(converter *KeyConverter, key string, n int) (string, interface{}) {
+	if converter.IsAlias(key) {
+		return fmt.Sprintf("alias = $%d", n), key
+	}
+
+	return fmt.Sprintf("id = $%d", n), converter.ID(key)
+}
+
+// scanKeys Puts keys of rows "id, url" selected by query to keysByURLs
+func scanKeys(ctx context.Context, db queryer, converter *KeyConverter, keysByURLs map[string]string, query string, values []interface{}) error {
+	rows, err := db.QueryContext(ctx, query, values...)
+
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var URL string
+
+		if err = rows.Scan(&id, &URL); err != nil {
+			return err
+		}
+
+		// inserted links are rolled back if ids exceed range of keys
+		key, err := converter.newKey(id)
+
+		if err != nil {
+			return err
+		}
+
+		keysByURLs[URL] = key
+	}
+
+	return rows.Err()
+}
+
+// scanLink Returns link of row with linkColumns
+func scanLink(row rowScanner, converter *KeyConverter, scanTags tagsScanFunc) (Link, error) {
+	var id int64
+	var link Link
+	var expiresAt sql.NullTime
+	var alias sql.NullString
+	var passwordHash sql.NullString
+	var createdAt sql.NullTime
+	var tags []string
+	var rules string
+	var variants string
+	var healthRedirects string
+	var healthCheckedAt sql.NullTime
+	var preview sql.NullString
+
+	err := row.Scan(
+		&id,
+		&link.URL,
+		&expiresAt,
+		&alias,
+		&link.Disabled,
+		&passwordHash,
+		&link.Owner,
+		&createdAt,
+		&link.Metadata.Title,
+		&link.Metadata.Description,
+		scanTags(&tags),
+		&link.Metadata.Notes,
+		&link.UTMTemplate,
+		&rules,
+		&variants,
+		&link.Fallback,
+		&link.Down,
+		&link.Health.Status,
+		&healthRedirects,
+		&link.Health.Error,
+		&healthCheckedAt,
+		&preview,
+	)
+
+	if err != nil {
+		return Link{}, err
+	}
+
+	if link.Rules, err = decodeRules(rules); err != nil {
+		return Link{}, err
+	}
+
+	if link.Variants, err = decodeVariants(variants); err != nil {
+		return Link{}, err
+	}
+
+	if err = json.Unmarshal([]byte(healthRedirects), &link.Health.Redirects); err != nil {
+		return Link{}, err
+	}
+
+	if link.Preview, err = decodePreview(preview.String); err != nil {
+		return Link{}, err
+	}
+
+	link.Key = converter.Key(id)
+	link.Alias = alias.String
+	link.PasswordHash = passwordHash.String
+	link.ExpiresAt = expiresAt.Time
+	link.CreatedAt = createdAt.Time.UTC()
+	link.Metadata.Tags = nilIfEmpty(tags)
+	link.Health.Redirects = nilIfEmpty(link.Health.Redirects)
+
+	if healthCheckedAt.Valid {
+		link.Health.CheckedAt = healthCheckedAt.Time.UTC()
+	}
+
+	return link, nil
+}
+
+// queryLinks Returns links selected by query, which is expected to return up to limit rows
+func queryLinks(ctx context.Context, db queryer, converter *KeyConverter, scanTags tagsScanFunc, limit int, query string, values ...interface{}) ([]Link, error) {
+	rows, err := db.QueryContext(ctx, query, values...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	links := make([]Link, 0, limit)
+
+	for rows.Next() {
+		link, err := scanLink(rows, converter, scanTags)
+
+		if err != nil {
+			return nil, err
+		}
+
+		links = append(links, link)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
+
+// getLinks Returns links found by keys or aliases, keys of missing links are omitted
+func getLinks(ctx context.Context, db queryer, converter *KeyConverter, scanTags tagsScanFunc, placeholders placeholdersFunc, keys []string) (map[string]Link, error) {
+	if len(keys) == 0 {
+		return map[string]Link{}, nil
+	}
+
+	var idValues []interface{}
+	var aliasValues []interface{}
+	ids := make(map[int64]string, len(keys))
+	aliases := make(map[string]bool, len(keys))
+
+	for _, key := range keys {
+		if converter.IsAlias(key) {
+			aliasValues = append(aliasValues, key)
+			aliases[key] = true
+		} else {
+			idValues = append(idValues, converter.ID(key))
+			ids[converter.ID(key)] = key
+		}
+	}
+
+	var conditions []string
+
+	if len(idValues) > 0 {
+		conditions = append(conditions, "id IN ("+placeholders(1, len(idValues))+")")
+	}
+
+	if len(aliasValues) > 0 {
+		conditions = append(conditions, "alias IN ("+placeholders(len(idValues)+1, len(aliasValues))+")")
+	}
+
+	query := "SELECT " + linkColumns + " FROM links WHERE (" + strings.Join(conditions, " OR ") + ") AND deleted_at IS NULL LIMIT " + strconv.Itoa(len(keys))
+	found, err := queryLinks(ctx, db, converter, scanTags, len(keys), query, append(idValues, aliasValues...)...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	links := make(map[string]Link, len(keys))
+
+	for _, link := range found {
+		if key, ok := ids[converter.ID(link.Key)]; ok {
+			links[key] = link
+		}
+
+		if link.Alias != "" && aliases[link.Alias] {
+			links[link.Alias] = link
+		}
+	}
+
+	return links, nil
+}
+
+// numberedPlaceholders Returns placeholders $first, $first+1 and so on
+func numberedPlaceholders(first, count int) string {
+	placeholders := make([]string, count)
+
+	for i := range placeholders {
+		placeholders[i] = "$" + strconv.Itoa(first+i)
+	}
+
+	return strings.Join(placeholders, ", ")
+}
+
+// positionalPlaceholders Returns placeholders bound in order of appearance
+func positionalPlaceholders(_, count int) string {
+	return strings.Repeat("?, ", count-1) + "?"
+}
+
+// linkValuesFunc Returns values of insertColumns of new link
+type linkValuesFunc func(URL string, options LinkOptions) []interface{}
+
+// splitBatches Splits URLs into parts of size
+func splitBatches(URLs []string, size int) [][]string {
+	var batches [][]string
+
+	for len(URLs) > size {
+		batches = append(batches, URLs[:size])
+		URLs = URLs[size:]
+	}
+
+	return append(batches, URLs)
+}
+
+// insertLinks Inserts links with URLs in batches of batchSize within one transaction and puts their keys to keysByURLs.
+// URL hashes are inserted too if unique is set, links conflicting with existing ones are skipped then
+func insertLinks(ctx context.Context, db *sql.DB, converter *KeyConverter, placeholders placeholdersFunc, linkValues linkValuesFunc, batchSize int,
+	keysByURLs map[string]string, URLs []string, options LinkOptions, unique bool) error {
+	tx, err := db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	columns := insertColumnsCount
+
+	if unique {
+		columns++
+	}
+
+	for _, batch := range splitBatches(URLs, batchSize) {
+		rows := make([]string, 0, len(batch))
+		values := make([]interface{}, 0, len(batch)*columns)
+
+		for _, URL := range batch {
+			rows = append(rows, "("+placeholders(len(values)+1, columns)+")")
+			values = append(values, linkValues(URL, options)...)
+
+			if unique {
+				values = append(values, urlHash(URL, options))
+			}
+		}
+
+		query := "INSERT INTO links(" + insertColumns + ") VALUES " + strings.Join(rows, ", ") + " RETURNING id, url"
+
+		if unique {
+			query = "INSERT INTO links(" + insertColumns + ", url_hash) VALUES " + strings.Join(rows, ", ") +
+				" ON CONFLICT (url_hash) DO NOTHING RETURNING id, url"
+		}
+
+		if err = scanKeys(ctx, tx, converter, keysByURLs, query, values); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// scanExistingKeys Puts keys of existing links with URLs skipped by insertLinks to keysByURLs and marks their URLs as existing
+func scanExistingKeys(ctx context.Context, db queryer, converter *KeyConverter, placeholders placeholdersFunc, batchSize int,
+	keysByURLs map[string]string, existing map[string]bool, URLs []string, options LinkOptions) error {
+	var skipped []string
+
+	for _, URL := range URLs {
+		if _, ok := keysByURLs[URL]; !ok {
+			skipped = append(skipped, URL)
+		}
+	}
+
+	if len(skipped) == 0 {
+		return nil
+	}
+
+	created := make(map[string]bool, len(keysByURLs))
+
+	for URL := range keysByURLs {
+		created[URL] = true
+	}
+
+	for _, batch := range splitBatches(skipped, batchSize) {
+		values := make([]interface{}, 0, len(batch))
+
+		for _, URL := range batch {
+			values = append(values, urlHash(URL, options))
+		}
+
+		query := "SELECT id, url FROM links WHERE url_hash IN (" + placeholders(1, len(values)) + ")"
+
+		if err := scanKeys(ctx, db, converter, keysByURLs, query, values); err != nil {
+			return err
+		}
+	}
+
+	for URL := range keysByURLs {
+		if !created[URL] {
+			existing[URL] = true
+		}
+	}
+
+	return nil
+}
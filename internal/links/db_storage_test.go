@@ -0,0 +1,622 @@
+package links
+
+import (
+	"database/sql"
+	"github.com/stretchr/testify/suite"
+	"strconv"
+	"time"
+)
+
+// dbStorage Storage of links in database
+type dbStorage interface {
+	StorageInterface
+	CheckAlphabet() error
+	CheckSequentialMaxID() error
+}
+
+// dbStorageSuite Shared tests of storages of links in database, database must be empty before each test
+type dbStorageSuite struct {
+	suite.Suite
+	db         *sql.DB
+	batchSize  int
+	newStorage func(converter *KeyConverter) dbStorage
+}
+
+func (s *dbStorageSuite) TestStoreURLs() {
+	tests := []struct {
+		name     string
+		urls     []string
+		expected map[string]string
+	}{
+		{"Empty", []string{}, map[string]string{}},
+		{"Single row", []string{"https://example.com"}, map[string]string{"https://example.com": "1"}},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			storage := s.newStorage(newTestConverter(AlphabetBase36, "", 0))
+			data, err := storage.StoreURLs(tt.urls, LinkOptions{})
+
+			s.NoError(err)
+			s.Equal(tt.expected, data)
+		})
+	}
+}
+
+func (s *dbStorageSuite) TestStoreURLsExpiresAt() {
+	storage := s.newStorage(newTestConverter(AlphabetBase36, "", 0))
+	expiresAt := time.Date(2024, 2, 8, 12, 0, 0, 0, time.UTC)
+	data, err := storage.StoreURLs([]string{"https://example.com"}, LinkOptions{ExpiresAt: expiresAt})
+
+	s.NoError(err)
+	s.Equal(map[string]string{"https://example.com": "1"}, data)
+
+	link, err := storage.GetLink("1")
+
+	s.NoError(err)
+	s.Equal("https://example.com", link.URL)
+	s.True(expiresAt.Equal(link.ExpiresAt))
+}
+
+func (s *dbStorageSuite) TestStoreAlias() {
+	storage := s.newStorage(newTestConverter(AlphabetBase36, "", 0))
+	err := storage.StoreAlias("spring-sale", "https://example.com", LinkOptions{})
+
+	s.NoError(err)
+
+	err = storage.StoreAlias("spring-sale", "https://example2.com", LinkOptions{})
+
+	s.ErrorIs(err, ErrAliasTaken)
+
+	link, err := storage.GetLink("spring-sale")
+
+	s.NoError(err)
+	s.Equal("https://example.com", link.URL)
+
+	links, err := storage.GetLinks([]string{"1", "spring-sale", "winter-sale"})
+
+	s.NoError(err)
+	s.Equal(map[string]Link{
+		"1":           {Key: "1", Alias: "spring-sale", URL: "https://example.com"},
+		"spring-sale": {Key: "1", Alias: "spring-sale", URL: "https://example.com"},
+	}, links)
+}
+
+func (s *dbStorageSuite) TestStorePassword() {
+	storage := s.newStorage(newTestConverter(AlphabetBase36, "", 0))
+	_, err := storage.StoreURLs([]string{"https://example.com"}, LinkOptions{PasswordHash: "$2a$10$hash"})
+
+	s.NoError(err)
+
+	err = storage.StoreAlias("secret-sale", "https://example2.com", LinkOptions{PasswordHash: "$2a$10$hash"})
+
+	s.NoError(err)
+
+	links, err := storage.GetLinks([]string{"1", "secret-sale"})
+
+	s.NoError(err)
+	s.Equal(map[string]Link{
+		"1":           {Key: "1", URL: "https://example.com", PasswordHash: "$2a$10$hash"},
+		"secret-sale": {Key: "2", Alias: "secret-sale", URL: "https://example2.com", PasswordHash: "$2a$10$hash"},
+	}, links)
+}
+
+func (s *dbStorageSuite) TestGetLink() {
+	tests := []struct {
+		name        string
+		key         string
+		expectedURL string
+	}{
+		{"Empty", "", ""},
+		{"Non-existing", "aawd1", ""},
+		{"Existing", "1", "https://example.com"},
+	}
+
+	_, _ = s.db.Exec("INSERT INTO links (url) VALUES ('https://example.com')")
+	storage := s.newStorage(newTestConverter(AlphabetBase36, "", 0))
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			link, err := storage.GetLink(tt.key)
+
+			s.NoError(err)
+			s.Equal(tt.expectedURL, link.URL)
+		})
+	}
+}
+
+func (s *dbStorageSuite) TestGetLinks() {
+	tests := []struct {
+		name          string
+		keys          []string
+		expectedLinks map[string]Link
+	}{
+		{"Empty", []string{"", ""}, map[string]Link{}},
+		{"Non-existing", []string{"aawd1"}, map[string]Link{}},
+		{"Existing", []string{"1", "2"}, map[string]Link{
+			"1": {Key: "1", URL: "https://example.com"},
+			"2": {Key: "2", URL: "https://example2.com"},
+		}},
+	}
+
+	_, _ = s.db.Exec("INSERT INTO links (url) VALUES ('https://example.com')")
+	_, _ = s.db.Exec("INSERT INTO links (url) VALUES ('https://example2.com')")
+	storage := s.newStorage(newTestConverter(AlphabetBase36, "", 0))
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			links, err := storage.GetLinks(tt.keys)
+
+			s.NoError(err)
+			s.Equal(tt.expectedLinks, links)
+		})
+	}
+}
+
+func (s *dbStorageSuite) TestUpdateLink() {
+	_, _ = s.db.Exec("INSERT INTO links (url) VALUES ('https://example.com')")
+	storage := s.newStorage(newTestConverter(AlphabetBase36, "", 0))
+	URL := "https://example.org"
+	disabled := true
+
+	link, err := storage.UpdateLink("1", LinkUpdate{URL: &URL})
+
+	s.NoError(err)
+	s.Equal(Link{Key: "1", URL: "https://example.org"}, link)
+
+	link, err = storage.UpdateLink("1", LinkUpdate{Disabled: &disabled})
+
+	s.NoError(err)
+	s.Equal(Link{Key: "1", URL: "https://example.org", Disabled: true}, link)
+
+	passwordHash := "$2a$10$hash"
+	link, err = storage.UpdateLink("1", LinkUpdate{PasswordHash: &passwordHash})
+
+	s.NoError(err)
+	s.Equal(Link{Key: "1", URL: "https://example.org", Disabled: true, PasswordHash: passwordHash}, link)
+
+	passwordHash = ""
+	link, err = storage.UpdateLink("1", LinkUpdate{PasswordHash: &passwordHash})
+
+	s.NoError(err)
+	s.Equal(Link{Key: "1", URL: "https://example.org", Disabled: true}, link)
+
+	_, err = storage.UpdateLink("2", LinkUpdate{URL: &URL})
+
+	s.ErrorIs(err, ErrLinkNotFound)
+}
+
+func (s *dbStorageSuite) TestDeleteLink() {
+	_, _ = s.db.Exec("INSERT INTO links (url) VALUES ('https://example.com')")
+	storage := s.newStorage(newTestConverter(AlphabetBase36, "", 0))
+
+	link, err := storage.DeleteLink("1")
+
+	s.NoError(err)
+	s.Equal(Link{Key: "1", URL: "https://example.com"}, link)
+
+	_, err = storage.DeleteLink("1")
+
+	s.ErrorIs(err, ErrLinkNotFound)
+
+	link, err = storage.GetLink("1")
+
+	s.NoError(err)
+	s.Equal(Link{}, link)
+}
+
+func (s *dbStorageSuite) TestNonEnumerableKeys() {
+	_, _ = s.db.Exec("INSERT INTO links (url) VALUES ('https://example1.com')")
+	storage := s.newStorage(newTestConverter(AlphabetBase36, "secret", 1))
+
+	URLs, err := storage.StoreURLs([]string{"https://example2.com"}, LinkOptions{})
+	key := URLs["https://example2.com"]
+
+	s.NoError(err)
+	s.Len(key, permutedKeyLength)
+
+	link, err := storage.GetLink("2")
+
+	s.NoError(err)
+	s.Equal(Link{}, link)
+
+	links, err := storage.GetLinks([]string{"1", "2", key})
+
+	s.NoError(err)
+	s.Equal(map[string]Link{
+		"1": {Key: "1", URL: "https://example1.com"},
+		key: {Key: key, URL: "https://example2.com"},
+	}, links)
+}
+
+func (s *dbStorageSuite) TestStoreUniqueURLs() {
+	storage := s.newStorage(newTestConverter(AlphabetBase36, "", 0))
+	expiresAt := time.Date(2024, 2, 8, 12, 0, 0, 0, time.UTC)
+
+	_, _, _ = storage.StoreUniqueURLs([]string{"https://example1.com"}, LinkOptions{})
+	_ = storage.StoreAlias("spring-sale", "https://example2.com", LinkOptions{})
+	_, _, _ = storage.StoreUniqueURLs([]string{"https://example3.com"}, LinkOptions{ExpiresAt: expiresAt})
+	_, _, _ = storage.StoreUniqueURLs([]string{"https://example4.com"}, LinkOptions{})
+	disabled := true
+	_, _ = storage.UpdateLink("4", LinkUpdate{Disabled: &disabled})
+
+	keys, existing, err := storage.StoreUniqueURLs([]string{
+		"https://example1.com",
+		"https://example2.com",
+		"https://example3.com",
+		"https://example4.com",
+		"https://example5.com",
+		"https://example5.com",
+	}, LinkOptions{})
+
+	s.NoError(err)
+	s.Equal("1", keys["https://example1.com"])
+	s.Len(keys, 5)
+	s.Equal(map[string]bool{"https://example1.com": true}, existing)
+
+	keys, existing, err = storage.StoreUniqueURLs([]string{"https://example3.com"}, LinkOptions{ExpiresAt: expiresAt})
+
+	s.NoError(err)
+	s.Equal(map[string]string{"https://example3.com": "3"}, keys)
+	s.Equal(map[string]bool{"https://example3.com": true}, existing)
+
+	_, _ = storage.DeleteLink("1")
+	keys, existing, err = storage.StoreUniqueURLs([]string{"https://example1.com"}, LinkOptions{})
+
+	s.NoError(err)
+	s.NotEqual("1", keys["https://example1.com"])
+	s.Equal(map[string]bool{}, existing)
+}
+
+func (s *dbStorageSuite) TestListLinks() {
+	storage := s.newStorage(newTestConverter(AlphabetBase36, "", 0))
+	day := func(d int) LinkOptions {
+		return LinkOptions{Owner: "client", CreatedAt: time.Date(2024, 2, d, 12, 0, 0, 0, time.UTC)}
+	}
+
+	_, _ = storage.StoreURLs([]string{"https://example.com/1"}, day(7))
+	_, _ = storage.StoreURLs([]string{"https://blog.example.com/2", "https://example.org/3"}, day(5))
+	_ = storage.StoreAlias("spring-sale", "https://Example.com:8080/4", day(6))
+	_, _ = storage.StoreURLs([]string{"https://example.com/5"}, LinkOptions{CreatedAt: day(8).CreatedAt})
+	_, _ = storage.StoreURLs([]string{"https://notexample.com/6"}, day(9))
+	_, _ = storage.DeleteLink("1")
+	tags := []string{"sale", "spring"}
+
+	for _, key := range []string{"3", "6"} {
+		_, _ = storage.UpdateLink(key, LinkUpdate{Tags: &tags})
+	}
+
+	tests := []struct {
+		name     string
+		query    ListQuery
+		expected []string
+	}{
+		{"Newest first", ListQuery{Owner: "client", Sort: SortCreatedDesc, Limit: 10}, []string{"6", "4", "3", "2"}},
+		{"Oldest first", ListQuery{Owner: "client", Sort: SortCreatedAsc, Limit: 10}, []string{"2", "3", "4", "6"}},
+		{"Limit", ListQuery{Owner: "client", Sort: SortCreatedAsc, Limit: 2}, []string{"2", "3"}},
+		{"Cursor ascending", ListQuery{Owner: "client", Sort: SortCreatedAsc, Limit: 10,
+			Cursor: ListCursor{CreatedAt: day(5).CreatedAt, Key: "2"}}, []string{"3", "4", "6"}},
+		{"Cursor descending", ListQuery{Owner: "client", Sort: SortCreatedDesc, Limit: 10,
+			Cursor: ListCursor{CreatedAt: day(5).CreatedAt, Key: "3"}}, []string{"2"}},
+		{"Domain", ListQuery{Owner: "client", Domain: "example.com", Sort: SortCreatedAsc, Limit: 10}, []string{"2", "4"}},
+		{"Other owner", ListQuery{Sort: SortCreatedAsc, Limit: 10}, []string{"5"}},
+		{"Tag", ListQuery{Owner: "client", Tag: "sale", Sort: SortCreatedAsc, Limit: 10}, []string{"3", "6"}},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			found, err := storage.ListLinks(tt.query)
+
+			s.NoError(err)
+
+			keys := make([]string, 0, len(found))
+
+			for _, link := range found {
+				keys = append(keys, link.Key)
+			}
+
+			s.Equal(tt.expected, keys)
+		})
+	}
+
+	found, _ := storage.ListLinks(ListQuery{Owner: "client", Domain: "example.com", Sort: SortCreatedAsc, Limit: 10})
+
+	s.Require().Len(found, 2)
+	s.Equal("spring-sale", found[1].Alias)
+	s.Equal("client", found[1].Owner)
+	s.True(day(6).CreatedAt.Equal(found[1].CreatedAt))
+}
+
+func (s *dbStorageSuite) TestMetadata() {
+	storage := s.newStorage(newTestConverter(AlphabetBase36, "", 0))
+	metadata := Metadata{Title: "Spring sale", Description: "Sale of spring", Tags: []string{"sale", "spring"}, Notes: "internal"}
+
+	_, _ = storage.StoreURLs([]string{"https://example1.com"}, LinkOptions{})
+	_, _ = storage.StoreURLs([]string{"https://example2.com"}, LinkOptions{Metadata: metadata})
+	_ = storage.StoreAlias("spring-sale", "https://example3.com", LinkOptions{Metadata: metadata})
+
+	link, err := storage.GetLink("1")
+
+	s.NoError(err)
+	s.Equal(Link{Key: "1", URL: "https://example1.com"}, link)
+
+	link, err = storage.GetLink("spring-sale")
+
+	s.NoError(err)
+	s.Equal(metadata, link.Metadata)
+
+	title := "Summer sale"
+	tags := []string{}
+	link, err = storage.UpdateLink("2", LinkUpdate{Title: &title, Tags: &tags})
+
+	s.NoError(err)
+	s.Equal(Metadata{Title: "Summer sale", Description: "Sale of spring", Notes: "internal"}, link.Metadata)
+
+	disabled := true
+	link, err = storage.UpdateLink("2", LinkUpdate{Disabled: &disabled})
+
+	s.NoError(err)
+	s.Equal("Summer sale", link.Metadata.Title)
+}
+
+func (s *dbStorageSuite) TestRules() {
+	storage := s.newStorage(newTestConverter(AlphabetBase36, "", 0))
+	rules := Rules{{URL: "https://example.com/de", Languages: []string{"de"}, Query: map[string]string{"ref": ""}}}
+
+	_, _ = storage.StoreURLs([]string{"https://example1.com"}, LinkOptions{Rules: rules})
+	_, _, _ = storage.StoreUniqueURLs([]string{"https://example2.com"}, LinkOptions{})
+
+	link, err := storage.GetLink("1")
+
+	s.NoError(err)
+	s.Equal(rules, link.Rules)
+
+	link, err = storage.GetLink("2")
+
+	s.NoError(err)
+	s.Nil(link.Rules)
+
+	link, err = storage.UpdateLink("2", LinkUpdate{Rules: &rules})
+
+	s.NoError(err)
+	s.Equal(rules, link.Rules)
+
+	// link with rules is not reused by deduplication
+	_, existing, err := storage.StoreUniqueURLs([]string{"https://example2.com"}, LinkOptions{})
+
+	s.NoError(err)
+	s.Empty(existing)
+
+	link, err = storage.UpdateLink("1", LinkUpdate{Rules: &Rules{}})
+
+	s.NoError(err)
+	s.Nil(link.Rules)
+}
+
+func (s *dbStorageSuite) TestVariants() {
+	storage := s.newStorage(newTestConverter(AlphabetBase36, "", 0))
+	variants := Variants{{URL: "https://example.com/a", Weight: 1}, {URL: "https://example.com/b", Weight: 3}}
+
+	_, _ = storage.StoreURLs([]string{"https://example1.com"}, LinkOptions{Variants: variants})
+	_, _, _ = storage.StoreUniqueURLs([]string{"https://example2.com"}, LinkOptions{})
+
+	link, err := storage.GetLink("1")
+
+	s.NoError(err)
+	s.Equal(variants, link.Variants)
+
+	link, err = storage.UpdateLink("2", LinkUpdate{Variants: &variants})
+
+	s.NoError(err)
+	s.Equal(variants, link.Variants)
+
+	// link with variants is not reused by deduplication
+	_, existing, err := storage.StoreUniqueURLs([]string{"https://example2.com"}, LinkOptions{})
+
+	s.NoError(err)
+	s.Empty(existing)
+
+	link, err = storage.UpdateLink("1", LinkUpdate{Variants: &Variants{}})
+
+	s.NoError(err)
+	s.Nil(link.Variants)
+}
+
+func (s *dbStorageSuite) TestHealth() {
+	storage := s.newStorage(newTestConverter(AlphabetBase36, "", 0))
+	now := time.Date(2024, 2, 7, 12, 0, 0, 0, time.UTC)
+
+	_, _ = storage.StoreURLs([]string{"https://example.com/1", "mailto:info@example.com", "https://example.com/3"}, LinkOptions{Owner: "client"})
+	_, _ = storage.StoreURLs([]string{"https://example.com/4"}, LinkOptions{Owner: "client", ExpiresAt: now.Add(-time.Hour)})
+	_, _ = storage.StoreURLs([]string{"https://example.com/5"}, LinkOptions{Owner: "client"})
+	disabled := true
+	_, _ = storage.UpdateLink("3", LinkUpdate{Disabled: &disabled})
+	due, err := storage.LinksToCheck(now, now.Add(-time.Hour), 10)
+
+	s.NoError(err)
+	s.Require().Len(due, 2)
+	s.Equal("1", due[0].Key)
+	s.Equal("5", due[1].Key)
+
+	broken := Health{Status: 404, Redirects: []string{"https://example.com/moved"}, CheckedAt: now.Add(-2 * time.Hour)}
+
+	s.NoError(storage.StoreHealth(Link{Key: "5", URL: "https://example.com/5"}, Health{Status: 200, CheckedAt: now}))
+	s.NoError(storage.StoreHealth(Link{Key: "1", URL: "https://example.com/1"}, broken))
+	s.NoError(storage.StoreHealth(Link{Key: "1", URL: "https://example.com/other"}, Health{Status: 200, CheckedAt: now}))
+
+	due, _ = storage.LinksToCheck(now, now.Add(-time.Hour), 10)
+
+	s.Require().Len(due, 1)
+	s.Equal("1", due[0].Key)
+	s.Equal(broken.Redirects, due[0].Health.Redirects)
+	s.True(broken.CheckedAt.Equal(due[0].Health.CheckedAt))
+
+	found, err := storage.ListLinks(ListQuery{Owner: "client", Health: HealthBroken, Sort: SortCreatedAsc, Limit: 10})
+
+	s.NoError(err)
+	s.Require().Len(found, 1)
+	s.Equal("1", found[0].Key)
+	s.Equal(404, found[0].Health.Status)
+
+	found, _ = storage.ListLinks(ListQuery{Owner: "client", Health: HealthOK, Sort: SortCreatedAsc, Limit: 10})
+
+	s.Require().Len(found, 1)
+	s.Equal("5", found[0].Key)
+
+	// new destination is not checked yet
+	URL := "https://example.com/new"
+	link, err := storage.UpdateLink("1", LinkUpdate{URL: &URL})
+
+	s.NoError(err)
+	s.True(link.Health.IsZero())
+}
+
+func (s *dbStorageSuite) TestPreview() {
+	storage := s.newStorage(newTestConverter(AlphabetBase36, "", 0))
+
+	_, _ = storage.StoreURLs([]string{"https://example.com"}, LinkOptions{})
+	_ = storage.StoreAlias("spring-sale", "https://example.org", LinkOptions{})
+	preview := Preview{
+		Title:     "Example",
+		OpenGraph: map[string]string{"title": "Example, Inc."},
+		Favicon:   "https://example.com/favicon.ico",
+		FetchedAt: time.Date(2024, 2, 7, 12, 0, 0, 0, time.UTC),
+	}
+
+	s.NoError(storage.StorePreview(Link{Key: "1", URL: "https://example.com"}, preview))
+	s.NoError(storage.StorePreview(Link{Key: "spring-sale", URL: "https://example.org/other"}, preview))
+
+	link, err := storage.GetLink("1")
+
+	s.NoError(err)
+	s.Equal(preview.Title, link.Preview.Title)
+	s.Equal(preview.OpenGraph, link.Preview.OpenGraph)
+	s.True(preview.FetchedAt.Equal(link.Preview.FetchedAt))
+
+	link, _ = storage.GetLink("spring-sale")
+
+	s.True(link.Preview.IsZero())
+
+	// new destination is not fetched yet
+	URL := "https://example.com/new"
+	link, err = storage.UpdateLink("1", LinkUpdate{URL: &URL})
+
+	s.NoError(err)
+	s.True(link.Preview.IsZero())
+}
+
+func (s *dbStorageSuite) TestFallback() {
+	storage := s.newStorage(newTestConverter(AlphabetBase36, "", 0))
+
+	_, _ = storage.StoreURLs([]string{"https://example.com"}, LinkOptions{Fallback: "https://example.com/maintenance"})
+	_, _ = storage.StoreURLs([]string{"https://example.org"}, LinkOptions{})
+
+	link, err := storage.GetLink("1")
+
+	s.NoError(err)
+	s.Equal("https://example.com/maintenance", link.Fallback)
+	s.False(link.IsDown())
+
+	fallback := "https://example.org/maintenance"
+	down := true
+	link, err = storage.UpdateLink("2", LinkUpdate{Fallback: &fallback, Down: &down})
+
+	s.NoError(err)
+	s.Equal(fallback, link.Fallback)
+	s.True(link.IsDown())
+
+	fallback = ""
+	down = false
+	link, err = storage.UpdateLink("2", LinkUpdate{Fallback: &fallback, Down: &down})
+
+	s.NoError(err)
+	s.Empty(link.Fallback)
+	s.False(link.IsDown())
+
+	// links which were down or have fallback are not reused by deduplication
+	_, existing, err := storage.StoreUniqueURLs([]string{"https://example.com", "https://example.org"}, LinkOptions{})
+
+	s.NoError(err)
+	s.Empty(existing)
+}
+
+func (s *dbStorageSuite) TestUTMTemplate() {
+	storage := s.newStorage(newTestConverter(AlphabetBase36, "", 0))
+
+	_, _ = storage.StoreURLs([]string{"https://example1.com"}, LinkOptions{UTMTemplate: "spring"})
+	_ = storage.StoreAlias("spring-sale", "https://example2.com", LinkOptions{UTMTemplate: "spring"})
+
+	link, err := storage.GetLink("spring-sale")
+
+	s.NoError(err)
+	s.Equal("spring", link.UTMTemplate)
+
+	template := "autumn"
+	link, err = storage.UpdateLink("1", LinkUpdate{UTMTemplate: &template})
+
+	s.NoError(err)
+	s.Equal("autumn", link.UTMTemplate)
+
+	template = ""
+	link, err = storage.UpdateLink("1", LinkUpdate{UTMTemplate: &template})
+
+	s.NoError(err)
+	s.Equal("", link.UTMTemplate)
+}
+
+func (s *dbStorageSuite) TestCheckAlphabet() {
+	s.NoError(s.newStorage(newTestConverter(AlphabetBase62, "", 0)).CheckAlphabet())
+	s.NoError(s.newStorage(newTestConverter(AlphabetBase62, "secret", 0)).CheckAlphabet())
+
+	err := s.newStorage(newTestConverter(AlphabetBase36, "", 0)).CheckAlphabet()
+
+	s.ErrorIs(err, ErrAlphabetMismatch)
+}
+
+func (s *dbStorageSuite) TestCheckSequentialMaxID() {
+	_, _ = s.db.Exec("INSERT INTO links (url) VALUES ('https://example1.com'), ('https://example2.com')")
+
+	s.NoError(s.newStorage(newTestConverter(AlphabetBase36, "", 5)).CheckSequentialMaxID())
+
+	storage := s.newStorage(newTestConverter(AlphabetBase36, "secret", 0))
+
+	s.NoError(storage.CheckSequentialMaxID())
+
+	link, err := storage.GetLink("2")
+
+	s.NoError(err)
+	s.Equal("https://example2.com", link.URL)
+
+	s.NoError(s.newStorage(newTestConverter(AlphabetBase36, "secret", 2)).CheckSequentialMaxID())
+
+	err = s.newStorage(newTestConverter(AlphabetBase36, "secret", 5)).CheckSequentialMaxID()
+
+	s.EqualError(err, "configured last id with sequential key does not match recorded one: 2 is stored, 5 is configured")
+}
+
+func (s *dbStorageSuite) TestStoreURLsInBatches() {
+	storage := s.newStorage(newTestConverter(AlphabetBase36, "", 0))
+	URLs := make([]string, 0, s.batchSize*2+1)
+
+	for i := 0; i < cap(URLs); i++ {
+		URLs = append(URLs, "https://example.com/"+strconv.Itoa(i))
+	}
+
+	data, err := storage.StoreURLs(URLs, LinkOptions{})
+
+	s.NoError(err)
+	s.Len(data, len(URLs))
+	s.Equal("1", data[URLs[0]])
+
+	data, existing, err := storage.StoreUniqueURLs(append(URLs, "https://example.org"), LinkOptions{})
+
+	s.NoError(err)
+	s.Len(data, len(URLs)+1)
+	s.Empty(existing)
+
+	data, existing, err = storage.StoreUniqueURLs(URLs, LinkOptions{})
+
+	s.NoError(err)
+	s.Len(data, len(URLs))
+	s.Len(existing, len(URLs))
+}
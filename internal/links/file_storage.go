@@ -7,28 +7,94 @@ import (
 	"github.com/dzhdmitry/link-shorter/internal/utils"
 	"io"
 	"os"
+	"slices"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 )
 
 type FileStorage struct {
 	filename   string
-	links      map[int64]string
+	converter  *KeyConverter
+	links      map[int64]Link
+	aliases    map[string]int64
+	urls       map[string]int64
 	lastNumber int64
-	mu         sync.Mutex
+	alphabet   string
+	version    int
+	// sequentialMaxID Last id with sequential key, recorded when the secret is enabled, -1 if it is not recorded
+	sequentialMaxID int64
+	mu              sync.RWMutex
 }
 
-func NewFileStorage(filename string) (*FileStorage, error) {
-	s := FileStorage{filename: filename, links: map[int64]string{}}
+func NewFileStorage(filename string, converter *KeyConverter) (*FileStorage, error) {
+	s := FileStorage{filename: filename, converter: converter, links: map[int64]Link{}, aliases: map[string]int64{}}
+	s.urls = map[string]int64{}
+	s.sequentialMaxID = -1
 	err := s.Restore()
 
 	if err != nil {
 		return nil, err
 	}
 
+	if err = s.checkAlphabet(); err != nil {
+		return nil, err
+	}
+
+	if err = s.checkSequentialMaxID(); err != nil {
+		return nil, err
+	}
+
 	return &s, nil
 }
 
+// checkAlphabet Compares alphabet of stored links with configured one. Files without "alphabet" record
+// were written with base36 keys, empty file is marked with configured alphabet unless it is base36
+func (fs *FileStorage) checkAlphabet() error {
+	if fs.alphabet == "" && fs.lastNumber > 0 {
+		fs.alphabet = AlphabetBase36
+	}
+
+	if fs.alphabet == "" {
+		fs.alphabet = fs.converter.Alphabet()
+
+		if fs.alphabet == AlphabetBase36 {
+			return nil
+		}
+
+		return fs.persist([][]string{{recordAlphabet, fs.alphabet}})
+	}
+
+	if fs.alphabet != fs.converter.Alphabet() {
+		return fmt.Errorf("%w: %s is stored, %s is configured", ErrAlphabetMismatch, fs.alphabet, fs.converter.Alphabet())
+	}
+
+	return nil
+}
+
+// checkSequentialMaxID Records last id with sequential key when the secret is enabled for the first time: configured one,
+// or the last stored id if it is greater, so keys of stored links are not changed. Recorded id is used afterwards
+func (fs *FileStorage) checkSequentialMaxID() error {
+	if !fs.converter.HasSecret() {
+		return nil
+	}
+
+	if fs.sequentialMaxID == -1 {
+		fs.sequentialMaxID = max(fs.converter.SequentialMaxID(), fs.lastNumber)
+
+		if err := fs.persist([][]string{{recordSequential, strconv.FormatInt(fs.sequentialMaxID, 10)}}); err != nil {
+			return err
+		}
+	}
+
+	return fs.converter.applySequentialMaxID(fs.sequentialMaxID)
+}
+
+// persistFunc Writes records of change, it is called under lock of storage, so records are written in order they are made
+type persistFunc func(records [][]string) error
+
 func (fs *FileStorage) persist(idsURLs [][]string) error {
 	file, err := os.OpenFile(fs.filename, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
 
@@ -48,33 +114,450 @@ func (fs *FileStorage) persist(idsURLs [][]string) error {
 	return nil
 }
 
-func (fs *FileStorage) generate(URLs []string) ([][]string, map[string]string) {
+const recordUpdate = "update"
+const recordDisable = "disable"
+const recordEnable = "enable"
+const recordDelete = "delete"
+const recordPassword = "password"
+const recordMetadata = "meta"
+const recordUTMTemplate = "utm"
+const recordRules = "rules"
+const recordVariants = "variants"
+const recordHealth = "health"
+const recordFallback = "fallback"
+const recordDown = "down"
+const recordUp = "up"
+const recordPreview = "preview"
+const recordAlphabet = "alphabet"
+const recordSequential = "sequential"
+const recordVersion = "version"
+
+// formatVersion Version of records written to file. Files without "version" record are version 1,
+// version 2 adds metadata of links, version 3 adds UTM template, version 4 adds redirect rules, version 5 adds A/B split variants,
+// version 6 adds health of destinations, version 7 adds fallback and marking destination as down, version 8 adds previews of destinations
+const formatVersion = 8
+
+// linkRecordColumns Maximum number of columns of link record by version
+var linkRecordColumns = map[int]int{1: 6, 2: 10, 3: 11, 4: 12, 5: 13, 6: 13, 7: 14, 8: 14}
+
+// recordVersions Minimal version of change records
+var recordVersions = map[string]int{
+	recordMetadata:    2,
+	recordUTMTemplate: 3,
+	recordRules:       4,
+	recordVariants:    5,
+	recordHealth:      6,
+	recordFallback:    7,
+	recordDown:        7,
+	recordUp:          7,
+	recordPreview:     8,
+}
+
+// minVersion Returns minimal version of file which can have the record
+func minVersion(record []string) int {
+	if version, ok := recordVersions[record[0]]; ok {
+		return version
+	}
+
+	version := 1
+
+	for linkRecordColumns[version] < len(record) && version < formatVersion {
+		version++
+	}
+
+	return version
+}
+
+// formatTime Formats time of record, zero time is empty
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.UTC().Format(time.RFC3339)
+}
+
+// linkRecord Makes record "id,URL[,expiresAt[,alias[,owner[,createdAt[,title[,description[,tags[,notes[,utmTemplate[,rules[,variants[,fallback]]]]]]]]]]]]",
+// empty trailing columns are omitted, tags are separated by space, rules and variants are JSON
+func (fs *FileStorage) linkRecord(id int64, link Link) []string {
+	record := []string{
+		fmt.Sprintf("%d", id),
+		link.URL,
+		formatTime(link.ExpiresAt),
+		link.Alias,
+		link.Owner,
+		formatTime(link.CreatedAt),
+		link.Metadata.Title,
+		link.Metadata.Description,
+		strings.Join(link.Metadata.Tags, " "),
+		link.Metadata.Notes,
+		link.UTMTemplate,
+		encodeRules(link.Rules),
+		encodeVariants(link.Variants),
+		link.Fallback,
+	}
+
+	for len(record) > 2 && record[len(record)-1] == "" {
+		record = record[:len(record)-1]
+	}
+
+	return record
+}
+
+// metadataRecord Makes record "meta,id,title,description,tags,notes"
+func (fs *FileStorage) metadataRecord(id int64, metadata Metadata) []string {
+	return []string{
+		recordMetadata,
+		fmt.Sprintf("%d", id),
+		metadata.Title,
+		metadata.Description,
+		strings.Join(metadata.Tags, " "),
+		metadata.Notes,
+	}
+}
+
+// healthRecord Makes record "health,id,status,checkedAt,redirects,error", redirects are separated by space
+func (fs *FileStorage) healthRecord(id int64, health Health) []string {
+	return []string{
+		recordHealth,
+		fmt.Sprintf("%d", id),
+		strconv.Itoa(health.Status),
+		formatTime(health.CheckedAt),
+		strings.Join(health.Redirects, " "),
+		health.Error,
+	}
+}
+
+// upgrade Prepends "version" record to records unless file is already of current version or
+// all records can be written in version of file. Old files are upgraded on the first write which needs it only
+func (fs *FileStorage) upgrade(records [][]string) [][]string {
+	if fs.version >= formatVersion {
+		return records
+	}
+
+	for _, record := range records {
+		if minVersion(record) > max(fs.version, 1) {
+			fs.version = formatVersion
+
+			return append([][]string{{recordVersion, strconv.Itoa(formatVersion)}}, records...)
+		}
+	}
+
+	return records
+}
+
+func newLink(URL string, options LinkOptions) Link {
+	metadata := options.Metadata
+	metadata.Tags = nilIfEmpty(metadata.Tags)
+
+	return Link{
+		URL:          URL,
+		ExpiresAt:    options.ExpiresAt,
+		Owner:        options.Owner,
+		CreatedAt:    options.CreatedAt,
+		PasswordHash: options.PasswordHash,
+		Metadata:     metadata,
+		UTMTemplate:  options.UTMTemplate,
+		Rules:        nilIfEmpty(options.Rules),
+		Variants:     nilIfEmpty(options.Variants),
+		Fallback:     options.Fallback,
+	}
+}
+
+// newLinkRecords Makes record of new link, followed by record of its password, so they are written together
+func (fs *FileStorage) newLinkRecords(id int64, link Link) [][]string {
+	records := [][]string{fs.linkRecord(id, link)}
+
+	if link.PasswordHash != "" {
+		records = append(records, []string{recordPassword, fmt.Sprintf("%d", id), link.PasswordHash})
+	}
+
+	return records
+}
+
+func (fs *FileStorage) generate(URLs []string, options LinkOptions, persist persistFunc) (map[string]string, error) {
 	fs.mu.Lock()
 
 	defer fs.mu.Unlock()
 
+	if err := fs.converter.checkNewID(fs.lastNumber + int64(len(URLs))); err != nil {
+		return nil, err
+	}
+
 	var idsURLs [][]string
 	keysByURLs := make(map[string]string, len(URLs))
 
 	for _, URL := range URLs {
 		fs.lastNumber++
-		fs.links[fs.lastNumber] = URL
-		idsURLs = append(idsURLs, []string{fmt.Sprintf("%d", fs.lastNumber), URL})
-		keysByURLs[URL] = convertNumberToKey(fs.lastNumber)
+		fs.links[fs.lastNumber] = newLink(URL, options)
+		fs.indexURL(fs.lastNumber, fs.links[fs.lastNumber])
+		idsURLs = append(idsURLs, fs.newLinkRecords(fs.lastNumber, fs.links[fs.lastNumber])...)
+		keysByURLs[URL] = fs.converter.Key(fs.lastNumber)
 	}
 
-	return idsURLs, keysByURLs
+	if err := persist(fs.upgrade(idsURLs)); err != nil {
+		return nil, err
+	}
+
+	return keysByURLs, nil
 }
 
-// StoreURLs Returns map with key=URL, value=key
-func (fs *FileStorage) StoreURLs(URLs []string) (map[string]string, error) {
-	idsURLs, keysByURLs := fs.generate(URLs)
+// generateUnique Works as generate, but reuses links from reverse index. Returns set of reused URLs
+func (fs *FileStorage) generateUnique(URLs []string, options LinkOptions, persist persistFunc) (map[string]string, map[string]bool, error) {
+	fs.mu.Lock()
 
-	if err := fs.persist(idsURLs); err != nil {
-		return nil, err
+	defer fs.mu.Unlock()
+
+	if err := fs.converter.checkNewID(fs.lastNumber + int64(len(URLs))); err != nil {
+		return nil, nil, err
 	}
 
-	return keysByURLs, nil
+	var idsURLs [][]string
+	keysByURLs := make(map[string]string, len(URLs))
+	existing := map[string]bool{}
+
+	for _, URL := range URLs {
+		if _, ok := keysByURLs[URL]; ok {
+			continue
+		}
+
+		if id, ok := fs.urls[dedupKey(URL, options.ExpiresAt, options.Owner)]; ok {
+			keysByURLs[URL] = fs.converter.Key(id)
+			existing[URL] = true
+
+			continue
+		}
+
+		fs.lastNumber++
+		fs.links[fs.lastNumber] = newLink(URL, options)
+		fs.urls[dedupKey(URL, options.ExpiresAt, options.Owner)] = fs.lastNumber
+		idsURLs = append(idsURLs, fs.newLinkRecords(fs.lastNumber, fs.links[fs.lastNumber])...)
+		keysByURLs[URL] = fs.converter.Key(fs.lastNumber)
+	}
+
+	if err := persist(fs.upgrade(idsURLs)); err != nil {
+		return nil, nil, err
+	}
+
+	return keysByURLs, existing, nil
+}
+
+// indexURL Adds generated link to reverse index unless URL is already indexed
+func (fs *FileStorage) indexURL(id int64, link Link) {
+	key := dedupKey(link.URL, link.ExpiresAt, link.Owner)
+
+	if _, ok := fs.urls[key]; !ok && link.Alias == "" && link.PasswordHash == "" && link.UTMTemplate == "" && len(link.Rules) == 0 &&
+		len(link.Variants) == 0 && link.Fallback == "" {
+		fs.urls[key] = id
+	}
+}
+
+// unindexURL Removes link from reverse index, changed, disabled, down, deleted and links with UTM template, rules, variants
+// or fallback are not reused
+func (fs *FileStorage) unindexURL(id int64, link Link) {
+	key := dedupKey(link.URL, link.ExpiresAt, link.Owner)
+
+	if fs.urls[key] == id {
+		delete(fs.urls, key)
+	}
+}
+
+func (fs *FileStorage) generateAlias(alias, URL string, options LinkOptions, persist persistFunc) error {
+	fs.mu.Lock()
+
+	defer fs.mu.Unlock()
+
+	if _, ok := fs.aliases[alias]; ok {
+		return ErrAliasTaken
+	}
+
+	fs.lastNumber++
+	link := newLink(URL, options)
+	link.Alias = alias
+	fs.links[fs.lastNumber] = link
+	fs.aliases[alias] = fs.lastNumber
+
+	return persist(fs.upgrade(fs.newLinkRecords(fs.lastNumber, fs.links[fs.lastNumber])))
+}
+
+func (fs *FileStorage) update(key string, update LinkUpdate, persist persistFunc) (Link, error) {
+	fs.mu.Lock()
+
+	defer fs.mu.Unlock()
+
+	id, ok := fs.findID(key)
+
+	if !ok {
+		return Link{}, ErrLinkNotFound
+	}
+
+	var records [][]string
+	link := fs.links[id]
+	idRaw := fmt.Sprintf("%d", id)
+
+	if update.URL != nil || (update.Disabled != nil && *update.Disabled) || update.PasswordHash != nil ||
+		(update.UTMTemplate != nil && *update.UTMTemplate != "") || (update.Rules != nil && len(*update.Rules) > 0) ||
+		(update.Variants != nil && len(*update.Variants) > 0) || (update.Fallback != nil && *update.Fallback != "") ||
+		(update.Down != nil && *update.Down) {
+		fs.unindexURL(id, link)
+	}
+
+	if update.URL != nil {
+		// new destination is neither checked nor fetched yet
+		link.URL = *update.URL
+		link.Health = Health{}
+		link.Preview = Preview{}
+		records = append(records, []string{recordUpdate, idRaw, link.URL})
+	}
+
+	if update.Disabled != nil {
+		link.Disabled = *update.Disabled
+
+		if link.Disabled {
+			records = append(records, []string{recordDisable, idRaw})
+		} else {
+			records = append(records, []string{recordEnable, idRaw})
+		}
+	}
+
+	if update.PasswordHash != nil {
+		link.PasswordHash = *update.PasswordHash
+		records = append(records, []string{recordPassword, idRaw, link.PasswordHash})
+	}
+
+	if update.ChangesMetadata() {
+		link.Metadata = update.apply(link.Metadata)
+		records = append(records, fs.metadataRecord(id, link.Metadata))
+	}
+
+	if update.UTMTemplate != nil {
+		link.UTMTemplate = *update.UTMTemplate
+		records = append(records, []string{recordUTMTemplate, idRaw, link.UTMTemplate})
+	}
+
+	if update.Rules != nil {
+		link.Rules = nilIfEmpty(*update.Rules)
+		records = append(records, []string{recordRules, idRaw, encodeRules(link.Rules)})
+	}
+
+	if update.Variants != nil {
+		link.Variants = nilIfEmpty(*update.Variants)
+		records = append(records, []string{recordVariants, idRaw, encodeVariants(link.Variants)})
+	}
+
+	if update.Fallback != nil {
+		link.Fallback = *update.Fallback
+		records = append(records, []string{recordFallback, idRaw, link.Fallback})
+	}
+
+	if update.Down != nil {
+		link.Down = *update.Down
+
+		if link.Down {
+			records = append(records, []string{recordDown, idRaw})
+		} else {
+			records = append(records, []string{recordUp, idRaw})
+		}
+	}
+
+	fs.links[id] = link
+	link.Key = fs.converter.Key(id)
+
+	return link, persist(fs.upgrade(records))
+}
+
+// storeHealth Sets health of link, record is made only if outcome of check differs from the previous one.
+// Time of check is not written otherwise, so links are checked again sooner after restore
+func (fs *FileStorage) storeHealth(key, URL string, health Health, persist persistFunc) error {
+	fs.mu.Lock()
+
+	defer fs.mu.Unlock()
+
+	id, ok := fs.findID(key)
+
+	if !ok || fs.links[id].URL != URL {
+		return nil
+	}
+
+	link := fs.links[id]
+	previous := link.Health
+	link.Health = health
+	fs.links[id] = link
+
+	if !previous.IsZero() && previous.sameOutcome(health) {
+		return nil
+	}
+
+	return persist(fs.upgrade([][]string{fs.healthRecord(id, health)}))
+}
+
+// storePreview Sets preview of link, unless it was deleted or its URL was changed
+func (fs *FileStorage) storePreview(key, URL string, preview Preview, persist persistFunc) error {
+	fs.mu.Lock()
+
+	defer fs.mu.Unlock()
+
+	id, ok := fs.findID(key)
+
+	if !ok || fs.links[id].URL != URL {
+		return nil
+	}
+
+	link := fs.links[id]
+	link.Preview = preview
+	fs.links[id] = link
+
+	return persist(fs.upgrade([][]string{{recordPreview, fmt.Sprintf("%d", id), encodePreview(preview)}}))
+}
+
+func (fs *FileStorage) delete(key string, persist persistFunc) (Link, error) {
+	fs.mu.Lock()
+
+	defer fs.mu.Unlock()
+
+	id, ok := fs.findID(key)
+
+	if !ok {
+		return Link{}, ErrLinkNotFound
+	}
+
+	link := fs.links[id]
+	link.Key = fs.converter.Key(id)
+
+	// alias remains reserved for the deleted link
+	delete(fs.links, id)
+	fs.unindexURL(id, link)
+
+	return link, persist([][]string{{recordDelete, fmt.Sprintf("%d", id)}})
+}
+
+// StoreURLs Returns map with key=URL, value=key
+func (fs *FileStorage) StoreURLs(URLs []string, options LinkOptions) (map[string]string, error) {
+	return fs.generate(URLs, options, fs.persist)
+}
+
+func (fs *FileStorage) StoreUniqueURLs(URLs []string, options LinkOptions) (map[string]string, map[string]bool, error) {
+	return fs.generateUnique(URLs, options, fs.persist)
+}
+
+func (fs *FileStorage) StoreAlias(alias, URL string, options LinkOptions) error {
+	return fs.generateAlias(alias, URL, options, fs.persist)
+}
+
+func (fs *FileStorage) UpdateLink(key string, update LinkUpdate) (Link, error) {
+	return fs.update(key, update, fs.persist)
+}
+
+func (fs *FileStorage) DeleteLink(key string) (Link, error) {
+	return fs.delete(key, fs.persist)
+}
+
+func (fs *FileStorage) StoreHealth(link Link, health Health) error {
+	return fs.storeHealth(link.Key, link.URL, health, fs.persist)
+}
+
+func (fs *FileStorage) StorePreview(link Link, preview Preview) error {
+	return fs.storePreview(link.Key, link.URL, preview, fs.persist)
 }
 
 func (fs *FileStorage) Restore() error {
@@ -91,6 +574,7 @@ func (fs *FileStorage) Restore() error {
 	defer file.Close()
 
 	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
 
 	for {
 		record, err := reader.Read()
@@ -103,49 +587,464 @@ func (fs *FileStorage) Restore() error {
 			return err
 		}
 
+		if err = fs.restoreRecord(record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (fs *FileStorage) restoreRecord(record []string) error {
+	switch record[0] {
+	case recordUpdate, recordDisable, recordEnable, recordDelete, recordPassword, recordMetadata, recordUTMTemplate, recordRules,
+		recordVariants, recordHealth, recordFallback, recordDown, recordUp, recordPreview:
+		return fs.restoreChange(record)
+	case recordAlphabet:
+		if len(record) != 2 {
+			return errors.New("file has malformed data")
+		}
+
+		fs.alphabet = record[1]
+
+		return nil
+	case recordSequential:
 		if len(record) != 2 {
 			return errors.New("file has malformed data")
 		}
 
-		idRaw, URL := record[0], record[1]
-		id, err := strconv.ParseInt(idRaw, 10, 64)
+		id, err := strconv.ParseInt(record[1], 10, 64)
+
+		if err != nil || id < 0 {
+			return errors.New("file has malformed data")
+		}
+
+		fs.sequentialMaxID = id
+
+		return nil
+	case recordVersion:
+		return fs.restoreVersion(record)
+	}
+
+	if len(record) < 2 || len(record) > linkRecordColumns[max(fs.version, 1)] {
+		return errors.New("file has malformed data")
+	}
+
+	idRaw, URL := record[0], record[1]
+	id, err := strconv.ParseInt(idRaw, 10, 64)
+
+	if err != nil {
+		return err
+	}
+
+	link := Link{URL: URL}
+
+	if len(record) > 2 && record[2] != "" {
+		link.ExpiresAt, err = time.Parse(time.RFC3339, record[2])
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(record) > 3 && record[3] != "" {
+		link.Alias = record[3]
+		fs.aliases[link.Alias] = id
+	}
+
+	if len(record) > 4 {
+		link.Owner = record[4]
+	}
+
+	if len(record) > 5 && record[5] != "" {
+		link.CreatedAt, err = time.Parse(time.RFC3339, record[5])
 
 		if err != nil {
 			return err
 		}
+	}
+
+	if len(record) > 6 {
+		link.Metadata = restoreMetadata(record[6:min(len(record), 10)])
+	}
+
+	if len(record) > 10 {
+		link.UTMTemplate = record[10]
+	}
+
+	if len(record) > 11 {
+		if link.Rules, err = decodeRules(record[11]); err != nil {
+			return errors.New("file has malformed data")
+		}
+	}
+
+	if len(record) > 12 {
+		if link.Variants, err = decodeVariants(record[12]); err != nil {
+			return errors.New("file has malformed data")
+		}
+	}
+
+	if len(record) > 13 {
+		link.Fallback = record[13]
+	}
+
+	fs.links[id] = link
+	fs.indexURL(id, link)
+	fs.lastNumber = id
+
+	return nil
+}
+
+// restoreVersion Applies "version,number" record, files of newer versions can not be read
+func (fs *FileStorage) restoreVersion(record []string) error {
+	if len(record) != 2 {
+		return errors.New("file has malformed data")
+	}
+
+	version, err := strconv.Atoi(record[1])
+
+	if err != nil || version < 1 {
+		return errors.New("file has malformed data")
+	}
+
+	if version > formatVersion {
+		return fmt.Errorf("file has unsupported format version %d", version)
+	}
+
+	fs.version = version
+
+	return nil
+}
+
+// restoreMetadata Makes metadata of columns "title[,description[,tags[,notes]]]"
+func restoreMetadata(columns []string) Metadata {
+	columns = append(columns, make([]string, 4-len(columns))...)
+
+	return Metadata{
+		Title:       columns[0],
+		Description: columns[1],
+		Tags:        nilIfEmpty(strings.Fields(columns[2])),
+		Notes:       columns[3],
+	}
+}
+
+// restoreChange Applies "update,id,URL", "disable,id", "enable,id", "delete,id", "password,id,hash",
+// "meta,id,title,description,tags,notes", "utm,id,template", "rules,id,rules", "variants,id,variants",
+// "health,id,status,checkedAt,redirects,error", "fallback,id,URL", "down,id", "up,id" or "preview,id,preview" record
+func (fs *FileStorage) restoreChange(record []string) error {
+	if len(record) < 2 || minVersion(record) > max(fs.version, 1) {
+		return errors.New("file has malformed data")
+	}
+
+	switch record[0] {
+	case recordUpdate, recordPassword, recordUTMTemplate, recordRules, recordVariants, recordFallback, recordPreview:
+		if len(record) != 3 {
+			return errors.New("file has malformed data")
+		}
+	case recordMetadata, recordHealth:
+		if len(record) != 6 {
+			return errors.New("file has malformed data")
+		}
+	}
 
-		fs.links[id] = URL
-		fs.lastNumber = id
+	id, err := strconv.ParseInt(record[1], 10, 64)
+
+	if err != nil {
+		return err
 	}
 
+	link, ok := fs.links[id]
+
+	if !ok {
+		return nil
+	}
+
+	switch record[0] {
+	case recordUpdate:
+		fs.unindexURL(id, link)
+		link.URL = record[2]
+		link.Health = Health{}
+		link.Preview = Preview{}
+	case recordDisable:
+		fs.unindexURL(id, link)
+		link.Disabled = true
+	case recordEnable:
+		link.Disabled = false
+	case recordPassword:
+		fs.unindexURL(id, link)
+		link.PasswordHash = record[2]
+	case recordMetadata:
+		link.Metadata = restoreMetadata(record[2:])
+	case recordUTMTemplate:
+		if record[2] != "" {
+			fs.unindexURL(id, link)
+		}
+
+		link.UTMTemplate = record[2]
+	case recordRules:
+		rules, err := decodeRules(record[2])
+
+		if err != nil {
+			return errors.New("file has malformed data")
+		}
+
+		if len(rules) > 0 {
+			fs.unindexURL(id, link)
+		}
+
+		link.Rules = rules
+	case recordVariants:
+		variants, err := decodeVariants(record[2])
+
+		if err != nil {
+			return errors.New("file has malformed data")
+		}
+
+		if len(variants) > 0 {
+			fs.unindexURL(id, link)
+		}
+
+		link.Variants = variants
+	case recordHealth:
+		if link.Health, err = restoreHealth(record[2:]); err != nil {
+			return errors.New("file has malformed data")
+		}
+	case recordFallback:
+		if record[2] != "" {
+			fs.unindexURL(id, link)
+		}
+
+		link.Fallback = record[2]
+	case recordDown:
+		fs.unindexURL(id, link)
+		link.Down = true
+	case recordUp:
+		link.Down = false
+	case recordPreview:
+		if link.Preview, err = decodePreview(record[2]); err != nil {
+			return errors.New("file has malformed data")
+		}
+	case recordDelete:
+		fs.unindexURL(id, link)
+		delete(fs.links, id)
+
+		return nil
+	}
+
+	fs.links[id] = link
+
 	return nil
 }
 
-func (fs *FileStorage) GetURL(key string) (string, error) {
-	return fs.links[convertKeyToNumber(key)], nil
+// restoreHealth Makes health of columns "status,checkedAt,redirects,error"
+func restoreHealth(columns []string) (Health, error) {
+	status, err := strconv.Atoi(columns[0])
+
+	if err != nil {
+		return Health{}, err
+	}
+
+	checkedAt, err := time.Parse(time.RFC3339, columns[1])
+
+	if err != nil {
+		return Health{}, err
+	}
+
+	return Health{
+		Status:    status,
+		Redirects: nilIfEmpty(strings.Fields(columns[2])),
+		Error:     columns[3],
+		CheckedAt: checkedAt,
+	}, nil
+}
+
+func (fs *FileStorage) findID(key string) (int64, bool) {
+	id := fs.converter.ID(key)
+
+	if fs.converter.IsAlias(key) {
+		var ok bool
+
+		if id, ok = fs.aliases[key]; !ok {
+			return 0, false
+		}
+	}
+
+	_, ok := fs.links[id]
+
+	return id, ok
 }
 
-func (fs *FileStorage) GetURLs(keys []string) (map[string]string, error) {
-	URLs := make(map[string]string, len(keys))
+func (fs *FileStorage) findLink(key string) (Link, bool) {
+	id, ok := fs.findID(key)
+
+	if !ok {
+		return Link{}, false
+	}
+
+	link := fs.links[id]
+	link.Key = fs.converter.Key(id)
+
+	return link, true
+}
+
+func (fs *FileStorage) GetLink(key string) (Link, error) {
+	fs.mu.RLock()
+
+	defer fs.mu.RUnlock()
+
+	link, _ := fs.findLink(key)
+
+	return link, nil
+}
+
+func (fs *FileStorage) GetLinks(keys []string) (map[string]Link, error) {
+	fs.mu.RLock()
+
+	defer fs.mu.RUnlock()
+
+	links := make(map[string]Link, len(keys))
 
 	for _, key := range keys {
-		if URL, ok := fs.links[convertKeyToNumber(key)]; ok {
-			URLs[key] = URL
+		if link, ok := fs.findLink(key); ok {
+			links[key] = link
+		}
+	}
+
+	return links, nil
+}
+
+func (fs *FileStorage) ListLinks(query ListQuery) ([]Link, error) {
+	fs.mu.RLock()
+
+	defer fs.mu.RUnlock()
+
+	var ids []int64
+	descending := query.Sort == SortCreatedDesc
+	cursorID := fs.converter.ID(query.Cursor.Key)
+
+	// before Reports whether link a goes before link b in ascending order
+	before := func(aCreatedAt time.Time, a int64, bCreatedAt time.Time, b int64) bool {
+		if !aCreatedAt.Equal(bCreatedAt) {
+			return aCreatedAt.Before(bCreatedAt)
+		}
+
+		return a < b
+	}
+
+	for id, link := range fs.links {
+		if link.Owner != query.Owner || (query.Domain != "" && !matchesDomain(link.URL, query.Domain)) {
+			continue
+		}
+
+		if query.Tag != "" && !slices.Contains(link.Metadata.Tags, query.Tag) {
+			continue
+		}
+
+		if !link.Health.matches(query.Health) {
+			continue
+		}
+
+		if !query.Cursor.IsZero() {
+			if descending && !before(link.CreatedAt, id, query.Cursor.CreatedAt, cursorID) {
+				continue
+			}
+
+			if !descending && !before(query.Cursor.CreatedAt, cursorID, link.CreatedAt, id) {
+				continue
+			}
+		}
+
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := fs.links[ids[i]], fs.links[ids[j]]
+
+		if descending {
+			return before(b.CreatedAt, ids[j], a.CreatedAt, ids[i])
+		}
+
+		return before(a.CreatedAt, ids[i], b.CreatedAt, ids[j])
+	})
+
+	if len(ids) > query.Limit {
+		ids = ids[:query.Limit]
+	}
+
+	links := make([]Link, 0, len(ids))
+
+	for _, id := range ids {
+		link := fs.links[id]
+		link.Key = fs.converter.Key(id)
+		links = append(links, link)
+	}
+
+	return links, nil
+}
+
+func (fs *FileStorage) LinksToCheck(now, checkedBefore time.Time, limit int) ([]Link, error) {
+	fs.mu.RLock()
+
+	defer fs.mu.RUnlock()
+
+	var ids []int64
+
+	for id, link := range fs.links {
+		if link.Disabled || link.IsExpired(now) || !isCheckable(link.URL) {
+			continue
 		}
+
+		if !link.Health.IsZero() && !link.Health.CheckedAt.Before(checkedBefore) {
+			continue
+		}
+
+		ids = append(ids, id)
 	}
 
-	return URLs, nil
+	// links which were never checked have zero time and go first
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := fs.links[ids[i]].Health.CheckedAt, fs.links[ids[j]].Health.CheckedAt
+
+		if !a.Equal(b) {
+			return a.Before(b)
+		}
+
+		return ids[i] < ids[j]
+	})
+
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+
+	links := make([]Link, 0, len(ids))
+
+	for _, id := range ids {
+		link := fs.links[id]
+		link.Key = fs.converter.Key(id)
+		links = append(links, link)
+	}
+
+	return links, nil
 }
 
+// FileStorageAsync Works as FileStorage, but writes records in background. Records are queued in order they are made
+// and each background write takes the whole queue, so they are written in the same order
 type FileStorageAsync struct {
 	logger     *utils.Logger
 	background *utils.Background
 	fs         *FileStorage
+	queue      [][]string
 	mu         sync.Mutex
+	writeMu    sync.Mutex
 }
 
-func NewFileStorageAsync(logger *utils.Logger, background *utils.Background, filename string) (*FileStorageAsync, error) {
-	fs, err := NewFileStorage(filename)
+func NewFileStorageAsync(
+	logger *utils.Logger,
+	background *utils.Background,
+	filename string,
+	converter *KeyConverter,
+) (*FileStorageAsync, error) {
+	fs, err := NewFileStorage(filename, converter)
 
 	if err != nil {
 		return nil, err
@@ -158,30 +1057,85 @@ func NewFileStorageAsync(logger *utils.Logger, background *utils.Background, fil
 	}, nil
 }
 
-func (fsa *FileStorageAsync) StoreURLs(URLs []string) (map[string]string, error) {
-	idsURLs, keysByURLs := fsa.fs.generate(URLs)
+// persistInBackground Queues records, it is called under lock of storage
+func (fsa *FileStorageAsync) persistInBackground(records [][]string) error {
+	if len(records) == 0 {
+		return nil
+	}
 
-	fsa.background.Run(func() {
-		fsa.mu.Lock()
+	fsa.mu.Lock()
+	fsa.queue = append(fsa.queue, records...)
+	fsa.mu.Unlock()
 
-		defer fsa.mu.Unlock()
+	fsa.background.Run(fsa.flush)
 
-		if err := fsa.fs.persist(idsURLs); err != nil {
-			fsa.logger.LogError(err)
-		}
-	})
+	return nil
+}
 
-	return keysByURLs, nil
+// flush Writes queued records, queue is empty if previous flush has already written them
+func (fsa *FileStorageAsync) flush() {
+	fsa.writeMu.Lock()
+
+	defer fsa.writeMu.Unlock()
+
+	fsa.mu.Lock()
+	records := fsa.queue
+	fsa.queue = nil
+	fsa.mu.Unlock()
+
+	if len(records) == 0 {
+		return
+	}
+
+	if err := fsa.fs.persist(records); err != nil {
+		fsa.logger.LogError(err)
+	}
+}
+
+func (fsa *FileStorageAsync) StoreURLs(URLs []string, options LinkOptions) (map[string]string, error) {
+	return fsa.fs.generate(URLs, options, fsa.persistInBackground)
+}
+
+func (fsa *FileStorageAsync) StoreUniqueURLs(URLs []string, options LinkOptions) (map[string]string, map[string]bool, error) {
+	return fsa.fs.generateUnique(URLs, options, fsa.persistInBackground)
+}
+
+func (fsa *FileStorageAsync) StoreAlias(alias, URL string, options LinkOptions) error {
+	return fsa.fs.generateAlias(alias, URL, options, fsa.persistInBackground)
+}
+
+func (fsa *FileStorageAsync) UpdateLink(key string, update LinkUpdate) (Link, error) {
+	return fsa.fs.update(key, update, fsa.persistInBackground)
+}
+
+func (fsa *FileStorageAsync) DeleteLink(key string) (Link, error) {
+	return fsa.fs.delete(key, fsa.persistInBackground)
+}
+
+func (fsa *FileStorageAsync) StoreHealth(link Link, health Health) error {
+	return fsa.fs.storeHealth(link.Key, link.URL, health, fsa.persistInBackground)
+}
+
+func (fsa *FileStorageAsync) StorePreview(link Link, preview Preview) error {
+	return fsa.fs.storePreview(link.Key, link.URL, preview, fsa.persistInBackground)
 }
 
 func (fsa *FileStorageAsync) Restore() error {
 	return nil
 }
 
-func (fsa *FileStorageAsync) GetURL(key string) (string, error) {
-	return fsa.fs.GetURL(key)
+func (fsa *FileStorageAsync) GetLink(key string) (Link, error) {
+	return fsa.fs.GetLink(key)
+}
+
+func (fsa *FileStorageAsync) GetLinks(keys []string) (map[string]Link, error) {
+	return fsa.fs.GetLinks(keys)
+}
+
+func (fsa *FileStorageAsync) ListLinks(query ListQuery) ([]Link, error) {
+	return fsa.fs.ListLinks(query)
 }
 
-func (fsa *FileStorageAsync) GetURLs(keys []string) (map[string]string, error) {
-	return fsa.fs.GetURLs(keys)
+func (fsa *FileStorageAsync) LinksToCheck(now, checkedBefore time.Time, limit int) ([]Link, error) {
+	return fsa.fs.LinksToCheck(now, checkedBefore, limit)
 }
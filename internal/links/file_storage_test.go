@@ -1,22 +1,26 @@
 package links
 
 import (
+	"fmt"
 	"github.com/dzhdmitry/link-shorter/internal/utils"
 	"github.com/stretchr/testify/require"
 	"io"
 	"os"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 var testdata = "./../../test/testdata"
 
 func TestStoreURLs(t *testing.T) {
 	_ = os.Remove(testdata + "/results/test_store.csv")
-	s, err := NewFileStorage(testdata + "/results/test_store.csv")
+	s, err := NewFileStorage(testdata+"/results/test_store.csv", newTestConverter(AlphabetBase36, "", 0))
 
 	require.NoError(t, err)
 
-	URLs, err := s.StoreURLs([]string{"https://example.com"})
+	URLs, err := s.StoreURLs([]string{"https://example.com"}, LinkOptions{})
 
 	require.Equal(t, map[string]string{"https://example.com": "1"}, URLs)
 	require.NoError(t, err)
@@ -27,24 +31,86 @@ func TestStoreURLs(t *testing.T) {
 	require.Equal(t, "1,https://example.com\n", string(data))
 }
 
+func TestStoreURLsExpiresAt(t *testing.T) {
+	_ = os.Remove(testdata + "/results/test_store.csv")
+	s, err := NewFileStorage(testdata+"/results/test_store.csv", newTestConverter(AlphabetBase36, "", 0))
+
+	require.NoError(t, err)
+
+	expiresAt := time.Date(2024, 2, 8, 12, 0, 0, 0, time.UTC)
+	URLs, err := s.StoreURLs([]string{"https://example.com"}, LinkOptions{ExpiresAt: expiresAt})
+
+	require.Equal(t, map[string]string{"https://example.com": "1"}, URLs)
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(testdata + "/results/test_store.csv")
+
+	require.NoError(t, err)
+	require.Equal(t, "1,https://example.com,2024-02-08T12:00:00Z\n", string(data))
+
+	link, err := s.GetLink("1")
+
+	require.NoError(t, err)
+	require.Equal(t, Link{Key: "1", URL: "https://example.com", ExpiresAt: expiresAt}, link)
+}
+
+func TestStoreAlias(t *testing.T) {
+	_ = os.Remove(testdata + "/results/test_store.csv")
+	s, err := NewFileStorage(testdata+"/results/test_store.csv", newTestConverter(AlphabetBase36, "", 0))
+
+	require.NoError(t, err)
+
+	err = s.StoreAlias("spring-sale", "https://example.com", LinkOptions{})
+
+	require.NoError(t, err)
+
+	err = s.StoreAlias("spring-sale", "https://example2.com", LinkOptions{})
+
+	require.ErrorIs(t, err, ErrAliasTaken)
+
+	data, err := os.ReadFile(testdata + "/results/test_store.csv")
+
+	require.NoError(t, err)
+	require.Equal(t, "1,https://example.com,,spring-sale\n", string(data))
+
+	link, err := s.GetLink("spring-sale")
+
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com", link.URL)
+}
+
 func TestRestore(t *testing.T) {
 	tests := []struct {
 		name            string
 		filepath        string
 		expectedLastKey int64
-		expectedLinks   map[int64]string
+		expectedLinks   map[int64]Link
 	}{
-		{"Non-existed file", testdata + "/non-existing.csv", 0, map[int64]string{}},
-		{"Regular file", testdata + "/test_restore.csv", 3, map[int64]string{
-			1: "https://example1.com",
-			2: "https://example2.com",
-			3: "https://example3.com",
+		{"Non-existed file", testdata + "/non-existing.csv", 0, map[int64]Link{}},
+		{"Regular file", testdata + "/test_restore.csv", 3, map[int64]Link{
+			1: {URL: "https://example1.com"},
+			2: {URL: "https://example2.com"},
+			3: {URL: "https://example3.com"},
+		}},
+		{"File with expiration", testdata + "/test_restore_expiration.csv", 2, map[int64]Link{
+			1: {URL: "https://example1.com"},
+			2: {URL: "https://example2.com", ExpiresAt: time.Date(2024, 2, 8, 12, 0, 0, 0, time.UTC)},
+		}},
+		{"File with aliases", testdata + "/test_restore_alias.csv", 3, map[int64]Link{
+			1: {URL: "https://example1.com"},
+			2: {Alias: "spring-sale", URL: "https://example2.com"},
+			3: {Alias: "summer_sale", URL: "https://example3.com", ExpiresAt: time.Date(2024, 2, 8, 12, 0, 0, 0, time.UTC)},
+		}},
+		{"File with changes", testdata + "/test_restore_changes.csv", 4, map[int64]Link{
+			1: {URL: "https://example1-updated.com"},
+			3: {URL: "https://example3.com", Disabled: true},
+			4: {URL: "https://example4.com"},
 		}},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			s, err := NewFileStorage(tt.filepath)
+			s, err := NewFileStorage(tt.filepath, newTestConverter(AlphabetBase36, "", 0))
 
 			require.NoError(t, err)
 
@@ -57,7 +123,7 @@ func TestRestore(t *testing.T) {
 	}
 }
 
-func TestGetURL(t *testing.T) {
+func TestGetLinkFromFile(t *testing.T) {
 	tests := []struct {
 		name     string
 		key      string
@@ -68,57 +134,864 @@ func TestGetURL(t *testing.T) {
 		{"Regular", "2", "https://example2.com"},
 	}
 
-	s, _ := NewFileStorage(testdata + "/test_restore.csv")
+	s, _ := NewFileStorage(testdata+"/test_restore.csv", newTestConverter(AlphabetBase36, "", 0))
 	_ = s.Restore()
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			url, err := s.GetURL(tt.key)
+			link, err := s.GetLink(tt.key)
 
 			require.NoError(t, err)
-			require.Equal(t, tt.expected, url)
+			require.Equal(t, tt.expected, link.URL)
 		})
 	}
 }
 
-func TestGetURLs(t *testing.T) {
+func TestRestoreAliases(t *testing.T) {
+	s, err := NewFileStorage(testdata+"/test_restore_alias.csv", newTestConverter(AlphabetBase36, "", 0))
+
+	require.NoError(t, err)
+	require.Equal(t, map[string]int64{"spring-sale": 2, "summer_sale": 3}, s.aliases)
+
+	links, err := s.GetLinks([]string{"1", "spring-sale", "summer_sale", "winter-sale"})
+
+	require.NoError(t, err)
+	require.Equal(t, map[string]Link{
+		"1":           {Key: "1", URL: "https://example1.com"},
+		"spring-sale": {Key: "2", Alias: "spring-sale", URL: "https://example2.com"},
+		"summer_sale": {Key: "3", Alias: "summer_sale", URL: "https://example3.com", ExpiresAt: time.Date(2024, 2, 8, 12, 0, 0, 0, time.UTC)},
+	}, links)
+}
+
+func TestGetLinksFromFile(t *testing.T) {
 	tests := []struct {
-		name         string
-		keys         []string
-		expectedURLs map[string]string
+		name          string
+		keys          []string
+		expectedLinks map[string]Link
 	}{
-		{"Empty", []string{"", ""}, map[string]string{}},
-		{"Non-existing", []string{"aawd1"}, map[string]string{}},
-		{"Existing", []string{"2", "3"}, map[string]string{
-			"2": "https://example2.com",
-			"3": "https://example3.com",
+		{"Empty", []string{"", ""}, map[string]Link{}},
+		{"Non-existing", []string{"aawd1"}, map[string]Link{}},
+		{"Existing", []string{"2", "3"}, map[string]Link{
+			"2": {Key: "2", URL: "https://example2.com"},
+			"3": {Key: "3", URL: "https://example3.com"},
 		}},
 	}
 
-	s, _ := NewFileStorage(testdata + "/test_restore.csv")
+	s, _ := NewFileStorage(testdata+"/test_restore.csv", newTestConverter(AlphabetBase36, "", 0))
 	_ = s.Restore()
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			url, err := s.GetURLs(tt.keys)
+			links, err := s.GetLinks(tt.keys)
 
 			require.NoError(t, err)
-			require.Equal(t, tt.expectedURLs, url)
+			require.Equal(t, tt.expectedLinks, links)
 		})
 	}
 }
 
+func TestUpdateLink(t *testing.T) {
+	_ = os.Remove(testdata + "/results/test_store.csv")
+	s, err := NewFileStorage(testdata+"/results/test_store.csv", newTestConverter(AlphabetBase36, "", 0))
+
+	require.NoError(t, err)
+
+	_, _ = s.StoreURLs([]string{"https://example.com"}, LinkOptions{})
+	_ = s.StoreAlias("spring-sale", "https://example2.com", LinkOptions{})
+
+	URL := "https://example.org"
+	disabled := true
+	link, err := s.UpdateLink("1", LinkUpdate{URL: &URL})
+
+	require.NoError(t, err)
+	require.Equal(t, Link{Key: "1", URL: "https://example.org"}, link)
+
+	link, err = s.UpdateLink("spring-sale", LinkUpdate{Disabled: &disabled})
+
+	require.NoError(t, err)
+	require.Equal(t, Link{Key: "2", Alias: "spring-sale", URL: "https://example2.com", Disabled: true}, link)
+
+	_, err = s.UpdateLink("3", LinkUpdate{URL: &URL})
+
+	require.ErrorIs(t, err, ErrLinkNotFound)
+
+	data, err := os.ReadFile(testdata + "/results/test_store.csv")
+
+	require.NoError(t, err)
+	require.Equal(t, "1,https://example.com\n"+
+		"2,https://example2.com,,spring-sale\n"+
+		"update,1,https://example.org\n"+
+		"disable,2\n", string(data))
+
+	restored, err := NewFileStorage(testdata+"/results/test_store.csv", newTestConverter(AlphabetBase36, "", 0))
+
+	require.NoError(t, err)
+	require.Equal(t, s.links, restored.links)
+}
+
+func TestStorePassword(t *testing.T) {
+	_ = os.Remove(testdata + "/results/test_store.csv")
+	s, err := NewFileStorage(testdata+"/results/test_store.csv", newTestConverter(AlphabetBase36, "", 0))
+
+	require.NoError(t, err)
+
+	_, err = s.StoreURLs([]string{"https://example.com"}, LinkOptions{PasswordHash: "$2a$10$hash"})
+
+	require.NoError(t, err)
+	require.NoError(t, s.StoreAlias("secret-sale", "https://example2.com", LinkOptions{PasswordHash: "$2a$10$hash"}))
+
+	data, err := os.ReadFile(testdata + "/results/test_store.csv")
+
+	require.NoError(t, err)
+	require.Equal(t, "1,https://example.com\n"+
+		"password,1,$2a$10$hash\n"+
+		"2,https://example2.com,,secret-sale\n"+
+		"password,2,$2a$10$hash\n", string(data))
+
+	restored, err := NewFileStorage(testdata+"/results/test_store.csv", newTestConverter(AlphabetBase36, "", 0))
+
+	require.NoError(t, err)
+	require.Equal(t, s.links, restored.links)
+	require.Equal(t, "$2a$10$hash", restored.links[2].PasswordHash)
+}
+
+func TestUpdateLinkPassword(t *testing.T) {
+	_ = os.Remove(testdata + "/results/test_store.csv")
+	s, err := NewFileStorage(testdata+"/results/test_store.csv", newTestConverter(AlphabetBase36, "", 0))
+
+	require.NoError(t, err)
+
+	_, _ = s.StoreURLs([]string{"https://example.com", "https://example2.com"}, LinkOptions{})
+
+	passwordHash := "$2a$10$hash"
+	noPassword := ""
+	link, err := s.UpdateLink("1", LinkUpdate{PasswordHash: &passwordHash})
+
+	require.NoError(t, err)
+	require.Equal(t, Link{Key: "1", URL: "https://example.com", PasswordHash: passwordHash}, link)
+
+	_, _ = s.UpdateLink("2", LinkUpdate{PasswordHash: &passwordHash})
+	link, err = s.UpdateLink("2", LinkUpdate{PasswordHash: &noPassword})
+
+	require.NoError(t, err)
+	require.Equal(t, Link{Key: "2", URL: "https://example2.com"}, link)
+
+	data, err := os.ReadFile(testdata + "/results/test_store.csv")
+
+	require.NoError(t, err)
+	require.Equal(t, "1,https://example.com\n"+
+		"2,https://example2.com\n"+
+		"password,1,$2a$10$hash\n"+
+		"password,2,$2a$10$hash\n"+
+		"password,2,\n", string(data))
+
+	restored, err := NewFileStorage(testdata+"/results/test_store.csv", newTestConverter(AlphabetBase36, "", 0))
+
+	require.NoError(t, err)
+	require.Equal(t, s.links, restored.links)
+}
+
+func TestDeleteLink(t *testing.T) {
+	_ = os.Remove(testdata + "/results/test_store.csv")
+	s, err := NewFileStorage(testdata+"/results/test_store.csv", newTestConverter(AlphabetBase36, "", 0))
+
+	require.NoError(t, err)
+
+	_, _ = s.StoreURLs([]string{"https://example.com"}, LinkOptions{})
+	_ = s.StoreAlias("spring-sale", "https://example2.com", LinkOptions{})
+
+	link, err := s.DeleteLink("spring-sale")
+
+	require.NoError(t, err)
+	require.Equal(t, Link{Key: "2", Alias: "spring-sale", URL: "https://example2.com"}, link)
+
+	_, err = s.DeleteLink("2")
+
+	require.ErrorIs(t, err, ErrLinkNotFound)
+
+	link, err = s.GetLink("spring-sale")
+
+	require.NoError(t, err)
+	require.Equal(t, Link{}, link)
+
+	err = s.StoreAlias("spring-sale", "https://example3.com", LinkOptions{})
+
+	require.ErrorIs(t, err, ErrAliasTaken)
+
+	restored, err := NewFileStorage(testdata+"/results/test_store.csv", newTestConverter(AlphabetBase36, "", 0))
+
+	require.NoError(t, err)
+	require.Equal(t, s.links, restored.links)
+	require.Equal(t, s.aliases, restored.aliases)
+}
+
+func TestNonEnumerableKeys(t *testing.T) {
+	_ = os.Remove(testdata + "/results/test_store.csv")
+	s, err := NewFileStorage(testdata+"/results/test_store.csv", newTestConverter(AlphabetBase36, "", 0))
+
+	require.NoError(t, err)
+
+	_, _ = s.StoreURLs([]string{"https://example1.com"}, LinkOptions{})
+
+	s, err = NewFileStorage(testdata+"/results/test_store.csv", newTestConverter(AlphabetBase36, "secret", 1))
+
+	require.NoError(t, err)
+
+	URLs, err := s.StoreURLs([]string{"https://example2.com"}, LinkOptions{})
+
+	require.NoError(t, err)
+	require.Len(t, URLs["https://example2.com"], permutedKeyLength)
+
+	links, err := s.GetLinks([]string{"1", "2", URLs["https://example2.com"]})
+
+	require.NoError(t, err)
+	require.Equal(t, map[string]Link{
+		"1":                          {Key: "1", URL: "https://example1.com"},
+		URLs["https://example2.com"]: {Key: URLs["https://example2.com"], URL: "https://example2.com"},
+	}, links)
+}
+
+func TestStoreURLsOutOfKeyRange(t *testing.T) {
+	_ = os.Remove(testdata + "/results/test_store.csv")
+	s, err := NewFileStorage(testdata+"/results/test_store.csv", newTestConverter(AlphabetBase36, "secret", 0))
+
+	require.NoError(t, err)
+
+	s.lastNumber = pow(36, permutedKeyLength) - 3
+	URLs, err := s.StoreURLs([]string{"https://example1.com", "https://example2.com"}, LinkOptions{})
+
+	require.NoError(t, err)
+	require.Len(t, URLs["https://example2.com"], permutedKeyLength)
+
+	_, err = s.StoreURLs([]string{"https://example3.com"}, LinkOptions{})
+
+	require.ErrorIs(t, err, ErrKeysExhausted)
+
+	_, _, err = s.StoreUniqueURLs([]string{"https://example3.com"}, LinkOptions{})
+
+	require.ErrorIs(t, err, ErrKeysExhausted)
+	require.Len(t, s.links, 2)
+}
+
+func TestSequentialMaxID(t *testing.T) {
+	_ = os.Remove(testdata + "/results/test_store.csv")
+	s, err := NewFileStorage(testdata+"/results/test_store.csv", newTestConverter(AlphabetBase36, "", 0))
+
+	require.NoError(t, err)
+
+	_, _ = s.StoreURLs([]string{"https://example1.com", "https://example2.com"}, LinkOptions{})
+
+	s, err = NewFileStorage(testdata+"/results/test_store.csv", newTestConverter(AlphabetBase36, "secret", 0))
+
+	require.NoError(t, err)
+
+	URLs, err := s.StoreURLs([]string{"https://example3.com"}, LinkOptions{})
+
+	require.NoError(t, err)
+
+	link, err := s.GetLink("2")
+
+	require.NoError(t, err)
+	require.Equal(t, "https://example2.com", link.URL)
+
+	data, err := os.ReadFile(testdata + "/results/test_store.csv")
+
+	require.NoError(t, err)
+	require.Equal(t, "1,https://example1.com\n2,https://example2.com\nsequential,2\n3,https://example3.com\n", string(data))
+
+	s, err = NewFileStorage(testdata+"/results/test_store.csv", newTestConverter(AlphabetBase36, "secret", 2))
+
+	require.NoError(t, err)
+
+	link, err = s.GetLink(URLs["https://example3.com"])
+
+	require.NoError(t, err)
+	require.Equal(t, "https://example3.com", link.URL)
+
+	_, err = NewFileStorage(testdata+"/results/test_store.csv", newTestConverter(AlphabetBase36, "secret", 5))
+
+	require.EqualError(t, err, "configured last id with sequential key does not match recorded one: 2 is stored, 5 is configured")
+}
+
+func TestStoreUniqueURLs(t *testing.T) {
+	_ = os.Remove(testdata + "/results/test_store.csv")
+	s, err := NewFileStorage(testdata+"/results/test_store.csv", newTestConverter(AlphabetBase36, "", 0))
+
+	require.NoError(t, err)
+
+	expiresAt := time.Date(2024, 2, 8, 12, 0, 0, 0, time.UTC)
+	_, _ = s.StoreURLs([]string{"https://example1.com"}, LinkOptions{})
+	_ = s.StoreAlias("spring-sale", "https://example2.com", LinkOptions{})
+	_, _ = s.StoreURLs([]string{"https://example3.com"}, LinkOptions{ExpiresAt: expiresAt})
+	_, _ = s.StoreURLs([]string{"https://example4.com"}, LinkOptions{})
+	disabled := true
+	_, _ = s.UpdateLink("4", LinkUpdate{Disabled: &disabled})
+
+	keys, existing, err := s.StoreUniqueURLs([]string{
+		"https://example1.com",
+		"https://example2.com",
+		"https://example3.com",
+		"https://example4.com",
+		"https://example5.com",
+		"https://example5.com",
+	}, LinkOptions{})
+
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"https://example1.com": "1",
+		"https://example2.com": "5",
+		"https://example3.com": "6",
+		"https://example4.com": "7",
+		"https://example5.com": "8",
+	}, keys)
+	require.Equal(t, map[string]bool{"https://example1.com": true}, existing)
+
+	restored, err := NewFileStorage(testdata+"/results/test_store.csv", newTestConverter(AlphabetBase36, "", 0))
+
+	require.NoError(t, err)
+	require.Equal(t, s.urls, restored.urls)
+
+	keys, existing, err = restored.StoreUniqueURLs([]string{"https://example3.com", "https://example4.com"}, LinkOptions{ExpiresAt: expiresAt})
+
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"https://example3.com": "3", "https://example4.com": "9"}, keys)
+	require.Equal(t, map[string]bool{"https://example3.com": true}, existing)
+
+	_, _ = restored.DeleteLink("1")
+	keys, existing, err = restored.StoreUniqueURLs([]string{"https://example1.com"}, LinkOptions{})
+
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"https://example1.com": "a"}, keys)
+	require.Equal(t, map[string]bool{}, existing)
+}
+
+func TestStoreOwnerAndCreatedAt(t *testing.T) {
+	_ = os.Remove(testdata + "/results/test_store.csv")
+	s, err := NewFileStorage(testdata+"/results/test_store.csv", newTestConverter(AlphabetBase36, "", 0))
+
+	require.NoError(t, err)
+
+	createdAt := time.Date(2024, 2, 7, 12, 0, 0, 0, time.UTC)
+	_, _ = s.StoreURLs([]string{"https://example1.com"}, LinkOptions{Owner: "client", CreatedAt: createdAt})
+	_ = s.StoreAlias("spring-sale", "https://example2.com", LinkOptions{Owner: "client"})
+	_, _ = s.StoreURLs([]string{"https://example3.com"}, LinkOptions{CreatedAt: createdAt})
+
+	data, err := os.ReadFile(testdata + "/results/test_store.csv")
+
+	require.NoError(t, err)
+	require.Equal(t, "1,https://example1.com,,,client,2024-02-07T12:00:00Z\n"+
+		"2,https://example2.com,,spring-sale,client\n"+
+		"3,https://example3.com,,,,2024-02-07T12:00:00Z\n", string(data))
+
+	restored, err := NewFileStorage(testdata+"/results/test_store.csv", newTestConverter(AlphabetBase36, "", 0))
+
+	require.NoError(t, err)
+	require.Equal(t, s.links, restored.links)
+
+	link, err := restored.GetLink("1")
+
+	require.NoError(t, err)
+	require.Equal(t, Link{Key: "1", URL: "https://example1.com", Owner: "client", CreatedAt: createdAt}, link)
+
+	keys, existing, err := restored.StoreUniqueURLs([]string{"https://example1.com"}, LinkOptions{Owner: "other"})
+
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"https://example1.com": "4"}, keys)
+	require.Equal(t, map[string]bool{}, existing)
+}
+
+func TestListLinksFromFile(t *testing.T) {
+	_ = os.Remove(testdata + "/results/test_store.csv")
+	s, err := NewFileStorage(testdata+"/results/test_store.csv", newTestConverter(AlphabetBase36, "", 0))
+
+	require.NoError(t, err)
+
+	day := func(d int) LinkOptions {
+		return LinkOptions{Owner: "client", CreatedAt: time.Date(2024, 2, d, 12, 0, 0, 0, time.UTC)}
+	}
+
+	_, _ = s.StoreURLs([]string{"https://example.com/1"}, day(7))
+	_, _ = s.StoreURLs([]string{"https://blog.example.com/2", "https://example.org/3"}, day(5))
+	_ = s.StoreAlias("spring-sale", "https://example.com/4", day(6))
+	_, _ = s.StoreURLs([]string{"https://example.com/5"}, LinkOptions{CreatedAt: day(8).CreatedAt})
+	_, _ = s.StoreURLs([]string{"https://notexample.com/6"}, day(9))
+	_, _ = s.DeleteLink("1")
+	tags := []string{"sale", "spring"}
+
+	for _, key := range []string{"3", "6"} {
+		_, _ = s.UpdateLink(key, LinkUpdate{Tags: &tags})
+	}
+
+	tests := []struct {
+		name     string
+		query    ListQuery
+		expected []string
+	}{
+		{"Newest first", ListQuery{Owner: "client", Sort: SortCreatedDesc, Limit: 10}, []string{"6", "4", "3", "2"}},
+		{"Oldest first", ListQuery{Owner: "client", Sort: SortCreatedAsc, Limit: 10}, []string{"2", "3", "4", "6"}},
+		{"Limit", ListQuery{Owner: "client", Sort: SortCreatedAsc, Limit: 2}, []string{"2", "3"}},
+		{"Cursor ascending", ListQuery{Owner: "client", Sort: SortCreatedAsc, Limit: 10,
+			Cursor: ListCursor{CreatedAt: day(5).CreatedAt, Key: "2"}}, []string{"3", "4", "6"}},
+		{"Cursor descending", ListQuery{Owner: "client", Sort: SortCreatedDesc, Limit: 10,
+			Cursor: ListCursor{CreatedAt: day(5).CreatedAt, Key: "3"}}, []string{"2"}},
+		{"Domain", ListQuery{Owner: "client", Domain: "example.com", Sort: SortCreatedAsc, Limit: 10}, []string{"2", "4"}},
+		{"Other owner", ListQuery{Sort: SortCreatedAsc, Limit: 10}, []string{"5"}},
+		{"Tag", ListQuery{Owner: "client", Tag: "sale", Sort: SortCreatedAsc, Limit: 10}, []string{"3", "6"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found, err := s.ListLinks(tt.query)
+
+			require.NoError(t, err)
+
+			keys := make([]string, 0, len(found))
+
+			for _, link := range found {
+				keys = append(keys, link.Key)
+			}
+
+			require.Equal(t, tt.expected, keys)
+		})
+	}
+
+	found, _ := s.ListLinks(ListQuery{Owner: "client", Domain: "example.com", Sort: SortCreatedAsc, Limit: 10})
+
+	require.Equal(t, Link{Key: "4", Alias: "spring-sale", URL: "https://example.com/4", Owner: "client", CreatedAt: day(6).CreatedAt}, found[1])
+}
+
+func TestStoreMetadata(t *testing.T) {
+	_ = os.Remove(testdata + "/results/test_store.csv")
+	s, err := NewFileStorage(testdata+"/results/test_store.csv", newTestConverter(AlphabetBase36, "", 0))
+
+	require.NoError(t, err)
+
+	metadata := Metadata{Title: "Spring sale", Description: "Sale, spring", Tags: []string{"sale", "spring"}, Notes: "line 1\nline 2"}
+	_, _ = s.StoreURLs([]string{"https://example1.com"}, LinkOptions{})
+	_, _ = s.StoreURLs([]string{"https://example2.com"}, LinkOptions{Owner: "client", Metadata: metadata})
+	_, _ = s.StoreURLs([]string{"https://example3.com"}, LinkOptions{Metadata: Metadata{Tags: []string{}}})
+
+	title := "Summer sale"
+	tags := []string{}
+	link, err := s.UpdateLink("2", LinkUpdate{Title: &title, Tags: &tags})
+
+	require.NoError(t, err)
+	require.Equal(t, Metadata{Title: "Summer sale", Description: "Sale, spring", Notes: "line 1\nline 2"}, link.Metadata)
+
+	data, err := os.ReadFile(testdata + "/results/test_store.csv")
+
+	require.NoError(t, err)
+	require.Equal(t, "1,https://example1.com\n"+
+		"version,8\n"+
+		"2,https://example2.com,,,client,,Spring sale,\"Sale, spring\",sale spring,\"line 1\nline 2\"\n"+
+		"3,https://example3.com\n"+
+		"meta,2,Summer sale,\"Sale, spring\",,\"line 1\nline 2\"\n", string(data))
+
+	restored, err := NewFileStorage(testdata+"/results/test_store.csv", newTestConverter(AlphabetBase36, "", 0))
+
+	require.NoError(t, err)
+	require.Equal(t, s.links, restored.links)
+
+	_, _ = restored.StoreURLs([]string{"https://example4.com"}, LinkOptions{Metadata: Metadata{Title: "Autumn"}})
+	data, err = os.ReadFile(testdata + "/results/test_store.csv")
+
+	require.NoError(t, err)
+	require.Equal(t, 1, strings.Count(string(data), "version,8\n"))
+}
+
+func TestStoreUTMTemplate(t *testing.T) {
+	require.NoError(t, os.WriteFile(testdata+"/results/test_store.csv", []byte("version,2\n1,https://example1.com,,,,,title\n"), 0600))
+	s, err := NewFileStorage(testdata+"/results/test_store.csv", newTestConverter(AlphabetBase36, "", 0))
+
+	require.NoError(t, err)
+
+	_, _ = s.StoreURLs([]string{"https://example2.com"}, LinkOptions{UTMTemplate: "spring"})
+	template := ""
+	link, err := s.UpdateLink("2", LinkUpdate{UTMTemplate: &template})
+
+	require.NoError(t, err)
+	require.Equal(t, "", link.UTMTemplate)
+
+	template = "autumn"
+	_, _ = s.UpdateLink("1", LinkUpdate{UTMTemplate: &template})
+
+	data, err := os.ReadFile(testdata + "/results/test_store.csv")
+
+	require.NoError(t, err)
+	require.Equal(t, "version,2\n1,https://example1.com,,,,,title\n"+
+		"version,8\n"+
+		"2,https://example2.com,,,,,,,,,spring\n"+
+		"utm,2,\n"+
+		"utm,1,autumn\n", string(data))
+
+	restored, err := NewFileStorage(testdata+"/results/test_store.csv", newTestConverter(AlphabetBase36, "", 0))
+
+	require.NoError(t, err)
+	require.Equal(t, s.links, restored.links)
+	require.Equal(t, s.urls, restored.urls)
+	require.Equal(t, "autumn", restored.links[1].UTMTemplate)
+
+	// links with UTM template are not reused by deduplication
+	_, existing, err := restored.StoreUniqueURLs([]string{"https://example1.com", "https://example2.com"}, LinkOptions{})
+
+	require.NoError(t, err)
+	require.Empty(t, existing)
+}
+
+func TestStoreRules(t *testing.T) {
+	require.NoError(t, os.WriteFile(testdata+"/results/test_store.csv", []byte(""), 0600))
+	s, err := NewFileStorage(testdata+"/results/test_store.csv", newTestConverter(AlphabetBase36, "", 0))
+
+	require.NoError(t, err)
+
+	rules := Rules{{URL: "https://example.com/de", Languages: []string{"de"}}}
+	_, _ = s.StoreURLs([]string{"https://example.com"}, LinkOptions{Rules: rules})
+	_, _ = s.StoreURLs([]string{"https://example.org"}, LinkOptions{})
+	rules = Rules{{URL: "https://apps.apple.com/app", Platforms: []string{PlatformIOS}}}
+	link, err := s.UpdateLink("2", LinkUpdate{Rules: &rules})
+
+	require.NoError(t, err)
+	require.Equal(t, rules, link.Rules)
+
+	link, err = s.UpdateLink("1", LinkUpdate{Rules: &Rules{}})
+
+	require.NoError(t, err)
+	require.Nil(t, link.Rules)
+
+	data, err := os.ReadFile(testdata + "/results/test_store.csv")
+
+	require.NoError(t, err)
+	require.Equal(t, "version,8\n"+
+		`1,https://example.com,,,,,,,,,,"[{""url"":""https://example.com/de"",""languages"":[""de""]}]"`+"\n"+
+		"2,https://example.org\n"+
+		`rules,2,"[{""url"":""https://apps.apple.com/app"",""platforms"":[""ios""]}]"`+"\n"+
+		"rules,1,\n", string(data))
+
+	restored, err := NewFileStorage(testdata+"/results/test_store.csv", newTestConverter(AlphabetBase36, "", 0))
+
+	require.NoError(t, err)
+	require.Equal(t, s.links, restored.links)
+	require.Equal(t, s.urls, restored.urls)
+
+	// links with rules are not reused by deduplication
+	_, existing, err := restored.StoreUniqueURLs([]string{"https://example.com", "https://example.org"}, LinkOptions{})
+
+	require.NoError(t, err)
+	require.Empty(t, existing)
+}
+
+func TestStoreVariants(t *testing.T) {
+	require.NoError(t, os.WriteFile(testdata+"/results/test_store.csv", []byte(""), 0600))
+	s, err := NewFileStorage(testdata+"/results/test_store.csv", newTestConverter(AlphabetBase36, "", 0))
+
+	require.NoError(t, err)
+
+	variants := Variants{{URL: "https://example.com/a", Weight: 1}, {URL: "https://example.com/b", Weight: 3}}
+	_, _ = s.StoreURLs([]string{"https://example.com"}, LinkOptions{Variants: variants})
+	_, _ = s.StoreURLs([]string{"https://example.org"}, LinkOptions{})
+	variants = Variants{{URL: "https://example.org/a", Weight: 1}, {URL: "https://example.org/b", Weight: 1}}
+	link, err := s.UpdateLink("2", LinkUpdate{Variants: &variants})
+
+	require.NoError(t, err)
+	require.Equal(t, variants, link.Variants)
+
+	link, err = s.UpdateLink("1", LinkUpdate{Variants: &Variants{}})
+
+	require.NoError(t, err)
+	require.Nil(t, link.Variants)
+
+	data, err := os.ReadFile(testdata + "/results/test_store.csv")
+
+	require.NoError(t, err)
+	require.Equal(t, "version,8\n"+
+		`1,https://example.com,,,,,,,,,,,"[{""url"":""https://example.com/a"",""weight"":1},{""url"":""https://example.com/b"",""weight"":3}]"`+"\n"+
+		"2,https://example.org\n"+
+		`variants,2,"[{""url"":""https://example.org/a"",""weight"":1},{""url"":""https://example.org/b"",""weight"":1}]"`+"\n"+
+		"variants,1,\n", string(data))
+
+	restored, err := NewFileStorage(testdata+"/results/test_store.csv", newTestConverter(AlphabetBase36, "", 0))
+
+	require.NoError(t, err)
+	require.Equal(t, s.links, restored.links)
+	require.Equal(t, s.urls, restored.urls)
+
+	// links with variants are not reused by deduplication
+	_, existing, err := restored.StoreUniqueURLs([]string{"https://example.com", "https://example.org"}, LinkOptions{})
+
+	require.NoError(t, err)
+	require.Empty(t, existing)
+}
+
+func TestStoreHealth(t *testing.T) {
+	require.NoError(t, os.WriteFile(testdata+"/results/test_store.csv", []byte(""), 0600))
+	s, err := NewFileStorage(testdata+"/results/test_store.csv", newTestConverter(AlphabetBase36, "", 0))
+
+	require.NoError(t, err)
+
+	now := time.Date(2024, 2, 7, 12, 0, 0, 0, time.UTC)
+	_, _ = s.StoreURLs([]string{"https://example.com/1", "mailto:info@example.com", "https://example.com/3"}, LinkOptions{})
+	_, _ = s.StoreURLs([]string{"https://example.com/4"}, LinkOptions{ExpiresAt: now.Add(-time.Hour)})
+	_, _ = s.StoreURLs([]string{"https://example.com/5"}, LinkOptions{})
+	disabled := true
+	_, _ = s.UpdateLink("3", LinkUpdate{Disabled: &disabled})
+	due, err := s.LinksToCheck(now, now.Add(-time.Hour), 10)
+
+	require.NoError(t, err)
+	require.Len(t, due, 2)
+	require.Equal(t, "1", due[0].Key)
+	require.Equal(t, "5", due[1].Key)
+
+	broken := Health{Status: 404, Redirects: []string{"https://example.com/moved"}, CheckedAt: now.Add(-2 * time.Hour)}
+
+	require.NoError(t, s.StoreHealth(Link{Key: "5", URL: "https://example.com/5"}, Health{Status: 200, CheckedAt: now.Add(-2 * time.Hour)}))
+	require.NoError(t, s.StoreHealth(Link{Key: "1", URL: "https://example.com/1"}, broken))
+	require.NoError(t, s.StoreHealth(Link{Key: "1", URL: "https://example.com/other"}, Health{Status: 200, CheckedAt: now}))
+
+	due, _ = s.LinksToCheck(now, now.Add(-time.Hour), 1)
+
+	require.Len(t, due, 1)
+	require.Equal(t, "1", due[0].Key)
+	require.Equal(t, broken, due[0].Health)
+
+	// the same outcome only moves time of check
+	broken.CheckedAt = now
+
+	require.NoError(t, s.StoreHealth(Link{Key: "1", URL: "https://example.com/1"}, broken))
+
+	due, _ = s.LinksToCheck(now, now.Add(-time.Hour), 10)
+
+	require.Len(t, due, 1)
+	require.Equal(t, "5", due[0].Key)
+
+	found, err := s.ListLinks(ListQuery{Health: HealthBroken, Sort: SortCreatedAsc, Limit: 10})
+
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	require.Equal(t, "1", found[0].Key)
+
+	found, _ = s.ListLinks(ListQuery{Health: HealthOK, Sort: SortCreatedAsc, Limit: 10})
+
+	require.Len(t, found, 1)
+	require.Equal(t, "5", found[0].Key)
+
+	data, err := os.ReadFile(testdata + "/results/test_store.csv")
+
+	require.NoError(t, err)
+	require.Equal(t, "1,https://example.com/1\n2,mailto:info@example.com\n3,https://example.com/3\n"+
+		"4,https://example.com/4,2024-02-07T11:00:00Z\n5,https://example.com/5\ndisable,3\n"+
+		"version,8\nhealth,5,200,2024-02-07T10:00:00Z,,\n"+
+		"health,1,404,2024-02-07T10:00:00Z,https://example.com/moved,\n", string(data))
+
+	restored, err := NewFileStorage(testdata+"/results/test_store.csv", newTestConverter(AlphabetBase36, "", 0))
+
+	require.NoError(t, err)
+
+	link, _ := restored.GetLink("1")
+
+	require.Equal(t, 404, link.Health.Status)
+	require.Equal(t, []string{"https://example.com/moved"}, link.Health.Redirects)
+	require.Equal(t, now.Add(-2*time.Hour), link.Health.CheckedAt)
+
+	// new destination is not checked yet
+	URL := "https://example.com/new"
+	link, err = restored.UpdateLink("1", LinkUpdate{URL: &URL})
+
+	require.NoError(t, err)
+	require.True(t, link.Health.IsZero())
+
+	restored, err = NewFileStorage(testdata+"/results/test_store.csv", newTestConverter(AlphabetBase36, "", 0))
+
+	require.NoError(t, err)
+
+	link, _ = restored.GetLink("1")
+
+	require.True(t, link.Health.IsZero())
+}
+
+func TestStoreFallback(t *testing.T) {
+	require.NoError(t, os.WriteFile(testdata+"/results/test_store.csv", []byte(""), 0600))
+	s, err := NewFileStorage(testdata+"/results/test_store.csv", newTestConverter(AlphabetBase36, "", 0))
+
+	require.NoError(t, err)
+
+	_, _ = s.StoreURLs([]string{"https://example.com"}, LinkOptions{Fallback: "https://example.com/maintenance"})
+	_, _ = s.StoreURLs([]string{"https://example.org"}, LinkOptions{})
+	fallback := "https://example.org/maintenance"
+	down := true
+	link, err := s.UpdateLink("2", LinkUpdate{Fallback: &fallback, Down: &down})
+
+	require.NoError(t, err)
+	require.Equal(t, fallback, link.Fallback)
+	require.True(t, link.IsDown())
+
+	fallback = ""
+	down = false
+	link, err = s.UpdateLink("1", LinkUpdate{Fallback: &fallback, Down: &down})
+
+	require.NoError(t, err)
+	require.Empty(t, link.Fallback)
+	require.False(t, link.IsDown())
+
+	data, err := os.ReadFile(testdata + "/results/test_store.csv")
+
+	require.NoError(t, err)
+	require.Equal(t, "version,8\n"+
+		"1,https://example.com,,,,,,,,,,,,https://example.com/maintenance\n"+
+		"2,https://example.org\n"+
+		"fallback,2,https://example.org/maintenance\ndown,2\n"+
+		"fallback,1,\nup,1\n", string(data))
+
+	restored, err := NewFileStorage(testdata+"/results/test_store.csv", newTestConverter(AlphabetBase36, "", 0))
+
+	require.NoError(t, err)
+	require.Equal(t, s.links, restored.links)
+	require.Equal(t, s.urls, restored.urls)
+
+	// links which are down or have fallback are not reused by deduplication
+	_, existing, err := restored.StoreUniqueURLs([]string{"https://example.org"}, LinkOptions{})
+
+	require.NoError(t, err)
+	require.Empty(t, existing)
+}
+
+func TestStorePreview(t *testing.T) {
+	require.NoError(t, os.WriteFile(testdata+"/results/test_store.csv", []byte("1,https://example.com\n"), 0600))
+	s, err := NewFileStorage(testdata+"/results/test_store.csv", newTestConverter(AlphabetBase36, "", 0))
+
+	require.NoError(t, err)
+
+	_ = s.StoreAlias("spring-sale", "https://example.org", LinkOptions{})
+	preview := Preview{
+		Title:     "Example",
+		OpenGraph: map[string]string{"title": "Example, Inc."},
+		Favicon:   "https://example.com/favicon.ico",
+		FetchedAt: time.Date(2024, 2, 7, 12, 0, 0, 0, time.UTC),
+	}
+
+	require.NoError(t, s.StorePreview(Link{Key: "1", URL: "https://example.com"}, preview))
+	require.NoError(t, s.StorePreview(Link{Key: "spring-sale", URL: "https://example.org/other"}, preview))
+
+	link, _ := s.GetLink("1")
+
+	require.Equal(t, preview, link.Preview)
+
+	link, _ = s.GetLink("spring-sale")
+
+	require.True(t, link.Preview.IsZero())
+
+	data, err := os.ReadFile(testdata + "/results/test_store.csv")
+
+	require.NoError(t, err)
+	require.Equal(t, "1,https://example.com\n2,https://example.org,,spring-sale\nversion,8\n"+
+		`preview,1,"{""title"":""Example"",""open_graph"":{""title"":""Example, Inc.""},`+
+		`""favicon"":""https://example.com/favicon.ico"",""fetched_at"":""2024-02-07T12:00:00Z""}"`+"\n", string(data))
+
+	restored, err := NewFileStorage(testdata+"/results/test_store.csv", newTestConverter(AlphabetBase36, "", 0))
+
+	require.NoError(t, err)
+	require.Equal(t, s.links, restored.links)
+
+	// new destination is not fetched yet
+	URL := "https://example.com/new"
+	link, err = restored.UpdateLink("1", LinkUpdate{URL: &URL})
+
+	require.NoError(t, err)
+	require.True(t, link.Preview.IsZero())
+
+	restored, err = NewFileStorage(testdata+"/results/test_store.csv", newTestConverter(AlphabetBase36, "", 0))
+
+	require.NoError(t, err)
+
+	link, _ = restored.GetLink("1")
+
+	require.True(t, link.Preview.IsZero())
+}
+
+func TestRestoreVersion(t *testing.T) {
+	tests := []struct {
+		name          string
+		data          string
+		expectedError string
+	}{
+		{"Metadata of version 1", "1,https://example.com,,,,,title\n", "file has malformed data"},
+		{"Metadata record of version 1", "1,https://example.com\nmeta,1,title,,,\n", "file has malformed data"},
+		{"UTM template of version 2", "version,2\n1,https://example.com,,,,,,,,,spring\n", "file has malformed data"},
+		{"UTM template record of version 2", "version,2\n1,https://example.com\nutm,1,spring\n", "file has malformed data"},
+		{"Rules of version 3", "version,3\n1,https://example.com,,,,,,,,,,[]\n", "file has malformed data"},
+		{"Rules record of version 3", "version,3\n1,https://example.com\nrules,1,[]\n", "file has malformed data"},
+		{"Malformed rules", "version,4\n1,https://example.com\nrules,1,{\n", "file has malformed data"},
+		{"Variants of version 4", "version,4\n1,https://example.com,,,,,,,,,,,[]\n", "file has malformed data"},
+		{"Variants record of version 4", "version,4\n1,https://example.com\nvariants,1,[]\n", "file has malformed data"},
+		{"Malformed variants", "version,5\n1,https://example.com\nvariants,1,{\n", "file has malformed data"},
+		{"Health record of version 5", "version,5\n1,https://example.com\nhealth,1,200,2024-02-07T12:00:00Z,,\n", "file has malformed data"},
+		{"Malformed health", "version,6\n1,https://example.com\nhealth,1,ok,2024-02-07T12:00:00Z,,\n", "file has malformed data"},
+		{"Fallback of version 6", "version,6\n1,https://example.com,,,,,,,,,,,,https://example.org\n", "file has malformed data"},
+		{"Fallback record of version 6", "version,6\n1,https://example.com\nfallback,1,https://example.org\n", "file has malformed data"},
+		{"Down record of version 6", "version,6\n1,https://example.com\ndown,1\n", "file has malformed data"},
+		{"Malformed fallback", "version,7\n1,https://example.com\nfallback,1\n", "file has malformed data"},
+		{"Preview record of version 7", "version,7\n1,https://example.com\npreview,1,\n", "file has malformed data"},
+		{"Malformed preview", "version,8\n1,https://example.com\npreview,1,{\n", "file has malformed data"},
+		{"Unsupported version", "version,9\n1,https://example.com\n", "file has unsupported format version 9"},
+		{"Invalid version", "version,new\n", "file has malformed data"},
+		{"Invalid sequential id", "sequential,-1\n", "file has malformed data"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, os.WriteFile(testdata+"/results/test_store.csv", []byte(tt.data), 0600))
+
+			_, err := NewFileStorage(testdata+"/results/test_store.csv", newTestConverter(AlphabetBase36, "", 0))
+
+			require.EqualError(t, err, tt.expectedError)
+		})
+	}
+}
+
+func TestAlphabetMismatch(t *testing.T) {
+	_ = os.Remove(testdata + "/results/test_store.csv")
+	s, err := NewFileStorage(testdata+"/results/test_store.csv", newTestConverter(AlphabetBase62, "", 0))
+
+	require.NoError(t, err)
+
+	URLs, err := s.StoreURLs([]string{"https://example.com"}, LinkOptions{})
+
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(testdata + "/results/test_store.csv")
+
+	require.NoError(t, err)
+	require.Equal(t, "alphabet,base62\n1,https://example.com\n", string(data))
+
+	s, err = NewFileStorage(testdata+"/results/test_store.csv", newTestConverter(AlphabetBase62, "", 0))
+
+	require.NoError(t, err)
+
+	link, err := s.GetLink(URLs["https://example.com"])
+
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com", link.URL)
+
+	_, err = NewFileStorage(testdata+"/results/test_store.csv", newTestConverter(AlphabetBase36, "", 0))
+
+	require.ErrorIs(t, err, ErrAlphabetMismatch)
+
+	_, err = NewFileStorage(testdata+"/test_restore.csv", newTestConverter(AlphabetHumanSafe, "", 0))
+
+	require.EqualError(t, err, "configured key alphabet does not match alphabet of stored links: base36 is stored, human-safe is configured")
+}
+
 func TestAsyncStoreURLs(t *testing.T) {
 	_ = os.Remove(testdata + "/results/test_store.csv")
 	background := &utils.Background{}
 	s, err := NewFileStorageAsync(
 		utils.NewLogger(io.Discard, &utils.Clock{}),
 		background, testdata+"/results/test_store.csv",
+		newTestConverter(AlphabetBase36, "", 0),
 	)
 
 	require.NoError(t, err)
 
-	URLs, err := s.StoreURLs([]string{"https://example.com"})
+	URLs, err := s.StoreURLs([]string{"https://example.com"}, LinkOptions{})
 
 	require.NoError(t, err)
 	require.Equal(t, map[string]string{"https://example.com": "1"}, URLs)
@@ -130,3 +1003,55 @@ func TestAsyncStoreURLs(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, "1,https://example.com\n", string(data))
 }
+
+func TestAsyncPersistsInOrder(t *testing.T) {
+	require.NoError(t, os.WriteFile(testdata+"/results/test_store.csv", []byte("1,https://example.com\n"), 0600))
+
+	background := &utils.Background{}
+	s, err := NewFileStorageAsync(
+		utils.NewLogger(io.Discard, &utils.Clock{}),
+		background, testdata+"/results/test_store.csv",
+		newTestConverter(AlphabetBase36, "", 0),
+	)
+
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			URL := fmt.Sprintf("https://example.com/%d", i)
+			keys, err := s.StoreURLs([]string{URL}, LinkOptions{})
+
+			require.NoError(t, err)
+
+			title := fmt.Sprintf("Page %d", i)
+			_, err = s.UpdateLink(keys[URL], LinkUpdate{Title: &title})
+
+			require.NoError(t, err)
+
+			_, err = s.GetLinks([]string{keys[URL], "1"})
+
+			require.NoError(t, err)
+
+			if i%2 == 0 {
+				_, err = s.DeleteLink(keys[URL])
+
+				require.NoError(t, err)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	background.Wait()
+
+	restored, err := NewFileStorage(testdata+"/results/test_store.csv", newTestConverter(AlphabetBase36, "", 0))
+
+	require.NoError(t, err)
+	require.Equal(t, s.fs.links, restored.links)
+	require.Len(t, restored.links, 26)
+}
@@ -0,0 +1,228 @@
+package links
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dzhdmitry/link-shorter/internal/utils"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const HealthBroken = "broken"
+const HealthOK = "ok"
+
+// maxHealthRedirects Number of redirects followed by health check, longer chains are reported as broken
+const maxHealthRedirects = 10
+
+// Health Result of the last check of link destination. Status is HTTP status of the last response, 0 if request failed,
+// Redirects are URLs which destination redirected to, in order. Zero health means link was not checked yet
+type Health struct {
+	Status    int
+	Redirects []string
+	Error     string
+	CheckedAt time.Time
+}
+
+func (h Health) IsZero() bool {
+	return h.CheckedAt.IsZero()
+}
+
+// IsBroken Reports whether destination could not be requested or responded with error status
+func (h Health) IsBroken() bool {
+	return !h.IsZero() && (h.Error != "" || h.Status >= http.StatusBadRequest)
+}
+
+// matches Reports whether health is of state "broken" or "ok", any health matches empty state
+func (h Health) matches(state string) bool {
+	switch state {
+	case HealthBroken:
+		return h.IsBroken()
+	case HealthOK:
+		return !h.IsZero() && !h.IsBroken()
+	}
+
+	return true
+}
+
+// sameOutcome Reports whether results of checks differ only by time
+func (h Health) sameOutcome(other Health) bool {
+	return h.Status == other.Status && h.Error == other.Error && strings.Join(h.Redirects, "\n") == strings.Join(other.Redirects, "\n")
+}
+
+// isCheckable Reports whether destination is requested by health checker, only http and https URLs are checked
+func isCheckable(URL string) bool {
+	lower := strings.ToLower(URL)
+
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+type HealthStorageInterface interface {
+	// LinksToCheck Returns up to limit http and https links, which are neither disabled nor expired at now and were not checked
+	// since checkedBefore. Links which were never checked go first, then the least recently checked ones
+	LinksToCheck(now, checkedBefore time.Time, limit int) ([]Link, error)
+	// StoreHealth Records health of link returned by LinksToCheck, it is skipped if link was deleted or its URL was changed since
+	StoreHealth(link Link, health Health) error
+}
+
+// HealthChecker Requests destinations of links in background and records their health.
+// Every check interval each link is requested once, links are taken from storage by batches
+type HealthChecker struct {
+	storage   HealthStorageInterface
+	client    *http.Client
+	clock     utils.ClockInterface
+	logger    *utils.Logger
+	interval  time.Duration
+	batchSize int
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+func NewHealthChecker(
+	storage HealthStorageInterface,
+	client *http.Client,
+	clock utils.ClockInterface,
+	logger *utils.Logger,
+	background *utils.Background,
+	interval time.Duration,
+	batchSize int,
+) *HealthChecker {
+	c := HealthChecker{
+		storage:   storage,
+		client:    client,
+		clock:     clock,
+		logger:    logger,
+		interval:  interval,
+		batchSize: batchSize,
+	}
+
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+
+	background.Run(c.run)
+
+	return &c
+}
+
+// Close Stops checking, request in progress is cancelled
+func (c *HealthChecker) Close() {
+	c.cancel()
+}
+
+func (c *HealthChecker) run() {
+	// links become due for check continuously, so storage is polled more often than each link is checked
+	ticker := time.NewTicker(min(c.interval, time.Minute))
+
+	defer ticker.Stop()
+
+	for {
+		if err := c.CheckLinks(); err != nil {
+			c.logger.LogError(err)
+		}
+
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CheckLinks Checks all links which were not checked during the last interval. Returns when there are no such links
+// or checker is closed
+func (c *HealthChecker) CheckLinks() error {
+	checkedBefore := c.clock.Now().Add(-c.interval)
+
+	for c.ctx.Err() == nil {
+		due, err := c.storage.LinksToCheck(c.clock.Now(), checkedBefore, c.batchSize)
+
+		if err != nil {
+			return err
+		}
+
+		for _, link := range due {
+			health := c.Check(link.URL)
+
+			if c.ctx.Err() != nil {
+				return nil
+			}
+
+			if err = c.storage.StoreHealth(link, health); err != nil {
+				return err
+			}
+		}
+
+		if len(due) < c.batchSize {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// Check Requests URL with HEAD, or with GET if server does not support HEAD, and follows redirects
+func (c *HealthChecker) Check(URL string) Health {
+	health := c.request(http.MethodHead, URL)
+
+	if health.Status == http.StatusMethodNotAllowed || health.Status == http.StatusNotImplemented {
+		health = c.request(http.MethodGet, URL)
+	}
+
+	health.CheckedAt = c.clock.Now().UTC().Truncate(time.Second)
+
+	return health
+}
+
+func (c *HealthChecker) request(method, URL string) Health {
+	var health Health
+	client := *c.client
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) > maxHealthRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxHealthRedirects)
+		}
+
+		// redirect policy of client, e.g. refusing internal addresses, still applies
+		if c.client.CheckRedirect != nil {
+			if err := c.client.CheckRedirect(req, via); err != nil {
+				return err
+			}
+		}
+
+		health.Redirects = append(health.Redirects, req.URL.String())
+
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(c.ctx, method, URL, nil)
+
+	if err != nil {
+		health.Error = err.Error()
+
+		return health
+	}
+
+	response, err := client.Do(req)
+
+	if err != nil {
+		var urlErr *url.Error
+
+		// error is reported without method and URL, which are known
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+
+		health.Error = err.Error()
+
+		return health
+	}
+
+	defer response.Body.Close()
+
+	// body is not needed, a little of it is read to let connection be reused
+	_, _ = io.CopyN(io.Discard, response.Body, 4096)
+	health.Status = response.StatusCode
+
+	return health
+}
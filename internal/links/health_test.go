@@ -0,0 +1,189 @@
+package links
+
+import (
+	"errors"
+	"github.com/dzhdmitry/link-shorter/internal/utils"
+	"github.com/dzhdmitry/link-shorter/test"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestDestinations() *httptest.Server {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("/get-only", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/found", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/found", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ok", http.StatusFound)
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func newTestHealthChecker(storage HealthStorageInterface, client *http.Client) (*HealthChecker, *utils.Background) {
+	background := &utils.Background{}
+	checker := NewHealthChecker(storage, client, &test.Clock{}, utils.NewLogger(io.Discard, &test.Clock{}), background, time.Hour, 2)
+
+	return checker, background
+}
+
+func TestHealthCheckerCheck(t *testing.T) {
+	server := newTestDestinations()
+
+	defer server.Close()
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closedURL := closed.URL
+
+	closed.Close()
+
+	checker, background := newTestHealthChecker(&testHealthStorage{}, server.Client())
+
+	defer background.Wait()
+	defer checker.Close()
+
+	checkedAt := time.Date(2024, 2, 7, 12, 0, 0, 0, time.UTC)
+	loop := make([]string, 0, maxHealthRedirects)
+
+	for i := 0; i < maxHealthRedirects; i++ {
+		loop = append(loop, server.URL+"/loop")
+	}
+
+	tests := []struct {
+		name     string
+		URL      string
+		expected Health
+		broken   bool
+	}{
+		{"OK", server.URL + "/ok", Health{Status: 200, CheckedAt: checkedAt}, false},
+		{"Not found", server.URL + "/missing", Health{Status: 404, CheckedAt: checkedAt}, true},
+		{"HEAD is not allowed", server.URL + "/get-only", Health{Status: 200, CheckedAt: checkedAt}, false},
+		{"Redirects", server.URL + "/moved", Health{
+			Status:    200,
+			Redirects: []string{server.URL + "/found", server.URL + "/ok"},
+			CheckedAt: checkedAt,
+		}, false},
+		{"Too many redirects", server.URL + "/loop", Health{
+			Redirects: loop,
+			Error:     "stopped after 10 redirects",
+			CheckedAt: checkedAt,
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			health := checker.Check(tt.URL)
+
+			require.Equal(t, tt.expected, health)
+			require.Equal(t, tt.broken, health.IsBroken())
+		})
+	}
+
+	health := checker.Check(closedURL + "/ok")
+
+	require.Equal(t, 0, health.Status)
+	require.Contains(t, health.Error, "connection refused")
+	require.True(t, health.IsBroken())
+}
+
+func TestHealthCheckerKeepsRedirectPolicy(t *testing.T) {
+	server := newTestDestinations()
+
+	defer server.Close()
+
+	client := server.Client()
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if req.URL.Path == "/ok" {
+			return errors.New("URL must not point to internal address")
+		}
+
+		return nil
+	}
+	checker, background := newTestHealthChecker(&testHealthStorage{}, client)
+
+	defer background.Wait()
+	defer checker.Close()
+
+	health := checker.Check(server.URL + "/moved")
+
+	require.Equal(t, Health{
+		Redirects: []string{server.URL + "/found"},
+		Error:     "URL must not point to internal address",
+		CheckedAt: time.Date(2024, 2, 7, 12, 0, 0, 0, time.UTC),
+	}, health)
+	require.True(t, health.IsBroken())
+}
+
+type testHealthStorage struct {
+	//
+}
+
+func (s *testHealthStorage) LinksToCheck(_, _ time.Time, _ int) ([]Link, error) {
+	return nil, nil
+}
+
+func (s *testHealthStorage) StoreHealth(_ Link, _ Health) error {
+	return nil
+}
+
+func TestHealthCheckerCheckLinks(t *testing.T) {
+	server := newTestDestinations()
+
+	defer server.Close()
+
+	s, err := NewFileStorage(t.TempDir()+"/storage.csv", newTestConverter(AlphabetBase36, "", 0))
+
+	require.NoError(t, err)
+
+	_, _ = s.StoreURLs([]string{server.URL + "/ok", server.URL + "/missing", server.URL + "/moved"}, LinkOptions{Owner: "client"})
+	_, _ = s.StoreURLs([]string{"mailto:info@example.com"}, LinkOptions{Owner: "client"})
+	checker, background := newTestHealthChecker(s, server.Client())
+	now := (&test.Clock{}).Now()
+
+	require.Eventually(t, func() bool {
+		due, err := s.LinksToCheck(now, now.Add(-time.Hour), 10)
+
+		return err == nil && len(due) == 0
+	}, time.Second, 10*time.Millisecond)
+
+	checker.Close()
+	background.Wait()
+
+	found, err := s.ListLinks(ListQuery{Owner: "client", Health: HealthBroken, Sort: SortCreatedAsc, Limit: 10})
+
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	require.Equal(t, server.URL+"/missing", found[0].URL)
+
+	found, _ = s.ListLinks(ListQuery{Owner: "client", Health: HealthOK, Sort: SortCreatedAsc, Limit: 10})
+
+	require.Len(t, found, 2)
+	require.Equal(t, []string{server.URL + "/found", server.URL + "/ok"}, found[1].Health.Redirects)
+
+	link, _ := s.GetLink("4")
+
+	require.True(t, link.Health.IsZero())
+}
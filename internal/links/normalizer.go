@@ -0,0 +1,222 @@
+package links
+
+import (
+	"fmt"
+	"golang.org/x/net/idna"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+const NormalizeLowercase = "lowercase"
+const NormalizeDefaultPort = "default-port"
+const NormalizeDotSegments = "dot-segments"
+const NormalizePunycode = "punycode"
+const NormalizeSortQuery = "sort-query"
+const NormalizeStripTracking = "strip-tracking"
+
+var defaultPorts = map[string]string{"http": "80", "https": "443"}
+
+type normalizeStep func(u *url.URL, n *Normalizer) error
+
+var normalizeSteps = map[string]normalizeStep{
+	NormalizeLowercase:     lowercaseSchemeHost,
+	NormalizeDefaultPort:   dropDefaultPort,
+	NormalizeDotSegments:   resolveDotSegments,
+	NormalizePunycode:      punycodeHost,
+	NormalizeSortQuery:     sortQuery,
+	NormalizeStripTracking: stripTracking,
+}
+
+// Normalizer Brings URLs to canonical form by applying configured steps in given order
+type Normalizer struct {
+	steps          []normalizeStep
+	trackingParams []string
+}
+
+// NewNormalizer Makes normalizer of steps by names. Tracking parameters are names of query parameters removed
+// by "strip-tracking" step, name ending with "*" matches all parameters with such prefix
+func NewNormalizer(steps []string, trackingParams []string) (*Normalizer, error) {
+	n := Normalizer{trackingParams: trackingParams}
+
+	for _, name := range steps {
+		step, ok := normalizeSteps[name]
+
+		if !ok {
+			return nil, fmt.Errorf("unknown URL normalization step: %s", name)
+		}
+
+		n.steps = append(n.steps, step)
+	}
+
+	return &n, nil
+}
+
+// Normalize Returns canonical form of absolute URL
+func (n *Normalizer) Normalize(URL string) (string, error) {
+	if len(n.steps) == 0 {
+		return URL, nil
+	}
+
+	u, err := url.Parse(URL)
+
+	if err != nil {
+		return "", err
+	}
+
+	for _, step := range n.steps {
+		if err = step(u, n); err != nil {
+			return "", err
+		}
+	}
+
+	return u.String(), nil
+}
+
+// joinHostPort Makes host of URL, IPv6 addresses are put in brackets
+func joinHostPort(hostname, port string) string {
+	if port != "" {
+		return net.JoinHostPort(hostname, port)
+	}
+
+	if strings.Contains(hostname, ":") {
+		return "[" + hostname + "]"
+	}
+
+	return hostname
+}
+
+func lowercaseSchemeHost(u *url.URL, _ *Normalizer) error {
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+
+	return nil
+}
+
+func dropDefaultPort(u *url.URL, _ *Normalizer) error {
+	if port := u.Port(); port != "" && port == defaultPorts[strings.ToLower(u.Scheme)] {
+		u.Host = joinHostPort(u.Hostname(), "")
+	}
+
+	return nil
+}
+
+// resolveDotSegments Removes "." and ".." segments of path as described in RFC 3986, section 5.2.4
+func resolveDotSegments(u *url.URL, _ *Normalizer) error {
+	escaped := u.EscapedPath()
+
+	if !strings.Contains(escaped, ".") {
+		return nil
+	}
+
+	var output []string
+	segments := strings.Split(escaped, "/")
+
+	for i, segment := range segments {
+		switch segment {
+		case ".":
+		case "..":
+			// leading empty segment of absolute path is never removed
+			if len(output) > 1 {
+				output = output[:len(output)-1]
+			}
+		default:
+			output = append(output, segment)
+		}
+
+		if (segment == "." || segment == "..") && i == len(segments)-1 {
+			output = append(output, "")
+		}
+	}
+
+	resolved := strings.Join(output, "/")
+	path, err := url.PathUnescape(resolved)
+
+	if err != nil {
+		return err
+	}
+
+	u.Path, u.RawPath = path, resolved
+
+	return nil
+}
+
+func punycodeHost(u *url.URL, _ *Normalizer) error {
+	if net.ParseIP(u.Hostname()) != nil {
+		return nil
+	}
+
+	hostname, err := idna.Lookup.ToASCII(u.Hostname())
+
+	if err != nil {
+		return fmt.Errorf("invalid host: %w", err)
+	}
+
+	u.Host = joinHostPort(hostname, u.Port())
+
+	return nil
+}
+
+// queryPairs Splits raw query to "name=value" pairs keeping their encoding
+func queryPairs(rawQuery string) []string {
+	var pairs []string
+
+	for _, pair := range strings.Split(rawQuery, "&") {
+		if pair != "" {
+			pairs = append(pairs, pair)
+		}
+	}
+
+	return pairs
+}
+
+func queryPairName(pair string) string {
+	name, _, _ := strings.Cut(pair, "=")
+
+	if unescaped, err := url.QueryUnescape(name); err == nil {
+		return unescaped
+	}
+
+	return name
+}
+
+// sortQuery Sorts query parameters by name, values of the same parameter keep their order
+func sortQuery(u *url.URL, _ *Normalizer) error {
+	pairs := queryPairs(u.RawQuery)
+
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return queryPairName(pairs[i]) < queryPairName(pairs[j])
+	})
+
+	u.RawQuery = strings.Join(pairs, "&")
+
+	return nil
+}
+
+func (n *Normalizer) isTracking(name string) bool {
+	name = strings.ToLower(name)
+
+	for _, param := range n.trackingParams {
+		if prefix, ok := strings.CutSuffix(param, "*"); (ok && strings.HasPrefix(name, prefix)) || name == param {
+			return true
+		}
+	}
+
+	return false
+}
+
+func stripTracking(u *url.URL, n *Normalizer) error {
+	var pairs []string
+
+	for _, pair := range queryPairs(u.RawQuery) {
+		if !n.isTracking(queryPairName(pair)) {
+			pairs = append(pairs, pair)
+		}
+	}
+
+	u.RawQuery = strings.Join(pairs, "&")
+	u.ForceQuery = false
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package links
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tracking := []string{"utm_*", "gclid", "fbclid"}
+
+	tests := []struct {
+		name     string
+		steps    []string
+		URL      string
+		expected string
+	}{
+		{"No steps", []string{}, "HTTP://Example.com:80/a/../b?utm_source=x", "HTTP://Example.com:80/a/../b?utm_source=x"},
+		{"Lowercase", []string{NormalizeLowercase}, "HTTPS://User@Example.COM/Path?Q=A", "https://User@example.com/Path?Q=A"},
+		{"Default HTTP port", []string{NormalizeDefaultPort}, "http://example.com:80/a", "http://example.com/a"},
+		{"Default HTTPS port", []string{NormalizeDefaultPort}, "https://example.com:443/a", "https://example.com/a"},
+		{"Other port", []string{NormalizeDefaultPort}, "http://example.com:443/a", "http://example.com:443/a"},
+		{"IPv6 default port", []string{NormalizeDefaultPort}, "http://[::1]:80/a", "http://[::1]/a"},
+		{"Parent segment", []string{NormalizeDotSegments}, "http://example.com/a/b/../c", "http://example.com/a/c"},
+		{"Current segment", []string{NormalizeDotSegments}, "http://example.com/a/./b/.", "http://example.com/a/b/"},
+		{"Parent of root", []string{NormalizeDotSegments}, "http://example.com/../../a", "http://example.com/a"},
+		{"Trailing parent", []string{NormalizeDotSegments}, "http://example.com/a/b/..", "http://example.com/a/"},
+		{"Encoded path", []string{NormalizeDotSegments}, "http://example.com/a%2Fb/../c%20d", "http://example.com/c%20d"},
+		{"Dots in names", []string{NormalizeDotSegments}, "http://example.com/a.../b.html", "http://example.com/a.../b.html"},
+		{"IDN host", []string{NormalizePunycode}, "https://пример.рф/путь", "https://xn--e1afmkfd.xn--p1ai/%D0%BF%D1%83%D1%82%D1%8C"},
+		{"IDN host with port", []string{NormalizePunycode}, "https://Bücher.de:8080/", "https://xn--bcher-kva.de:8080/"},
+		{"IP host", []string{NormalizePunycode}, "http://[::1]:8080/", "http://[::1]:8080/"},
+		{"Sort query", []string{NormalizeSortQuery}, "http://example.com/?b=2&a=1&b=1&c", "http://example.com/?a=1&b=2&b=1&c"},
+		{"Sort encoded query", []string{NormalizeSortQuery}, "http://example.com/?z=%20&a%62=1", "http://example.com/?a%62=1&z=%20"},
+		{"Strip tracking", []string{NormalizeStripTracking}, "http://example.com/?utm_source=x&id=1&UTM_Medium=y&gclid=z", "http://example.com/?id=1"},
+		{"Strip all query", []string{NormalizeStripTracking}, "http://example.com/a?utm_source=x", "http://example.com/a"},
+		{"Keep fragment", []string{NormalizeStripTracking}, "http://example.com/a?fbclid=1#top", "http://example.com/a#top"},
+		{
+			"All steps",
+			[]string{NormalizeLowercase, NormalizeDefaultPort, NormalizeDotSegments, NormalizePunycode, NormalizeSortQuery, NormalizeStripTracking},
+			"HTTP://Example.com:80/a/../b?utm_source=x&z=1&a=2",
+			"http://example.com/b?a=2&z=1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := NewNormalizer(tt.steps, tracking)
+
+			require.NoError(t, err)
+
+			URL, err := n.Normalize(tt.URL)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, URL)
+		})
+	}
+}
+
+func TestNewNormalizerUnknownStep(t *testing.T) {
+	_, err := NewNormalizer([]string{NormalizeLowercase, "lowercase-path"}, nil)
+
+	assert.EqualError(t, err, "unknown URL normalization step: lowercase-path")
+}
@@ -0,0 +1,88 @@
+package links
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+const permutationRounds = 4
+
+// permutation Keyed reversible permutation of numbers in range [0, domain), made of Feistel network
+// over the smallest even number of bits covering the domain, values out of the domain are encrypted again (cycle walking)
+type permutation struct {
+	secret   []byte
+	domain   int64
+	halfBits uint
+	halfMask uint64
+}
+
+func newPermutation(secret string, domain int64) *permutation {
+	bits := uint(0)
+
+	for int64(1)<<bits < domain {
+		bits++
+	}
+
+	halfBits := (bits + 1) / 2
+
+	return &permutation{
+		secret:   []byte(secret),
+		domain:   domain,
+		halfBits: halfBits,
+		halfMask: 1<<halfBits - 1,
+	}
+}
+
+func (p *permutation) round(round int, value uint64) uint64 {
+	message := make([]byte, 9)
+	message[0] = byte(round)
+	binary.BigEndian.PutUint64(message[1:], value)
+
+	mac := hmac.New(sha256.New, p.secret)
+	_, _ = mac.Write(message)
+
+	return binary.BigEndian.Uint64(mac.Sum(nil)) & p.halfMask
+}
+
+func (p *permutation) encryptOnce(value uint64) uint64 {
+	left, right := value>>p.halfBits, value&p.halfMask
+
+	for r := 0; r < permutationRounds; r++ {
+		left, right = right, left^p.round(r, right)
+	}
+
+	return left<<p.halfBits | right
+}
+
+func (p *permutation) decryptOnce(value uint64) uint64 {
+	left, right := value>>p.halfBits, value&p.halfMask
+
+	for r := permutationRounds - 1; r >= 0; r-- {
+		left, right = right^p.round(r, left), left
+	}
+
+	return left<<p.halfBits | right
+}
+
+// encrypt Number must be in range [0, domain)
+func (p *permutation) encrypt(number int64) int64 {
+	value := p.encryptOnce(uint64(number))
+
+	for value >= uint64(p.domain) {
+		value = p.encryptOnce(value)
+	}
+
+	return int64(value)
+}
+
+// decrypt Number must be in range [0, domain)
+func (p *permutation) decrypt(number int64) int64 {
+	value := p.decryptOnce(uint64(number))
+
+	for value >= uint64(p.domain) {
+		value = p.decryptOnce(value)
+	}
+
+	return int64(value)
+}
@@ -0,0 +1,261 @@
+package links
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const PlatformIOS = "ios"
+const PlatformAndroid = "android"
+const PlatformWindows = "windows"
+const PlatformMacOS = "macos"
+const PlatformLinux = "linux"
+const PlatformOther = "other"
+
+// Platforms All platforms rules can match
+var Platforms = []string{PlatformIOS, PlatformAndroid, PlatformWindows, PlatformMacOS, PlatformLinux, PlatformOther}
+
+// userAgentPlatforms Tokens are checked in order, because iOS and Android user agents mention "Mac OS X" and "Linux"
+var userAgentPlatforms = []struct {
+	token    string
+	platform string
+}{
+	{"iphone", PlatformIOS},
+	{"ipad", PlatformIOS},
+	{"ipod", PlatformIOS},
+	{"android", PlatformAndroid},
+	{"windows", PlatformWindows},
+	{"macintosh", PlatformMacOS},
+	{"mac os x", PlatformMacOS},
+	{"cros", PlatformLinux},
+	{"linux", PlatformLinux},
+}
+
+func userAgentPlatform(userAgent string) string {
+	userAgent = strings.ToLower(userAgent)
+
+	for _, p := range userAgentPlatforms {
+		if strings.Contains(userAgent, p.token) {
+			return p.platform
+		}
+	}
+
+	return PlatformOther
+}
+
+// preferredLanguage Returns language of Accept-Language header with the highest quality, the first one of equal ones
+func preferredLanguage(acceptLanguage string) string {
+	preferred := ""
+	preferredQuality := 0.0
+
+	for _, item := range strings.Split(acceptLanguage, ",") {
+		language, params, _ := strings.Cut(strings.TrimSpace(item), ";")
+		quality := 1.0
+
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+
+			if quality, err = strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+		}
+
+		if language != "" && language != "*" && quality > preferredQuality {
+			preferred, preferredQuality = strings.ToLower(language), quality
+		}
+	}
+
+	return preferred
+}
+
+// Visit Request to follow the link, rules are matched against it
+type Visit struct {
+	UserAgent      string
+	AcceptLanguage string
+	Query          url.Values
+	Time           time.Time
+}
+
+// Rule Sends visitor to URL if all conditions which are set match. Language matches preferred language of visitor
+// with its subtags ("de" matches "de-AT"). Time window is "HH:MM" from inclusive to exclusive in timezone (UTC by default),
+// window goes over midnight if from is later than to. Query parameter with empty value matches any value
+type Rule struct {
+	URL       string            `json:"url"`
+	Platforms []string          `json:"platforms,omitempty"`
+	Languages []string          `json:"languages,omitempty"`
+	TimeFrom  string            `json:"time_from,omitempty"`
+	TimeTo    string            `json:"time_to,omitempty"`
+	Timezone  string            `json:"timezone,omitempty"`
+	Query     map[string]string `json:"query,omitempty"`
+}
+
+// Rules Ordered rules of the link, the first matching rule chooses destination
+type Rules []Rule
+
+// Destination Returns URL of the first matching rule, false if no rule matches
+func (r Rules) Destination(visit Visit) (string, bool) {
+	for _, rule := range r {
+		if rule.Matches(visit) {
+			return rule.URL, true
+		}
+	}
+
+	return "", false
+}
+
+func (r Rule) Matches(visit Visit) bool {
+	if len(r.Platforms) > 0 && !slices.Contains(r.Platforms, userAgentPlatform(visit.UserAgent)) {
+		return false
+	}
+
+	if len(r.Languages) > 0 && !r.matchesLanguage(preferredLanguage(visit.AcceptLanguage)) {
+		return false
+	}
+
+	if r.TimeFrom != "" && !r.matchesTime(visit.Time) {
+		return false
+	}
+
+	for name, value := range r.Query {
+		if !visit.Query.Has(name) || (value != "" && visit.Query.Get(name) != value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (r Rule) matchesLanguage(language string) bool {
+	for _, ruleLanguage := range r.Languages {
+		ruleLanguage = strings.ToLower(ruleLanguage)
+
+		if language == ruleLanguage || strings.HasPrefix(language, ruleLanguage+"-") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r Rule) matchesTime(t time.Time) bool {
+	location, err := r.location()
+
+	if err != nil {
+		return false
+	}
+
+	from, errFrom := parseMinutes(r.TimeFrom)
+	to, errTo := parseMinutes(r.TimeTo)
+
+	if errFrom != nil || errTo != nil {
+		return false
+	}
+
+	t = t.In(location)
+	minutes := t.Hour()*60 + t.Minute()
+
+	if from < to {
+		return from <= minutes && minutes < to
+	}
+
+	return minutes >= from || minutes < to
+}
+
+func (r Rule) location() (*time.Location, error) {
+	if r.Timezone == "" {
+		return time.UTC, nil
+	}
+
+	return time.LoadLocation(r.Timezone)
+}
+
+// parseMinutes Returns minutes since midnight of "HH:MM" time
+func parseMinutes(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+var languagePattern = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`)
+
+// Validate Returns error if rule has no conditions or any condition is invalid, URL is validated by caller
+func (r Rule) Validate() error {
+	if len(r.Platforms) == 0 && len(r.Languages) == 0 && r.TimeFrom == "" && r.TimeTo == "" && len(r.Query) == 0 {
+		return errors.New("rule must have at least one condition")
+	}
+
+	for _, platform := range r.Platforms {
+		if !slices.Contains(Platforms, platform) {
+			return fmt.Errorf("unknown platform: %s", platform)
+		}
+	}
+
+	for _, language := range r.Languages {
+		if !languagePattern.MatchString(language) {
+			return fmt.Errorf("invalid language: %s", language)
+		}
+	}
+
+	if r.TimeFrom != "" || r.TimeTo != "" || r.Timezone != "" {
+		from, errFrom := parseMinutes(r.TimeFrom)
+		to, errTo := parseMinutes(r.TimeTo)
+
+		if errFrom != nil || errTo != nil || from == to {
+			return errors.New("time_from and time_to must be different times in HH:MM format")
+		}
+
+		if _, err := r.location(); err != nil {
+			return fmt.Errorf("unknown timezone: %s", r.Timezone)
+		}
+	}
+
+	for name := range r.Query {
+		if name == "" {
+			return errors.New("name of query parameter must not be empty")
+		}
+	}
+
+	return nil
+}
+
+// encodeRules Returns JSON of rules, empty rules are empty string
+func encodeRules(rules Rules) string {
+	if len(rules) == 0 {
+		return ""
+	}
+
+	// rules consist of strings only, so encoding never fails
+	encoded, _ := json.Marshal(rules)
+
+	return string(encoded)
+}
+
+// decodeRules Returns rules of JSON, empty string and empty list are nil rules
+func decodeRules(data string) (Rules, error) {
+	var rules Rules
+
+	if data == "" {
+		return nil, nil
+	}
+
+	if err := json.Unmarshal([]byte(data), &rules); err != nil {
+		return nil, err
+	}
+
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	return rules, nil
+}
@@ -0,0 +1,149 @@
+package links
+
+import (
+	"github.com/stretchr/testify/require"
+	"net/url"
+	"testing"
+	"time"
+)
+
+const userAgentIPhone = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Mobile/15E148 Safari/604.1"
+const userAgentAndroid = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/121.0.0.0 Mobile Safari/537.36"
+const userAgentWindows = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/121.0.0.0 Safari/537.36"
+
+func TestUserAgentPlatform(t *testing.T) {
+	tests := []struct {
+		name      string
+		userAgent string
+		expected  string
+	}{
+		{"iPhone", userAgentIPhone, PlatformIOS},
+		{"iPad", "Mozilla/5.0 (iPad; CPU OS 17_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Mobile/15E148 Safari/604.1", PlatformIOS},
+		{"Android", userAgentAndroid, PlatformAndroid},
+		{"Windows", userAgentWindows, PlatformWindows},
+		{"macOS", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Safari/605.1.15", PlatformMacOS},
+		{"Linux", "Mozilla/5.0 (X11; Linux x86_64; rv:122.0) Gecko/20100101 Firefox/122.0", PlatformLinux},
+		{"ChromeOS", "Mozilla/5.0 (X11; CrOS x86_64 14541.0.0) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/121.0.0.0 Safari/537.36", PlatformLinux},
+		{"curl", "curl/8.5.0", PlatformOther},
+		{"Empty", "", PlatformOther},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, userAgentPlatform(tt.userAgent))
+		})
+	}
+}
+
+func TestPreferredLanguage(t *testing.T) {
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		expected       string
+	}{
+		{"Single", "de", "de"},
+		{"First of equal", "de-AT, en", "de-at"},
+		{"Highest quality", "en;q=0.8, de;q=0.9, fr;q=0.1", "de"},
+		{"Default quality", "en;q=0.8, de", "de"},
+		{"Any language", "*, en;q=0.5", "en"},
+		{"Invalid quality", "de;q=high, en;q=0.5", "en"},
+		{"Refused", "de;q=0", ""},
+		{"Empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, preferredLanguage(tt.acceptLanguage))
+		})
+	}
+}
+
+func TestRulesDestination(t *testing.T) {
+	rules := Rules{
+		{URL: "https://apps.apple.com/app", Platforms: []string{PlatformIOS}},
+		{URL: "https://play.google.com/app", Platforms: []string{PlatformAndroid}},
+		{URL: "https://example.com/de", Languages: []string{"de"}},
+		{URL: "https://example.com/night", TimeFrom: "22:00", TimeTo: "06:00", Timezone: "Europe/Berlin"},
+		{URL: "https://example.com/promo", Query: map[string]string{"ref": "promo", "src": ""}},
+	}
+	day := time.Date(2024, 2, 7, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		visit    Visit
+		expected string
+	}{
+		{"iOS", Visit{UserAgent: userAgentIPhone, AcceptLanguage: "de", Time: day}, "https://apps.apple.com/app"},
+		{"Android", Visit{UserAgent: userAgentAndroid, Time: day}, "https://play.google.com/app"},
+		{"German", Visit{UserAgent: userAgentWindows, AcceptLanguage: "de-DE,en;q=0.5", Time: day}, "https://example.com/de"},
+		{"German is not preferred", Visit{UserAgent: userAgentWindows, AcceptLanguage: "en,de;q=0.5", Time: day}, ""},
+		{"German subtag rule does not match language", Visit{AcceptLanguage: "deu", Time: day}, ""},
+		{"Night in timezone", Visit{Time: time.Date(2024, 2, 7, 21, 30, 0, 0, time.UTC)}, "https://example.com/night"},
+		{"Night after midnight", Visit{Time: time.Date(2024, 2, 7, 4, 59, 0, 0, time.UTC)}, "https://example.com/night"},
+		{"End of night is excluded", Visit{Time: time.Date(2024, 2, 7, 5, 0, 0, 0, time.UTC)}, ""},
+		{"Query", Visit{Query: url.Values{"ref": {"promo"}, "src": {"mail"}}, Time: day}, "https://example.com/promo"},
+		{"Query value differs", Visit{Query: url.Values{"ref": {"other"}, "src": {"mail"}}, Time: day}, ""},
+		{"Query parameter is missing", Visit{Query: url.Values{"ref": {"promo"}}, Time: day}, ""},
+		{"Default", Visit{UserAgent: userAgentWindows, AcceptLanguage: "en", Time: day}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			URL, ok := rules.Destination(tt.visit)
+
+			require.Equal(t, tt.expected, URL)
+			require.Equal(t, tt.expected != "", ok)
+		})
+	}
+}
+
+func TestRuleMatchesAllConditions(t *testing.T) {
+	rule := Rule{URL: "https://example.com/de-ios", Platforms: []string{PlatformIOS}, Languages: []string{"de"}}
+
+	tests := []struct {
+		name     string
+		visit    Visit
+		expected bool
+	}{
+		{"Both match", Visit{UserAgent: userAgentIPhone, AcceptLanguage: "de"}, true},
+		{"Platform differs", Visit{UserAgent: userAgentAndroid, AcceptLanguage: "de"}, false},
+		{"Language differs", Visit{UserAgent: userAgentIPhone, AcceptLanguage: "fr"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, rule.Matches(tt.visit))
+		})
+	}
+}
+
+func TestRuleValidate(t *testing.T) {
+	tests := []struct {
+		name          string
+		rule          Rule
+		expectedError string
+	}{
+		{"Valid", Rule{Platforms: []string{PlatformIOS}, Languages: []string{"de", "pt-BR"}, TimeFrom: "09:00", TimeTo: "18:00",
+			Timezone: "Europe/Berlin", Query: map[string]string{"ref": ""}}, ""},
+		{"No conditions", Rule{URL: "https://example.com"}, "rule must have at least one condition"},
+		{"Unknown platform", Rule{Platforms: []string{"symbian"}}, "unknown platform: symbian"},
+		{"Invalid language", Rule{Languages: []string{"german"}}, "invalid language: german"},
+		{"Time without end", Rule{TimeFrom: "09:00"}, "time_from and time_to must be different times in HH:MM format"},
+		{"Invalid time", Rule{TimeFrom: "9am", TimeTo: "18:00"}, "time_from and time_to must be different times in HH:MM format"},
+		{"Empty window", Rule{TimeFrom: "09:00", TimeTo: "09:00"}, "time_from and time_to must be different times in HH:MM format"},
+		{"Unknown timezone", Rule{TimeFrom: "09:00", TimeTo: "18:00", Timezone: "Mars/Olympus"}, "unknown timezone: Mars/Olympus"},
+		{"Empty query parameter", Rule{Query: map[string]string{"": "x"}}, "name of query parameter must not be empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rule.Validate()
+
+			if tt.expectedError == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tt.expectedError)
+			}
+		})
+	}
+}
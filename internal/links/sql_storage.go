@@ -2,140 +2,399 @@ package links
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/lib/pq"
 	"strconv"
 	"strings"
 	"time"
 )
 
+// sqlBatchSize Number of links inserted by one statement, which keeps number of its parameters below PostgreSQL limit
+const sqlBatchSize = 4000
+
 type SQLStorage struct {
-	db      *sql.DB
-	timeout time.Duration
+	db        *sql.DB
+	timeout   time.Duration
+	converter *KeyConverter
 }
 
-func NewSQLStorage(db *sql.DB, timeout int) *SQLStorage {
+func NewSQLStorage(db *sql.DB, timeout int, converter *KeyConverter) *SQLStorage {
 	s := SQLStorage{
-		db:      db,
-		timeout: time.Second * time.Duration(timeout),
+		db:        db,
+		timeout:   time.Second * time.Duration(timeout),
+		converter: converter,
 	}
 
 	return &s
 }
 
-func (s *SQLStorage) StoreURLs(URLs []string) (map[string]string, error) {
-	if len(URLs) == 0 {
-		return map[string]string{}, nil
+// CheckAlphabet Records configured alphabet of keys if none is recorded yet and compares it with recorded one
+func (s *SQLStorage) CheckAlphabet() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+
+	defer cancel()
+
+	return checkAlphabet(ctx, s.db, s.converter)
+}
+
+// CheckSequentialMaxID Records last id with sequential key when the secret is enabled for the first time: configured one,
+// or the last stored id if it is greater, so keys of stored links are not changed. Recorded id is used afterwards
+func (s *SQLStorage) CheckSequentialMaxID() error {
+	if !s.converter.HasSecret() {
+		return nil
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
 
 	defer cancel()
 
-	var placeholders []string
-	var values []interface{}
-	n := 1
+	query := "INSERT INTO settings(name, value) SELECT 'sequential_max_id', GREATEST($1::bigint, COALESCE(MAX(id), 0))::text FROM links " +
+		"ON CONFLICT (name) DO NOTHING"
 
-	for _, URL := range URLs {
-		placeholders = append(placeholders, fmt.Sprintf("($%d)", n))
-		values = append(values, URL)
-		n++
+	return checkSequentialMaxID(ctx, s.db, s.converter, query, "SELECT value::bigint FROM settings WHERE name = 'sequential_max_id'")
+}
+
+// insertColumns Columns of new link set by linkValues
+const insertColumns = "url, expires_at, owner, created_at, title, description, tags, notes, utm_template, rules, variants, fallback, " +
+	"password_hash"
+const insertColumnsCount = 13
+
+// linkValues Returns values of insertColumns of new link
+func (s *SQLStorage) linkValues(URL string, options LinkOptions) []interface{} {
+	return []interface{}{
+		URL,
+		sql.NullTime{Time: options.ExpiresAt, Valid: !options.ExpiresAt.IsZero()},
+		options.Owner,
+		sql.NullTime{Time: options.CreatedAt, Valid: !options.CreatedAt.IsZero()},
+		options.Metadata.Title,
+		options.Metadata.Description,
+		// nil array would be NULL
+		pq.StringArray(append([]string{}, options.Metadata.Tags...)),
+		options.Metadata.Notes,
+		options.UTMTemplate,
+		jsonList(encodeRules(options.Rules)),
+		jsonList(encodeVariants(options.Variants)),
+		options.Fallback,
+		sql.NullString{String: options.PasswordHash, Valid: options.PasswordHash != ""},
+	}
+}
+
+// jsonList Returns encoded list as value of jsonb column, list which is not set is empty list
+func jsonList(encoded string) string {
+	if encoded == "" {
+		return "[]"
 	}
 
-	query := "INSERT INTO links(url) VALUES " + strings.Join(placeholders, ", ") + " RETURNING id"
-	rows, err := s.db.QueryContext(ctx, query, values...)
+	return encoded
+}
+
+// linkPlaceholders Makes placeholders of linkValues starting from $n
+func (s *SQLStorage) linkPlaceholders(n int) string {
+	placeholders := make([]string, 0, insertColumnsCount)
 
-	if err != nil {
+	for i := n; i < n+insertColumnsCount; i++ {
+		placeholders = append(placeholders, fmt.Sprintf("$%d", i))
+	}
+
+	return strings.Join(placeholders, ", ")
+}
+
+func (s *SQLStorage) StoreURLs(URLs []string, options LinkOptions) (map[string]string, error) {
+	keysByURLs := make(map[string]string, len(URLs))
+
+	if len(URLs) == 0 {
+		return keysByURLs, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+
+	defer cancel()
+
+	if err := insertLinks(ctx, s.db, s.converter, numberedPlaceholders, s.linkValues, sqlBatchSize, keysByURLs, URLs, options, false); err != nil {
 		return nil, err
 	}
 
-	defer rows.Close()
+	return keysByURLs, nil
+}
+
+// urlHash Makes value of url_hash column, which has unique index to deduplicate links
+func urlHash(URL string, options LinkOptions) []byte {
+	hash := sha256.Sum256([]byte(dedupKey(URL, options.ExpiresAt, options.Owner)))
 
+	return hash[:]
+}
+
+func (s *SQLStorage) StoreUniqueURLs(URLs []string, options LinkOptions) (map[string]string, map[string]bool, error) {
 	keysByURLs := make(map[string]string, len(URLs))
-	i := 0
+	existing := map[string]bool{}
 
-	for rows.Next() {
-		var id int64
+	if len(URLs) == 0 {
+		return keysByURLs, existing, nil
+	}
 
-		err := rows.Scan(&id)
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
 
-		if err != nil {
-			return nil, err
-		}
+	defer cancel()
 
-		key := convertNumberToKey(id)
-		keysByURLs[URLs[i]] = key
-		i++
+	if err := insertLinks(ctx, s.db, s.converter, numberedPlaceholders, s.linkValues, sqlBatchSize, keysByURLs, URLs, options, true); err != nil {
+		return nil, nil, err
 	}
 
-	if err = rows.Err(); err != nil {
-		return nil, err
+	err := scanExistingKeys(ctx, s.db, s.converter, numberedPlaceholders, sqlBatchSize, keysByURLs, existing, URLs, options)
+
+	if err != nil {
+		return nil, nil, err
 	}
 
-	return keysByURLs, nil
+	return keysByURLs, existing, nil
+}
+
+func (s *SQLStorage) StoreAlias(alias, URL string, options LinkOptions) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+
+	defer cancel()
+
+	var id int64
+	query := "INSERT INTO links(" + insertColumns + ", alias) VALUES (" + s.linkPlaceholders(1) + fmt.Sprintf(", $%d) ", insertColumnsCount+1) +
+		"ON CONFLICT (alias) DO NOTHING RETURNING id"
+	err := s.db.QueryRowContext(ctx, query, append(s.linkValues(URL, options), alias)...).Scan(&id)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrAliasTaken
+	}
+
+	return err
+}
+
+// pqTags Scans tags from array column
+func pqTags(tags *[]string) sql.Scanner {
+	return pq.Array(tags)
+}
+
+func (s *SQLStorage) scanLink(row rowScanner) (Link, error) {
+	return scanLink(row, s.converter, pqTags)
 }
 
-func (s *SQLStorage) GetURL(key string) (string, error) {
+func (s *SQLStorage) GetLink(key string) (Link, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
 
 	defer cancel()
 
-	var URL string
-	query := "SELECT url FROM links WHERE id = $1 LIMIT 1"
-	err := s.db.QueryRowContext(ctx, query, convertKeyToNumber(key)).Scan(&URL)
+	condition, value := keyCondition(s.converter, key, 1)
+	query := "SELECT " + linkColumns + " FROM links WHERE " + condition + " AND deleted_at IS NULL LIMIT 1"
+	link, err := s.scanLink(s.db.QueryRowContext(ctx, query, value))
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return "", nil
+			return Link{}, nil
 		}
 
-		return "", err
+		return Link{}, err
 	}
 
-	return URL, nil
+	return link, nil
 }
 
-func (s *SQLStorage) GetURLs(keys []string) (map[string]string, error) {
+func (s *SQLStorage) GetLinks(keys []string) (map[string]Link, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
 
 	defer cancel()
 
-	var placeholders []string
-	var values []interface{}
+	return getLinks(ctx, s.db, s.converter, pqTags, numberedPlaceholders, keys)
+}
+
+func (s *SQLStorage) UpdateLink(key string, update LinkUpdate) (Link, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+
+	defer cancel()
+
+	URL := sql.NullString{}
+	disabled := sql.NullBool{}
+	passwordHash := sql.NullString{}
 
-	for i, key := range keys {
-		placeholders = append(placeholders, fmt.Sprintf("$%d", i+1))
-		values = append(values, convertKeyToNumber(key))
+	if update.URL != nil {
+		URL = sql.NullString{String: *update.URL, Valid: true}
 	}
 
-	query := "SELECT id, url FROM links WHERE id IN (" + strings.Join(placeholders, ", ") + ") LIMIT " + strconv.Itoa(len(keys))
-	rows, err := s.db.QueryContext(ctx, query, values...)
+	if update.Disabled != nil {
+		disabled = sql.NullBool{Bool: *update.Disabled, Valid: true}
+	}
 
-	if err != nil {
-		return nil, err
+	if update.PasswordHash != nil {
+		passwordHash = sql.NullString{String: *update.PasswordHash, Valid: true}
 	}
 
-	defer rows.Close()
+	metadata := update.apply(Metadata{})
+	title := sql.NullString{String: metadata.Title, Valid: update.Title != nil}
+	description := sql.NullString{String: metadata.Description, Valid: update.Description != nil}
+	notes := sql.NullString{String: metadata.Notes, Valid: update.Notes != nil}
+	UTMTemplate := sql.NullString{}
 
-	URLs := make(map[string]string, len(keys))
+	if update.UTMTemplate != nil {
+		UTMTemplate = sql.NullString{String: *update.UTMTemplate, Valid: true}
+	}
 
-	for rows.Next() {
-		var id int64
-		var URL string
+	var tags pq.StringArray
 
-		err := rows.Scan(&id, &URL)
+	if update.Tags != nil {
+		tags = append(pq.StringArray{}, metadata.Tags...)
+	}
 
-		if err != nil {
-			return nil, err
-		}
+	rules := sql.NullString{}
 
-		URLs[convertNumberToKey(id)] = URL
+	if update.Rules != nil {
+		rules = sql.NullString{String: jsonList(encodeRules(*update.Rules)), Valid: true}
 	}
 
-	if err = rows.Err(); err != nil {
-		return nil, err
+	variants := sql.NullString{}
+
+	if update.Variants != nil {
+		variants = sql.NullString{String: jsonList(encodeVariants(*update.Variants)), Valid: true}
+	}
+
+	fallback := sql.NullString{}
+
+	if update.Fallback != nil {
+		fallback = sql.NullString{String: *update.Fallback, Valid: true}
+	}
+
+	down := sql.NullBool{}
+
+	if update.Down != nil {
+		down = sql.NullBool{Bool: *update.Down, Valid: true}
+	}
+
+	condition, value := keyCondition(s.converter, key, 1)
+	// changed, disabled, down, protected links and links with UTM template, rules, variants or fallback are not reused by deduplication
+	query := "UPDATE links SET url = COALESCE($2, url), disabled = COALESCE($3, disabled), " +
+		"password_hash = CASE WHEN $4::text IS NULL THEN password_hash ELSE NULLIF($4::text, '') END, " +
+		"url_hash = CASE WHEN $2 IS NULL AND $3 IS NOT TRUE AND $4::text IS NULL AND COALESCE($9, '') = '' " +
+		"AND COALESCE(jsonb_array_length($10::jsonb), 0) = 0 AND COALESCE(jsonb_array_length($11::jsonb), 0) = 0 " +
+		"AND COALESCE($12, '') = '' AND $13::boolean IS NOT TRUE THEN url_hash END, " +
+		"title = COALESCE($5, title), description = COALESCE($6, description), tags = COALESCE($7::text[], tags), " +
+		"notes = COALESCE($8, notes), utm_template = COALESCE($9, utm_template), rules = COALESCE($10::jsonb, rules), " +
+		"variants = COALESCE($11::jsonb, variants), fallback = COALESCE($12, fallback), down = COALESCE($13, down), " +
+		// new destination is neither checked nor fetched yet
+		"health_status = CASE WHEN $2 IS NULL THEN health_status ELSE 0 END, " +
+		"health_redirects = CASE WHEN $2 IS NULL THEN health_redirects ELSE '[]' END, " +
+		"health_error = CASE WHEN $2 IS NULL THEN health_error ELSE '' END, " +
+		"health_checked_at = CASE WHEN $2 IS NULL THEN health_checked_at END, " +
+		"preview = CASE WHEN $2 IS NULL THEN preview END " +
+		"WHERE " + condition + " AND deleted_at IS NULL RETURNING " + linkColumns
+	link, err := s.scanLink(s.db.QueryRowContext(ctx, query, value, URL, disabled, passwordHash, title, description, tags, notes, UTMTemplate, rules, variants,
+		fallback, down))
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return Link{}, ErrLinkNotFound
+	}
+
+	return link, err
+}
+
+func (s *SQLStorage) DeleteLink(key string) (Link, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+
+	defer cancel()
+
+	condition, value := keyCondition(s.converter, key, 1)
+	query := "UPDATE links SET deleted_at = now(), url_hash = NULL WHERE " + condition + " AND deleted_at IS NULL RETURNING " + linkColumns
+	link, err := s.scanLink(s.db.QueryRowContext(ctx, query, value))
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return Link{}, ErrLinkNotFound
+	}
+
+	return link, err
+}
+
+// hostExpression Extracts lowercase host of url column, user info and port are skipped
+const hostExpression = "lower(substring(url from '^[^:]+://(?:[^@/?#]*@)?([^/:?#]+)'))"
+
+// createdAtExpression Creation time used for sorting, links created before it was recorded go first as zero time
+const createdAtExpression = "COALESCE(created_at, '0001-01-01 00:00:00+00')"
+
+func (s *SQLStorage) ListLinks(query ListQuery) ([]Link, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+
+	defer cancel()
+
+	conditions := []string{"owner = $1", "deleted_at IS NULL"}
+	values := []interface{}{query.Owner}
+	order, comparison := "ASC", ">"
+
+	if query.Sort == SortCreatedDesc {
+		order, comparison = "DESC", "<"
+	}
+
+	if query.Domain != "" {
+		values = append(values, query.Domain)
+		n := len(values)
+		conditions = append(conditions, fmt.Sprintf("(%s = $%d OR right(%s, length($%d) + 1) = '.' || $%d)", hostExpression, n, hostExpression, n, n))
+	}
+
+	if query.Tag != "" {
+		values = append(values, query.Tag)
+		conditions = append(conditions, fmt.Sprintf("tags @> ARRAY[$%d::text]", len(values)))
+	}
+
+	switch query.Health {
+	case HealthBroken:
+		conditions = append(conditions, "health_checked_at IS NOT NULL", "(health_error <> '' OR health_status >= 400)")
+	case HealthOK:
+		conditions = append(conditions, "health_checked_at IS NOT NULL", "health_error = ''", "health_status < 400")
 	}
 
-	return URLs, nil
+	if !query.Cursor.IsZero() {
+		values = append(values, query.Cursor.CreatedAt, s.converter.ID(query.Cursor.Key))
+		conditions = append(conditions, fmt.Sprintf("(%s, id) %s ($%d, $%d)", createdAtExpression, comparison, len(values)-1, len(values)))
+	}
+
+	sqlQuery := "SELECT " + linkColumns + " FROM links WHERE " + strings.Join(conditions, " AND ") +
+		" ORDER BY " + createdAtExpression + " " + order + ", id " + order + " LIMIT " + strconv.Itoa(query.Limit)
+
+	return queryLinks(ctx, s.db, s.converter, pqTags, query.Limit, sqlQuery, values...)
+}
+
+func (s *SQLStorage) LinksToCheck(now, checkedBefore time.Time, limit int) ([]Link, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+
+	defer cancel()
+
+	query := "SELECT " + linkColumns + " FROM links WHERE deleted_at IS NULL AND disabled = false " +
+		"AND (expires_at IS NULL OR expires_at > $1) AND url ~* '^https?://' " +
+		"AND (health_checked_at IS NULL OR health_checked_at < $2) " +
+		"ORDER BY health_checked_at ASC NULLS FIRST, id ASC LIMIT " + strconv.Itoa(limit)
+
+	return queryLinks(ctx, s.db, s.converter, pqTags, limit, query, now, checkedBefore)
+}
+
+func (s *SQLStorage) StoreHealth(link Link, health Health) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+
+	defer cancel()
+
+	// redirects consist of strings only, so encoding never fails
+	redirects, _ := json.Marshal(append([]string{}, health.Redirects...))
+	condition, value := keyCondition(s.converter, link.Key, 1)
+	query := "UPDATE links SET health_status = $3, health_redirects = $4, health_error = $5, health_checked_at = $6 " +
+		"WHERE " + condition + " AND url = $2 AND deleted_at IS NULL"
+	_, err := s.db.ExecContext(ctx, query, value, link.URL, health.Status, string(redirects), health.Error, health.CheckedAt)
+
+	return err
+}
+
+func (s *SQLStorage) StorePreview(link Link, preview Preview) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+
+	defer cancel()
+
+	condition, value := keyCondition(s.converter, link.Key, 1)
+	query := "UPDATE links SET preview = NULLIF($3, '')::jsonb WHERE " + condition + " AND url = $2 AND deleted_at IS NULL"
+	_, err := s.db.ExecContext(ctx, query, value, link.URL, encodePreview(preview))
+
+	return err
 }
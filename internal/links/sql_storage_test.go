@@ -1,7 +1,6 @@
 package links
 
 import (
-	"database/sql"
 	"github.com/dzhdmitry/link-shorter/internal/db"
 	"github.com/dzhdmitry/link-shorter/test"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
@@ -11,8 +10,7 @@ import (
 )
 
 type SQLStorageSuite struct {
-	suite.Suite
-	db *sql.DB
+	dbStorageSuite
 }
 
 func (s *SQLStorageSuite) SetupSuite() {
@@ -23,10 +21,15 @@ func (s *SQLStorageSuite) SetupSuite() {
 	}
 
 	s.db = openDB
+	s.batchSize = sqlBatchSize
+	s.newStorage = func(converter *KeyConverter) dbStorage {
+		return NewSQLStorage(s.db, 1, converter)
+	}
 }
 
 func (s *SQLStorageSuite) SetupTest() {
 	_, _ = s.db.Exec("TRUNCATE links")
+	_, _ = s.db.Exec("TRUNCATE settings")
 	_, _ = s.db.Exec("ALTER SEQUENCE links_id_seq RESTART")
 }
 
@@ -36,79 +39,6 @@ func (s *SQLStorageSuite) TearDownSuite() {
 	}
 }
 
-func (s *SQLStorageSuite) TestStoreURLs() {
-	tests := []struct {
-		name     string
-		urls     []string
-		expected map[string]string
-	}{
-		{"Empty", []string{}, map[string]string{}},
-		{"Single row", []string{"https://example.com"}, map[string]string{"https://example.com": "1"}},
-	}
-
-	for _, tt := range tests {
-		s.Run(tt.name, func() {
-			storage := NewSQLStorage(s.db, 1)
-			data, err := storage.StoreURLs(tt.urls)
-
-			s.NoError(err)
-			s.Equal(tt.expected, data)
-		})
-	}
-}
-
-func (s *SQLStorageSuite) TestGetURL() {
-	tests := []struct {
-		name        string
-		key         string
-		expectedURL string
-	}{
-		{"Empty", "", ""},
-		{"Non-existing", "aawd1", ""},
-		{"Existing", "1", "https://example.com"},
-	}
-
-	_, _ = s.db.Exec("INSERT INTO links (url) VALUES ('https://example.com')")
-	storage := NewSQLStorage(s.db, 1)
-
-	for _, tt := range tests {
-		s.Run(tt.name, func() {
-			url, err := storage.GetURL(tt.key)
-
-			s.NoError(err)
-			s.Equal(tt.expectedURL, url)
-		})
-	}
-}
-
-func (s *SQLStorageSuite) TestGetURLs() {
-	tests := []struct {
-		name         string
-		keys         []string
-		expectedURLs map[string]string
-	}{
-		{"Empty", []string{"", ""}, map[string]string{}},
-		{"Non-existing", []string{"aawd1"}, map[string]string{}},
-		{"Existing", []string{"1", "2"}, map[string]string{
-			"1": "https://example.com",
-			"2": "https://example2.com",
-		}},
-	}
-
-	_, _ = s.db.Exec("INSERT INTO links (url) VALUES ('https://example.com')")
-	_, _ = s.db.Exec("INSERT INTO links (url) VALUES ('https://example2.com')")
-	storage := NewSQLStorage(s.db, 1)
-
-	for _, tt := range tests {
-		s.Run(tt.name, func() {
-			url, err := storage.GetURLs(tt.keys)
-
-			s.NoError(err)
-			s.Equal(tt.expectedURLs, url)
-		})
-	}
-}
-
 func TestSQLStorage(t *testing.T) {
 	suite.Run(t, new(SQLStorageSuite))
 }
@@ -0,0 +1,426 @@
+package links
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"modernc.org/sqlite"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sqliteBatchSize Number of links inserted by one statement, which keeps number of its parameters below SQLite limit.
+// Statements with many parameters use positional ones, as driver binds numbered ones in quadratic time
+const sqliteBatchSize = 500
+
+// hostRegexp Extracts host of URL the same way as hostExpression of SQLStorage
+var hostRegexp = regexp.MustCompile("^[^:]+://(?:[^@/?#]*@)?([^/:?#]+)")
+
+func init() {
+	// SQLite has no regular expressions, so host of url column is extracted by function
+	sqlite.MustRegisterDeterministicScalarFunction("url_host", 1, func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		URL, ok := args[0].(string)
+
+		if !ok {
+			return nil, nil
+		}
+
+		match := hostRegexp.FindStringSubmatch(URL)
+
+		if match == nil {
+			return nil, nil
+		}
+
+		return strings.ToLower(match[1]), nil
+	})
+}
+
+// SQLiteStorage Keeps links in SQLite database the same way as SQLStorage keeps them in PostgreSQL.
+// Lists are stored as JSON and times are stored as text in UTC, so they are compared in chronological order
+type SQLiteStorage struct {
+	db        *sql.DB
+	timeout   time.Duration
+	converter *KeyConverter
+}
+
+func NewSQLiteStorage(db *sql.DB, timeout int, converter *KeyConverter) *SQLiteStorage {
+	s := SQLiteStorage{
+		db:        db,
+		timeout:   time.Second * time.Duration(timeout),
+		converter: converter,
+	}
+
+	return &s
+}
+
+// CheckAlphabet Records configured alphabet of keys if none is recorded yet and compares it with recorded one
+func (s *SQLiteStorage) CheckAlphabet() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+
+	defer cancel()
+
+	return checkAlphabet(ctx, s.db, s.converter)
+}
+
+// sqliteTime Returns value of datetime column, zero time is NULL
+func sqliteTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t.UTC(), Valid: !t.IsZero()}
+}
+
+// sqliteTags Returns tags as value of JSON column
+func sqliteTags(tags []string) string {
+	// tags consist of strings only, so encoding never fails
+	encoded, _ := json.Marshal(append([]string{}, tags...))
+
+	return string(encoded)
+}
+
+// CheckSequentialMaxID Records last id with sequential key when the secret is enabled for the first time: configured one,
+// or the last stored id if it is greater, so keys of stored links are not changed. Recorded id is used afterwards
+func (s *SQLiteStorage) CheckSequentialMaxID() error {
+	if !s.converter.HasSecret() {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+
+	defer cancel()
+
+	// WHERE clause separates SELECT from upsert clause
+	query := "INSERT INTO settings(name, value) SELECT 'sequential_max_id', CAST(MAX($1, COALESCE(MAX(id), 0)) AS TEXT) FROM links " +
+		"WHERE true ON CONFLICT (name) DO NOTHING"
+
+	return checkSequentialMaxID(ctx, s.db, s.converter, query, "SELECT CAST(value AS INTEGER) FROM settings WHERE name = 'sequential_max_id'")
+}
+
+// linkValues Returns values of insertColumns of new link
+func (s *SQLiteStorage) linkValues(URL string, options LinkOptions) []interface{} {
+	return []interface{}{
+		URL,
+		sqliteTime(options.ExpiresAt),
+		options.Owner,
+		sqliteTime(options.CreatedAt),
+		options.Metadata.Title,
+		options.Metadata.Description,
+		sqliteTags(options.Metadata.Tags),
+		options.Metadata.Notes,
+		options.UTMTemplate,
+		jsonList(encodeRules(options.Rules)),
+		jsonList(encodeVariants(options.Variants)),
+		options.Fallback,
+		sql.NullString{String: options.PasswordHash, Valid: options.PasswordHash != ""},
+	}
+}
+
+// linkPlaceholders Makes positional placeholders of linkValues
+func (s *SQLiteStorage) linkPlaceholders() string {
+	return strings.Repeat("?, ", insertColumnsCount-1) + "?"
+}
+
+func (s *SQLiteStorage) StoreURLs(URLs []string, options LinkOptions) (map[string]string, error) {
+	keysByURLs := make(map[string]string, len(URLs))
+
+	if len(URLs) == 0 {
+		return keysByURLs, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+
+	defer cancel()
+
+	if err := insertLinks(ctx, s.db, s.converter, positionalPlaceholders, s.linkValues, sqliteBatchSize, keysByURLs, URLs, options, false); err != nil {
+		return nil, err
+	}
+
+	return keysByURLs, nil
+}
+
+func (s *SQLiteStorage) StoreUniqueURLs(URLs []string, options LinkOptions) (map[string]string, map[string]bool, error) {
+	keysByURLs := make(map[string]string, len(URLs))
+	existing := map[string]bool{}
+
+	if len(URLs) == 0 {
+		return keysByURLs, existing, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+
+	defer cancel()
+
+	if err := insertLinks(ctx, s.db, s.converter, positionalPlaceholders, s.linkValues, sqliteBatchSize, keysByURLs, URLs, options, true); err != nil {
+		return nil, nil, err
+	}
+
+	err := scanExistingKeys(ctx, s.db, s.converter, positionalPlaceholders, sqliteBatchSize, keysByURLs, existing, URLs, options)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return keysByURLs, existing, nil
+}
+
+func (s *SQLiteStorage) StoreAlias(alias, URL string, options LinkOptions) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+
+	defer cancel()
+
+	var id int64
+	query := "INSERT INTO links(" + insertColumns + ", alias) VALUES (" + s.linkPlaceholders() + ", ?) " +
+		"ON CONFLICT (alias) DO NOTHING RETURNING id"
+	err := s.db.QueryRowContext(ctx, query, append(s.linkValues(URL, options), alias)...).Scan(&id)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrAliasTaken
+	}
+
+	return err
+}
+
+// sqliteTagsColumn Scans tags from JSON column
+type sqliteTagsColumn struct {
+	tags *[]string
+}
+
+func (c sqliteTagsColumn) Scan(src any) error {
+	switch src := src.(type) {
+	case string:
+		return json.Unmarshal([]byte(src), c.tags)
+	case []byte:
+		return json.Unmarshal(src, c.tags)
+	}
+
+	return fmt.Errorf("unsupported type of tags column: %T", src)
+}
+
+// sqliteTagsScanner Scans tags from JSON column
+func sqliteTagsScanner(tags *[]string) sql.Scanner {
+	return sqliteTagsColumn{tags: tags}
+}
+
+func (s *SQLiteStorage) scanLink(row rowScanner) (Link, error) {
+	return scanLink(row, s.converter, sqliteTagsScanner)
+}
+
+func (s *SQLiteStorage) GetLink(key string) (Link, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+
+	defer cancel()
+
+	condition, value := keyCondition(s.converter, key, 1)
+	query := "SELECT " + linkColumns + " FROM links WHERE " + condition + " AND deleted_at IS NULL LIMIT 1"
+	link, err := s.scanLink(s.db.QueryRowContext(ctx, query, value))
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Link{}, nil
+		}
+
+		return Link{}, err
+	}
+
+	return link, nil
+}
+
+func (s *SQLiteStorage) GetLinks(keys []string) (map[string]Link, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+
+	defer cancel()
+
+	// many keys are bound to positional placeholders, see sqliteBatchSize
+	return getLinks(ctx, s.db, s.converter, sqliteTagsScanner, positionalPlaceholders, keys)
+}
+
+func (s *SQLiteStorage) UpdateLink(key string, update LinkUpdate) (Link, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+
+	defer cancel()
+
+	URL := sql.NullString{}
+	disabled := sql.NullBool{}
+	passwordHash := sql.NullString{}
+
+	if update.URL != nil {
+		URL = sql.NullString{String: *update.URL, Valid: true}
+	}
+
+	if update.Disabled != nil {
+		disabled = sql.NullBool{Bool: *update.Disabled, Valid: true}
+	}
+
+	if update.PasswordHash != nil {
+		passwordHash = sql.NullString{String: *update.PasswordHash, Valid: true}
+	}
+
+	metadata := update.apply(Metadata{})
+	title := sql.NullString{String: metadata.Title, Valid: update.Title != nil}
+	description := sql.NullString{String: metadata.Description, Valid: update.Description != nil}
+	notes := sql.NullString{String: metadata.Notes, Valid: update.Notes != nil}
+	tags := sql.NullString{}
+	UTMTemplate := sql.NullString{}
+
+	if update.Tags != nil {
+		tags = sql.NullString{String: sqliteTags(metadata.Tags), Valid: true}
+	}
+
+	if update.UTMTemplate != nil {
+		UTMTemplate = sql.NullString{String: *update.UTMTemplate, Valid: true}
+	}
+
+	rules := sql.NullString{}
+
+	if update.Rules != nil {
+		rules = sql.NullString{String: jsonList(encodeRules(*update.Rules)), Valid: true}
+	}
+
+	variants := sql.NullString{}
+
+	if update.Variants != nil {
+		variants = sql.NullString{String: jsonList(encodeVariants(*update.Variants)), Valid: true}
+	}
+
+	fallback := sql.NullString{}
+
+	if update.Fallback != nil {
+		fallback = sql.NullString{String: *update.Fallback, Valid: true}
+	}
+
+	down := sql.NullBool{}
+
+	if update.Down != nil {
+		down = sql.NullBool{Bool: *update.Down, Valid: true}
+	}
+
+	condition, value := keyCondition(s.converter, key, 1)
+	// changed, disabled, down, protected links and links with UTM template, rules, variants or fallback are not reused by deduplication
+	query := "UPDATE links SET url = COALESCE($2, url), disabled = COALESCE($3, disabled), " +
+		"password_hash = CASE WHEN $4 IS NULL THEN password_hash ELSE NULLIF($4, '') END, " +
+		"url_hash = CASE WHEN $2 IS NULL AND $3 IS NOT TRUE AND $4 IS NULL AND COALESCE($9, '') = '' " +
+		"AND COALESCE(json_array_length($10), 0) = 0 AND COALESCE(json_array_length($11), 0) = 0 " +
+		"AND COALESCE($12, '') = '' AND $13 IS NOT TRUE THEN url_hash END, " +
+		"title = COALESCE($5, title), description = COALESCE($6, description), tags = COALESCE($7, tags), " +
+		"notes = COALESCE($8, notes), utm_template = COALESCE($9, utm_template), rules = COALESCE($10, rules), " +
+		"variants = COALESCE($11, variants), fallback = COALESCE($12, fallback), down = COALESCE($13, down), " +
+		// new destination is neither checked nor fetched yet
+		"health_status = CASE WHEN $2 IS NULL THEN health_status ELSE 0 END, " +
+		"health_redirects = CASE WHEN $2 IS NULL THEN health_redirects ELSE '[]' END, " +
+		"health_error = CASE WHEN $2 IS NULL THEN health_error ELSE '' END, " +
+		"health_checked_at = CASE WHEN $2 IS NULL THEN health_checked_at END, " +
+		"preview = CASE WHEN $2 IS NULL THEN preview END " +
+		"WHERE " + condition + " AND deleted_at IS NULL RETURNING " + linkColumns
+	link, err := s.scanLink(s.db.QueryRowContext(ctx, query, value, URL, disabled, passwordHash, title, description, tags, notes, UTMTemplate, rules, variants,
+		fallback, down))
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return Link{}, ErrLinkNotFound
+	}
+
+	return link, err
+}
+
+func (s *SQLiteStorage) DeleteLink(key string) (Link, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+
+	defer cancel()
+
+	condition, value := keyCondition(s.converter, key, 1)
+	query := "UPDATE links SET deleted_at = $2, url_hash = NULL WHERE " + condition + " AND deleted_at IS NULL RETURNING " + linkColumns
+	link, err := s.scanLink(s.db.QueryRowContext(ctx, query, value, time.Now().UTC()))
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return Link{}, ErrLinkNotFound
+	}
+
+	return link, err
+}
+
+// sqliteCreatedAtExpression Creation time used for sorting, links created before it was recorded go first as zero time
+const sqliteCreatedAtExpression = "COALESCE(created_at, '0001-01-01 00:00:00+00:00')"
+
+func (s *SQLiteStorage) ListLinks(query ListQuery) ([]Link, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+
+	defer cancel()
+
+	conditions := []string{"owner = $1", "deleted_at IS NULL"}
+	values := []interface{}{query.Owner}
+	order, comparison := "ASC", ">"
+
+	if query.Sort == SortCreatedDesc {
+		order, comparison = "DESC", "<"
+	}
+
+	if query.Domain != "" {
+		values = append(values, query.Domain)
+		n := len(values)
+		conditions = append(conditions, fmt.Sprintf("(url_host(url) = $%d OR substr(url_host(url), -length($%d) - 1) = '.' || $%d)", n, n, n))
+	}
+
+	if query.Tag != "" {
+		values = append(values, query.Tag)
+		conditions = append(conditions, fmt.Sprintf("EXISTS (SELECT 1 FROM json_each(tags) WHERE value = $%d)", len(values)))
+	}
+
+	switch query.Health {
+	case HealthBroken:
+		conditions = append(conditions, "health_checked_at IS NOT NULL", "(health_error <> '' OR health_status >= 400)")
+	case HealthOK:
+		conditions = append(conditions, "health_checked_at IS NOT NULL", "health_error = ''", "health_status < 400")
+	}
+
+	if !query.Cursor.IsZero() {
+		values = append(values, query.Cursor.CreatedAt.UTC(), s.converter.ID(query.Cursor.Key))
+		conditions = append(conditions, fmt.Sprintf("(%s, id) %s ($%d, $%d)", sqliteCreatedAtExpression, comparison, len(values)-1, len(values)))
+	}
+
+	sqlQuery := "SELECT " + linkColumns + " FROM links WHERE " + strings.Join(conditions, " AND ") +
+		" ORDER BY " + sqliteCreatedAtExpression + " " + order + ", id " + order + " LIMIT " + strconv.Itoa(query.Limit)
+
+	return queryLinks(ctx, s.db, s.converter, sqliteTagsScanner, query.Limit, sqlQuery, values...)
+}
+
+func (s *SQLiteStorage) LinksToCheck(now, checkedBefore time.Time, limit int) ([]Link, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+
+	defer cancel()
+
+	// LIKE is case-insensitive for ASCII characters
+	query := "SELECT " + linkColumns + " FROM links WHERE deleted_at IS NULL AND disabled = false " +
+		"AND (expires_at IS NULL OR expires_at > $1) AND (url LIKE 'http://%' OR url LIKE 'https://%') " +
+		"AND (health_checked_at IS NULL OR health_checked_at < $2) " +
+		"ORDER BY health_checked_at ASC NULLS FIRST, id ASC LIMIT " + strconv.Itoa(limit)
+
+	return queryLinks(ctx, s.db, s.converter, sqliteTagsScanner, limit, query, now.UTC(), checkedBefore.UTC())
+}
+
+func (s *SQLiteStorage) StoreHealth(link Link, health Health) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+
+	defer cancel()
+
+	// redirects consist of strings only, so encoding never fails
+	redirects, _ := json.Marshal(append([]string{}, health.Redirects...))
+	condition, value := keyCondition(s.converter, link.Key, 1)
+	query := "UPDATE links SET health_status = $3, health_redirects = $4, health_error = $5, health_checked_at = $6 " +
+		"WHERE " + condition + " AND url = $2 AND deleted_at IS NULL"
+	_, err := s.db.ExecContext(ctx, query, value, link.URL, health.Status, string(redirects), health.Error, sqliteTime(health.CheckedAt))
+
+	return err
+}
+
+func (s *SQLiteStorage) StorePreview(link Link, preview Preview) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+
+	defer cancel()
+
+	condition, value := keyCondition(s.converter, link.Key, 1)
+	query := "UPDATE links SET preview = NULLIF($3, '') WHERE " + condition + " AND url = $2 AND deleted_at IS NULL"
+	_, err := s.db.ExecContext(ctx, query, value, link.URL, encodePreview(preview))
+
+	return err
+}
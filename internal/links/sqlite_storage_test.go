@@ -0,0 +1,36 @@
+package links
+
+import (
+	"github.com/dzhdmitry/link-shorter/internal/db"
+	"github.com/stretchr/testify/suite"
+	"path/filepath"
+	"testing"
+)
+
+type SQLiteStorageSuite struct {
+	dbStorageSuite
+}
+
+func (s *SQLiteStorageSuite) SetupTest() {
+	openDB, err := db.OpenSQLite(filepath.Join(s.T().TempDir(), "links.db"))
+
+	if err != nil {
+		panic(err)
+	}
+
+	s.db = openDB
+	s.batchSize = sqliteBatchSize
+	s.newStorage = func(converter *KeyConverter) dbStorage {
+		return NewSQLiteStorage(s.db, 1, converter)
+	}
+}
+
+func (s *SQLiteStorageSuite) TearDownTest() {
+	if s.db != nil {
+		s.db.Close()
+	}
+}
+
+func TestSQLiteStorage(t *testing.T) {
+	suite.Run(t, new(SQLiteStorageSuite))
+}
@@ -0,0 +1,233 @@
+package links
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const statsTopLimit = 10
+const statsDateFormat = "2006-01-02"
+
+type DailyClicks struct {
+	Date   string
+	Clicks int
+}
+
+type Counter struct {
+	Name  string
+	Count int
+}
+
+// VariantClicks Clicks of visitors sent to variant of A/B split
+type VariantClicks struct {
+	Variant        int
+	Clicks         int
+	UniqueVisitors int
+}
+
+type LinkStats struct {
+	TotalClicks    int
+	UniqueVisitors int
+	Daily          []DailyClicks
+	TopReferrers   []Counter
+	TopUserAgents  []Counter
+	Variants       []VariantClicks
+}
+
+// Stats Computes clicks statistics of links, clicks made by key and by alias of link are counted together
+type Stats struct {
+	storage StorageInterface
+	clicks  ClicksStorageInterface
+}
+
+func NewStats(storage StorageInterface, clicks ClicksStorageInterface) *Stats {
+	return &Stats{
+		storage: storage,
+		clicks:  clicks,
+	}
+}
+
+// GetStats Returns statistics of clicks made from beginning of "from" day till end of "to" day (UTC)
+func (s *Stats) GetStats(key string, from, to time.Time) (LinkStats, error) {
+	link, err := s.storage.GetLink(key)
+
+	if err != nil {
+		return LinkStats{}, err
+	}
+
+	if link.URL == "" {
+		return LinkStats{}, ErrLinkNotFound
+	}
+
+	keys := []string{link.Key}
+
+	if link.Alias != "" {
+		keys = append(keys, link.Alias)
+	}
+
+	from = truncateToDay(from)
+	to = truncateToDay(to).AddDate(0, 0, 1)
+
+	return s.clicks.GetStats(keys, from, to)
+}
+
+func truncateToDay(t time.Time) time.Time {
+	t = t.UTC()
+
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// dailyBreakdown Makes list of clicks per day in range [from, to), days without clicks are included
+func dailyBreakdown(counts map[string]int, from, to time.Time) []DailyClicks {
+	var daily []DailyClicks
+
+	for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+		date := day.Format(statsDateFormat)
+		daily = append(daily, DailyClicks{Date: date, Clicks: counts[date]})
+	}
+
+	return daily
+}
+
+// topCounters Returns counters with the greatest counts, counters with equal counts are sorted by name
+func topCounters(counts map[string]int, limit int) []Counter {
+	counters := make([]Counter, 0, len(counts))
+
+	for name, count := range counts {
+		counters = append(counters, Counter{Name: name, Count: count})
+	}
+
+	sort.Slice(counters, func(i, j int) bool {
+		if counters[i].Count != counters[j].Count {
+			return counters[i].Count > counters[j].Count
+		}
+
+		return counters[i].Name < counters[j].Name
+	})
+
+	if len(counters) > limit {
+		counters = counters[:limit]
+	}
+
+	return counters
+}
+
+// userAgentFamilies Tokens are checked in order, because browsers mention each other in user agent
+var userAgentFamilies = []struct {
+	token  string
+	family string
+}{
+	{"bot", "Bot"},
+	{"crawler", "Bot"},
+	{"spider", "Bot"},
+	{"curl/", "curl"},
+	{"edg/", "Edge"},
+	{"opr/", "Opera"},
+	{"yabrowser/", "Yandex Browser"},
+	{"firefox/", "Firefox"},
+	{"chrome/", "Chrome"},
+	{"crios/", "Chrome"},
+	{"safari/", "Safari"},
+}
+
+func userAgentFamily(userAgent string) string {
+	userAgent = strings.ToLower(userAgent)
+
+	for _, f := range userAgentFamilies {
+		if strings.Contains(userAgent, f.token) {
+			return f.family
+		}
+	}
+
+	return "Other"
+}
+
+// statsCollector Aggregates clicks one by one, used by storages which can not aggregate clicks by themselves
+type statsCollector struct {
+	from       time.Time
+	to         time.Time
+	keys       map[string]bool
+	total      int
+	visitors   map[string]bool
+	daily      map[string]int
+	referrers  map[string]int
+	userAgents map[string]int
+	variants   map[int]*variantCollector
+}
+
+type variantCollector struct {
+	clicks   int
+	visitors map[string]bool
+}
+
+func newStatsCollector(keys []string, from, to time.Time) *statsCollector {
+	c := statsCollector{
+		from:       from,
+		to:         to,
+		keys:       make(map[string]bool, len(keys)),
+		visitors:   map[string]bool{},
+		daily:      map[string]int{},
+		referrers:  map[string]int{},
+		userAgents: map[string]int{},
+		variants:   map[int]*variantCollector{},
+	}
+
+	for _, key := range keys {
+		c.keys[key] = true
+	}
+
+	return &c
+}
+
+func (c *statsCollector) add(click Click) {
+	if !c.keys[click.Key] || click.ClickedAt.Before(c.from) || !click.ClickedAt.Before(c.to) {
+		return
+	}
+
+	visitor := fmt.Sprintf("%s|%s", click.IP, click.UserAgent)
+	c.total++
+	c.visitors[visitor] = true
+	c.daily[click.ClickedAt.UTC().Format(statsDateFormat)]++
+	c.userAgents[userAgentFamily(click.UserAgent)]++
+
+	if click.Referrer != "" {
+		c.referrers[click.Referrer]++
+	}
+
+	if click.Variant > 0 {
+		if c.variants[click.Variant] == nil {
+			c.variants[click.Variant] = &variantCollector{visitors: map[string]bool{}}
+		}
+
+		c.variants[click.Variant].clicks++
+		c.variants[click.Variant].visitors[visitor] = true
+	}
+}
+
+// variantClicks Returns clicks of variants sorted by number of variant
+func (c *statsCollector) variantClicks() []VariantClicks {
+	var variants []VariantClicks
+
+	for number, variant := range c.variants {
+		variants = append(variants, VariantClicks{Variant: number, Clicks: variant.clicks, UniqueVisitors: len(variant.visitors)})
+	}
+
+	sort.Slice(variants, func(i, j int) bool {
+		return variants[i].Variant < variants[j].Variant
+	})
+
+	return variants
+}
+
+func (c *statsCollector) stats() LinkStats {
+	return LinkStats{
+		TotalClicks:    c.total,
+		UniqueVisitors: len(c.visitors),
+		Daily:          dailyBreakdown(c.daily, c.from, c.to),
+		TopReferrers:   topCounters(c.referrers, statsTopLimit),
+		TopUserAgents:  topCounters(c.userAgents, statsTopLimit),
+		Variants:       c.variantClicks(),
+	}
+}
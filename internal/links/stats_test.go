@@ -0,0 +1,72 @@
+package links
+
+import (
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestGetStats(t *testing.T) {
+	_ = os.Remove(testdata + "/results/test_store.csv")
+	s, err := NewFileStorage(testdata+"/results/test_store.csv", newTestConverter(AlphabetBase36, "", 0))
+
+	require.NoError(t, err)
+
+	_, _ = s.StoreURLs([]string{"https://example.com"}, LinkOptions{})
+	_ = s.StoreAlias("spring-sale", "https://example.com/sale", LinkOptions{})
+
+	clicks := &testClicksStorage{}
+	_ = clicks.StoreClicks([]Click{
+		{Key: "1", ClickedAt: time.Date(2024, 2, 7, 10, 0, 0, 0, time.UTC), IP: "10.1.2.0"},
+		{Key: "2", ClickedAt: time.Date(2024, 2, 7, 11, 0, 0, 0, time.UTC), IP: "10.1.2.0"},
+		{Key: "spring-sale", ClickedAt: time.Date(2024, 2, 7, 12, 0, 0, 0, time.UTC), Referrer: "https://example.org", IP: "10.1.3.0"},
+		{Key: "spring-sale", ClickedAt: time.Date(2024, 2, 8, 12, 0, 0, 0, time.UTC), IP: "10.1.3.0"},
+	})
+
+	stats := NewStats(s, clicks)
+	result, err := stats.GetStats("spring-sale", time.Date(2024, 2, 7, 13, 0, 0, 0, time.UTC), time.Date(2024, 2, 7, 9, 0, 0, 0, time.UTC))
+
+	require.NoError(t, err)
+	require.Equal(t, LinkStats{
+		TotalClicks:    2,
+		UniqueVisitors: 2,
+		Daily:          []DailyClicks{{Date: "2024-02-07", Clicks: 2}},
+		TopReferrers:   []Counter{{Name: "https://example.org", Count: 1}},
+		TopUserAgents:  []Counter{{Name: "Other", Count: 2}},
+	}, result)
+
+	_, err = stats.GetStats("3", time.Date(2024, 2, 7, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 7, 0, 0, 0, 0, time.UTC))
+
+	require.ErrorIs(t, err, ErrLinkNotFound)
+}
+
+func TestUserAgentFamily(t *testing.T) {
+	tests := []struct {
+		name      string
+		userAgent string
+		expected  string
+	}{
+		{"Chrome", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/121.0.0.0 Safari/537.36", "Chrome"},
+		{"Edge", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/121.0.0.0 Safari/537.36 Edg/121.0.0.0", "Edge"},
+		{"Opera", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 OPR/106.0.0.0", "Opera"},
+		{"Firefox", "Mozilla/5.0 (X11; Linux x86_64; rv:122.0) Gecko/20100101 Firefox/122.0", "Firefox"},
+		{"Safari", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Mobile/15E148 Safari/604.1", "Safari"},
+		{"Chrome on iOS", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/121.0.6167.66 Mobile/15E148 Safari/604.1", "Chrome"},
+		{"Bot", "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", "Bot"},
+		{"curl", "curl/8.5.0", "curl"},
+		{"Empty", "", "Other"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, userAgentFamily(tt.userAgent))
+		})
+	}
+}
+
+func TestTopCounters(t *testing.T) {
+	counters := topCounters(map[string]int{"b": 2, "a": 2, "c": 3, "d": 1}, 3)
+
+	require.Equal(t, []Counter{{Name: "c", Count: 3}, {Name: "a", Count: 2}, {Name: "b", Count: 2}}, counters)
+}
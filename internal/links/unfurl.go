@@ -0,0 +1,283 @@
+package links
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dzhdmitry/link-shorter/internal/utils"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// unfurlConcurrency Number of destinations fetched at once, other links wait for their turn
+const unfurlConcurrency = 4
+
+var errNotPage = errors.New("destination is not HTML page")
+
+// Preview Metadata of destination page: content of <title>, Open Graph properties without "og:" prefix
+// (title, description, image, site_name, etc.) and URL of favicon. Zero preview means page was not fetched
+type Preview struct {
+	Title     string            `json:"title,omitempty"`
+	OpenGraph map[string]string `json:"open_graph,omitempty"`
+	Favicon   string            `json:"favicon,omitempty"`
+	FetchedAt time.Time         `json:"fetched_at"`
+}
+
+func (p Preview) IsZero() bool {
+	return p.FetchedAt.IsZero()
+}
+
+// encodePreview Returns JSON of preview, zero preview is empty string
+func encodePreview(preview Preview) string {
+	if preview.IsZero() {
+		return ""
+	}
+
+	// preview consists of strings and time only, so encoding never fails
+	data, _ := json.Marshal(preview)
+
+	return string(data)
+}
+
+// decodePreview Returns preview of JSON, empty string is zero preview
+func decodePreview(data string) (Preview, error) {
+	var preview Preview
+
+	if data == "" {
+		return preview, nil
+	}
+
+	err := json.Unmarshal([]byte(data), &preview)
+
+	return preview, err
+}
+
+type PreviewStorageInterface interface {
+	// StorePreview Records preview of link destination, it is skipped if link was deleted or its URL was changed since
+	StorePreview(link Link, preview Preview) error
+}
+
+// Unfurler Fetches destination pages of links in background and records their previews.
+// Only beginning of page up to maxSize bytes is read, time of request is limited by client
+type Unfurler struct {
+	storage    PreviewStorageInterface
+	client     *http.Client
+	clock      utils.ClockInterface
+	logger     *utils.Logger
+	background *utils.Background
+	maxSize    int64
+	slots      chan struct{}
+	ctx        context.Context
+	cancel     context.CancelFunc
+}
+
+func NewUnfurler(
+	storage PreviewStorageInterface,
+	client *http.Client,
+	clock utils.ClockInterface,
+	logger *utils.Logger,
+	background *utils.Background,
+	maxSize int64,
+) *Unfurler {
+	u := Unfurler{
+		storage:    storage,
+		client:     client,
+		clock:      clock,
+		logger:     logger,
+		background: background,
+		maxSize:    maxSize,
+		slots:      make(chan struct{}, unfurlConcurrency),
+	}
+
+	u.ctx, u.cancel = context.WithCancel(context.Background())
+
+	return &u
+}
+
+// Close Stops fetching, requests in progress are cancelled and waiting links are skipped
+func (u *Unfurler) Close() {
+	u.cancel()
+}
+
+// Unfurl Fetches destination of the link by key in background and records its preview.
+// Destinations which are not http or https are skipped
+func (u *Unfurler) Unfurl(key, URL string) {
+	if !isCheckable(URL) {
+		return
+	}
+
+	u.background.Run(func() {
+		select {
+		case <-u.ctx.Done():
+			return
+		case u.slots <- struct{}{}:
+		}
+
+		defer func() { <-u.slots }()
+
+		preview, err := u.Fetch(URL)
+
+		if u.ctx.Err() != nil {
+			return
+		}
+
+		if err != nil {
+			// unreachable destination is not a failure of service, health checker reports it
+			u.logger.LogInfo(fmt.Sprintf("preview of %s is not fetched: %s", URL, err))
+
+			return
+		}
+
+		if err = u.storage.StorePreview(Link{Key: key, URL: URL}, preview); err != nil {
+			u.logger.LogError(err)
+		}
+	})
+}
+
+// Fetch Requests destination page and extracts its preview
+func (u *Unfurler) Fetch(URL string) (Preview, error) {
+	req, err := http.NewRequestWithContext(u.ctx, http.MethodGet, URL, nil)
+
+	if err != nil {
+		return Preview{}, err
+	}
+
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	response, err := u.client.Do(req)
+
+	if err != nil {
+		var urlErr *url.Error
+
+		// error is reported without method and URL, which are known
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+
+		return Preview{}, err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode >= http.StatusBadRequest {
+		return Preview{}, fmt.Errorf("destination responded with status %d", response.StatusCode)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(response.Header.Get("Content-Type"))
+
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return Preview{}, errNotPage
+	}
+
+	// relative URLs of page are resolved against the last URL of redirects
+	preview := parsePreview(io.LimitReader(response.Body, u.maxSize), response.Request.URL)
+	preview.FetchedAt = u.clock.Now().UTC().Truncate(time.Second)
+
+	return preview, nil
+}
+
+// parsePreview Reads head of HTML page. The first <title>, value of each Open Graph property and favicon are taken,
+// favicon defaults to /favicon.ico of the page host
+func parsePreview(page io.Reader, base *url.URL) Preview {
+	var preview Preview
+	var title strings.Builder
+	inTitle := false
+	tokenizer := html.NewTokenizer(page)
+
+	for head := true; head; {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			// end of page or of its allowed size
+			head = false
+		case html.TextToken:
+			if inTitle {
+				title.Write(tokenizer.Text())
+			}
+		case html.EndTagToken:
+			if name, _ := tokenizer.TagName(); atom.Lookup(name) == atom.Title {
+				inTitle = false
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+
+			switch token.DataAtom {
+			case atom.Title:
+				inTitle = title.Len() == 0
+			case atom.Meta:
+				property, content := attribute(token, "property"), attribute(token, "content")
+
+				if !strings.HasPrefix(property, "og:") || content == "" {
+					break
+				}
+
+				if preview.OpenGraph == nil {
+					preview.OpenGraph = map[string]string{}
+				}
+
+				name := strings.TrimPrefix(property, "og:")
+
+				if _, ok := preview.OpenGraph[name]; !ok {
+					if name == "image" || name == "url" {
+						content = resolveURL(base, content)
+					}
+
+					preview.OpenGraph[name] = content
+				}
+			case atom.Link:
+				if preview.Favicon == "" && hasToken(attribute(token, "rel"), "icon") && attribute(token, "href") != "" {
+					preview.Favicon = resolveURL(base, attribute(token, "href"))
+				}
+			case atom.Body:
+				head = false
+			}
+		}
+	}
+
+	preview.Title = strings.Join(strings.Fields(title.String()), " ")
+
+	if preview.Favicon == "" {
+		preview.Favicon = resolveURL(base, "/favicon.ico")
+	}
+
+	return preview
+}
+
+// attribute Returns value of attribute of HTML tag, empty if tag has no such attribute
+func attribute(token html.Token, name string) string {
+	for _, attr := range token.Attr {
+		if attr.Key == name {
+			return strings.TrimSpace(attr.Val)
+		}
+	}
+
+	return ""
+}
+
+// hasToken Reports whether space-separated list of attribute contains token in any case
+func hasToken(list, token string) bool {
+	for _, t := range strings.Fields(list) {
+		if strings.EqualFold(t, token) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// resolveURL Returns absolute URL of reference on page, invalid reference is returned as is
+func resolveURL(base *url.URL, reference string) string {
+	ref, err := url.Parse(reference)
+
+	if err != nil {
+		return reference
+	}
+
+	return base.ResolveReference(ref).String()
+}
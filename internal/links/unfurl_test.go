@@ -0,0 +1,182 @@
+package links
+
+import (
+	"github.com/dzhdmitry/link-shorter/internal/utils"
+	"github.com/dzhdmitry/link-shorter/test"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testPage = `<!DOCTYPE html>
+<html>
+<head>
+	<meta charset="utf-8">
+	<title>
+		Spring sale &amp; more
+	</title>
+	<meta property="og:title" content="Spring sale">
+	<meta property="og:description" content="Discounts up to 50%">
+	<meta property="og:image" content="/images/sale.png">
+	<meta name="description" content="Not Open Graph">
+	<link rel="stylesheet" href="/style.css">
+	<link rel="Shortcut Icon" href="icons/favicon.png">
+</head>
+<body>
+	<title>Not a title of page</title>
+	<meta property="og:type" content="website">
+</body>
+</html>`
+
+func newTestPages() *httptest.Server {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/sale/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(testPage))
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/sale/", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/bare", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte("<p>No head"))
+	})
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte("<html><head><!--" + strings.Repeat("-", 2048) + "--><title>Too far</title></head></html>"))
+	})
+	mux.HandleFunc("/file.pdf", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		_, _ = w.Write([]byte("%PDF-1.4"))
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func newTestUnfurler(storage PreviewStorageInterface, client *http.Client) (*Unfurler, *utils.Background) {
+	background := &utils.Background{}
+	unfurler := NewUnfurler(storage, client, &test.Clock{}, utils.NewLogger(io.Discard, &test.Clock{}), background, 1024)
+
+	return unfurler, background
+}
+
+func TestUnfurlerFetch(t *testing.T) {
+	server := newTestPages()
+
+	defer server.Close()
+
+	client := server.Client()
+	client.Timeout = 100 * time.Millisecond
+	unfurler, background := newTestUnfurler(&testPreviewStorage{}, client)
+
+	defer background.Wait()
+	defer unfurler.Close()
+
+	fetchedAt := time.Date(2024, 2, 7, 12, 0, 0, 0, time.UTC)
+	sale := Preview{
+		Title: "Spring sale & more",
+		OpenGraph: map[string]string{
+			"title":       "Spring sale",
+			"description": "Discounts up to 50%",
+			"image":       server.URL + "/images/sale.png",
+		},
+		Favicon:   server.URL + "/sale/icons/favicon.png",
+		FetchedAt: fetchedAt,
+	}
+
+	tests := []struct {
+		name          string
+		URL           string
+		expected      Preview
+		expectedError string
+	}{
+		{"Page", server.URL + "/sale/", sale, ""},
+		{"Redirect", server.URL + "/moved", sale, ""},
+		{"Without head", server.URL + "/bare", Preview{Favicon: server.URL + "/favicon.ico", FetchedAt: fetchedAt}, ""},
+		{"Larger than limit", server.URL + "/large", Preview{Favicon: server.URL + "/favicon.ico", FetchedAt: fetchedAt}, ""},
+		{"Not HTML", server.URL + "/file.pdf", Preview{}, "destination is not HTML page"},
+		{"Not found", server.URL + "/missing", Preview{}, "destination responded with status 404"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			preview, err := unfurler.Fetch(tt.URL)
+
+			if tt.expectedError == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tt.expectedError)
+			}
+
+			require.Equal(t, tt.expected, preview)
+		})
+	}
+
+	_, err := unfurler.Fetch(server.URL + "/slow")
+
+	require.ErrorContains(t, err, "Client.Timeout exceeded")
+}
+
+type testPreviewStorage struct {
+	//
+}
+
+func (s *testPreviewStorage) StorePreview(_ Link, _ Preview) error {
+	return nil
+}
+
+func TestUnfurlerUnfurl(t *testing.T) {
+	server := newTestPages()
+
+	defer server.Close()
+
+	s, err := NewFileStorage(t.TempDir()+"/storage.csv", newTestConverter(AlphabetBase36, "", 0))
+
+	require.NoError(t, err)
+
+	URLs := []string{server.URL + "/sale/", server.URL + "/missing", "mailto:info@example.com"}
+	keys, _ := s.StoreURLs(URLs, LinkOptions{})
+	unfurler, background := newTestUnfurler(s, server.Client())
+
+	for _, URL := range URLs {
+		unfurler.Unfurl(keys[URL], URL)
+	}
+
+	background.Wait()
+
+	link, _ := s.GetLink(keys[server.URL+"/sale/"])
+
+	require.Equal(t, "Spring sale & more", link.Preview.Title)
+	require.Equal(t, "Spring sale", link.Preview.OpenGraph["title"])
+
+	link, _ = s.GetLink(keys[server.URL+"/missing"])
+
+	require.True(t, link.Preview.IsZero())
+
+	link, _ = s.GetLink(keys["mailto:info@example.com"])
+
+	require.True(t, link.Preview.IsZero())
+
+	// closed unfurler skips links
+	unfurler.Close()
+	unfurler.Unfurl(keys[server.URL+"/missing"], server.URL+"/sale/")
+	background.Wait()
+
+	link, _ = s.GetLink(keys[server.URL+"/missing"])
+
+	require.True(t, link.Preview.IsZero())
+}
@@ -0,0 +1,99 @@
+package links
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const variantsMaxCount = 10
+const variantMaxWeight = 1000
+
+// Variant Destination of A/B split, visitors are split between variants in proportion to weights
+type Variant struct {
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+}
+
+// Variants Destinations of A/B split, variants are numbered from 1 in order of the list
+type Variants []Variant
+
+// Choose Returns number of variant which n falls in when range of total weight is divided by weights,
+// 0 if there are no variants
+func (v Variants) Choose(n uint64) int {
+	total := 0
+
+	for _, variant := range v {
+		total += variant.Weight
+	}
+
+	if total <= 0 {
+		return 0
+	}
+
+	bucket := int(n % uint64(total))
+
+	for i, variant := range v {
+		if bucket < variant.Weight {
+			return i + 1
+		}
+
+		bucket -= variant.Weight
+	}
+
+	return 0
+}
+
+// URL Returns URL of variant by number, false if there is no such variant
+func (v Variants) URL(number int) (string, bool) {
+	if number < 1 || number > len(v) {
+		return "", false
+	}
+
+	return v[number-1].URL, true
+}
+
+// Validate Returns error if there are less than two variants or weight is out of range, URLs are validated by caller
+func (v Variants) Validate() error {
+	if len(v) < 2 || len(v) > variantsMaxCount {
+		return fmt.Errorf("from 2 to %d variants must be provided", variantsMaxCount)
+	}
+
+	for _, variant := range v {
+		if variant.Weight < 1 || variant.Weight > variantMaxWeight {
+			return fmt.Errorf("weight of variant must be from 1 to %d", variantMaxWeight)
+		}
+	}
+
+	return nil
+}
+
+// encodeVariants Returns JSON of variants, empty variants are empty string
+func encodeVariants(variants Variants) string {
+	if len(variants) == 0 {
+		return ""
+	}
+
+	// variants consist of strings and numbers only, so encoding never fails
+	encoded, _ := json.Marshal(variants)
+
+	return string(encoded)
+}
+
+// decodeVariants Returns variants of JSON, empty string and empty list are nil variants
+func decodeVariants(data string) (Variants, error) {
+	var variants Variants
+
+	if data == "" {
+		return nil, nil
+	}
+
+	if err := json.Unmarshal([]byte(data), &variants); err != nil {
+		return nil, err
+	}
+
+	if len(variants) == 0 {
+		return nil, nil
+	}
+
+	return variants, nil
+}
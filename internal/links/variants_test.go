@@ -0,0 +1,78 @@
+package links
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestVariantsChoose(t *testing.T) {
+	variants := Variants{
+		{URL: "https://example.com/a", Weight: 1},
+		{URL: "https://example.com/b", Weight: 3},
+		{URL: "https://example.com/c", Weight: 2},
+	}
+
+	tests := []struct {
+		name     string
+		variants Variants
+		n        uint64
+		expected int
+	}{
+		{"First", variants, 0, 1},
+		{"Start of second", variants, 1, 2},
+		{"End of second", variants, 3, 2},
+		{"Third", variants, 5, 3},
+		{"Wraps around total weight", variants, 6, 1},
+		{"Large number", variants, 1<<64 - 1, 2},
+		{"No variants", nil, 5, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, tt.variants.Choose(tt.n))
+		})
+	}
+}
+
+func TestVariantsURL(t *testing.T) {
+	variants := Variants{{URL: "https://example.com/a", Weight: 1}, {URL: "https://example.com/b", Weight: 1}}
+
+	URL, ok := variants.URL(2)
+
+	require.True(t, ok)
+	require.Equal(t, "https://example.com/b", URL)
+
+	_, ok = variants.URL(0)
+
+	require.False(t, ok)
+
+	_, ok = variants.URL(3)
+
+	require.False(t, ok)
+}
+
+func TestVariantsValidate(t *testing.T) {
+	tests := []struct {
+		name          string
+		variants      Variants
+		expectedError string
+	}{
+		{"Valid", Variants{{Weight: 1}, {Weight: 1000}}, ""},
+		{"Single variant", Variants{{Weight: 1}}, "from 2 to 10 variants must be provided"},
+		{"Too many variants", make(Variants, 11), "from 2 to 10 variants must be provided"},
+		{"Zero weight", Variants{{Weight: 1}, {Weight: 0}}, "weight of variant must be from 1 to 1000"},
+		{"Too large weight", Variants{{Weight: 1001}, {Weight: 1}}, "weight of variant must be from 1 to 1000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.variants.Validate()
+
+			if tt.expectedError == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tt.expectedError)
+			}
+		})
+	}
+}
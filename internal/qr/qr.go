@@ -0,0 +1,593 @@
+package qr
+
+import (
+	"errors"
+	"strings"
+)
+
+// Level Error correction level, part of codewords is spent to restore damaged symbol
+type Level int
+
+const (
+	LevelL Level = iota // ~7% of codewords can be restored
+	LevelM              // ~15%
+	LevelQ              // ~25%
+	LevelH              // ~30%
+)
+
+var ErrDataTooLong = errors.New("data is too long for QR code")
+
+const minVersion = 1
+const maxVersion = 40
+
+// formatBits Bits of level used in format information, they are not in order of levels
+var formatBits = [4]int{1, 0, 3, 2}
+
+// eccCodewordsPerBlock Indexed by level and version, version 0 is not used
+var eccCodewordsPerBlock = [4][41]int{
+	{-1, 7, 10, 15, 20, 26, 18, 20, 24, 30, 18, 20, 24, 26, 30, 22, 24, 28, 30, 28, 28, 28, 28, 30, 30, 26, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{-1, 10, 16, 26, 18, 24, 16, 18, 22, 22, 26, 30, 22, 22, 24, 24, 28, 28, 26, 26, 26, 26, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28},
+	{-1, 13, 22, 18, 26, 18, 24, 18, 22, 20, 24, 28, 26, 24, 20, 30, 24, 28, 28, 26, 30, 28, 30, 30, 30, 30, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{-1, 17, 28, 22, 16, 22, 28, 26, 26, 24, 28, 24, 28, 22, 24, 24, 30, 28, 28, 26, 28, 30, 24, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+}
+
+// eccBlocks Number of error correction blocks, indexed by level and version
+var eccBlocks = [4][41]int{
+	{-1, 1, 1, 1, 1, 1, 2, 2, 2, 2, 4, 4, 4, 4, 4, 6, 6, 6, 6, 7, 8, 8, 9, 9, 10, 12, 12, 12, 13, 14, 15, 16, 17, 18, 19, 19, 20, 21, 22, 24, 25},
+	{-1, 1, 1, 1, 2, 2, 4, 4, 4, 5, 5, 5, 8, 9, 9, 10, 10, 11, 13, 14, 16, 17, 17, 18, 20, 21, 23, 25, 26, 28, 29, 31, 33, 35, 37, 38, 40, 43, 45, 47, 49},
+	{-1, 1, 1, 2, 2, 4, 4, 6, 6, 8, 8, 8, 10, 12, 16, 12, 17, 16, 18, 21, 20, 23, 23, 25, 27, 29, 34, 34, 35, 38, 40, 43, 45, 48, 51, 53, 56, 59, 62, 65, 68},
+	{-1, 1, 1, 2, 4, 4, 4, 5, 6, 8, 8, 11, 11, 16, 16, 18, 16, 19, 21, 25, 25, 25, 34, 30, 32, 35, 37, 40, 42, 45, 48, 51, 54, 57, 60, 63, 66, 70, 74, 77, 81},
+}
+
+// ParseLevel Parses level letter, case-insensitive
+func ParseLevel(level string) (Level, error) {
+	switch strings.ToUpper(level) {
+	case "L":
+		return LevelL, nil
+	case "M":
+		return LevelM, nil
+	case "Q":
+		return LevelQ, nil
+	case "H":
+		return LevelH, nil
+	}
+
+	return 0, errors.New("unknown error correction level: " + level)
+}
+
+// Code Square matrix of modules, true is dark module
+type Code struct {
+	version    int
+	size       int
+	modules    [][]bool
+	isFunction [][]bool
+}
+
+func (c *Code) Version() int {
+	return c.version
+}
+
+// Size Number of modules on a side, quiet zone is not included
+func (c *Code) Size() int {
+	return c.size
+}
+
+// Dark Reports whether module in column x and row y is dark
+func (c *Code) Dark(x, y int) bool {
+	return c.modules[y][x]
+}
+
+// Encode Encodes data in byte mode using the smallest version which fits data with given level
+func Encode(data []byte, level Level) (*Code, error) {
+	version := minVersion
+
+	for ; version <= maxVersion; version++ {
+		if dataBitsLength(len(data), version) <= dataCodewords(version, level)*8 {
+			break
+		}
+	}
+
+	if version > maxVersion {
+		return nil, ErrDataTooLong
+	}
+
+	codewords := addErrorCorrection(encodeData(data, version, level), version, level)
+	c := newCode(version)
+
+	c.drawFunctionPatterns()
+	c.drawCodewords(codewords)
+	c.applyBestMask(level)
+
+	return c, nil
+}
+
+func charCountBits(version int) int {
+	if version <= 9 {
+		return 8
+	}
+
+	return 16
+}
+
+func dataBitsLength(length, version int) int {
+	return 4 + charCountBits(version) + length*8
+}
+
+// rawDataModules Number of modules left for codewords after function patterns are drawn
+func rawDataModules(version int) int {
+	result := (16*version+128)*version + 64
+
+	if version >= 2 {
+		alignments := version/7 + 2
+		result -= (25*alignments-10)*alignments - 55
+
+		if version >= 7 {
+			result -= 36
+		}
+	}
+
+	return result
+}
+
+func dataCodewords(version int, level Level) int {
+	return rawDataModules(version)/8 - eccCodewordsPerBlock[level][version]*eccBlocks[level][version]
+}
+
+type bitBuffer struct {
+	bits []bool
+}
+
+func (b *bitBuffer) append(value, length int) {
+	for i := length - 1; i >= 0; i-- {
+		b.bits = append(b.bits, (value>>i)&1 != 0)
+	}
+}
+
+func (b *bitBuffer) bytes() []byte {
+	result := make([]byte, len(b.bits)/8)
+
+	for i, bit := range b.bits {
+		if bit {
+			result[i/8] |= 1 << (7 - i%8)
+		}
+	}
+
+	return result
+}
+
+// encodeData Makes data codewords: mode, length, data, terminator and padding
+func encodeData(data []byte, version int, level Level) []byte {
+	capacity := dataCodewords(version, level) * 8
+	b := bitBuffer{}
+
+	b.append(0b0100, 4)
+	b.append(len(data), charCountBits(version))
+
+	for _, d := range data {
+		b.append(int(d), 8)
+	}
+
+	b.append(0, min(4, capacity-len(b.bits)))
+	b.append(0, (8-len(b.bits)%8)%8)
+
+	for pad := 0xEC; len(b.bits) < capacity; pad ^= 0xEC ^ 0x11 {
+		b.append(pad, 8)
+	}
+
+	return b.bytes()
+}
+
+// addErrorCorrection Splits data to blocks, appends error correction codewords to each block and interleaves blocks
+func addErrorCorrection(data []byte, version int, level Level) []byte {
+	blocksNumber := eccBlocks[level][version]
+	blockEccLength := eccCodewordsPerBlock[level][version]
+	rawCodewords := rawDataModules(version) / 8
+	shortBlocksNumber := blocksNumber - rawCodewords%blocksNumber
+	shortBlockLength := rawCodewords / blocksNumber
+	divisor := reedSolomonDivisor(blockEccLength)
+	blocks := make([][]byte, 0, blocksNumber)
+
+	for i, k := 0, 0; i < blocksNumber; i++ {
+		dataLength := shortBlockLength - blockEccLength
+
+		if i >= shortBlocksNumber {
+			dataLength++
+		}
+
+		block := append([]byte{}, data[k:k+dataLength]...)
+		k += dataLength
+		block = append(block, reedSolomonRemainder(block, divisor)...)
+		blocks = append(blocks, block)
+	}
+
+	result := make([]byte, 0, rawCodewords)
+
+	for i := 0; i <= shortBlockLength; i++ {
+		for j, block := range blocks {
+			// short blocks have no codeword at the last data position of long blocks
+			if j < shortBlocksNumber && i == shortBlockLength-blockEccLength {
+				continue
+			}
+
+			index := i
+
+			if j < shortBlocksNumber && i > shortBlockLength-blockEccLength {
+				index--
+			}
+
+			result = append(result, block[index])
+		}
+	}
+
+	return result
+}
+
+// gfMultiply Multiplies numbers in GF(2^8) with polynomial x^8 + x^4 + x^3 + x^2 + 1
+func gfMultiply(x, y byte) byte {
+	var z byte
+
+	for i := 7; i >= 0; i-- {
+		carry := z >> 7
+		z = z<<1 ^ carry*0x1D
+		z ^= (y >> i & 1) * x
+	}
+
+	return z
+}
+
+func reedSolomonDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	var root byte = 1
+
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = gfMultiply(result[j], root)
+
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+
+		root = gfMultiply(root, 0x02)
+	}
+
+	return result
+}
+
+func reedSolomonRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+
+		for i, d := range divisor {
+			result[i] ^= gfMultiply(d, factor)
+		}
+	}
+
+	return result
+}
+
+func newCode(version int) *Code {
+	size := version*4 + 17
+	c := Code{version: version, size: size}
+	c.modules = make([][]bool, size)
+	c.isFunction = make([][]bool, size)
+
+	for i := 0; i < size; i++ {
+		c.modules[i] = make([]bool, size)
+		c.isFunction[i] = make([]bool, size)
+	}
+
+	return &c
+}
+
+func (c *Code) setFunction(x, y int, dark bool) {
+	c.modules[y][x] = dark
+	c.isFunction[y][x] = true
+}
+
+func (c *Code) drawFunctionPatterns() {
+	for i := 0; i < c.size; i++ {
+		c.setFunction(6, i, i%2 == 0)
+		c.setFunction(i, 6, i%2 == 0)
+	}
+
+	c.drawFinderPattern(3, 3)
+	c.drawFinderPattern(c.size-4, 3)
+	c.drawFinderPattern(3, c.size-4)
+
+	positions := c.alignmentPositions()
+	last := len(positions) - 1
+
+	for i, x := range positions {
+		for j, y := range positions {
+			// alignment patterns do not overlap finder patterns
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+
+			c.drawAlignmentPattern(x, y)
+		}
+	}
+
+	// format and version are reserved here and drawn again after mask is chosen
+	c.drawFormat(0, 0)
+	c.drawVersion()
+}
+
+// drawFinderPattern Draws finder pattern with separator around it, centered at x, y
+func (c *Code) drawFinderPattern(x, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			distance := max(abs(dx), abs(dy))
+			xx, yy := x+dx, y+dy
+
+			if xx >= 0 && xx < c.size && yy >= 0 && yy < c.size {
+				c.setFunction(xx, yy, distance != 2 && distance != 4)
+			}
+		}
+	}
+}
+
+func (c *Code) drawAlignmentPattern(x, y int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			c.setFunction(x+dx, y+dy, max(abs(dx), abs(dy)) != 1)
+		}
+	}
+}
+
+func (c *Code) alignmentPositions() []int {
+	if c.version == 1 {
+		return []int{}
+	}
+
+	number := c.version/7 + 2
+	step := (c.version*8 + number*3 + 5) / (number*4 - 4) * 2
+	result := make([]int, number)
+	result[0] = 6
+
+	for i, position := number-1, c.size-7; i >= 1; i, position = i-1, position-step {
+		result[i] = position
+	}
+
+	return result
+}
+
+// formatInformation Makes 15 bits of level and mask protected by BCH code
+func formatInformation(level Level, mask int) int {
+	data := formatBits[level]<<3 | mask
+	remainder := data
+
+	for i := 0; i < 10; i++ {
+		remainder = remainder<<1 ^ (remainder>>9)*0x537
+	}
+
+	return (data<<10 | remainder) ^ 0x5412
+}
+
+// versionInformation Makes 18 bits of version protected by BCH code
+func versionInformation(version int) int {
+	remainder := version
+
+	for i := 0; i < 12; i++ {
+		remainder = remainder<<1 ^ (remainder>>11)*0x1F25
+	}
+
+	return version<<12 | remainder
+}
+
+func (c *Code) drawFormat(level Level, mask int) {
+	bits := formatInformation(level, mask)
+	bit := func(i int) bool {
+		return bits>>i&1 != 0
+	}
+
+	for i := 0; i <= 5; i++ {
+		c.setFunction(8, i, bit(i))
+	}
+
+	c.setFunction(8, 7, bit(6))
+	c.setFunction(8, 8, bit(7))
+	c.setFunction(7, 8, bit(8))
+
+	for i := 9; i < 15; i++ {
+		c.setFunction(14-i, 8, bit(i))
+	}
+
+	for i := 0; i < 8; i++ {
+		c.setFunction(c.size-1-i, 8, bit(i))
+	}
+
+	for i := 8; i < 15; i++ {
+		c.setFunction(8, c.size-15+i, bit(i))
+	}
+
+	c.setFunction(8, c.size-8, true)
+}
+
+func (c *Code) drawVersion() {
+	if c.version < 7 {
+		return
+	}
+
+	bits := versionInformation(c.version)
+
+	for i := 0; i < 18; i++ {
+		dark := bits>>i&1 != 0
+		a, b := c.size-11+i%3, i/3
+
+		c.setFunction(a, b, dark)
+		c.setFunction(b, a, dark)
+	}
+}
+
+// drawCodewords Places codewords in zigzag order from bottom right corner, skipping function modules
+func (c *Code) drawCodewords(codewords []byte) {
+	i := 0
+
+	for right := c.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+
+		for vertical := 0; vertical < c.size; vertical++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vertical
+
+				if (right+1)&2 == 0 {
+					y = c.size - 1 - vertical
+				}
+
+				if !c.isFunction[y][x] && i < len(codewords)*8 {
+					c.modules[y][x] = codewords[i>>3]>>(7-i&7)&1 != 0
+					i++
+				}
+			}
+		}
+	}
+}
+
+func masked(mask, x, y int) bool {
+	switch mask {
+	case 0:
+		return (x+y)%2 == 0
+	case 1:
+		return y%2 == 0
+	case 2:
+		return x%3 == 0
+	case 3:
+		return (x+y)%3 == 0
+	case 4:
+		return (x/3+y/2)%2 == 0
+	case 5:
+		return x*y%2+x*y%3 == 0
+	case 6:
+		return (x*y%2+x*y%3)%2 == 0
+	default:
+		return ((x+y)%2+x*y%3)%2 == 0
+	}
+}
+
+// applyMask Inverts data modules selected by mask, applying the same mask twice restores modules
+func (c *Code) applyMask(mask int) {
+	for y := 0; y < c.size; y++ {
+		for x := 0; x < c.size; x++ {
+			if !c.isFunction[y][x] && masked(mask, x, y) {
+				c.modules[y][x] = !c.modules[y][x]
+			}
+		}
+	}
+}
+
+func (c *Code) applyBestMask(level Level) {
+	best := 0
+	minPenalty := -1
+
+	for mask := 0; mask < 8; mask++ {
+		c.applyMask(mask)
+		c.drawFormat(level, mask)
+
+		if penalty := c.penalty(); minPenalty < 0 || penalty < minPenalty {
+			best = mask
+			minPenalty = penalty
+		}
+
+		c.applyMask(mask)
+	}
+
+	c.applyMask(best)
+	c.drawFormat(level, best)
+}
+
+var finderLikePatterns = [2][11]bool{
+	{true, false, true, true, true, false, true, false, false, false, false},
+	{false, false, false, false, true, false, true, true, true, false, true},
+}
+
+// penalty Scores symbol by rules of the standard, mask with the lowest score is used
+func (c *Code) penalty() int {
+	result := 0
+	dark := 0
+
+	for i := 0; i < c.size; i++ {
+		row := func(j int) bool { return c.modules[i][j] }
+		column := func(j int) bool { return c.modules[j][i] }
+
+		result += c.linePenalty(row) + c.linePenalty(column)
+	}
+
+	for y := 0; y < c.size; y++ {
+		for x := 0; x < c.size; x++ {
+			if c.modules[y][x] {
+				dark++
+			}
+
+			if x < c.size-1 && y < c.size-1 {
+				color := c.modules[y][x]
+
+				if color == c.modules[y][x+1] && color == c.modules[y+1][x] && color == c.modules[y+1][x+1] {
+					result += 3
+				}
+			}
+		}
+	}
+
+	total := c.size * c.size
+	result += abs(dark*100/total-50) / 5 * 10
+
+	return result
+}
+
+// linePenalty Scores runs of the same color and finder-like patterns in a row or a column
+func (c *Code) linePenalty(module func(i int) bool) int {
+	result := 0
+	run := 1
+
+	for i := 1; i <= c.size; i++ {
+		if i < c.size && module(i) == module(i-1) {
+			run++
+
+			continue
+		}
+
+		if run >= 5 {
+			result += run - 2
+		}
+
+		run = 1
+	}
+
+	for i := 0; i+11 <= c.size; i++ {
+		for _, pattern := range finderLikePatterns {
+			matches := true
+
+			for j, dark := range pattern {
+				if module(i+j) != dark {
+					matches = false
+
+					break
+				}
+			}
+
+			if matches {
+				result += 40
+			}
+		}
+	}
+
+	return result
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+
+	return x
+}
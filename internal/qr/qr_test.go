@@ -0,0 +1,135 @@
+package qr
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		name          string
+		level         string
+		expected      Level
+		expectedError string
+	}{
+		{"Low", "L", LevelL, ""},
+		{"Medium lowercase", "m", LevelM, ""},
+		{"Quartile", "Q", LevelQ, ""},
+		{"High", "H", LevelH, ""},
+		{"Unknown", "X", 0, "unknown error correction level: X"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			level, err := ParseLevel(tt.level)
+
+			if tt.expectedError == "" {
+				require.NoError(t, err)
+				require.Equal(t, tt.expected, level)
+			} else {
+				require.EqualError(t, err, tt.expectedError)
+			}
+		})
+	}
+}
+
+func TestReedSolomonRemainder(t *testing.T) {
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+
+	require.Equal(t, []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}, reedSolomonRemainder(data, reedSolomonDivisor(10)))
+}
+
+func TestFormatInformation(t *testing.T) {
+	require.Equal(t, 0b101010000010010, formatInformation(LevelM, 0))
+	require.Equal(t, 0b110011000101111, formatInformation(LevelL, 4))
+	require.Equal(t, 0b000100000111011, formatInformation(LevelH, 7))
+}
+
+func TestVersionInformation(t *testing.T) {
+	require.Equal(t, 0b000111110010010100, versionInformation(7))
+	require.Equal(t, 0b101000110001101001, versionInformation(40))
+}
+
+func TestEncodeVersion(t *testing.T) {
+	tests := []struct {
+		name            string
+		length          int
+		level           Level
+		expectedVersion int
+	}{
+		{"Empty", 0, LevelH, 1},
+		{"Full version 1", 17, LevelL, 1},
+		{"Over version 1", 18, LevelL, 2},
+		{"Full version 1 high", 7, LevelH, 1},
+		{"Over version 1 high", 8, LevelH, 2},
+		{"Full version 9", 230, LevelL, 9},
+		{"Longer length field", 231, LevelL, 10},
+		{"Full version 40", 2953, LevelL, 40},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := Encode(bytes.Repeat([]byte("a"), tt.length), tt.level)
+
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedVersion, code.Version())
+			require.Equal(t, tt.expectedVersion*4+17, code.Size())
+		})
+	}
+}
+
+func TestEncodeTooLong(t *testing.T) {
+	_, err := Encode(bytes.Repeat([]byte("a"), 2954), LevelL)
+
+	require.ErrorIs(t, err, ErrDataTooLong)
+}
+
+func TestEncodeFunctionPatterns(t *testing.T) {
+	code, err := Encode([]byte("http://localhost/go/1"), LevelM)
+
+	require.NoError(t, err)
+	require.Equal(t, 2, code.Version())
+
+	finder := []string{
+		"#######.",
+		"#.....#.",
+		"#.###.#.",
+		"#.###.#.",
+		"#.###.#.",
+		"#.....#.",
+		"#######.",
+		"........",
+	}
+
+	for y, row := range finder {
+		for x, module := range row {
+			dark := module == '#'
+
+			require.Equal(t, dark, code.Dark(x, y), "top left finder at %d,%d", x, y)
+			require.Equal(t, dark, code.Dark(code.Size()-1-x, y), "top right finder at %d,%d", x, y)
+			require.Equal(t, dark, code.Dark(x, code.Size()-1-y), "bottom left finder at %d,%d", x, y)
+		}
+	}
+
+	for i := 8; i < code.Size()-8; i++ {
+		require.Equal(t, i%2 == 0, code.Dark(i, 6), "horizontal timing at %d", i)
+		require.Equal(t, i%2 == 0, code.Dark(6, i), "vertical timing at %d", i)
+	}
+
+	require.True(t, code.Dark(8, code.Size()-8), "dark module")
+
+	alignment := []string{
+		"#####",
+		"#...#",
+		"#.#.#",
+		"#...#",
+		"#####",
+	}
+
+	for y, row := range alignment {
+		for x, module := range row {
+			require.Equal(t, module == '#', code.Dark(16+x, 16+y), "alignment at %d,%d", x, y)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package qr
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+)
+
+var ErrSizeTooSmall = errors.New("size is too small for QR code")
+
+// layout Computes module size in pixels and offset of the first module, so symbol with margin is centered in image
+func (c *Code) layout(size, margin int) (int, int, error) {
+	modules := c.size + margin*2
+	scale := size / modules
+
+	if scale < 1 {
+		return 0, 0, ErrSizeTooSmall
+	}
+
+	return scale, (size-scale*modules)/2 + margin*scale, nil
+}
+
+// PNG Renders code as grayscale PNG image of size x size pixels, margin is measured in modules
+func (c *Code) PNG(size, margin int) ([]byte, error) {
+	scale, offset, err := c.layout(size, margin)
+
+	if err != nil {
+		return nil, err
+	}
+
+	img := image.NewGray(image.Rect(0, 0, size, size))
+
+	for i := range img.Pix {
+		img.Pix[i] = 0xFF
+	}
+
+	for y := 0; y < c.size; y++ {
+		for x := 0; x < c.size; x++ {
+			if !c.modules[y][x] {
+				continue
+			}
+
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					img.SetGray(offset+x*scale+dx, offset+y*scale+dy, color.Gray{})
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+
+	if err = png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// SVG Renders code as SVG image of size x size pixels, margin is measured in modules
+func (c *Code) SVG(size, margin int) ([]byte, error) {
+	if size < c.size+margin*2 {
+		return nil, ErrSizeTooSmall
+	}
+
+	modules := c.size + margin*2
+	var path strings.Builder
+
+	for y := 0; y < c.size; y++ {
+		for x := 0; x < c.size; x++ {
+			if c.modules[y][x] {
+				_, _ = fmt.Fprintf(&path, "M%d,%dh1v1h-1z", x+margin, y+margin)
+			}
+		}
+	}
+
+	svg := fmt.Sprintf(
+		`<?xml version="1.0" encoding="UTF-8"?>`+"\n"+
+			`<svg xmlns="http://www.w3.org/2000/svg" version="1.1" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+
+			`<rect width="100%%" height="100%%" fill="#FFFFFF"/><path d="%s" fill="#000000"/></svg>`+"\n",
+		size, size, modules, modules, path.String(),
+	)
+
+	return []byte(svg), nil
+}
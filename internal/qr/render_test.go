@@ -0,0 +1,69 @@
+package qr
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestPNG(t *testing.T) {
+	code, err := Encode([]byte("http://localhost/go/1"), LevelM)
+
+	require.NoError(t, err)
+
+	// version 2 code with margin is 33 modules of 3 pixels, 1 spare pixel is left at the bottom right
+	data, err := code.PNG(100, 4)
+
+	require.NoError(t, err)
+
+	img, err := png.Decode(bytes.NewReader(data))
+
+	require.NoError(t, err)
+	require.Equal(t, image.Rect(0, 0, 100, 100), img.Bounds())
+
+	gray := func(x, y int) uint8 {
+		return color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y
+	}
+
+	require.Equal(t, uint8(0xFF), gray(0, 0))
+	require.Equal(t, uint8(0xFF), gray(11, 11))
+	require.Equal(t, uint8(0x00), gray(12, 12))
+	require.Equal(t, uint8(0x00), gray(14, 14))
+	require.Equal(t, uint8(0xFF), gray(15, 15))
+	require.Equal(t, uint8(0x00), gray(18, 18))
+	require.Equal(t, uint8(0xFF), gray(99, 99))
+}
+
+func TestPNGTooSmall(t *testing.T) {
+	code, err := Encode([]byte("http://localhost/go/1"), LevelM)
+
+	require.NoError(t, err)
+
+	_, err = code.PNG(32, 4)
+
+	require.ErrorIs(t, err, ErrSizeTooSmall)
+}
+
+func TestSVG(t *testing.T) {
+	code, err := Encode([]byte("http://localhost/go/1"), LevelM)
+
+	require.NoError(t, err)
+
+	data, err := code.SVG(100, 2)
+
+	require.NoError(t, err)
+
+	svg := string(data)
+
+	require.True(t, strings.HasPrefix(svg, `<?xml version="1.0" encoding="UTF-8"?>`))
+	require.Contains(t, svg, `width="100" height="100" viewBox="0 0 29 29"`)
+	require.Contains(t, svg, `<path d="M2,2h1v1h-1zM3,2h1v1h-1z`)
+
+	_, err = code.SVG(28, 2)
+
+	require.ErrorIs(t, err, ErrSizeTooSmall)
+}
@@ -0,0 +1,15 @@
+package utils
+
+import "math/rand"
+
+type RandomInterface interface {
+	Uint64() uint64
+}
+
+type Random struct {
+	//
+}
+
+func (r *Random) Uint64() uint64 {
+	return rand.Uint64()
+}
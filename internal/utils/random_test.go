@@ -0,0 +1,12 @@
+package utils
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestUint64(t *testing.T) {
+	r := Random{}
+
+	assert.NotEqual(t, r.Uint64(), r.Uint64())
+}
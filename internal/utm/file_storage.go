@@ -0,0 +1,176 @@
+package utm
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"sync"
+)
+
+const recordTemplate = "template"
+const recordDelete = "delete"
+
+// FileStorage Keeps templates in memory and appends changes to CSV file: "template,name,source,medium,campaign,content"
+// on store and "delete,name" on delete
+type FileStorage struct {
+	filename  string
+	templates map[string]Template
+	mu        sync.Mutex
+}
+
+func NewFileStorage(filename string) (*FileStorage, error) {
+	s := FileStorage{filename: filename, templates: map[string]Template{}}
+
+	if err := s.restore(); err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+func (fs *FileStorage) persist(record []string) error {
+	file, err := os.OpenFile(fs.filename, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	w := csv.NewWriter(file)
+	defer w.Flush()
+
+	return w.Write(record)
+}
+
+func (fs *FileStorage) StoreTemplate(template Template) error {
+	fs.mu.Lock()
+
+	defer fs.mu.Unlock()
+
+	err := fs.persist([]string{
+		recordTemplate,
+		template.Name,
+		template.Source,
+		template.Medium,
+		template.Campaign,
+		template.Content,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	fs.templates[template.Name] = template
+
+	return nil
+}
+
+func (fs *FileStorage) GetTemplate(name string) (Template, error) {
+	fs.mu.Lock()
+
+	defer fs.mu.Unlock()
+
+	template, ok := fs.templates[name]
+
+	if !ok {
+		return Template{}, ErrTemplateNotFound
+	}
+
+	return template, nil
+}
+
+func (fs *FileStorage) ListTemplates() ([]Template, error) {
+	fs.mu.Lock()
+
+	defer fs.mu.Unlock()
+
+	templates := make([]Template, 0, len(fs.templates))
+
+	for _, template := range fs.templates {
+		templates = append(templates, template)
+	}
+
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].Name < templates[j].Name
+	})
+
+	return templates, nil
+}
+
+func (fs *FileStorage) DeleteTemplate(name string) error {
+	fs.mu.Lock()
+
+	defer fs.mu.Unlock()
+
+	if _, ok := fs.templates[name]; !ok {
+		return ErrTemplateNotFound
+	}
+
+	if err := fs.persist([]string{recordDelete, name}); err != nil {
+		return err
+	}
+
+	delete(fs.templates, name)
+
+	return nil
+}
+
+func (fs *FileStorage) restore() error {
+	file, err := os.Open(fs.filename)
+
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		return err
+	}
+
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+
+	for {
+		record, err := reader.Read()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if err = fs.restoreRecord(record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (fs *FileStorage) restoreRecord(record []string) error {
+	if record[0] == recordDelete && len(record) == 2 {
+		delete(fs.templates, record[1])
+
+		return nil
+	}
+
+	if record[0] != recordTemplate || len(record) != 6 {
+		return errors.New("file has malformed data")
+	}
+
+	fs.templates[record[1]] = Template{
+		Name:     record[1],
+		Source:   record[2],
+		Medium:   record[3],
+		Campaign: record[4],
+		Content:  record[5],
+	}
+
+	return nil
+}
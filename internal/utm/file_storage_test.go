@@ -0,0 +1,64 @@
+package utm
+
+import (
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+)
+
+func TestFileStorage(t *testing.T) {
+	filename := t.TempDir() + "/utm_templates.csv"
+	s, err := NewFileStorage(filename)
+
+	require.NoError(t, err)
+
+	spring := Template{Name: "spring", Source: "newsletter", Medium: "email", Campaign: "spring, sale"}
+	autumn := Template{Name: "autumn", Source: "ads", Content: "banner"}
+
+	require.NoError(t, s.StoreTemplate(spring))
+	require.NoError(t, s.StoreTemplate(autumn))
+
+	spring.Campaign = "spring"
+
+	require.NoError(t, s.StoreTemplate(spring))
+
+	found, err := s.GetTemplate("spring")
+
+	require.NoError(t, err)
+	require.Equal(t, spring, found)
+
+	_, err = s.GetTemplate("summer")
+
+	require.ErrorIs(t, err, ErrTemplateNotFound)
+
+	templates, err := s.ListTemplates()
+
+	require.NoError(t, err)
+	require.Equal(t, []Template{autumn, spring}, templates)
+
+	require.NoError(t, s.DeleteTemplate("autumn"))
+	require.ErrorIs(t, s.DeleteTemplate("autumn"), ErrTemplateNotFound)
+
+	data, err := os.ReadFile(filename)
+
+	require.NoError(t, err)
+	require.Equal(t, "template,spring,newsletter,email,\"spring, sale\",\n"+
+		"template,autumn,ads,,,banner\n"+
+		"template,spring,newsletter,email,spring,\n"+
+		"delete,autumn\n", string(data))
+
+	restored, err := NewFileStorage(filename)
+
+	require.NoError(t, err)
+	require.Equal(t, s.templates, restored.templates)
+}
+
+func TestFileStorageMalformed(t *testing.T) {
+	filename := t.TempDir() + "/utm_templates.csv"
+
+	require.NoError(t, os.WriteFile(filename, []byte("template,spring,newsletter\n"), 0600))
+
+	_, err := NewFileStorage(filename)
+
+	require.EqualError(t, err, "file has malformed data")
+}
@@ -0,0 +1,120 @@
+package utm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const templateColumns = "name, source, medium, campaign, content"
+
+type SQLStorage struct {
+	db      *sql.DB
+	timeout time.Duration
+}
+
+func NewSQLStorage(db *sql.DB, timeout int) *SQLStorage {
+	return &SQLStorage{
+		db:      db,
+		timeout: time.Second * time.Duration(timeout),
+	}
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func (s *SQLStorage) scanTemplate(row rowScanner) (Template, error) {
+	var template Template
+
+	err := row.Scan(&template.Name, &template.Source, &template.Medium, &template.Campaign, &template.Content)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return Template{}, ErrTemplateNotFound
+	}
+
+	return template, err
+}
+
+func (s *SQLStorage) StoreTemplate(template Template) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+
+	defer cancel()
+
+	query := "INSERT INTO utm_templates(" + templateColumns + ") VALUES ($1, $2, $3, $4, $5) " +
+		"ON CONFLICT (name) DO UPDATE SET source = $2, medium = $3, campaign = $4, content = $5"
+	_, err := s.db.ExecContext(
+		ctx,
+		query,
+		template.Name,
+		template.Source,
+		template.Medium,
+		template.Campaign,
+		template.Content,
+	)
+
+	return err
+}
+
+func (s *SQLStorage) GetTemplate(name string) (Template, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+
+	defer cancel()
+
+	query := "SELECT " + templateColumns + " FROM utm_templates WHERE name = $1"
+
+	return s.scanTemplate(s.db.QueryRowContext(ctx, query, name))
+}
+
+func (s *SQLStorage) ListTemplates() ([]Template, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, "SELECT "+templateColumns+" FROM utm_templates ORDER BY name")
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	templates := []Template{}
+
+	for rows.Next() {
+		template, err := s.scanTemplate(rows)
+
+		if err != nil {
+			return nil, err
+		}
+
+		templates = append(templates, template)
+	}
+
+	return templates, rows.Err()
+}
+
+func (s *SQLStorage) DeleteTemplate(name string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, "DELETE FROM utm_templates WHERE name = $1", name)
+
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrTemplateNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package utm
+
+import (
+	"database/sql"
+	"github.com/dzhdmitry/link-shorter/internal/db"
+	"github.com/dzhdmitry/link-shorter/test"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type SQLStorageSuite struct {
+	suite.Suite
+	db *sql.DB
+}
+
+func (s *SQLStorageSuite) SetupSuite() {
+	openDB, err := db.OpenPostgres(test.PrepareTestDB(), 25, 25, "15m")
+
+	if err != nil {
+		panic(err)
+	}
+
+	s.db = openDB
+}
+
+func (s *SQLStorageSuite) SetupTest() {
+	_, _ = s.db.Exec("TRUNCATE utm_templates")
+}
+
+func (s *SQLStorageSuite) TearDownSuite() {
+	if s.db != nil {
+		s.db.Close()
+	}
+}
+
+func (s *SQLStorageSuite) TestStoreTemplate() {
+	storage := NewSQLStorage(s.db, 1)
+	spring := Template{Name: "spring", Source: "newsletter", Medium: "email", Campaign: "spring sale"}
+	autumn := Template{Name: "autumn", Source: "ads", Content: "banner"}
+
+	s.NoError(storage.StoreTemplate(spring))
+	s.NoError(storage.StoreTemplate(autumn))
+
+	spring.Campaign = "spring"
+
+	s.NoError(storage.StoreTemplate(spring))
+
+	found, err := storage.GetTemplate("spring")
+
+	s.NoError(err)
+	s.Equal(spring, found)
+
+	_, err = storage.GetTemplate("summer")
+
+	s.ErrorIs(err, ErrTemplateNotFound)
+
+	templates, err := storage.ListTemplates()
+
+	s.NoError(err)
+	s.Equal([]Template{autumn, spring}, templates)
+}
+
+func (s *SQLStorageSuite) TestDeleteTemplate() {
+	storage := NewSQLStorage(s.db, 1)
+
+	s.NoError(storage.StoreTemplate(Template{Name: "spring", Source: "newsletter"}))
+	s.NoError(storage.DeleteTemplate("spring"))
+	s.ErrorIs(storage.DeleteTemplate("spring"), ErrTemplateNotFound)
+
+	templates, err := storage.ListTemplates()
+
+	s.NoError(err)
+	s.Equal([]Template{}, templates)
+}
+
+func TestSQLStorage(t *testing.T) {
+	suite.Run(t, new(SQLStorageSuite))
+}
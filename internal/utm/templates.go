@@ -0,0 +1,73 @@
+package utm
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+)
+
+var ErrTemplateNotFound = errors.New("UTM template not found")
+
+// Template Named set of UTM parameters appended to destination of links on redirect
+type Template struct {
+	Name     string
+	Source   string
+	Medium   string
+	Campaign string
+	Content  string
+}
+
+// Params Returns names and values of parameters in order of appending, empty values are included
+func (t Template) Params() [][2]string {
+	return [][2]string{
+		{"utm_source", t.Source},
+		{"utm_medium", t.Medium},
+		{"utm_campaign", t.Campaign},
+		{"utm_content", t.Content},
+	}
+}
+
+// Apply Appends parameters of template to URL. Parameters which URL already has, even empty ones, are kept as is,
+// order and encoding of the existing query are not changed
+func (t Template) Apply(URL string) string {
+	u, err := url.Parse(URL)
+
+	if err != nil {
+		return URL
+	}
+
+	query := u.Query()
+	var added []string
+
+	for _, param := range t.Params() {
+		if param[1] == "" || query.Has(param[0]) {
+			continue
+		}
+
+		added = append(added, param[0]+"="+url.QueryEscape(param[1]))
+	}
+
+	if len(added) == 0 {
+		return URL
+	}
+
+	if u.RawQuery != "" {
+		u.RawQuery += "&"
+	}
+
+	u.RawQuery += strings.Join(added, "&")
+	u.ForceQuery = false
+
+	return u.String()
+}
+
+type StorageInterface interface {
+	// StoreTemplate Creates template or replaces template with the same name
+	StoreTemplate(template Template) error
+	// GetTemplate Returns ErrTemplateNotFound if there is no template by name
+	GetTemplate(name string) (Template, error)
+	// ListTemplates Returns all templates sorted by name
+	ListTemplates() ([]Template, error)
+	// DeleteTemplate Returns ErrTemplateNotFound if there is no template by name
+	DeleteTemplate(name string) error
+}
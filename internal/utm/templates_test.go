@@ -0,0 +1,36 @@
+package utm
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	template := Template{Name: "spring", Source: "newsletter", Medium: "email", Campaign: "spring sale"}
+
+	tests := []struct {
+		name     string
+		template Template
+		URL      string
+		expected string
+	}{
+		{"Without query", template, "https://example.com/path",
+			"https://example.com/path?utm_source=newsletter&utm_medium=email&utm_campaign=spring+sale"},
+		{"Existing query is kept", template, "https://example.com/?b=2&a=1%2C2",
+			"https://example.com/?b=2&a=1%2C2&utm_source=newsletter&utm_medium=email&utm_campaign=spring+sale"},
+		{"Existing parameters are not overwritten", template, "https://example.com/?utm_source=ads&utm_medium=",
+			"https://example.com/?utm_source=ads&utm_medium=&utm_campaign=spring+sale"},
+		{"Fragment", template, "https://example.com/#top",
+			"https://example.com/?utm_source=newsletter&utm_medium=email&utm_campaign=spring+sale#top"},
+		{"Empty query", Template{Content: "banner"}, "https://example.com/?", "https://example.com/?utm_content=banner"},
+		{"Empty template", Template{Name: "empty"}, "https://example.com/?a=1", "https://example.com/?a=1"},
+		{"All parameters are set", template, "https://example.com/?utm_source=a&utm_medium=b&utm_campaign=c",
+			"https://example.com/?utm_source=a&utm_medium=b&utm_campaign=c"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, tt.template.Apply(tt.URL))
+		})
+	}
+}
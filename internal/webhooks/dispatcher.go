@@ -0,0 +1,320 @@
+package webhooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dzhdmitry/link-shorter/internal/utils"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+var ErrEventsBufferFull = errors.New("webhook events buffer is full, event is dropped")
+
+// pollInterval Interval of checking queue for deliveries which are due to retry
+const pollInterval = 5 * time.Second
+
+// deliveriesBatchSize Number of deliveries taken from queue at once
+const deliveriesBatchSize = 100
+
+// maxRetryDelay Limit of delay between attempts, which is doubled after each failure
+const maxRetryDelay = 6 * time.Hour
+
+// event Published event which is not queued yet
+type event struct {
+	name    string
+	payload []byte
+}
+
+// Dispatcher Buffers published events and sends them to subscribed endpoints in background. Deliveries are kept
+// in persistent queue until endpoint responds with 2xx status, failed ones are retried with exponential backoff
+type Dispatcher struct {
+	storage     StorageInterface
+	client      *http.Client
+	clock       utils.ClockInterface
+	logger      *utils.Logger
+	events      chan event
+	maxAttempts int
+	retryDelay  time.Duration
+	closed      bool
+	mu          sync.RWMutex
+}
+
+func NewDispatcher(
+	storage StorageInterface,
+	client *http.Client,
+	clock utils.ClockInterface,
+	logger *utils.Logger,
+	background *utils.Background,
+	bufferSize int,
+	maxAttempts int,
+	retryDelay time.Duration,
+) *Dispatcher {
+	d := Dispatcher{
+		storage:     storage,
+		client:      client,
+		clock:       clock,
+		logger:      logger,
+		events:      make(chan event, bufferSize),
+		maxAttempts: maxAttempts,
+		retryDelay:  retryDelay,
+	}
+
+	background.Run(d.run)
+
+	return &d
+}
+
+// Publish Puts event with data to buffer without blocking, event is dropped if buffer is full or dispatcher is closed
+func (d *Dispatcher) Publish(name string, data any) {
+	id, err := randomID(8)
+
+	if err != nil {
+		d.logger.LogError(err)
+
+		return
+	}
+
+	payload, err := json.Marshal(map[string]any{
+		"id":         id,
+		"event":      name,
+		"created_at": d.clock.Now().UTC(),
+		"data":       data,
+	})
+
+	if err != nil {
+		d.logger.LogError(err)
+
+		return
+	}
+
+	d.mu.RLock()
+
+	defer d.mu.RUnlock()
+
+	if d.closed {
+		return
+	}
+
+	select {
+	case d.events <- event{name: name, payload: payload}:
+	default:
+		d.logger.LogError(ErrEventsBufferFull)
+	}
+}
+
+// Close Stops accepting events, buffered events are queued and sent in background.
+// Deliveries which fail then stay in queue and are retried after restart
+func (d *Dispatcher) Close() {
+	d.mu.Lock()
+
+	defer d.mu.Unlock()
+
+	if d.closed {
+		return
+	}
+
+	d.closed = true
+	close(d.events)
+}
+
+func (d *Dispatcher) run() {
+	ticker := time.NewTicker(pollInterval)
+
+	defer ticker.Stop()
+
+	for {
+		select {
+		case e, ok := <-d.events:
+			if !ok {
+				d.deliver()
+
+				return
+			}
+
+			if err := d.enqueue(e); err != nil {
+				d.logger.LogError(err)
+			}
+
+			// the rest of buffered events is queued before sending, so they are not delayed by one another
+			if len(d.events) == 0 {
+				d.deliver()
+			}
+		case <-ticker.C:
+			d.deliver()
+		}
+	}
+}
+
+// enqueue Makes delivery of event for each subscription which matches it
+func (d *Dispatcher) enqueue(e event) error {
+	subscriptions, err := d.storage.ListSubscriptions()
+
+	if err != nil {
+		return err
+	}
+
+	var deliveries []Delivery
+	now := d.clock.Now().UTC()
+
+	for _, subscription := range subscriptions {
+		if !subscription.Matches(e.name) {
+			continue
+		}
+
+		id, err := randomID(8)
+
+		if err != nil {
+			return err
+		}
+
+		deliveries = append(deliveries, Delivery{
+			ID:             id,
+			SubscriptionID: subscription.ID,
+			Event:          e.name,
+			Payload:        string(e.payload),
+			NextAttemptAt:  now,
+		})
+	}
+
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	return d.storage.EnqueueDeliveries(deliveries)
+}
+
+// deliver Sends deliveries which are due, until there are no more of them
+func (d *Dispatcher) deliver() {
+	for {
+		due, err := d.storage.DueDeliveries(d.clock.Now(), deliveriesBatchSize)
+
+		if err != nil {
+			d.logger.LogError(err)
+
+			return
+		}
+
+		if len(due) == 0 {
+			return
+		}
+
+		subscriptions, err := d.subscriptions()
+
+		if err != nil {
+			d.logger.LogError(err)
+
+			return
+		}
+
+		for _, delivery := range due {
+			if err = d.attempt(delivery, subscriptions); err != nil {
+				d.logger.LogError(err)
+
+				return
+			}
+		}
+
+		if len(due) < deliveriesBatchSize {
+			return
+		}
+	}
+}
+
+// subscriptions Returns map with key=ID, value=subscription
+func (d *Dispatcher) subscriptions() (map[string]Subscription, error) {
+	list, err := d.storage.ListSubscriptions()
+
+	if err != nil {
+		return nil, err
+	}
+
+	subscriptions := make(map[string]Subscription, len(list))
+
+	for _, subscription := range list {
+		subscriptions[subscription.ID] = subscription
+	}
+
+	return subscriptions, nil
+}
+
+// attempt Sends delivery once, it is removed from queue if it succeeds, subscription is deleted or attempts are exhausted
+func (d *Dispatcher) attempt(delivery Delivery, subscriptions map[string]Subscription) error {
+	subscription, ok := subscriptions[delivery.SubscriptionID]
+
+	if !ok {
+		return d.storage.RemoveDelivery(delivery.ID)
+	}
+
+	err := d.send(subscription, delivery)
+
+	if err == nil {
+		return d.storage.RemoveDelivery(delivery.ID)
+	}
+
+	delivery.Attempts++
+	delivery.LastError = err.Error()
+
+	if delivery.Attempts >= d.maxAttempts {
+		d.logger.LogError(fmt.Errorf("webhook delivery %s to %s is given up after %d attempts: %w", delivery.ID, subscription.URL, delivery.Attempts, err))
+
+		return d.storage.RemoveDelivery(delivery.ID)
+	}
+
+	delivery.NextAttemptAt = d.clock.Now().UTC().Add(d.backoff(delivery.Attempts))
+
+	return d.storage.RetryDelivery(delivery)
+}
+
+// backoff Returns delay after failed attempts: retry delay doubled after each failure except the first one
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.retryDelay
+
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, maxRetryDelay)
+}
+
+func (d *Dispatcher) send(subscription Subscription, delivery Delivery) error {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequest(http.MethodPost, subscription.URL, bytes.NewReader(body))
+
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, delivery.Event)
+	req.Header.Set(DeliveryHeader, delivery.ID)
+	req.Header.Set(SignatureHeader, Sign(subscription.Secret, body))
+
+	response, err := d.client.Do(req)
+
+	if err != nil {
+		var urlErr *url.Error
+
+		// error is reported without method and URL, which are known
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+
+		return err
+	}
+
+	defer response.Body.Close()
+
+	// body is not needed, a little of it is read to let connection be reused
+	_, _ = io.CopyN(io.Discard, response.Body, 4096)
+
+	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("endpoint responded with status %d", response.StatusCode)
+	}
+
+	return nil
+}
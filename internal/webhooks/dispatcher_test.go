@@ -0,0 +1,269 @@
+package webhooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/dzhdmitry/link-shorter/internal/utils"
+	"github.com/dzhdmitry/link-shorter/test"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testClock Returns time which is moved by test
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+// testEndpoint Records requests and responds with the next status of list, the last one is repeated
+type testEndpoint struct {
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+	mu       sync.Mutex
+}
+
+func (e *testEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mu.Lock()
+
+	defer e.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	e.requests = append(e.requests, r)
+	e.bodies = append(e.bodies, body)
+	status := e.statuses[min(len(e.requests), len(e.statuses))-1]
+
+	w.WriteHeader(status)
+}
+
+func newTestStorage(t *testing.T, subscriptions ...Subscription) *FileStorage {
+	s, err := NewFileStorage(t.TempDir() + "/webhooks.csv")
+
+	require.NoError(t, err)
+
+	for _, subscription := range subscriptions {
+		require.NoError(t, s.StoreSubscription(subscription))
+	}
+
+	return s
+}
+
+func TestDispatcherPublish(t *testing.T) {
+	endpoint := &testEndpoint{statuses: []int{http.StatusNoContent}}
+	server := httptest.NewServer(endpoint)
+
+	defer server.Close()
+
+	storage := newTestStorage(t,
+		Subscription{ID: "a1", URL: server.URL + "/created", Secret: "secret", Events: []string{EventLinkCreated}},
+		Subscription{ID: "b2", URL: server.URL + "/clicked", Secret: "other", Events: []string{EventLinkClicked}},
+	)
+	background := &utils.Background{}
+	d := NewDispatcher(storage, server.Client(), &test.Clock{}, utils.NewLogger(io.Discard, &test.Clock{}), background, 10, 3, time.Minute)
+
+	d.Publish(EventLinkCreated, map[string]string{"key": "1"})
+	d.Publish(EventLinkDeleted, map[string]string{"key": "1"})
+	d.Close()
+	background.Wait()
+
+	// closed dispatcher drops events
+	d.Publish(EventLinkCreated, map[string]string{"key": "2"})
+
+	require.Len(t, endpoint.requests, 1)
+
+	r := endpoint.requests[0]
+
+	require.Equal(t, http.MethodPost, r.Method)
+	require.Equal(t, "/created", r.URL.Path)
+	require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+	require.Equal(t, EventLinkCreated, r.Header.Get(EventHeader))
+	require.Len(t, r.Header.Get(DeliveryHeader), 16)
+	require.Equal(t, Sign("secret", endpoint.bodies[0]), r.Header.Get(SignatureHeader))
+
+	var payload struct {
+		ID        string
+		Event     string
+		CreatedAt time.Time `json:"created_at"`
+		Data      map[string]string
+	}
+
+	require.NoError(t, json.Unmarshal(endpoint.bodies[0], &payload))
+	require.Len(t, payload.ID, 16)
+	require.Equal(t, EventLinkCreated, payload.Event)
+	require.True(t, payload.CreatedAt.Equal((&test.Clock{}).Now()))
+	require.Equal(t, map[string]string{"key": "1"}, payload.Data)
+	require.Empty(t, storage.deliveries)
+}
+
+func TestDispatcherBufferFull(t *testing.T) {
+	storage := newTestStorage(t)
+	var log bytes.Buffer
+	background := &utils.Background{}
+	d := NewDispatcher(storage, http.DefaultClient, &test.Clock{}, utils.NewLogger(&log, &test.Clock{}), background, 1, 3, time.Minute)
+
+	// buffer is filled while dispatcher is blocked on storage
+	storage.mu.Lock()
+
+	for i := 0; i < 3; i++ {
+		d.Publish(EventLinkCreated, nil)
+	}
+
+	storage.mu.Unlock()
+	d.Close()
+	background.Wait()
+
+	require.Contains(t, log.String(), ErrEventsBufferFull.Error())
+}
+
+func TestDispatcherRetry(t *testing.T) {
+	endpoint := &testEndpoint{statuses: []int{http.StatusInternalServerError, http.StatusServiceUnavailable, http.StatusOK}}
+	server := httptest.NewServer(endpoint)
+
+	defer server.Close()
+
+	start := time.Date(2024, 2, 7, 12, 0, 0, 0, time.UTC)
+	clock := &testClock{now: start}
+	storage := newTestStorage(t, Subscription{ID: "a1", URL: server.URL, Secret: "secret", Events: Events})
+	d := &Dispatcher{
+		storage:     storage,
+		client:      server.Client(),
+		clock:       clock,
+		logger:      utils.NewLogger(io.Discard, clock),
+		maxAttempts: 5,
+		retryDelay:  time.Minute,
+	}
+
+	require.NoError(t, d.enqueue(event{name: EventLinkCreated, payload: []byte(`{"key":"1"}`)}))
+
+	d.deliver()
+
+	due, _ := storage.DueDeliveries(start.Add(time.Hour), 10)
+
+	require.Len(t, due, 1)
+	require.Equal(t, 1, due[0].Attempts)
+	require.Equal(t, start.Add(time.Minute), due[0].NextAttemptAt)
+	require.Equal(t, "endpoint responded with status 500", due[0].LastError)
+
+	// delivery is not due yet
+	clock.now = start.Add(30 * time.Second)
+	d.deliver()
+
+	require.Len(t, endpoint.requests, 1)
+
+	clock.now = start.Add(time.Minute)
+	d.deliver()
+
+	due, _ = storage.DueDeliveries(start.Add(time.Hour), 10)
+
+	require.Len(t, due, 1)
+	require.Equal(t, 2, due[0].Attempts)
+	require.Equal(t, start.Add(3*time.Minute), due[0].NextAttemptAt)
+	require.Equal(t, "endpoint responded with status 503", due[0].LastError)
+
+	clock.now = start.Add(3 * time.Minute)
+	d.deliver()
+
+	require.Len(t, endpoint.requests, 3)
+	require.Equal(t, endpoint.bodies[0], endpoint.bodies[2])
+	require.Equal(t, endpoint.requests[0].Header.Get(DeliveryHeader), endpoint.requests[2].Header.Get(DeliveryHeader))
+	require.Empty(t, storage.deliveries)
+}
+
+func TestDispatcherGiveUp(t *testing.T) {
+	endpoint := &testEndpoint{statuses: []int{http.StatusInternalServerError}}
+	server := httptest.NewServer(endpoint)
+
+	defer server.Close()
+
+	clock := &testClock{now: time.Date(2024, 2, 7, 12, 0, 0, 0, time.UTC)}
+	storage := newTestStorage(t, Subscription{ID: "a1", URL: server.URL, Secret: "secret", Events: Events})
+	var log bytes.Buffer
+	d := &Dispatcher{
+		storage:     storage,
+		client:      server.Client(),
+		clock:       clock,
+		logger:      utils.NewLogger(&log, clock),
+		maxAttempts: 2,
+		retryDelay:  time.Minute,
+	}
+
+	require.NoError(t, d.enqueue(event{name: EventLinkCreated, payload: []byte(`{}`)}))
+
+	d.deliver()
+	clock.now = clock.now.Add(time.Minute)
+	d.deliver()
+
+	require.Len(t, endpoint.requests, 2)
+	require.Empty(t, storage.deliveries)
+	require.Contains(t, log.String(), "is given up after 2 attempts: endpoint responded with status 500")
+
+	// delivery of deleted subscription is dropped without sending
+	require.NoError(t, d.enqueue(event{name: EventLinkCreated, payload: []byte(`{}`)}))
+
+	_, err := storage.DeleteSubscription("a1")
+
+	require.NoError(t, err)
+	require.NoError(t, storage.EnqueueDeliveries([]Delivery{{ID: "d1", SubscriptionID: "a1", NextAttemptAt: clock.now}}))
+
+	d.deliver()
+
+	require.Len(t, endpoint.requests, 2)
+	require.Empty(t, storage.deliveries)
+}
+
+func TestDispatcherQueueSurvivesRestart(t *testing.T) {
+	endpoint := &testEndpoint{statuses: []int{http.StatusBadGateway, http.StatusOK}}
+	server := httptest.NewServer(endpoint)
+
+	defer server.Close()
+
+	filename := t.TempDir() + "/webhooks.csv"
+	storage, err := NewFileStorage(filename)
+
+	require.NoError(t, err)
+	require.NoError(t, storage.StoreSubscription(Subscription{ID: "a1", URL: server.URL, Secret: "secret", Events: Events}))
+
+	background := &utils.Background{}
+	d := NewDispatcher(storage, server.Client(), &test.Clock{}, utils.NewLogger(io.Discard, &test.Clock{}), background, 10, 3, time.Minute)
+
+	d.Publish(EventLinkDeleted, map[string]string{"key": "1"})
+	d.Close()
+	background.Wait()
+
+	require.Len(t, endpoint.requests, 1)
+
+	// failed delivery is restored and sent after restart
+	restored, err := NewFileStorage(filename)
+
+	require.NoError(t, err)
+	require.Len(t, restored.deliveries, 1)
+
+	clock := &testClock{now: (&test.Clock{}).Now().Add(time.Minute)}
+	background = &utils.Background{}
+	d = NewDispatcher(restored, server.Client(), clock, utils.NewLogger(io.Discard, clock), background, 10, 3, time.Minute)
+
+	d.Close()
+	background.Wait()
+
+	require.Len(t, endpoint.requests, 2)
+	require.Equal(t, endpoint.bodies[0], endpoint.bodies[1])
+	require.Empty(t, restored.deliveries)
+}
+
+func TestDispatcherBackoff(t *testing.T) {
+	d := &Dispatcher{retryDelay: 30 * time.Second}
+
+	require.Equal(t, 30*time.Second, d.backoff(1))
+	require.Equal(t, time.Minute, d.backoff(2))
+	require.Equal(t, 4*time.Minute, d.backoff(4))
+	require.Equal(t, maxRetryDelay, d.backoff(20))
+	require.Equal(t, maxRetryDelay, d.backoff(1000))
+}
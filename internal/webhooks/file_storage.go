@@ -0,0 +1,351 @@
+package webhooks
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const recordSubscription = "subscription"
+const recordUnsubscribe = "unsubscribe"
+const recordDelivery = "delivery"
+const recordRetry = "retry"
+const recordDelivered = "delivered"
+
+var errMalformed = errors.New("file has malformed data")
+
+// FileStorage Keeps subscriptions and queue of deliveries in memory and appends changes to CSV file:
+// "subscription,id,URL,secret,events,createdAt" on subscribe, "unsubscribe,id" on delete,
+// "delivery,id,subscriptionID,event,payload,attempts,nextAttemptAt,error" on enqueue,
+// "retry,id,attempts,nextAttemptAt,error" on failed attempt and "delivered,id" on removal from queue.
+// Events are separated by space
+type FileStorage struct {
+	filename      string
+	subscriptions map[string]Subscription
+	deliveries    map[string]Delivery
+	mu            sync.Mutex
+}
+
+func NewFileStorage(filename string) (*FileStorage, error) {
+	s := FileStorage{filename: filename, subscriptions: map[string]Subscription{}, deliveries: map[string]Delivery{}}
+
+	if err := s.restore(); err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+func (fs *FileStorage) persist(records ...[]string) error {
+	file, err := os.OpenFile(fs.filename, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	w := csv.NewWriter(file)
+	defer w.Flush()
+
+	return w.WriteAll(records)
+}
+
+func (fs *FileStorage) StoreSubscription(subscription Subscription) error {
+	fs.mu.Lock()
+
+	defer fs.mu.Unlock()
+
+	err := fs.persist([]string{
+		recordSubscription,
+		subscription.ID,
+		subscription.URL,
+		subscription.Secret,
+		strings.Join(subscription.Events, " "),
+		subscription.CreatedAt.UTC().Format(time.RFC3339),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	fs.subscriptions[subscription.ID] = subscription
+
+	return nil
+}
+
+// ListSubscriptions Returns subscriptions, the oldest ones first
+func (fs *FileStorage) ListSubscriptions() ([]Subscription, error) {
+	fs.mu.Lock()
+
+	defer fs.mu.Unlock()
+
+	subscriptions := make([]Subscription, 0, len(fs.subscriptions))
+
+	for _, subscription := range fs.subscriptions {
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	slices.SortFunc(subscriptions, func(a, b Subscription) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	return subscriptions, nil
+}
+
+func (fs *FileStorage) DeleteSubscription(id string) (Subscription, error) {
+	fs.mu.Lock()
+
+	defer fs.mu.Unlock()
+
+	subscription, ok := fs.subscriptions[id]
+
+	if !ok {
+		return Subscription{}, ErrSubscriptionNotFound
+	}
+
+	if err := fs.persist([]string{recordUnsubscribe, id}); err != nil {
+		return Subscription{}, err
+	}
+
+	fs.unsubscribe(id)
+
+	return subscription, nil
+}
+
+func (fs *FileStorage) unsubscribe(id string) {
+	delete(fs.subscriptions, id)
+
+	for deliveryID, delivery := range fs.deliveries {
+		if delivery.SubscriptionID == id {
+			delete(fs.deliveries, deliveryID)
+		}
+	}
+}
+
+func (fs *FileStorage) EnqueueDeliveries(deliveries []Delivery) error {
+	fs.mu.Lock()
+
+	defer fs.mu.Unlock()
+
+	records := make([][]string, 0, len(deliveries))
+
+	for _, delivery := range deliveries {
+		records = append(records, []string{
+			recordDelivery,
+			delivery.ID,
+			delivery.SubscriptionID,
+			delivery.Event,
+			delivery.Payload,
+			strconv.Itoa(delivery.Attempts),
+			delivery.NextAttemptAt.UTC().Format(time.RFC3339Nano),
+			delivery.LastError,
+		})
+	}
+
+	if err := fs.persist(records...); err != nil {
+		return err
+	}
+
+	for _, delivery := range deliveries {
+		fs.deliveries[delivery.ID] = delivery
+	}
+
+	return nil
+}
+
+func (fs *FileStorage) DueDeliveries(now time.Time, limit int) ([]Delivery, error) {
+	fs.mu.Lock()
+
+	defer fs.mu.Unlock()
+
+	var due []Delivery
+
+	for _, delivery := range fs.deliveries {
+		if !delivery.NextAttemptAt.After(now) {
+			due = append(due, delivery)
+		}
+	}
+
+	slices.SortFunc(due, func(a, b Delivery) int {
+		if c := a.NextAttemptAt.Compare(b.NextAttemptAt); c != 0 {
+			return c
+		}
+
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	return due, nil
+}
+
+// RetryDelivery Stores failed attempt, delivery which is not queued anymore is skipped
+func (fs *FileStorage) RetryDelivery(delivery Delivery) error {
+	fs.mu.Lock()
+
+	defer fs.mu.Unlock()
+
+	queued, ok := fs.deliveries[delivery.ID]
+
+	if !ok {
+		return nil
+	}
+
+	err := fs.persist([]string{
+		recordRetry,
+		delivery.ID,
+		strconv.Itoa(delivery.Attempts),
+		delivery.NextAttemptAt.UTC().Format(time.RFC3339Nano),
+		delivery.LastError,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	queued.Attempts = delivery.Attempts
+	queued.NextAttemptAt = delivery.NextAttemptAt
+	queued.LastError = delivery.LastError
+	fs.deliveries[delivery.ID] = queued
+
+	return nil
+}
+
+func (fs *FileStorage) RemoveDelivery(id string) error {
+	fs.mu.Lock()
+
+	defer fs.mu.Unlock()
+
+	if _, ok := fs.deliveries[id]; !ok {
+		return nil
+	}
+
+	if err := fs.persist([]string{recordDelivered, id}); err != nil {
+		return err
+	}
+
+	delete(fs.deliveries, id)
+
+	return nil
+}
+
+func (fs *FileStorage) restore() error {
+	file, err := os.Open(fs.filename)
+
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		return err
+	}
+
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+
+	for {
+		record, err := reader.Read()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if err = fs.restoreRecord(record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (fs *FileStorage) restoreRecord(record []string) error {
+	switch {
+	case record[0] == recordSubscription && len(record) == 6:
+		createdAt, err := time.Parse(time.RFC3339, record[5])
+
+		if err != nil {
+			return err
+		}
+
+		fs.subscriptions[record[1]] = Subscription{
+			ID:        record[1],
+			URL:       record[2],
+			Secret:    record[3],
+			Events:    strings.Fields(record[4]),
+			CreatedAt: createdAt,
+		}
+	case record[0] == recordUnsubscribe && len(record) == 2:
+		fs.unsubscribe(record[1])
+	case record[0] == recordDelivery && len(record) == 8:
+		attempts, err := strconv.Atoi(record[5])
+
+		if err != nil {
+			return err
+		}
+
+		next, err := time.Parse(time.RFC3339Nano, record[6])
+
+		if err != nil {
+			return err
+		}
+
+		fs.deliveries[record[1]] = Delivery{
+			ID:             record[1],
+			SubscriptionID: record[2],
+			Event:          record[3],
+			Payload:        record[4],
+			Attempts:       attempts,
+			NextAttemptAt:  next,
+			LastError:      record[7],
+		}
+	case record[0] == recordRetry && len(record) == 5:
+		delivery, ok := fs.deliveries[record[1]]
+
+		if !ok {
+			// delivery of deleted subscription
+			return nil
+		}
+
+		attempts, err := strconv.Atoi(record[2])
+
+		if err != nil {
+			return err
+		}
+
+		next, err := time.Parse(time.RFC3339Nano, record[3])
+
+		if err != nil {
+			return err
+		}
+
+		delivery.Attempts = attempts
+		delivery.NextAttemptAt = next
+		delivery.LastError = record[4]
+		fs.deliveries[delivery.ID] = delivery
+	case record[0] == recordDelivered && len(record) == 2:
+		delete(fs.deliveries, record[1])
+	default:
+		return errMalformed
+	}
+
+	return nil
+}
@@ -0,0 +1,149 @@
+package webhooks
+
+import (
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestFileStorageSubscriptions(t *testing.T) {
+	filename := t.TempDir() + "/webhooks.csv"
+	s, err := NewFileStorage(filename)
+
+	require.NoError(t, err)
+
+	first := Subscription{
+		ID:        "b2",
+		URL:       "https://hooks.example.com/links?team=a,b",
+		Secret:    "secret",
+		Events:    []string{EventLinkCreated, EventLinkDeleted},
+		CreatedAt: time.Date(2024, 2, 7, 12, 0, 0, 0, time.UTC),
+	}
+	second := Subscription{
+		ID:        "a1",
+		URL:       "https://example.org/hook",
+		Secret:    "other",
+		Events:    []string{EventLinkClicked},
+		CreatedAt: time.Date(2024, 2, 8, 12, 0, 0, 0, time.UTC),
+	}
+
+	require.NoError(t, s.StoreSubscription(second))
+	require.NoError(t, s.StoreSubscription(first))
+
+	subscriptions, err := s.ListSubscriptions()
+
+	require.NoError(t, err)
+	require.Equal(t, []Subscription{first, second}, subscriptions)
+
+	deleted, err := s.DeleteSubscription("a1")
+
+	require.NoError(t, err)
+	require.Equal(t, second, deleted)
+
+	_, err = s.DeleteSubscription("a1")
+
+	require.ErrorIs(t, err, ErrSubscriptionNotFound)
+
+	subscriptions, err = s.ListSubscriptions()
+
+	require.NoError(t, err)
+	require.Equal(t, []Subscription{first}, subscriptions)
+
+	data, err := os.ReadFile(filename)
+
+	require.NoError(t, err)
+	require.Equal(t, "subscription,a1,https://example.org/hook,other,link.clicked,2024-02-08T12:00:00Z\n"+
+		"subscription,b2,\"https://hooks.example.com/links?team=a,b\",secret,link.created link.deleted,2024-02-07T12:00:00Z\n"+
+		"unsubscribe,a1\n", string(data))
+
+	restored, err := NewFileStorage(filename)
+
+	require.NoError(t, err)
+	require.Equal(t, s.subscriptions, restored.subscriptions)
+}
+
+func TestFileStorageDeliveries(t *testing.T) {
+	filename := t.TempDir() + "/webhooks.csv"
+	s, err := NewFileStorage(filename)
+
+	require.NoError(t, err)
+
+	now := time.Date(2024, 2, 7, 12, 0, 0, 0, time.UTC)
+	subscription := Subscription{ID: "a1", URL: "https://hooks.example.com", Events: Events, CreatedAt: now}
+	other := Subscription{ID: "b2", URL: "https://example.org/hook", Events: Events, CreatedAt: now}
+
+	require.NoError(t, s.StoreSubscription(subscription))
+	require.NoError(t, s.StoreSubscription(other))
+
+	first := Delivery{ID: "d1", SubscriptionID: "a1", Event: EventLinkCreated, Payload: `{"key":"1"}`, NextAttemptAt: now}
+	second := Delivery{ID: "d2", SubscriptionID: "b2", Event: EventLinkCreated, Payload: `{"key":"1"}`, NextAttemptAt: now}
+	third := Delivery{ID: "d3", SubscriptionID: "a1", Event: EventLinkDeleted, Payload: `{"key":"2"}`, NextAttemptAt: now.Add(time.Second)}
+
+	require.NoError(t, s.EnqueueDeliveries([]Delivery{third, second, first}))
+
+	due, err := s.DueDeliveries(now, 10)
+
+	require.NoError(t, err)
+	require.Equal(t, []Delivery{first, second}, due)
+
+	due, err = s.DueDeliveries(now.Add(time.Second), 1)
+
+	require.NoError(t, err)
+	require.Equal(t, []Delivery{first}, due)
+
+	first.Attempts = 1
+	first.NextAttemptAt = now.Add(time.Minute)
+	first.LastError = "endpoint responded with status 500"
+
+	require.NoError(t, s.RetryDelivery(first))
+	require.NoError(t, s.RemoveDelivery("d3"))
+
+	due, err = s.DueDeliveries(now.Add(time.Minute), 10)
+
+	require.NoError(t, err)
+	require.Equal(t, []Delivery{second, first}, due)
+
+	// deliveries of deleted subscription are dropped
+	_, err = s.DeleteSubscription("b2")
+
+	require.NoError(t, err)
+
+	due, err = s.DueDeliveries(now.Add(time.Minute), 10)
+
+	require.NoError(t, err)
+	require.Equal(t, []Delivery{first}, due)
+
+	// delivery which is not queued anymore is skipped
+	require.NoError(t, s.RetryDelivery(second))
+	require.NoError(t, s.RemoveDelivery("d2"))
+
+	restored, err := NewFileStorage(filename)
+
+	require.NoError(t, err)
+	require.Equal(t, s.subscriptions, restored.subscriptions)
+	require.Equal(t, s.deliveries, restored.deliveries)
+}
+
+func TestFileStorageMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"Unknown record", "hook,a1\n"},
+		{"Short subscription", "subscription,a1,https://hooks.example.com\n"},
+		{"Short delivery", "delivery,d1,a1,link.created\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := t.TempDir() + "/webhooks.csv"
+
+			require.NoError(t, os.WriteFile(filename, []byte(tt.data), 0600))
+
+			_, err := NewFileStorage(filename)
+
+			require.EqualError(t, err, "file has malformed data")
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package webhooks
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/lib/pq"
+	"time"
+)
+
+const subscriptionColumns = "id, url, secret, events, created_at"
+const deliveryColumns = "id, subscription_id, event, payload, attempts, next_attempt_at, last_error"
+
+type SQLStorage struct {
+	db      *sql.DB
+	timeout time.Duration
+}
+
+func NewSQLStorage(db *sql.DB, timeout int) *SQLStorage {
+	return &SQLStorage{
+		db:      db,
+		timeout: time.Second * time.Duration(timeout),
+	}
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func (s *SQLStorage) scanSubscription(row rowScanner) (Subscription, error) {
+	var subscription Subscription
+
+	err := row.Scan(
+		&subscription.ID,
+		&subscription.URL,
+		&subscription.Secret,
+		pq.Array(&subscription.Events),
+		&subscription.CreatedAt,
+	)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return Subscription{}, ErrSubscriptionNotFound
+	}
+
+	subscription.CreatedAt = subscription.CreatedAt.UTC()
+
+	return subscription, err
+}
+
+func (s *SQLStorage) StoreSubscription(subscription Subscription) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+
+	defer cancel()
+
+	query := "INSERT INTO webhook_subscriptions(" + subscriptionColumns + ") VALUES ($1, $2, $3, $4, $5)"
+	_, err := s.db.ExecContext(
+		ctx,
+		query,
+		subscription.ID,
+		subscription.URL,
+		subscription.Secret,
+		pq.Array(subscription.Events),
+		subscription.CreatedAt,
+	)
+
+	return err
+}
+
+// ListSubscriptions Returns subscriptions, the oldest ones first
+func (s *SQLStorage) ListSubscriptions() ([]Subscription, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+
+	defer cancel()
+
+	query := "SELECT " + subscriptionColumns + " FROM webhook_subscriptions ORDER BY created_at, id"
+	rows, err := s.db.QueryContext(ctx, query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	subscriptions := []Subscription{}
+
+	for rows.Next() {
+		subscription, err := s.scanSubscription(rows)
+
+		if err != nil {
+			return nil, err
+		}
+
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	return subscriptions, rows.Err()
+}
+
+// DeleteSubscription Deletes subscription, its queued deliveries are deleted by foreign key
+func (s *SQLStorage) DeleteSubscription(id string) (Subscription, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+
+	defer cancel()
+
+	query := "DELETE FROM webhook_subscriptions WHERE id = $1 RETURNING " + subscriptionColumns
+
+	return s.scanSubscription(s.db.QueryRowContext(ctx, query, id))
+}
+
+func (s *SQLStorage) EnqueueDeliveries(deliveries []Delivery) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	query := "INSERT INTO webhook_deliveries(" + deliveryColumns + ") VALUES ($1, $2, $3, $4, $5, $6, $7)"
+
+	for _, delivery := range deliveries {
+		_, err = tx.ExecContext(
+			ctx,
+			query,
+			delivery.ID,
+			delivery.SubscriptionID,
+			delivery.Event,
+			delivery.Payload,
+			delivery.Attempts,
+			delivery.NextAttemptAt,
+			delivery.LastError,
+		)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *SQLStorage) DueDeliveries(now time.Time, limit int) ([]Delivery, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+
+	defer cancel()
+
+	query := "SELECT " + deliveryColumns + " FROM webhook_deliveries WHERE next_attempt_at <= $1 " +
+		"ORDER BY next_attempt_at, id LIMIT $2"
+	rows, err := s.db.QueryContext(ctx, query, now, limit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var deliveries []Delivery
+
+	for rows.Next() {
+		var delivery Delivery
+
+		err = rows.Scan(
+			&delivery.ID,
+			&delivery.SubscriptionID,
+			&delivery.Event,
+			&delivery.Payload,
+			&delivery.Attempts,
+			&delivery.NextAttemptAt,
+			&delivery.LastError,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		delivery.NextAttemptAt = delivery.NextAttemptAt.UTC()
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// RetryDelivery Stores failed attempt, delivery which is not queued anymore is skipped
+func (s *SQLStorage) RetryDelivery(delivery Delivery) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+
+	defer cancel()
+
+	query := "UPDATE webhook_deliveries SET attempts = $2, next_attempt_at = $3, last_error = $4 WHERE id = $1"
+	_, err := s.db.ExecContext(ctx, query, delivery.ID, delivery.Attempts, delivery.NextAttemptAt, delivery.LastError)
+
+	return err
+}
+
+func (s *SQLStorage) RemoveDelivery(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, "DELETE FROM webhook_deliveries WHERE id = $1", id)
+
+	return err
+}
@@ -0,0 +1,127 @@
+package webhooks
+
+import (
+	"database/sql"
+	"github.com/dzhdmitry/link-shorter/internal/db"
+	"github.com/dzhdmitry/link-shorter/test"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+type SQLStorageSuite struct {
+	suite.Suite
+	db *sql.DB
+}
+
+func (s *SQLStorageSuite) SetupSuite() {
+	openDB, err := db.OpenPostgres(test.PrepareTestDB(), 25, 25, "15m")
+
+	if err != nil {
+		panic(err)
+	}
+
+	s.db = openDB
+}
+
+func (s *SQLStorageSuite) SetupTest() {
+	_, _ = s.db.Exec("TRUNCATE webhook_subscriptions CASCADE")
+}
+
+func (s *SQLStorageSuite) TearDownSuite() {
+	if s.db != nil {
+		s.db.Close()
+	}
+}
+
+func (s *SQLStorageSuite) TestSubscriptions() {
+	storage := NewSQLStorage(s.db, 1)
+	first := Subscription{
+		ID:        "b2",
+		URL:       "https://hooks.example.com/links",
+		Secret:    "secret",
+		Events:    []string{EventLinkCreated, EventLinkDeleted},
+		CreatedAt: time.Date(2024, 2, 7, 12, 0, 0, 0, time.UTC),
+	}
+	second := Subscription{
+		ID:        "a1",
+		URL:       "https://example.org/hook",
+		Secret:    "other",
+		Events:    []string{EventLinkClicked},
+		CreatedAt: time.Date(2024, 2, 8, 12, 0, 0, 0, time.UTC),
+	}
+
+	s.NoError(storage.StoreSubscription(second))
+	s.NoError(storage.StoreSubscription(first))
+
+	subscriptions, err := storage.ListSubscriptions()
+
+	s.NoError(err)
+	s.Equal([]Subscription{first, second}, subscriptions)
+
+	deleted, err := storage.DeleteSubscription("a1")
+
+	s.NoError(err)
+	s.Equal(second, deleted)
+
+	_, err = storage.DeleteSubscription("a1")
+
+	s.ErrorIs(err, ErrSubscriptionNotFound)
+
+	subscriptions, err = storage.ListSubscriptions()
+
+	s.NoError(err)
+	s.Equal([]Subscription{first}, subscriptions)
+}
+
+func (s *SQLStorageSuite) TestDeliveries() {
+	storage := NewSQLStorage(s.db, 1)
+	now := time.Date(2024, 2, 7, 12, 0, 0, 0, time.UTC)
+
+	s.NoError(storage.StoreSubscription(Subscription{ID: "a1", URL: "https://hooks.example.com", Events: Events, CreatedAt: now}))
+	s.NoError(storage.StoreSubscription(Subscription{ID: "b2", URL: "https://example.org/hook", Events: Events, CreatedAt: now}))
+
+	first := Delivery{ID: "d1", SubscriptionID: "a1", Event: EventLinkCreated, Payload: `{"key":"1"}`, NextAttemptAt: now}
+	second := Delivery{ID: "d2", SubscriptionID: "b2", Event: EventLinkCreated, Payload: `{"key":"1"}`, NextAttemptAt: now}
+	third := Delivery{ID: "d3", SubscriptionID: "a1", Event: EventLinkDeleted, Payload: `{"key":"2"}`, NextAttemptAt: now.Add(time.Second)}
+
+	s.NoError(storage.EnqueueDeliveries([]Delivery{third, second, first}))
+
+	due, err := storage.DueDeliveries(now, 10)
+
+	s.NoError(err)
+	s.Equal([]Delivery{first, second}, due)
+
+	due, err = storage.DueDeliveries(now.Add(time.Second), 1)
+
+	s.NoError(err)
+	s.Equal([]Delivery{first}, due)
+
+	first.Attempts = 1
+	first.NextAttemptAt = now.Add(time.Minute)
+	first.LastError = "endpoint responded with status 500"
+
+	s.NoError(storage.RetryDelivery(first))
+	s.NoError(storage.RemoveDelivery("d3"))
+
+	due, err = storage.DueDeliveries(now.Add(time.Minute), 10)
+
+	s.NoError(err)
+	s.Equal([]Delivery{second, first}, due)
+
+	// deliveries of deleted subscription are dropped
+	_, err = storage.DeleteSubscription("b2")
+
+	s.NoError(err)
+
+	due, err = storage.DueDeliveries(now.Add(time.Minute), 10)
+
+	s.NoError(err)
+	s.Equal([]Delivery{first}, due)
+}
+
+func TestSQLStorage(t *testing.T) {
+	suite.Run(t, new(SQLStorageSuite))
+}
@@ -0,0 +1,124 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+const EventLinkCreated = "link.created"
+const EventLinkUpdated = "link.updated"
+const EventLinkDeleted = "link.deleted"
+const EventLinkClicked = "link.clicked"
+
+// Events All events webhooks can be subscribed to
+var Events = []string{EventLinkCreated, EventLinkUpdated, EventLinkDeleted, EventLinkClicked}
+
+var ErrSubscriptionNotFound = errors.New("webhook subscription not found")
+
+// SignatureHeader Header of delivery with "sha256=" and hex of HMAC-SHA256 of request body signed by secret of subscription
+const SignatureHeader = "X-Webhook-Signature"
+const EventHeader = "X-Webhook-Event"
+const DeliveryHeader = "X-Webhook-Delivery"
+
+// Subscription Endpoint which receives events of the listed types
+type Subscription struct {
+	ID        string
+	URL       string
+	Secret    string
+	Events    []string
+	CreatedAt time.Time
+}
+
+// Matches Reports whether subscription receives events of the type
+func (s Subscription) Matches(event string) bool {
+	return slices.Contains(s.Events, event)
+}
+
+// Delivery Event queued for sending to endpoint of subscription. Payload is JSON body of request,
+// attempts are failed attempts so far, delivery is sent again not earlier than NextAttemptAt
+type Delivery struct {
+	ID             string
+	SubscriptionID string
+	Event          string
+	Payload        string
+	Attempts       int
+	NextAttemptAt  time.Time
+	LastError      string
+}
+
+type StorageInterface interface {
+	StoreSubscription(subscription Subscription) error
+	ListSubscriptions() ([]Subscription, error)
+	// DeleteSubscription Returns ErrSubscriptionNotFound if there is no subscription by ID, queued deliveries of it are dropped
+	DeleteSubscription(id string) (Subscription, error)
+	EnqueueDeliveries(deliveries []Delivery) error
+	// DueDeliveries Returns up to limit deliveries which should be sent at now, the earliest ones first
+	DueDeliveries(now time.Time, limit int) ([]Delivery, error)
+	// RetryDelivery Stores attempts, time of the next attempt and error of delivery which failed
+	RetryDelivery(delivery Delivery) error
+	// RemoveDelivery Removes delivery from queue after it was sent or given up
+	RemoveDelivery(id string) error
+}
+
+// ValidateEvents Returns error if list is empty or contains unknown event
+func ValidateEvents(events []string) error {
+	if len(events) == 0 {
+		return errors.New("at least one event must be provided")
+	}
+
+	for _, event := range events {
+		if !slices.Contains(Events, event) {
+			return fmt.Errorf("unknown event: %s", event)
+		}
+	}
+
+	return nil
+}
+
+// Sign Returns value of SignatureHeader for body signed by secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// randomID Returns random hex string of n bytes
+func randomID(n int) (string, error) {
+	b := make([]byte, n)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// NewSubscription Makes subscription with random ID, secret is generated unless given
+func NewSubscription(URL, secret string, events []string, createdAt time.Time) (Subscription, error) {
+	id, err := randomID(6)
+
+	if err != nil {
+		return Subscription{}, err
+	}
+
+	if secret == "" {
+		if secret, err = randomID(24); err != nil {
+			return Subscription{}, err
+		}
+	}
+
+	return Subscription{
+		ID:        id,
+		URL:       URL,
+		Secret:    secret,
+		Events:    slices.Clone(events),
+		CreatedAt: createdAt.UTC(),
+	}, nil
+}
@@ -0,0 +1,64 @@
+package webhooks
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	// signature of the example of RFC 4231, test case 2
+	signature := Sign("Jefe", []byte("what do ya want for nothing?"))
+
+	require.Equal(t, "sha256=5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843", signature)
+	require.NotEqual(t, signature, Sign("jefe", []byte("what do ya want for nothing?")))
+}
+
+func TestValidateEvents(t *testing.T) {
+	tests := []struct {
+		name          string
+		events        []string
+		expectedError string
+	}{
+		{"All", Events, ""},
+		{"One", []string{EventLinkClicked}, ""},
+		{"Empty", []string{}, "at least one event must be provided"},
+		{"Unknown", []string{EventLinkCreated, "link.viewed"}, "unknown event: link.viewed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateEvents(tt.events)
+
+			if tt.expectedError == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tt.expectedError)
+			}
+		})
+	}
+}
+
+func TestNewSubscription(t *testing.T) {
+	createdAt := time.Date(2024, 2, 7, 12, 0, 0, 0, time.UTC)
+	subscription, err := NewSubscription("https://hooks.example.com", "", []string{EventLinkCreated}, createdAt)
+
+	require.NoError(t, err)
+	require.Len(t, subscription.ID, 12)
+	require.Len(t, subscription.Secret, 48)
+	require.Equal(t, Subscription{
+		ID:        subscription.ID,
+		URL:       "https://hooks.example.com",
+		Secret:    subscription.Secret,
+		Events:    []string{EventLinkCreated},
+		CreatedAt: createdAt,
+	}, subscription)
+	require.True(t, subscription.Matches(EventLinkCreated))
+	require.False(t, subscription.Matches(EventLinkClicked))
+
+	other, err := NewSubscription("https://hooks.example.com", "0123456789abcdef", []string{EventLinkCreated}, createdAt)
+
+	require.NoError(t, err)
+	require.NotEqual(t, subscription.ID, other.ID)
+	require.Equal(t, "0123456789abcdef", other.Secret)
+}
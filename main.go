@@ -19,9 +19,15 @@ import (
 // @license.url   https://github.com/dzhdmitry/link-shorter?tab=MIT-1-ov-file
 
 // @host      localhost:8080
+
+// @securityDefinitions.apikey  ApiKeyAuth
+// @in                          header
+// @name                        Authorization
+// @description                 API key as "Bearer <key>"
 func main() {
 	config := app.Config{}
-	logger := utils.NewLogger(os.Stdout, &utils.Clock{})
+	clock := &utils.Clock{}
+	logger := utils.NewLogger(os.Stdout, clock)
 	background := &utils.Background{}
 
 	if err := cleanenv.ReadConfig(".env", &config); err != nil {
@@ -31,24 +37,63 @@ func main() {
 
 	flag.StringVar(&config.ProjectHost, "host", config.ProjectHost, "Project server host")
 	flag.IntVar(&config.ProjectPort, "port", config.ProjectPort, "Project server port")
-	flag.StringVar(&config.ProjectStorageType, "storage", config.ProjectStorageType, "Storage type (file|postgres)")
+	flag.StringVar(&config.ProjectStorageType, "storage", config.ProjectStorageType, "Storage type (file|postgres|sqlite)")
 	flag.BoolVar(&config.FileAsync, "file-async", config.FileAsync, "File storage is asynchronous|synchronous (true|false)")
 	flag.StringVar(&config.DbDSN, "db-dsn", config.DbDSN, "PostgreSQL DSN")
 	flag.IntVar(&config.DbMaxOpenConns, "db-max-open-conns", config.DbMaxOpenConns, "PostgreSQL max open connections")
 	flag.IntVar(&config.DbMaxIdleConns, "db-max-idle-conns", config.DbMaxIdleConns, "PostgreSQL max idle connections")
 	flag.StringVar(&config.DbMaxIdleTime, "db-max-idle-time", config.DbMaxIdleTime, "PostgreSQL max connection idle time")
-	flag.IntVar(&config.DbTimeout, "db-timeout", config.DbTimeout, "PostgreSQL queries execution timeout")
+	flag.IntVar(&config.DbTimeout, "db-timeout", config.DbTimeout, "PostgreSQL and SQLite queries execution timeout")
+	flag.StringVar(&config.SQLitePath, "sqlite-path", config.SQLitePath, "SQLite database file")
 	flag.StringVar(&config.CacheType, "cache", config.CacheType, "Cache type (disabled|in-memory|redis)")
 	flag.IntVar(&config.CacheCapacity, "cache-cap", config.CacheCapacity, "Capacity of in-memory cache")
 	flag.StringVar(&config.CacheRedisDSN, "redis", config.CacheRedisDSN, "Redis DSN")
 	flag.BoolVar(&config.LimiterEnabled, "limiter", config.LimiterEnabled, "Rate limiter is enabled")
 	flag.IntVar(&config.LimiterRPS, "limiter-rps", config.LimiterRPS, "Rate limiter maximum RPS per IP")
 	flag.IntVar(&config.LimiterBurst, "limiter-burst", config.LimiterBurst, "Rate limiter maximum burst")
+	flag.BoolVar(&config.AuthEnabled, "auth", config.AuthEnabled, "API key is required to create and read links")
+	flag.StringVar(&config.AdminAPIKey, "admin-api-key", config.AdminAPIKey, "API key with admin scope, used to issue other keys")
+	flag.IntVar(&config.RedirectStatus, "redirect-status", config.RedirectStatus, "HTTP status of /go/:key redirect (301|302|307|308)")
+	flag.BoolVar(&config.DedupEnabled, "dedup", config.DedupEnabled, "Return existing key for already shortened URL")
+	flag.StringVar(&config.SplitSticky, "split-sticky", config.SplitSticky, "Sticky assignment of A/B split variant (cookie|hash)")
+	flag.StringVar(&config.BlocklistFile, "blocklist", config.BlocklistFile, "File with denied domains, suffixes, regular expressions and CIDRs of destinations, disabled if empty")
+	flag.StringVar(&config.BlocklistReload, "blocklist-reload-interval", config.BlocklistReload, "Interval of checking blocklist file for changes")
+	flag.BoolVar(&config.HealthCheck, "health-check", config.HealthCheck, "Destinations of links are checked in background")
+	flag.StringVar(&config.HealthInterval, "health-check-interval", config.HealthInterval, "Interval of checking destination of each link")
+	flag.StringVar(&config.HealthTimeout, "health-check-timeout", config.HealthTimeout, "Timeout of request to destination")
+	flag.IntVar(&config.HealthBatchSize, "health-check-batch", config.HealthBatchSize, "Number of links taken from storage for checking at once")
+	flag.StringVar(&config.FallbackURL, "fallback-url", config.FallbackURL, "URL visitors are sent to when destination is down and link has no fallback")
+	flag.BoolVar(&config.Unfurl, "unfurl", config.Unfurl, "Title, Open Graph properties and favicon of destinations of new links are fetched in background")
+	flag.StringVar(&config.UnfurlTimeout, "unfurl-timeout", config.UnfurlTimeout, "Timeout of request to destination page")
+	flag.IntVar(&config.UnfurlMaxSize, "unfurl-max-size", config.UnfurlMaxSize, "Maximum number of bytes of destination page read")
+	flag.StringVar(&config.WebhookTimeout, "webhook-timeout", config.WebhookTimeout, "Timeout of webhook delivery request")
+	flag.StringVar(&config.WebhookRetryDelay, "webhook-retry-delay", config.WebhookRetryDelay, "Delay before the first retry of failed webhook delivery, doubled after each failure")
+	flag.IntVar(&config.WebhookMaxAttempts, "webhook-max-attempts", config.WebhookMaxAttempts, "Attempts of webhook delivery before it is given up")
+	flag.IntVar(&config.WebhookBufferSize, "webhook-buffer", config.WebhookBufferSize, "Size of buffer of webhook events")
+	flag.IntVar(&config.ClicksBufferSize, "clicks-buffer", config.ClicksBufferSize, "Size of clicks buffer")
+	flag.IntVar(&config.ClicksBatchSize, "clicks-batch", config.ClicksBatchSize, "Maximum number of clicks stored at once")
+	flag.StringVar(&config.ClicksFlushTime, "clicks-flush-time", config.ClicksFlushTime, "Interval of storing buffered clicks")
+	flag.StringVar(&config.URLNormalize, "url-normalize", config.URLNormalize, "Comma-separated URL normalization steps (lowercase|default-port|dot-segments|punycode|sort-query|strip-tracking)")
+	flag.StringVar(&config.URLSchemes, "url-schemes", config.URLSchemes, "Comma-separated schemes of URLs allowed besides http and https, e.g. mailto")
+	flag.StringVar(&config.URLAllowInternal, "url-allow-internal", config.URLAllowInternal, "Comma-separated hosts and CIDRs allowed as destinations although they are internal")
+	flag.BoolVar(&config.URLResolveHosts, "url-resolve-hosts", config.URLResolveHosts, "Resolve hosts of URLs and reject ones pointing to internal addresses")
+	flag.StringVar(&config.URLTrackingParams, "url-tracking-params", config.URLTrackingParams, "Comma-separated query parameters removed by strip-tracking, \"*\" matches prefix")
+	flag.IntVar(&config.PasswordAttempts, "password-attempts", config.PasswordAttempts, "Failed password attempts allowed for a protected link at once")
+	flag.StringVar(&config.PasswordInterval, "password-attempts-interval", config.PasswordInterval, "Interval of restoring one failed password attempt")
+	flag.StringVar(&config.KeyAlphabet, "key-alphabet", config.KeyAlphabet, "Alphabet of generated keys (base36|base62|human-safe)")
+	flag.StringVar(&config.KeySecret, "key-secret", config.KeySecret, "Secret of non-enumerable keys, keys are sequential if empty")
+	flag.Int64Var(&config.KeySequentialMaxID, "key-sequential-max-id", config.KeySequentialMaxID, "Last link id which keeps sequential key, last stored id is recorded when secret is enabled if not set")
 	flag.Parse()
 
+	if err := config.Validate(); err != nil {
+		logger.LogError(err)
+		os.Exit(1)
+	}
+
 	Container := container.Container{
 		Logger:     logger,
 		Background: background,
+		Clock:      clock,
 	}
 
 	linksCollection, dbConn, rdb, err := Container.CreateLinksCollection(config)
@@ -66,12 +111,104 @@ func main() {
 		os.Exit(1)
 	}
 
+	clicksRecorder, err := Container.CreateClicksRecorder(config, dbConn)
+
+	if err != nil {
+		logger.LogError(err)
+		os.Exit(1)
+	}
+
+	stats, err := Container.CreateStats(config, dbConn)
+
+	if err != nil {
+		logger.LogError(err)
+		os.Exit(1)
+	}
+
+	apiKeys, err := Container.CreateAPIKeys(config, dbConn)
+
+	if err != nil {
+		logger.LogError(err)
+		os.Exit(1)
+	}
+
+	utmTemplates, err := Container.CreateUTMTemplates(config, dbConn)
+
+	if err != nil {
+		logger.LogError(err)
+		os.Exit(1)
+	}
+
+	blocklist, err := Container.CreateBlocklist(config)
+
+	if err != nil {
+		logger.LogError(err)
+		os.Exit(1)
+	}
+
+	urlPolicy, err := Container.CreateURLPolicy(config)
+
+	if err != nil {
+		logger.LogError(err)
+		os.Exit(1)
+	}
+
+	healthChecker, err := Container.CreateHealthChecker(config)
+
+	if err != nil {
+		logger.LogError(err)
+		os.Exit(1)
+	}
+
+	unfurler, err := Container.CreateUnfurler(config)
+
+	if err != nil {
+		logger.LogError(err)
+		os.Exit(1)
+	}
+
+	webhooksStorage, err := Container.CreateWebhooks(config, dbConn)
+
+	if err != nil {
+		logger.LogError(err)
+		os.Exit(1)
+	}
+
+	dispatcher, err := Container.CreateDispatcher(config)
+
+	if err != nil {
+		logger.LogError(err)
+		os.Exit(1)
+	}
+
+	normalizer, err := links.NewNormalizer(config.NormalizeSteps(), config.TrackingParams())
+
+	if err != nil {
+		logger.LogError(err)
+		os.Exit(1)
+	}
+
+	validator := app.NewValidator(links.Alphabets[config.KeyAlphabet])
+	validator.Blocklist = blocklist
+	validator.URLPolicy = urlPolicy
 	application := app.Application{
-		Config:     config,
-		Logger:     logger,
-		Validator:  *app.NewValidator(links.Letters),
-		Links:      linksCollection,
-		Background: background,
+		Config:        config,
+		Logger:        logger,
+		Clock:         clock,
+		Random:        &utils.Random{},
+		Validator:     *validator,
+		Normalizer:    normalizer,
+		Links:         linksCollection,
+		Clicks:        clicksRecorder,
+		Stats:         stats,
+		APIKeys:       apiKeys,
+		UTMTemplates:  utmTemplates,
+		Blocklist:     blocklist,
+		HealthChecker: healthChecker,
+		Unfurler:      unfurler,
+		Webhooks:      webhooksStorage,
+		Dispatcher:    dispatcher,
+		Background:    background,
 	}
 
 	logger.LogInfo(config.Info())
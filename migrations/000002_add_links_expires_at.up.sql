@@ -0,0 +1 @@
+ALTER TABLE links ADD COLUMN IF NOT EXISTS expires_at timestamp(0) with time zone NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE links ADD COLUMN IF NOT EXISTS alias varchar(64) NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS links_alias_idx ON links (alias);
@@ -0,0 +1,2 @@
+ALTER TABLE links DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE links DROP COLUMN IF EXISTS disabled;
@@ -0,0 +1,2 @@
+ALTER TABLE links ADD COLUMN IF NOT EXISTS disabled boolean NOT NULL DEFAULT false;
+ALTER TABLE links ADD COLUMN IF NOT EXISTS deleted_at timestamp(0) with time zone NULL;
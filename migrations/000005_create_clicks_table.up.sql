@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS clicks (
+    id bigserial PRIMARY KEY,
+    key varchar(64) NOT NULL,
+    clicked_at timestamptz NOT NULL,
+    referrer text NOT NULL DEFAULT '',
+    user_agent text NOT NULL DEFAULT '',
+    ip varchar(45) NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS clicks_key_clicked_at_idx ON clicks (key, clicked_at);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS settings (
+    name varchar(64) PRIMARY KEY,
+    value text NOT NULL
+);
+
+-- links created before alphabet became configurable have base36 keys
+INSERT INTO settings (name, value)
+SELECT 'alphabet', 'base36' WHERE EXISTS (SELECT 1 FROM links)
+ON CONFLICT (name) DO NOTHING;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS links_url_hash_idx;
+ALTER TABLE links DROP COLUMN IF EXISTS url_hash;
@@ -0,0 +1,12 @@
+ALTER TABLE links ADD COLUMN IF NOT EXISTS url_hash bytea NULL;
+
+-- the oldest active generated link of every URL and expiration is reused by deduplication
+UPDATE links SET url_hash = sha256(convert_to(
+    url || E'\n' || COALESCE(to_char(expires_at AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"'), ''),
+    'UTF8'
+))
+WHERE id IN (
+    SELECT min(id) FROM links WHERE alias IS NULL AND deleted_at IS NULL AND NOT disabled GROUP BY url, expires_at
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS links_url_hash_idx ON links (url_hash);
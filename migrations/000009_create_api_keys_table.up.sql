@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+    id varchar(32) PRIMARY KEY,
+    hash char(64) NOT NULL UNIQUE,
+    name varchar(255) NOT NULL DEFAULT '',
+    scopes text[] NOT NULL,
+    rps integer NOT NULL DEFAULT 0,
+    daily_quota integer NOT NULL DEFAULT 0,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    revoked_at timestamptz NULL
+);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS links_owner_created_at_idx;
+ALTER TABLE links DROP COLUMN IF EXISTS created_at;
+ALTER TABLE links DROP COLUMN IF EXISTS owner;
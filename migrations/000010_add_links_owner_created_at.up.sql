@@ -0,0 +1,6 @@
+ALTER TABLE links ADD COLUMN IF NOT EXISTS owner varchar(32) NOT NULL DEFAULT '';
+-- creation time of links stored before is unknown
+ALTER TABLE links ADD COLUMN IF NOT EXISTS created_at timestamptz NULL;
+
+CREATE INDEX IF NOT EXISTS links_owner_created_at_idx ON links (owner, (COALESCE(created_at, '0001-01-01 00:00:00+00')), id)
+    WHERE deleted_at IS NULL;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS links_tags_idx;
+ALTER TABLE links DROP COLUMN IF EXISTS notes;
+ALTER TABLE links DROP COLUMN IF EXISTS tags;
+ALTER TABLE links DROP COLUMN IF EXISTS description;
+ALTER TABLE links DROP COLUMN IF EXISTS title;
@@ -0,0 +1,6 @@
+ALTER TABLE links ADD COLUMN IF NOT EXISTS title varchar(255) NOT NULL DEFAULT '';
+ALTER TABLE links ADD COLUMN IF NOT EXISTS description text NOT NULL DEFAULT '';
+ALTER TABLE links ADD COLUMN IF NOT EXISTS tags text[] NOT NULL DEFAULT '{}';
+ALTER TABLE links ADD COLUMN IF NOT EXISTS notes text NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS links_tags_idx ON links USING GIN (tags) WHERE deleted_at IS NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE links DROP COLUMN IF EXISTS utm_template;
+DROP TABLE IF EXISTS utm_templates;
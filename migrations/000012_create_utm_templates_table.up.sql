@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS utm_templates (
+    name varchar(64) PRIMARY KEY,
+    source varchar(255) NOT NULL DEFAULT '',
+    medium varchar(255) NOT NULL DEFAULT '',
+    campaign varchar(255) NOT NULL DEFAULT '',
+    content varchar(255) NOT NULL DEFAULT ''
+);
+
+-- template is referenced by name, links of deleted template are followed without UTM parameters
+ALTER TABLE links ADD COLUMN IF NOT EXISTS utm_template varchar(64) NOT NULL DEFAULT '';
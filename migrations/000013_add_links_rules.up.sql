@@ -0,0 +1,2 @@
+-- ordered redirect rules, the first matching rule chooses destination instead of url
+ALTER TABLE links ADD COLUMN IF NOT EXISTS rules jsonb NOT NULL DEFAULT '[]';
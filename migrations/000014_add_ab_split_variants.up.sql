@@ -0,0 +1,4 @@
+ALTER TABLE links ADD COLUMN IF NOT EXISTS variants jsonb NOT NULL DEFAULT '[]';
+
+-- number of A/B split variant visitor is sent to, 0 for links without variants
+ALTER TABLE clicks ADD COLUMN IF NOT EXISTS variant smallint NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS links_health_checked_at_idx;
+ALTER TABLE links DROP COLUMN IF EXISTS health_checked_at;
+ALTER TABLE links DROP COLUMN IF EXISTS health_error;
+ALTER TABLE links DROP COLUMN IF EXISTS health_redirects;
+ALTER TABLE links DROP COLUMN IF EXISTS health_status;
@@ -0,0 +1,7 @@
+-- result of the last check of destination, health_checked_at is NULL until link is checked
+ALTER TABLE links ADD COLUMN IF NOT EXISTS health_status smallint NOT NULL DEFAULT 0;
+ALTER TABLE links ADD COLUMN IF NOT EXISTS health_redirects jsonb NOT NULL DEFAULT '[]';
+ALTER TABLE links ADD COLUMN IF NOT EXISTS health_error text NOT NULL DEFAULT '';
+ALTER TABLE links ADD COLUMN IF NOT EXISTS health_checked_at timestamptz NULL;
+
+CREATE INDEX IF NOT EXISTS links_health_checked_at_idx ON links (health_checked_at NULLS FIRST, id) WHERE deleted_at IS NULL;
@@ -0,0 +1,4 @@
+-- url visitors are sent to when destination is down, empty if global fallback is used
+ALTER TABLE links ADD COLUMN IF NOT EXISTS fallback text NOT NULL DEFAULT '';
+-- destination is marked as down regardless of its health
+ALTER TABLE links ADD COLUMN IF NOT EXISTS down boolean NOT NULL DEFAULT false;
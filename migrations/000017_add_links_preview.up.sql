@@ -0,0 +1,2 @@
+-- title, Open Graph properties and favicon of destination page, NULL until page is fetched
+ALTER TABLE links ADD COLUMN IF NOT EXISTS preview jsonb NULL;
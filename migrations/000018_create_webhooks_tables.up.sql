@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id varchar(32) PRIMARY KEY,
+    url text NOT NULL,
+    secret varchar(255) NOT NULL,
+    events text[] NOT NULL,
+    created_at timestamptz NOT NULL
+);
+
+-- queue of deliveries, they are removed when sent, given up or subscription is deleted
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id varchar(32) PRIMARY KEY,
+    subscription_id varchar(32) NOT NULL REFERENCES webhook_subscriptions (id) ON DELETE CASCADE,
+    event varchar(64) NOT NULL,
+    payload text NOT NULL,
+    attempts integer NOT NULL DEFAULT 0,
+    next_attempt_at timestamptz NOT NULL,
+    last_error text NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_next_attempt_at_idx ON webhook_deliveries (next_attempt_at);
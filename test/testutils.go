@@ -28,6 +28,15 @@ func (t *Clock) Now() time.Time {
 	return time.Date(2024, 2, 7, 12, 0, 0, 0, location)
 }
 
+// Random Returns the same value every time
+type Random struct {
+	Value uint64
+}
+
+func (r *Random) Uint64() uint64 {
+	return r.Value
+}
+
 func resolveTestDSNs() (string, string) {
 	defaultDsn := os.Getenv("TEST_DEFAULT_DSN")
 